@@ -0,0 +1,171 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrDestructiveOperationDenied is returned by DeleteTable and Truncate when the guard of the store doesn't allow them.
+var ErrDestructiveOperationDenied = errors.New("destructive operation denied")
+
+// DestructiveGuard allows DeleteTable and Truncate on the tables of test environments and tear-down tooling.
+// Without guard, the store denies them.
+type DestructiveGuard struct {
+	// TableNamePatterns are the names of the tables allowed, as path.Match patterns such as "test-*".
+	// "*" allows all the tables. No table is allowed if empty.
+	TableNamePatterns []string
+}
+
+// allowed returns whether the guard allows the destructive operations on tableName.
+func (g *DestructiveGuard) allowed(tableName string) bool {
+	if g == nil {
+		return false
+	}
+
+	for _, pattern := range g.TableNamePatterns {
+		if ok, err := path.Match(pattern, tableName); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDestructive returns ErrDestructiveOperationDenied if the guard of the store doesn't allow operation.
+func (ddb *Store) checkDestructive(operation string) error {
+	if !ddb.destructiveGuard.allowed(ddb.tableName) {
+		return fmt.Errorf("%w: %s of table %s", ErrDestructiveOperationDenied, operation, ddb.tableName)
+	}
+
+	return nil
+}
+
+// DeleteTable deletes the table with all its items, and waits for it to be deleted.
+// The guard of the store (Config.DestructiveGuard) must allow it.
+// The values stored in S3 are left to the lifecycle rules of the bucket.
+// It runs through the middlewares as an OperationDeleteTable, without key.
+func (ddb *Store) DeleteTable(ctx context.Context) error {
+	return ddb.runOperation(ctx, &Operation{Name: OperationDeleteTable}, func(ctx context.Context, _ *Operation) error {
+		return ddb.deleteTable(ctx)
+	})
+}
+
+// deleteTable deletes the table, if the guard of the store allows it.
+func (ddb *Store) deleteTable(ctx context.Context) error {
+	if err := ddb.checkDestructive("DeleteTable"); err != nil {
+		return err
+	}
+
+	_, err := ddb.dynamoSvc.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ddb.dynamoSvc.WaitUntilTableNotExistsWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+}
+
+// Truncate deletes all the items of the table, keeping the table with its settings, stream and auto scaling.
+// The keys of the table are scanned, in ScanSegments concurrent segments, and deleted in batches,
+// including the items of the other stores sharing the table, and the expired items.
+// The guard of the store (Config.DestructiveGuard) must allow it.
+// It runs through the middlewares as an OperationTruncate, without key.
+func (ddb *Store) Truncate(ctx context.Context) error {
+	return ddb.runOperation(ctx, &Operation{Name: OperationTruncate}, func(ctx context.Context, _ *Operation) error {
+		return ddb.truncate(ctx)
+	})
+}
+
+// truncate deletes all the items of the table, if the guard of the store allows it.
+func (ddb *Store) truncate(ctx context.Context) error {
+	if err := ddb.checkDestructive("Truncate"); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.DeleteTree)
+	defer cancel()
+
+	si := &dynamodb.ScanInput{
+		TableName: aws.String(ddb.tableName),
+	}
+
+	// only the keys are read, unless the values in S3 must be deleted too.
+	if ddb.s3Overflow == nil {
+		_, keySchema := ddb.keySchema()
+
+		names := make(map[string]*string, len(keySchema))
+		projection := ""
+
+		for i, elem := range keySchema {
+			placeholder := fmt.Sprintf("#k%d", i)
+			names[placeholder] = elem.AttributeName
+
+			if projection != "" {
+				projection += ", "
+			}
+			projection += placeholder
+		}
+
+		si.ProjectionExpression = aws.String(projection)
+		si.ExpressionAttributeNames = names
+	}
+
+	var (
+		items []map[string]*dynamodb.AttributeValue
+		err   error
+	)
+
+	if ddb.scanSegments > 1 {
+		items, err = ddb.parallelScan(ctx, si, ddb.scanSegments)
+	} else {
+		items, err = ddb.scanPages(ctx, si)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	requests := make([]*dynamodb.WriteRequest, len(items))
+	for n, item := range items {
+		requests[n] = &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{Key: ddb.primaryKey(item)},
+		}
+	}
+
+	err = ddb.retryDeleteTree(ctx, map[string][]*dynamodb.WriteRequest{ddb.tableName: requests})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err = ddb.deleteS3Object(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// primaryKey returns the primary key attributes of item, as stored.
+func (ddb *Store) primaryKey(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	_, keySchema := ddb.keySchema()
+
+	key := make(map[string]*dynamodb.AttributeValue, len(keySchema))
+	for _, elem := range keySchema {
+		name := aws.StringValue(elem.AttributeName)
+		key[name] = item[name]
+	}
+
+	return key
+}
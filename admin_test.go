@@ -0,0 +1,63 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestructiveOperations(t *testing.T) {
+	mock := &mockedTruncate{mockedBatchStore: &mockedBatchStore{failBatch: -1}, items: []map[string]*dynamodb.AttributeValue{
+		newTestItem("foo", "YmFy"),
+		newTestItem("other/foo", "YmF6"),
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	// the operations are denied without guard.
+	assert.ErrorIs(t, kv.Truncate(ctx), ErrDestructiveOperationDenied)
+	assert.ErrorIs(t, kv.DeleteTable(ctx), ErrDestructiveOperationDenied)
+
+	kv.destructiveGuard = &DestructiveGuard{TableNamePatterns: []string{"prod-*"}}
+	assert.ErrorIs(t, kv.Truncate(ctx), ErrDestructiveOperationDenied)
+
+	kv.destructiveGuard.TableNamePatterns = append(kv.destructiveGuard.TableNamePatterns, TestTableName)
+
+	require.NoError(t, kv.Truncate(ctx))
+	assert.ElementsMatch(t, []string{"foo", "other/foo"}, mock.deleted)
+	// only the keys are scanned.
+	assert.Equal(t, "#k0", aws.StringValue(mock.scan.ProjectionExpression))
+
+	require.NoError(t, kv.DeleteTable(ctx))
+	assert.True(t, mock.tableDeleted)
+}
+
+// mockedTruncate scans items, records the batch deletes, and the deletion of the table.
+type mockedTruncate struct {
+	*mockedBatchStore
+
+	items        []map[string]*dynamodb.AttributeValue
+	scan         *dynamodb.ScanInput
+	tableDeleted bool
+}
+
+func (m *mockedTruncate) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	m.scan = input
+	fn(&dynamodb.ScanOutput{Items: m.items}, true)
+	return nil
+}
+
+func (m *mockedTruncate) DeleteTableWithContext(_ aws.Context, _ *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	m.tableDeleted = true
+	return &dynamodb.DeleteTableOutput{}, nil
+}
+
+func (m *mockedTruncate) WaitUntilTableNotExistsWithContext(_ aws.Context, _ *dynamodb.DescribeTableInput, _ ...request.WaiterOption) error {
+	return nil
+}
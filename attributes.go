@@ -0,0 +1,141 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// The placeholders of the configurable attribute names in the expressions,
+// so the names may be DynamoDB reserved words.
+const (
+	keyNamePlaceholder      = "#key"
+	revisionNamePlaceholder = "#revision"
+	valueNamePlaceholder    = "#value"
+	ttlNamePlaceholder      = "#ttl"
+)
+
+var (
+	// ErrAttributeNameConflict is returned when an attribute name is used twice, or by an internal attribute.
+	ErrAttributeNameConflict = errors.New("attribute name conflict")
+	// ErrPartitionKeyNameMissing is returned when a partition key value is configured without its attribute name.
+	ErrPartitionKeyNameMissing = errors.New("missing partition key name")
+)
+
+// AttributeNames overrides the names of the item attributes, to use a table written by another application.
+// The empty names keep their default.
+type AttributeNames struct {
+	// Key is the attribute holding the key, the partition key of the table ("id" by default),
+	// or its sort key with the directory layout.
+	Key string
+
+	// Revision is the number attribute holding the revision of the key ("version" by default).
+	Revision string
+
+	// Value is the attribute holding the value ("encoded_value" by default).
+	Value string
+
+	// ExpirationTime is the number attribute holding the expiration time of the key,
+	// in Unix seconds ("expiration_time" by default).
+	ExpirationTime string
+}
+
+// attributeNames returns the attribute names of the configuration,
+// with the sort key of the composite primary key as key attribute.
+func (c *Config) attributeNames() (AttributeNames, error) {
+	names := c.AttributeNames
+
+	if c.PartitionKeyName == "" && (c.PartitionKeyValue != "" || c.PartitionKeyFunc != nil) {
+		return names, ErrPartitionKeyNameMissing
+	}
+
+	if c.SortKeyName != "" {
+		if names.Key != "" && names.Key != c.SortKeyName {
+			return names, fmt.Errorf("%w: key %s and sort key %s", ErrAttributeNameConflict, names.Key, c.SortKeyName)
+		}
+		names.Key = c.SortKeyName
+	}
+
+	return names, names.validate(c.PartitionKeyName)
+}
+
+// validate checks the names, and the other attribute names if any, are distinct,
+// and are not used by an internal attribute.
+func (n AttributeNames) validate(others ...string) error {
+	used := map[string]bool{
+		compressionAttribute:     true,
+		encryptionKeyAttribute:   true,
+		encryptionNonceAttribute: true,
+		checksumAttribute:        true,
+		writerRegionAttribute:    true,
+		chunksAttribute:          true,
+		chunkIDAttribute:         true,
+		s3ObjectAttribute:        true,
+		directoryAttribute:       true,
+		prefixAttribute:          true,
+		createdAtAttribute:       true,
+		updatedAtAttribute:       true,
+
+		lockOwnerAttribute:      true,
+		lockHostnameAttribute:   true,
+		lockPIDAttribute:        true,
+		lockAcquiredAtAttribute: true,
+	}
+
+	names := append([]string{
+		nameOrDefault(n.Key, partitionKey),
+		nameOrDefault(n.Revision, revisionAttribute),
+		nameOrDefault(n.Value, encodedValueAttribute),
+		nameOrDefault(n.ExpirationTime, ttlAttribute),
+	}, others...)
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if used[name] {
+			return fmt.Errorf("%w: %s", ErrAttributeNameConflict, name)
+		}
+		used[name] = true
+	}
+
+	return nil
+}
+
+func nameOrDefault(name, defaultName string) string {
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
+
+// keyName returns the name of the key attribute.
+func (ddb *Store) keyName() string {
+	return nameOrDefault(ddb.attributeNames.Key, partitionKey)
+}
+
+// revisionName returns the name of the revision attribute.
+func (ddb *Store) revisionName() string {
+	return nameOrDefault(ddb.attributeNames.Revision, revisionAttribute)
+}
+
+// valueName returns the name of the value attribute.
+func (ddb *Store) valueName() string {
+	return nameOrDefault(ddb.attributeNames.Value, encodedValueAttribute)
+}
+
+// ttlName returns the name of the expiration time attribute.
+func (ddb *Store) ttlName() string {
+	return nameOrDefault(ddb.attributeNames.ExpirationTime, ttlAttribute)
+}
+
+// itemKey returns the key of an item, without the key prefix of the store.
+func (ddb *Store) itemKey(item map[string]*dynamodb.AttributeValue) string {
+	if v, ok := item[ddb.keyName()]; ok {
+		return strings.TrimPrefix(aws.StringValue(v.S), ddb.keyPrefix)
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeNamesValidate(t *testing.T) {
+	assert.NoError(t, AttributeNames{}.validate())
+	assert.NoError(t, AttributeNames{Key: "pk", Revision: "rev", Value: "value", ExpirationTime: "ttl"}.validate())
+
+	assert.ErrorIs(t, AttributeNames{Value: "id"}.validate(), ErrAttributeNameConflict)
+	assert.ErrorIs(t, AttributeNames{Revision: "ttl", ExpirationTime: "ttl"}.validate(), ErrAttributeNameConflict)
+	assert.ErrorIs(t, AttributeNames{Key: chunksAttribute}.validate(), ErrAttributeNameConflict)
+
+	_, err := New(context.Background(), nil, &Config{Bucket: TestTableName, AttributeNames: AttributeNames{Key: "version"}})
+	assert.ErrorIs(t, err, ErrAttributeNameConflict)
+}
+
+func TestAttributeNames(t *testing.T) {
+	kv := &Store{attributeNames: AttributeNames{Key: "pk", Revision: "rev", Value: "value", ExpirationTime: "ttl"}}
+
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("key")}}, kv.keyAttributes("key"))
+
+	item := map[string]*dynamodb.AttributeValue{
+		"pk":    {S: aws.String("key")},
+		"rev":   {N: aws.String("3")},
+		"value": {S: aws.String("dmFsdWU=")},
+		"ttl":   {N: aws.String("1")},
+	}
+
+	pair, err := kv.decodeItem(item)
+	require.NoError(t, err)
+	assert.Equal(t, &store.KVPair{Key: "key", Value: []byte("value"), LastIndex: 3}, pair)
+	assert.True(t, kv.isItemExpired(item))
+
+	_, _, exNames := kv.writeUpdate(kv.valueAttributes([]byte("value"), valueEncoding{}), &store.WriteOptions{TTL: time.Minute})
+	assert.Equal(t, "rev", aws.StringValue(exNames[revisionNamePlaceholder]))
+	assert.Equal(t, "ttl", aws.StringValue(exNames[ttlNamePlaceholder]))
+	assert.Equal(t, "value", aws.StringValue(exNames["#attr7"]))
+
+	exNames = make(map[string]*string)
+	kv.atomicPutCondition(nil, make(map[string]*dynamodb.AttributeValue), exNames)
+	assert.Equal(t, map[string]string{keyNamePlaceholder: "pk", ttlNamePlaceholder: "ttl"}, aws.StringValueMap(exNames))
+}
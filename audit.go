@@ -0,0 +1,183 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// The attributes of the items of the audit tables.
+const (
+	AuditAttributeKey       = "key"
+	AuditAttributeTime      = "time"
+	AuditAttributeOperation = "operation"
+	AuditAttributeRevision  = "revision"
+	AuditAttributeActor     = "actor"
+)
+
+// ErrAuditSinkMissing is returned by New when the audit is configured without sink.
+var ErrAuditSinkMissing = errors.New("audit sink is missing")
+
+// AuditConfig configures the audit of the mutations of the store:
+// each operation writing or deleting keys which succeeded is recorded to Sink, see isMutation,
+// with a record by key for the writes of several keys, such as a batch of DeleteMany, Commit, or Move.
+// The audit runs as the innermost middleware, so it records the keys as written.
+type AuditConfig struct {
+	// Sink records the mutations, such as NewTableAuditSink, NewLogsAuditSink, or an AuditFunc.
+	Sink AuditSink
+
+	// Actor identifies the writer of the store in the records, such as the name of the service.
+	Actor string
+
+	// FailOnError returns the errors of Sink from the operations, which were applied.
+	// The errors are logged otherwise.
+	FailOnError bool
+}
+
+// AuditRecord is a mutation of the store.
+type AuditRecord struct {
+	// Operation is the name of the operation, such as OperationPut.
+	Operation string
+	// Key is the key mutated, the prefix of DeleteTree, or empty for Truncate and DeleteTable.
+	Key string
+	// Revision is the revision written by the operations writing values, such as Put or PutMany,
+	// 0 for the deletes, Commit, and Move.
+	Revision uint64
+	Actor    string
+	Time     time.Time
+}
+
+// AuditSink records the mutations of the store.
+type AuditSink interface {
+	Audit(ctx context.Context, record *AuditRecord) error
+}
+
+// AuditFunc is an AuditSink calling a function.
+type AuditFunc func(ctx context.Context, record *AuditRecord) error
+
+// Audit calls f.
+func (f AuditFunc) Audit(ctx context.Context, record *AuditRecord) error {
+	return f(ctx, record)
+}
+
+// auditMiddleware returns the middleware recording the mutations which succeeded to the sink of config.
+func (ddb *Store) auditMiddleware(config *AuditConfig) Middleware {
+	return func(next OperationFunc) OperationFunc {
+		return func(ctx context.Context, op *Operation) error {
+			err := next(ctx, op)
+			if err != nil || !isMutation(op.Name) {
+				return err
+			}
+
+			keys := op.Keys
+			if keys == nil {
+				keys = []string{op.Key}
+			}
+
+			now := time.Now()
+
+			for _, key := range keys {
+				record := &AuditRecord{Operation: op.Name, Key: key, Actor: config.Actor, Time: now}
+				if op.Pair != nil {
+					record.Revision = op.Pair.LastIndex
+				}
+
+				if err = config.Sink.Audit(ctx, record); err != nil {
+					if config.FailOnError {
+						return err
+					}
+
+					ddb.log().Error("audit record failed", "key", key, "operation", op.Name, "error", err)
+				}
+			}
+
+			return nil
+		}
+	}
+}
+
+// isMutation reports whether the operation name writes or deletes keys.
+func isMutation(name string) bool {
+	switch name {
+	case OperationPut, OperationAtomicPut, OperationDelete, OperationAtomicDelete, OperationDeleteTree,
+		OperationPutMany, OperationDeleteMany, OperationCopy, OperationCopyTree, OperationImport, OperationMigrate,
+		OperationPatchJSON, OperationMove, OperationCommit, OperationTruncate, OperationDeleteTable:
+		return true
+	default:
+		return false
+	}
+}
+
+// tableAuditSink writes the records to a DynamoDB table.
+type tableAuditSink struct {
+	client    dynamodbiface.DynamoDBAPI
+	tableName string
+}
+
+// NewTableAuditSink returns an AuditSink writing the records to a DynamoDB table,
+// with the AuditAttributeKey (S) hash key, and the AuditAttributeTime (N, Unix nanoseconds) range key.
+func NewTableAuditSink(client dynamodbiface.DynamoDBAPI, tableName string) AuditSink {
+	return &tableAuditSink{client: client, tableName: tableName}
+}
+
+func (s *tableAuditSink) Audit(ctx context.Context, record *AuditRecord) error {
+	item := map[string]*dynamodb.AttributeValue{
+		AuditAttributeKey:       {S: aws.String(record.Key)},
+		AuditAttributeTime:      {N: aws.String(strconv.FormatInt(record.Time.UnixNano(), 10))},
+		AuditAttributeOperation: {S: aws.String(record.Operation)},
+		AuditAttributeRevision:  {N: aws.String(strconv.FormatUint(record.Revision, 10))},
+	}
+	if record.Actor != "" {
+		item[AuditAttributeActor] = &dynamodb.AttributeValue{S: aws.String(record.Actor)}
+	}
+
+	_, err := s.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      item,
+	})
+
+	return err
+}
+
+// logsAuditSink writes the records to a CloudWatch Logs stream.
+type logsAuditSink struct {
+	client cloudwatchlogsiface.CloudWatchLogsAPI
+	group  string
+	stream string
+}
+
+// NewLogsAuditSink returns an AuditSink writing the records as JSON events to an existing CloudWatch Logs stream.
+func NewLogsAuditSink(client cloudwatchlogsiface.CloudWatchLogsAPI, group, stream string) AuditSink {
+	return &logsAuditSink{client: client, group: group, stream: stream}
+}
+
+func (s *logsAuditSink) Audit(ctx context.Context, record *AuditRecord) error {
+	message, err := json.Marshal(map[string]interface{}{
+		AuditAttributeKey:       record.Key,
+		AuditAttributeTime:      record.Time.Format(time.RFC3339Nano),
+		AuditAttributeOperation: record.Operation,
+		AuditAttributeRevision:  record.Revision,
+		AuditAttributeActor:     record.Actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.PutLogEventsWithContext(ctx, &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+		LogEvents: []*cloudwatchlogs.InputLogEvent{
+			{Message: aws.String(string(message)), Timestamp: aws.Int64(record.Time.UnixMilli())},
+		},
+	})
+
+	return err
+}
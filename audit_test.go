@@ -0,0 +1,316 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	var records []*AuditRecord
+
+	sink := AuditFunc(func(_ context.Context, record *AuditRecord) error {
+		records = append(records, record)
+		return nil
+	})
+
+	kv := &Store{dynamoSvc: &mockedRegion{}, tableName: TestTableName}
+	kv.middlewares = []Middleware{kv.auditMiddleware(&AuditConfig{Sink: sink, Actor: "deployer"})}
+
+	ctx := context.Background()
+
+	require.NoError(t, kv.Put(ctx, "key", []byte("value"), nil))
+
+	// the reads and the failed writes are not recorded.
+	_, err := kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	kv.dynamoSvc = &mockedConditionalDelete{failed: true}
+	_, err = kv.AtomicDelete(ctx, "key", &store.KVPair{Key: "key", LastIndex: 1})
+	require.Error(t, err)
+
+	require.Len(t, records, 1)
+	assert.Equal(t, OperationPut, records[0].Operation)
+	assert.Equal(t, "key", records[0].Key)
+	assert.Equal(t, uint64(1), records[0].Revision)
+	assert.Equal(t, "deployer", records[0].Actor)
+	assert.WithinDuration(t, time.Now(), records[0].Time, time.Minute)
+
+	_, err = New(ctx, nil, &Config{
+		Bucket:         TestTableName,
+		DynamoDBClient: &mockedDescribeTable{},
+		StreamsClient:  &mockedStreams{},
+		Audit:          &AuditConfig{Actor: "deployer"},
+	})
+	assert.ErrorIs(t, err, ErrAuditSinkMissing)
+}
+
+// TestAuditBatch checks PutMany and DeleteMany record each key written, with its revision.
+func TestAuditBatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		records []*AuditRecord
+	)
+
+	mock := &mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"b": newTestItem("b", "dmFsdWU="),
+		},
+		failBatch: -1,
+	}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+	kv.middlewares = []Middleware{kv.auditMiddleware(&AuditConfig{Sink: AuditFunc(func(_ context.Context, record *AuditRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		records = append(records, record)
+		return nil
+	})})}
+
+	ctx := context.Background()
+
+	err := kv.PutMany(ctx, []*store.KVPair{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}}, nil)
+	require.NoError(t, err)
+
+	// the keys are written concurrently.
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+
+	require.Len(t, records, 2)
+	assert.Equal(t, &AuditRecord{Operation: OperationPutMany, Key: "a", Revision: 1, Time: records[0].Time}, records[0])
+	assert.Equal(t, &AuditRecord{Operation: OperationPutMany, Key: "b", Revision: 2, Time: records[1].Time}, records[1])
+
+	// the keys of a failed batch are not recorded.
+	mock.failBatch = mock.batches
+
+	err = kv.DeleteMany(ctx, []string{"a", "b"})
+	assert.Error(t, err)
+	assert.Len(t, records, 2)
+
+	require.NoError(t, kv.DeleteMany(ctx, []string{"a", "b"}))
+	require.Len(t, records, 4)
+	assert.Equal(t, OperationDeleteMany, records[2].Operation)
+	assert.Equal(t, []string{"a", "b"}, []string{records[2].Key, records[3].Key})
+	assert.Zero(t, records[3].Revision)
+}
+
+func TestAuditSinks(t *testing.T) {
+	record := &AuditRecord{Operation: OperationDelete, Key: "key", Actor: "deployer", Time: time.Unix(1700000000, 5)}
+
+	ctx := context.Background()
+
+	table := &mockedAuditTable{}
+	require.NoError(t, NewTableAuditSink(table, "audit").Audit(ctx, record))
+	assert.Equal(t, "audit", aws.StringValue(table.input.TableName))
+	assert.Equal(t, "1700000000000000005", aws.StringValue(table.input.Item[AuditAttributeTime].N))
+	assert.Equal(t, "deployer", aws.StringValue(table.input.Item[AuditAttributeActor].S))
+
+	logs := &mockedAuditLogs{}
+	require.NoError(t, NewLogsAuditSink(logs, "group", "stream").Audit(ctx, record))
+	require.Len(t, logs.input.LogEvents, 1)
+
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(aws.StringValue(logs.input.LogEvents[0].Message)), &event))
+	assert.Equal(t, OperationDelete, event[AuditAttributeOperation])
+	assert.Equal(t, int64(1700000000000), aws.Int64Value(logs.input.LogEvents[0].Timestamp))
+}
+
+// mockedAuditTable records the item written.
+type mockedAuditTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	input *dynamodb.PutItemInput
+}
+
+func (m *mockedAuditTable) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.input = input
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// mockedAuditLogs records the events written.
+type mockedAuditLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+
+	input *cloudwatchlogs.PutLogEventsInput
+}
+
+func (m *mockedAuditLogs) PutLogEventsWithContext(_ aws.Context, input *cloudwatchlogs.PutLogEventsInput, _ ...request.Option) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	m.input = input
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+// TestAuditMutations checks each method of the store writing or deleting keys is audited:
+// a new method must be added to the audited ones, or to the ones not writing keys.
+func TestAuditMutations(t *testing.T) {
+	notMutating := map[string]bool{
+		// reads.
+		"BatchGet": true, "Exists": true, "Export": true, "ExportToS3": true, "Get": true, "GetAt": true,
+		"GetLockInfo": true, "GetMany": true, "GetMeta": true, "GetWithMeta": true, "List": true, "ListDirectory": true,
+		"ListPage": true, "ListStream": true, "ListVersions": true, "Watch": true, "WatchEvents": true, "WatchEventsWithOptions": true, "WatchTree": true,
+		"WatchTreeWithOptions": true, "WatchWithOptions": true,
+		// the table, and the store itself.
+		"ActiveRegion": true, "Close": true, "EnsureTTL": true, "EnsureTable": true, "Ping": true, "Stats": true,
+		"TableInfo": true, "UpdateEncryption": true,
+		// the history table, and the expired keys.
+		"CompactHistory": true, "DeleteExpired": true,
+		// the locks write their keys with AtomicPut and AtomicDelete, the leases and the semaphores their own items.
+		"AcquireLease": true, "NewLock": true, "NewSemaphore": true,
+	}
+
+	audited := map[string]func(ctx context.Context, kv *Store) error{
+		"Put": func(ctx context.Context, kv *Store) error {
+			return kv.Put(ctx, "a", []byte("value"), nil)
+		},
+		"PutWithResult": func(ctx context.Context, kv *Store) error {
+			_, err := kv.PutWithResult(ctx, "a", []byte("value"), nil)
+			return err
+		},
+		"AtomicPut": func(ctx context.Context, kv *Store) error {
+			_, _, err := kv.AtomicPut(ctx, "new", []byte("value"), nil, nil)
+			return err
+		},
+		"PutIfNotExists": func(ctx context.Context, kv *Store) error {
+			_, err := kv.PutIfNotExists(ctx, "new", []byte("value"), nil)
+			return err
+		},
+		"PutIfValueEquals": func(ctx context.Context, kv *Store) error {
+			_, err := kv.PutIfValueEquals(ctx, "app/a", []byte("value"), []byte("value1"), nil)
+			return err
+		},
+		"Delete": func(ctx context.Context, kv *Store) error {
+			return kv.Delete(ctx, "app/a")
+		},
+		"AtomicDelete": func(ctx context.Context, kv *Store) error {
+			_, err := kv.AtomicDelete(ctx, "app/a", &store.KVPair{Key: "app/a", LastIndex: 1})
+			return err
+		},
+		"DeleteTree": func(ctx context.Context, kv *Store) error {
+			return kv.DeleteTree(ctx, "app/")
+		},
+		"PutMany": func(ctx context.Context, kv *Store) error {
+			return kv.PutMany(ctx, []*store.KVPair{{Key: "a", Value: []byte("value")}}, nil)
+		},
+		"DeleteMany": func(ctx context.Context, kv *Store) error {
+			return kv.DeleteMany(ctx, []string{"app/a"})
+		},
+		"Copy": func(ctx context.Context, kv *Store) error {
+			return kv.Copy(ctx, "app/a", "prod/a", nil)
+		},
+		"CopyTree": func(ctx context.Context, kv *Store) error {
+			return kv.CopyTree(ctx, "app/", "prod/", nil)
+		},
+		"Import": func(ctx context.Context, kv *Store) error {
+			_, err := kv.Import(ctx, strings.NewReader(`{"key":"b","value":"dmFsdWU="}`), nil)
+			return err
+		},
+		"Migrate": func(ctx context.Context, kv *Store) error {
+			src := &Store{dynamoSvc: newAuditStoreMock(), tableName: TestTableName}
+			return kv.Migrate(ctx, src, "app/", nil)
+		},
+		"Move": func(ctx context.Context, kv *Store) error {
+			return kv.Move(ctx, "app/a", "prod/a")
+		},
+		"Transact": func(ctx context.Context, kv *Store) error {
+			return kv.Transact(ctx).Put("a", []byte("value"), nil).Delete("b").Commit()
+		},
+		"PatchJSON": func(ctx context.Context, kv *Store) error {
+			kv.codec = NewDocumentCodec(encodedValueAttribute)
+			_, err := kv.PatchJSON(ctx, "doc", "name", []byte(`"valkeyrie"`))
+			return err
+		},
+		"NewWriteBuffer": func(ctx context.Context, kv *Store) error {
+			buffer := kv.NewWriteBuffer(nil)
+			if err := buffer.Put("a", []byte("value"), nil); err != nil {
+				return err
+			}
+			return buffer.Close(ctx)
+		},
+		"Truncate": func(ctx context.Context, kv *Store) error {
+			return kv.Truncate(ctx)
+		},
+		"DeleteTable": func(ctx context.Context, kv *Store) error {
+			return kv.DeleteTable(ctx)
+		},
+	}
+
+	ctx := context.Background()
+
+	storeType := reflect.TypeOf(&Store{})
+
+	for i := 0; i < storeType.NumMethod(); i++ {
+		name := storeType.Method(i).Name
+		if notMutating[name] {
+			continue
+		}
+
+		call, ok := audited[name]
+		if !assert.Truef(t, ok, "%s is neither audited nor listed as not writing keys", name) {
+			continue
+		}
+
+		var records []*AuditRecord
+
+		kv := &Store{
+			dynamoSvc:        newAuditStoreMock(),
+			tableName:        TestTableName,
+			destructiveGuard: &DestructiveGuard{TableNamePatterns: []string{TestTableName}},
+		}
+		kv.middlewares = []Middleware{kv.auditMiddleware(&AuditConfig{Sink: AuditFunc(func(_ context.Context, record *AuditRecord) error {
+			records = append(records, record)
+			return nil
+		})})}
+
+		require.NoError(t, call(ctx, kv), name)
+		assert.NotEmptyf(t, records, "%s is not audited", name)
+	}
+}
+
+// mockedAuditStore serves the writes of all the mutating methods of the store.
+type mockedAuditStore struct {
+	*mockedTruncate
+}
+
+func newAuditStoreMock() *mockedAuditStore {
+	items := map[string]map[string]*dynamodb.AttributeValue{
+		"app/a": newTestItem("app/a", "dmFsdWUx"),
+		"doc": {
+			partitionKey:          {S: aws.String("doc")},
+			revisionAttribute:     {N: aws.String("1")},
+			encodedValueAttribute: {M: map[string]*dynamodb.AttributeValue{}},
+		},
+	}
+
+	return &mockedAuditStore{mockedTruncate: &mockedTruncate{
+		mockedBatchStore: &mockedBatchStore{items: items, failBatch: -1},
+		items:            []map[string]*dynamodb.AttributeValue{items["app/a"]},
+	}}
+}
+
+func (m *mockedAuditStore) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &dynamodb.GetItemOutput{Item: m.mockedBatchStore.items[itemKey(input.Key)]}, nil
+}
+
+func (m *mockedAuditStore) ScanWithContext(_ aws.Context, _ *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+	return &dynamodb.ScanOutput{Items: m.mockedTruncate.items}, nil
+}
+
+func (m *mockedAuditStore) TransactWriteItemsWithContext(_ aws.Context, _ *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
@@ -0,0 +1,126 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+)
+
+const defaultTargetUtilization = 70
+
+// ErrAutoScalingNotConfigured is returned when creating a table with auto scaling on a store without auto scaling client.
+var ErrAutoScalingNotConfigured = errors.New("auto scaling is not configured")
+
+// AutoScalingConfig configures the auto scaling of the capacity of a provisioned table, and of its prefix index,
+// registered with Application Auto Scaling when the table is created.
+// The provisioned capacity of the table is its initial capacity.
+type AutoScalingConfig struct {
+	// TargetUtilization is the percentage of the provisioned capacity the capacity consumed is kept at,
+	// between 20 and 90, defaults to 70.
+	TargetUtilization float64
+
+	// MinReadCapacityUnits and MaxReadCapacityUnits bound the read capacity.
+	MinReadCapacityUnits int64
+	MaxReadCapacityUnits int64
+
+	// MinWriteCapacityUnits and MaxWriteCapacityUnits bound the write capacity.
+	MinWriteCapacityUnits int64
+	MaxWriteCapacityUnits int64
+}
+
+// scalableDimension a capacity scaled by Application Auto Scaling.
+type scalableDimension struct {
+	resourceID string
+	dimension  string
+	metric     string
+	min, max   int64
+}
+
+// registerAutoScaling registers the scalable targets and the target tracking policies of the capacity of the table
+// and of its prefix index, if any.
+func (ddb *Store) registerAutoScaling(ctx context.Context, config *AutoScalingConfig) error {
+	target := config.TargetUtilization
+	if target <= 0 {
+		target = defaultTargetUtilization
+	}
+
+	for _, dim := range ddb.scalableDimensions(config) {
+		_, err := ddb.scalingSvc.RegisterScalableTargetWithContext(ctx, &applicationautoscaling.RegisterScalableTargetInput{
+			ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceDynamodb),
+			ResourceId:        aws.String(dim.resourceID),
+			ScalableDimension: aws.String(dim.dimension),
+			MinCapacity:       aws.Int64(dim.min),
+			MaxCapacity:       aws.Int64(dim.max),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = ddb.scalingSvc.PutScalingPolicyWithContext(ctx, &applicationautoscaling.PutScalingPolicyInput{
+			PolicyName:        aws.String(fmt.Sprintf("%s-%s", ddb.tableName, dim.metric)),
+			PolicyType:        aws.String(applicationautoscaling.PolicyTypeTargetTrackingScaling),
+			ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceDynamodb),
+			ResourceId:        aws.String(dim.resourceID),
+			ScalableDimension: aws.String(dim.dimension),
+			TargetTrackingScalingPolicyConfiguration: &applicationautoscaling.TargetTrackingScalingPolicyConfiguration{
+				TargetValue: aws.Float64(target),
+				PredefinedMetricSpecification: &applicationautoscaling.PredefinedMetricSpecification{
+					PredefinedMetricType: aws.String(dim.metric),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scalableDimensions returns the read and write capacities of the table, and of its prefix index if any.
+func (ddb *Store) scalableDimensions(config *AutoScalingConfig) []scalableDimension {
+	resourceID := "table/" + ddb.tableName
+
+	dims := []scalableDimension{
+		{
+			resourceID: resourceID,
+			dimension:  applicationautoscaling.ScalableDimensionDynamodbTableReadCapacityUnits,
+			metric:     applicationautoscaling.MetricTypeDynamoDbreadCapacityUtilization,
+			min:        config.MinReadCapacityUnits,
+			max:        config.MaxReadCapacityUnits,
+		},
+		{
+			resourceID: resourceID,
+			dimension:  applicationautoscaling.ScalableDimensionDynamodbTableWriteCapacityUnits,
+			metric:     applicationautoscaling.MetricTypeDynamoDbwriteCapacityUtilization,
+			min:        config.MinWriteCapacityUnits,
+			max:        config.MaxWriteCapacityUnits,
+		},
+	}
+
+	if ddb.prefixIndex == "" {
+		return dims
+	}
+
+	indexID := resourceID + "/index/" + ddb.prefixIndex
+
+	return append(dims,
+		scalableDimension{
+			resourceID: indexID,
+			dimension:  applicationautoscaling.ScalableDimensionDynamodbIndexReadCapacityUnits,
+			metric:     applicationautoscaling.MetricTypeDynamoDbreadCapacityUtilization,
+			min:        config.MinReadCapacityUnits,
+			max:        config.MaxReadCapacityUnits,
+		},
+		scalableDimension{
+			resourceID: indexID,
+			dimension:  applicationautoscaling.ScalableDimensionDynamodbIndexWriteCapacityUnits,
+			metric:     applicationautoscaling.MetricTypeDynamoDbwriteCapacityUtilization,
+			min:        config.MinWriteCapacityUnits,
+			max:        config.MaxWriteCapacityUnits,
+		},
+	)
+}
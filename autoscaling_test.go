@@ -0,0 +1,73 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoScaling(t *testing.T) {
+	scaling := &mockedAutoScaling{}
+	kv := &Store{dynamoSvc: &mockedCreateTable{}, tableName: TestTableName, prefixIndex: "prefix-index"}
+
+	spec := &TableSpec{AutoScaling: &AutoScalingConfig{
+		MinReadCapacityUnits:  5,
+		MaxReadCapacityUnits:  100,
+		MinWriteCapacityUnits: 2,
+		MaxWriteCapacityUnits: 50,
+	}}
+
+	ctx := context.Background()
+
+	err := kv.EnsureTable(ctx, spec)
+	assert.ErrorIs(t, err, ErrAutoScalingNotConfigured)
+
+	kv.scalingSvc = scaling
+
+	err = kv.EnsureTable(ctx, spec)
+	require.NoError(t, err)
+
+	// the read and write capacities of the table and of its index.
+	require.Len(t, scaling.targets, 4)
+	assert.Equal(t, "table/"+TestTableName, aws.StringValue(scaling.targets[0].ResourceId))
+	assert.Equal(t, int64(100), aws.Int64Value(scaling.targets[0].MaxCapacity))
+	assert.Equal(t, applicationautoscaling.ScalableDimensionDynamodbTableWriteCapacityUnits, aws.StringValue(scaling.targets[1].ScalableDimension))
+	assert.Equal(t, int64(2), aws.Int64Value(scaling.targets[1].MinCapacity))
+	assert.Equal(t, "table/"+TestTableName+"/index/prefix-index", aws.StringValue(scaling.targets[2].ResourceId))
+
+	require.Len(t, scaling.policies, 4)
+	assert.InDelta(t, 70, aws.Float64Value(scaling.policies[0].TargetTrackingScalingPolicyConfiguration.TargetValue), 0)
+
+	// the on-demand tables don't scale.
+	scaling.targets = nil
+	spec.BillingMode = dynamodb.BillingModePayPerRequest
+
+	kv.dynamoSvc = &mockedCreateTable{}
+	require.NoError(t, kv.EnsureTable(ctx, spec))
+	assert.Empty(t, scaling.targets)
+}
+
+// mockedAutoScaling records the scalable targets and the scaling policies.
+type mockedAutoScaling struct {
+	applicationautoscalingiface.ApplicationAutoScalingAPI
+
+	targets  []*applicationautoscaling.RegisterScalableTargetInput
+	policies []*applicationautoscaling.PutScalingPolicyInput
+}
+
+func (m *mockedAutoScaling) RegisterScalableTargetWithContext(_ aws.Context, input *applicationautoscaling.RegisterScalableTargetInput, _ ...request.Option) (*applicationautoscaling.RegisterScalableTargetOutput, error) {
+	m.targets = append(m.targets, input)
+	return &applicationautoscaling.RegisterScalableTargetOutput{}, nil
+}
+
+func (m *mockedAutoScaling) PutScalingPolicyWithContext(_ aws.Context, input *applicationautoscaling.PutScalingPolicyInput, _ ...request.Option) (*applicationautoscaling.PutScalingPolicyOutput, error) {
+	m.policies = append(m.policies, input)
+	return &applicationautoscaling.PutScalingPolicyOutput{}, nil
+}
@@ -0,0 +1,439 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	// maxBatchGetItems the maximum number of keys in a BatchGetItem call.
+	maxBatchGetItems = 100
+
+	batchRetryBaseDelay = 50 * time.Millisecond
+	batchRetryMaxDelay  = time.Second
+
+	// maxBatchWriteAttempts the maximum number of attempts to write the requests of a batch.
+	maxBatchWriteAttempts = 8
+
+	// maxBatchGetAttempts the maximum number of attempts to read the keys of a batch.
+	maxBatchGetAttempts = 8
+
+	// maxConcurrentWrites the maximum number of keys written at once by the writes of many keys which can't be batched,
+	// such as PutMany.
+	maxConcurrentWrites = maxBatchWriteItems
+)
+
+var (
+	// ErrBatchUnprocessed is reported for the keys DynamoDB left unprocessed after all the attempts.
+	ErrBatchUnprocessed = errors.New("batch write unprocessed")
+	// ErrBatchGetUnprocessed is returned when DynamoDB left keys of a batch read unprocessed after all the attempts.
+	ErrBatchGetUnprocessed = errors.New("batch get unprocessed")
+)
+
+// BatchWriteError reports the keys the writes of many keys, such as PutMany or DeleteMany, failed to write.
+type BatchWriteError struct {
+	// Failed the error of each key not written.
+	Failed map[string]error
+}
+
+func (e *BatchWriteError) Error() string {
+	return fmt.Sprintf("%d keys not written", len(e.Failed))
+}
+
+// BatchGet returns the values of keys, in the order of keys, skipping the keys not found.
+// The keys are read with consistent reads, in batches of 100 keys,
+// but unlike GetMany the values are not a consistent snapshot.
+func (ddb *Store) BatchGet(ctx context.Context, keys []string) ([]*store.KVPair, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	keys = uniqueKeys(ddb.normalizeKeys(keys))
+
+	if err := ddb.checkKeys(keys); err != nil {
+		return nil, err
+	}
+
+	items, err := ddb.batchGetItems(ctx, keys, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.decodeKeys(ctx, keys, items, true)
+}
+
+// PutMany writes the values of pairs, maxConcurrentWrites keys at once.
+// The revisions of the keys are read first, in batches of 100 keys,
+// then each key is written by its own update incrementing its revision, conditioned on the revision read, like AtomicPut:
+// a key written by another client since it was read, such as by an atomic operation or a lock, is not overwritten,
+// and fails with store.ErrKeyModified, or store.ErrKeyExists if it didn't exist.
+// Each key runs through the middlewares as an OperationPutMany.
+// The writes aren't atomic: if some keys are not written, the others are, and a *BatchWriteError is returned.
+func (ddb *Store) PutMany(ctx context.Context, pairs []*store.KVPair, opts *store.WriteOptions) error {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	pairs = ddb.normalizePairs(pairs)
+
+	keys := make([]string, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+
+	if err := ddb.checkKeys(keys); err != nil {
+		return err
+	}
+
+	failed := make(map[string]error)
+
+	ddb.putPairs(ctx, OperationPutMany, pairs, func(*store.KVPair) *store.WriteOptions { return opts }, failed)
+
+	return batchError(failed)
+}
+
+// putPairs writes pairs like PutMany, each with the write options returned by optsOf,
+// and adds the keys not written to failed. Each key runs through the middlewares as an operation named name.
+// The last pair of a key wins. The keys of pairs must be normalized.
+func (ddb *Store) putPairs(ctx context.Context, name string, pairs []*store.KVPair,
+	optsOf func(pair *store.KVPair) *store.WriteOptions, failed map[string]error,
+) {
+	last := make(map[string]*store.KVPair, len(pairs))
+	keys := make([]string, 0, len(pairs))
+
+	for _, pair := range pairs {
+		if _, ok := last[pair.Key]; !ok {
+			keys = append(keys, pair.Key)
+		}
+		last[pair.Key] = pair
+	}
+
+	valid := make([]string, 0, len(keys))
+	for _, key := range keys {
+		// the invalid keys fail their own write, but would fail the read of the whole batch.
+		if err := ddb.checkKey(key); err != nil {
+			failed[key] = err
+			continue
+		}
+		valid = append(valid, key)
+	}
+
+	current, err := ddb.batchGetItems(ctx, valid, true)
+	if err != nil {
+		for _, key := range valid {
+			failed[key] = err
+		}
+		return
+	}
+
+	writeKeys(ctx, valid, func(ctx context.Context, key string) error {
+		previous, err := ddb.readRevision(key, current[key])
+		if err != nil {
+			return err
+		}
+
+		pair := last[key]
+		op := &Operation{Name: name, Key: key, Value: pair.Value, Previous: previous}
+
+		return ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+			_, op.Pair, err = ddb.atomicPut(ctx, op.Key, op.Value, op.Previous, optsOf(pair))
+			return err
+		})
+	}, failed)
+}
+
+// readRevision returns the pair at the revision of the item read for key, the state the write of key is conditioned on,
+// or nil if the item doesn't exist or is expired.
+func (ddb *Store) readRevision(key string, item map[string]*dynamodb.AttributeValue) (*store.KVPair, error) {
+	if item == nil || ddb.isItemExpired(item) {
+		return nil, nil
+	}
+
+	revision, err := strconv.ParseUint(aws.StringValue(item[ddb.revisionName()].N), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.KVPair{Key: key, LastIndex: revision}, nil
+}
+
+// writeKeys runs write for each key, maxConcurrentWrites keys at once, and adds the keys failed to failed.
+func writeKeys(ctx context.Context, keys []string, write func(ctx context.Context, key string) error, failed map[string]error) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	sem := make(chan struct{}, maxConcurrentWrites)
+
+	for _, key := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(key string) {
+			defer func() { <-sem }()
+			defer wg.Done()
+
+			if err := write(ctx, key); err != nil {
+				mu.Lock()
+				failed[key] = err
+				mu.Unlock()
+			}
+		}(key)
+	}
+
+	wg.Wait()
+}
+
+// DeleteMany deletes keys, in batches of 25 requests,
+// each batch running through the middlewares as an OperationDeleteMany with the keys it deleted.
+// The unprocessed requests of a batch are retried with an exponential backoff.
+// With the history, the keys are deleted like Delete instead, recording their deletion,
+// maxConcurrentWrites keys at once, each running through the middlewares as an OperationDeleteMany.
+// The keys which don't exist are deleted silently, whatever Config.DeleteNotFound.
+// If some keys are not deleted, the others are, and a *BatchWriteError is returned.
+func (ddb *Store) DeleteMany(ctx context.Context, keys []string) error {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	keys = uniqueKeys(ddb.normalizeKeys(keys))
+
+	if err := ddb.checkKeys(keys); err != nil {
+		return err
+	}
+
+	failed := make(map[string]error)
+
+	if ddb.history != nil {
+		writeKeys(ctx, keys, func(ctx context.Context, key string) error {
+			return ddb.runOperation(ctx, &Operation{Name: OperationDeleteMany, Key: key}, func(ctx context.Context, op *Operation) error {
+				if err := ddb.deleteKey(ctx, op.Key); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+					return err
+				}
+				return nil
+			})
+		}, failed)
+
+		return batchError(failed)
+	}
+
+	var current map[string]map[string]*dynamodb.AttributeValue
+	if ddb.hasExternalStorage() {
+		// the chunks or S3 objects, if any, must be removed.
+		var err error
+		current, err = ddb.batchGetItems(ctx, keys, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	requests := make(map[string]*dynamodb.WriteRequest, len(keys))
+	for _, key := range keys {
+		requests[key] = &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: ddb.keyAttributes(key)}}
+	}
+
+	ddb.batchWrite(ctx, OperationDeleteMany, requests, failed)
+	ddb.deleteReplaced(ctx, requests, current, failed)
+
+	return batchError(failed)
+}
+
+// batchWrite writes the requests by key in batches of maxBatchWriteItems,
+// retrying the unprocessed requests with an exponential backoff, and adds the keys not written to failed.
+// Each batch runs through the middlewares as an operation named name, with the keys written.
+func (ddb *Store) batchWrite(ctx context.Context, name string, requests map[string]*dynamodb.WriteRequest, failed map[string]error) {
+	keys := make([]string, 0, len(requests))
+	for key := range requests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for start := 0; start < len(keys); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batchKeys := keys[start:end]
+
+		op := &Operation{Name: name, Keys: batchKeys}
+
+		err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) error {
+			batch := make([]*dynamodb.WriteRequest, 0, len(batchKeys))
+			for _, key := range batchKeys {
+				batch = append(batch, requests[key])
+			}
+
+			unprocessed, err := ddb.writeBatch(ctx, batch)
+			if err != nil {
+				return err
+			}
+
+			for _, req := range unprocessed {
+				failed[ddb.requestKey(req)] = ErrBatchUnprocessed
+			}
+
+			op.Keys = nil
+			for _, key := range batchKeys {
+				if _, ok := failed[key]; !ok {
+					op.Keys = append(op.Keys, key)
+				}
+			}
+
+			if len(op.Keys) == 0 {
+				return ErrBatchUnprocessed
+			}
+
+			return nil
+		})
+		if err != nil {
+			for _, key := range batchKeys {
+				if _, ok := failed[key]; !ok {
+					failed[key] = err
+				}
+			}
+		}
+	}
+}
+
+// writeBatch writes a batch of requests,
+// and returns the requests still unprocessed after maxBatchWriteAttempts attempts.
+func (ddb *Store) writeBatch(ctx context.Context, batch []*dynamodb.WriteRequest) ([]*dynamodb.WriteRequest, error) {
+	for attempt := 0; attempt < maxBatchWriteAttempts && len(batch) > 0; attempt++ {
+		if attempt > 0 {
+			if err := sleepBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := ddb.dynamoSvc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{ddb.tableName: batch},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		batch = res.UnprocessedItems[ddb.tableName]
+	}
+
+	return batch, nil
+}
+
+// deleteReplaced removes the chunks or S3 objects of the current items replaced or deleted by the requests.
+func (ddb *Store) deleteReplaced(ctx context.Context, requests map[string]*dynamodb.WriteRequest,
+	current map[string]map[string]*dynamodb.AttributeValue, failed map[string]error,
+) {
+	for key := range requests {
+		if _, ok := failed[key]; ok {
+			continue
+		}
+
+		if err := ddb.deleteExternal(ctx, key, current[key]); err != nil {
+			failed[key] = err
+		}
+	}
+}
+
+// requestKey returns the key of the item written by a request.
+func (ddb *Store) requestKey(req *dynamodb.WriteRequest) string {
+	if req.PutRequest != nil {
+		return ddb.itemKey(req.PutRequest.Item)
+	}
+
+	return ddb.itemKey(req.DeleteRequest.Key)
+}
+
+func batchError(failed map[string]error) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return &BatchWriteError{Failed: failed}
+}
+
+// batchGetItems reads items by normalized key, retrying the unprocessed keys with an exponential backoff,
+// and returns ErrBatchGetUnprocessed with the keys still unprocessed after maxBatchGetAttempts attempts.
+func (ddb *Store) batchGetItems(ctx context.Context, keys []string, consistent bool) (map[string]map[string]*dynamodb.AttributeValue, error) {
+	items := make(map[string]map[string]*dynamodb.AttributeValue, len(keys))
+
+	for start := 0; start < len(keys); start += maxBatchGetItems {
+		end := start + maxBatchGetItems
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		attrs := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, key := range keys[start:end] {
+			attrs = append(attrs, ddb.keyAttributes(key))
+		}
+
+		request := map[string]*dynamodb.KeysAndAttributes{
+			ddb.tableName: {Keys: attrs, ConsistentRead: aws.Bool(consistent)},
+		}
+
+		for attempt := 0; len(request) > 0; attempt++ {
+			if attempt == maxBatchGetAttempts {
+				return nil, ddb.unprocessedKeysError(request)
+			}
+
+			if attempt > 0 {
+				if err := sleepBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
+			}
+
+			res, err := ddb.readSvc().BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range res.Responses[ddb.tableName] {
+				items[ddb.itemKey(item)] = item
+			}
+
+			request = res.UnprocessedKeys
+		}
+	}
+
+	return items, nil
+}
+
+// unprocessedKeysError returns the ErrBatchGetUnprocessed naming the keys of the unprocessed request.
+func (ddb *Store) unprocessedKeysError(request map[string]*dynamodb.KeysAndAttributes) error {
+	var keys []string
+	for _, key := range request[ddb.tableName].Keys {
+		keys = append(keys, ddb.itemKey(key))
+	}
+	sort.Strings(keys)
+
+	return fmt.Errorf("%w: %s", ErrBatchGetUnprocessed, strings.Join(keys, ", "))
+}
+
+// sleepBackoff waits before the retry attempt of a batch, doubling the delay at each attempt.
+func sleepBackoff(ctx context.Context, attempt int) error {
+	delay := batchRetryBaseDelay
+	for i := 1; i < attempt && delay < batchRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > batchRetryMaxDelay {
+		delay = batchRetryMaxDelay
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,506 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchGet(t *testing.T) {
+	mock := &mockedUnprocessedGet{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	keys := make([]string, 150)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("testBatchGet/%03d", i)
+		if i%2 == 0 {
+			mock.items[keys[i]] = newTestItem(keys[i], "dmFsdWU=")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	pairs, err := kv.BatchGet(ctx, keys)
+	require.NoError(t, err)
+	require.Len(t, pairs, 75)
+	assert.Equal(t, "testBatchGet/000", pairs[0].Key)
+	assert.Equal(t, "testBatchGet/148", pairs[74].Key)
+
+	// 2 batches, each retried once for its unprocessed keys.
+	assert.Equal(t, []int{100, 50, 50, 25}, mock.sizes)
+}
+
+func TestBatchGetUnprocessed(t *testing.T) {
+	mock := &mockedUnprocessedGet{items: make(map[string]map[string]*dynamodb.AttributeValue), throttled: true}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// the reads are not retried until the deadline.
+	_, err := kv.BatchGet(ctx, []string{"b", "a"})
+	assert.ErrorIs(t, err, ErrBatchGetUnprocessed)
+	assert.Contains(t, err.Error(), "a, b")
+	assert.Len(t, mock.sizes, maxBatchGetAttempts)
+}
+
+func TestPutMany(t *testing.T) {
+	mock := &mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"testPutMany/00": newTestItem("testPutMany/00", "dmFsdWU="),
+		},
+		failBatch: -1,
+	}
+	mock.items["testPutMany/00"][createdAtAttribute] = &dynamodb.AttributeValue{N: aws.String("1700000000000")}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	pairs := make([]*store.KVPair, 30)
+	for i := range pairs {
+		pairs[i] = &store.KVPair{Key: fmt.Sprintf("testPutMany/%02d", i), Value: []byte("value")}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require.NoError(t, kv.PutMany(ctx, pairs, nil))
+	assert.Len(t, mock.written, 30)
+
+	// the revisions are read first, and each key updated to its next revision.
+	assert.Equal(t, "2", aws.StringValue(mock.written["testPutMany/00"][revisionAttribute].N))
+	assert.Equal(t, "1", aws.StringValue(mock.written["testPutMany/01"][revisionAttribute].N))
+	assert.Equal(t, "dmFsdWU=", aws.StringValue(mock.written["testPutMany/01"][encodedValueAttribute].S))
+	assert.Equal(t, 1, mock.reads)
+	assert.Equal(t, 30, mock.updates)
+	assert.Zero(t, mock.batches)
+
+	// the creation time of the replaced keys is kept.
+	assert.Equal(t, "1700000000000", aws.StringValue(mock.written["testPutMany/00"][createdAtAttribute].N))
+	assert.Equal(t, mock.written["testPutMany/01"][updatedAtAttribute], mock.written["testPutMany/01"][createdAtAttribute])
+
+	// 5 updates fail.
+	mock.failUpdates = mock.updates + 5
+
+	err := kv.PutMany(ctx, pairs, nil)
+
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Len(t, batchErr.Failed, 5)
+	for _, err := range batchErr.Failed {
+		assert.ErrorIs(t, err, errBatchFailed)
+	}
+
+	err = kv.DeleteMany(ctx, []string{"testPutMany/00", "testPutMany/01"})
+	require.NoError(t, err)
+	assert.Len(t, mock.deleted, 2)
+	assert.Equal(t, []int{2}, mock.sizes)
+}
+
+func TestBatchWriteUnprocessed(t *testing.T) {
+	mock := &mockedBatchStore{failBatch: -1, unprocessed: 1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	pairs := make([]*store.KVPair, 30)
+	keys := make([]string, len(pairs))
+	for i := range pairs {
+		keys[i] = fmt.Sprintf("testBatchWriteUnprocessed/%02d", i)
+		pairs[i] = &store.KVPair{Key: keys[i], Value: []byte("value")}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require.NoError(t, kv.PutMany(ctx, pairs, nil))
+	assert.Len(t, mock.written, 30)
+
+	// the keys are deleted in batches of 25 requests, the unprocessed half of the first batch being retried.
+	require.NoError(t, kv.DeleteMany(ctx, keys))
+	assert.Empty(t, mock.written)
+	assert.Equal(t, []int{25, 13, 5}, mock.sizes)
+
+	// the requests still unprocessed after all the attempts fail.
+	mock.sizes, mock.throttled = nil, true
+
+	err := kv.DeleteMany(ctx, keys[:5])
+
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Len(t, batchErr.Failed, 5)
+	assert.ErrorIs(t, batchErr.Failed["testBatchWriteUnprocessed/00"], ErrBatchUnprocessed)
+	assert.Len(t, mock.sizes, maxBatchWriteAttempts)
+}
+
+// TestPutManyConcurrentWrite checks the writes of PutMany follow the revisions written by the other writes of the keys,
+// and don't overwrite the keys written since they were read.
+func TestPutManyConcurrentWrite(t *testing.T) {
+	mock := &mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"a": newTestItem("a", "dmFsdWU="),
+		},
+		failBatch: -1,
+	}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	require.NoError(t, kv.PutMany(ctx, []*store.KVPair{{Key: "a", Value: []byte("1")}}, nil))
+
+	pair, err := kv.PutWithResult(ctx, "a", []byte("2"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), pair.LastIndex)
+
+	require.NoError(t, kv.PutMany(ctx, []*store.KVPair{{Key: "a", Value: []byte("3")}}, nil))
+	assert.Equal(t, "4", aws.StringValue(mock.written["a"][revisionAttribute].N))
+
+	// "a" is updated, and "c" created, between the read of their revisions and their writes.
+	mock.afterRead = func() {
+		assert.NoError(t, kv.Put(ctx, "a", []byte("concurrent"), nil))
+		assert.NoError(t, kv.Put(ctx, "c", []byte("concurrent"), nil))
+	}
+
+	err = kv.PutMany(ctx, []*store.KVPair{
+		{Key: "a", Value: []byte("4")},
+		{Key: "b", Value: []byte("1")},
+		{Key: "c", Value: []byte("1")},
+	}, nil)
+
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, err, &batchErr)
+	require.Len(t, batchErr.Failed, 2)
+	assert.ErrorIs(t, batchErr.Failed["a"], store.ErrKeyModified)
+	assert.ErrorIs(t, batchErr.Failed["c"], store.ErrKeyExists)
+
+	// the concurrent writes are kept, at their own revisions.
+	assert.Equal(t, "Y29uY3VycmVudA==", aws.StringValue(mock.written["a"][encodedValueAttribute].S))
+	assert.Equal(t, "5", aws.StringValue(mock.written["a"][revisionAttribute].N))
+	assert.Equal(t, "1", aws.StringValue(mock.written["b"][revisionAttribute].N))
+	assert.Equal(t, "1", aws.StringValue(mock.written["c"][revisionAttribute].N))
+}
+
+func TestBatchNormalizedKeys(t *testing.T) {
+	mock := &mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"a/b": newTestItem("a/b", "dmFsdWU="),
+		},
+		failBatch: -1,
+	}
+	mock.items["a/b"][createdAtAttribute] = &dynamodb.AttributeValue{N: aws.String("1700000000000")}
+	kv := &Store{
+		dynamoSvc:        mock,
+		tableName:        TestTableName,
+		keyNormalization: &KeyNormalization{TrimLeadingSlash: true, CollapseSlashes: true},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	pairs, err := kv.BatchGet(ctx, []string{"/a//b", "a/b"})
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, "a/b", pairs[0].Key)
+
+	// the spellings of a key are written once, the last pair winning.
+	err = kv.PutMany(ctx, []*store.KVPair{
+		{Key: "/a//b", Value: []byte("first")},
+		{Key: "a/b", Value: []byte("second")},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, mock.written, 1)
+	assert.Equal(t, 1, mock.updates)
+	assert.Equal(t, "2", aws.StringValue(mock.written["a/b"][revisionAttribute].N))
+	assert.Equal(t, "1700000000000", aws.StringValue(mock.written["a/b"][createdAtAttribute].N))
+
+	err = kv.DeleteMany(ctx, []string{"/a//b", "a/b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/b"}, mock.deleted)
+}
+
+func TestPutManyInvalidKey(t *testing.T) {
+	mock := &mockedBatchStore{failBatch: -1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	err := kv.PutMany(context.Background(), []*store.KVPair{
+		{Key: "testPutMany/valid", Value: []byte("value")},
+		{Key: "", Value: []byte("value")},
+	}, nil)
+	assert.ErrorIs(t, err, ErrEmptyKey)
+
+	// nothing is read nor written.
+	assert.Equal(t, 0, mock.reads)
+	assert.Equal(t, 0, mock.updates)
+}
+
+// errBatchFailed a retryable batch failure.
+var errBatchFailed = fmt.Errorf("%w: batch failed", ErrThrottled)
+
+// mockedBatchStore serves the batch reads from written, then items, applies the updates of the keys to written,
+// failing the first failUpdates updates, and the updates whose condition doesn't hold, records the deletes, and applies the batch writes, recording their sizes,
+// failing the write batch of index failBatch, with failErr or errBatchFailed,
+// and leaving the second half of the requests of the first unprocessed write batches unprocessed,
+// all of them if throttled.
+type mockedBatchStore struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu          sync.Mutex
+	items       map[string]map[string]*dynamodb.AttributeValue
+	written     map[string]map[string]*dynamodb.AttributeValue
+	deleted     []string
+	reads       int
+	updates     int
+	failUpdates int
+	batches     int
+	failBatch   int
+	failErr     error
+	sizes       []int
+	unprocessed int
+	throttled   bool
+	// afterRead is run once, after the next batch read, as a write of another client.
+	afterRead func()
+}
+
+func (m *mockedBatchStore) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.updates++
+	if m.updates <= m.failUpdates {
+		if m.failErr != nil {
+			return nil, m.failErr
+		}
+		return nil, errBatchFailed
+	}
+
+	if m.written == nil {
+		m.written = make(map[string]map[string]*dynamodb.AttributeValue)
+	}
+
+	key := itemKey(input.Key)
+
+	current, ok := m.written[key]
+	if !ok {
+		current = m.items[key]
+	}
+
+	if !testConditionHolds(current, input) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)
+	}
+
+	m.written[key] = applyTestUpdate(current, input)
+
+	out := &dynamodb.UpdateItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllNew {
+		out.Attributes = m.written[key]
+	} else if revision, ok := current[revisionAttribute]; ok {
+		out.Attributes = map[string]*dynamodb.AttributeValue{revisionAttribute: revision}
+	}
+
+	return out, nil
+}
+
+func (m *mockedBatchStore) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := itemKey(input.Key)
+	m.deleted = append(m.deleted, key)
+
+	out := &dynamodb.DeleteItemOutput{}
+	if item, ok := m.written[key]; ok {
+		out.Attributes = item
+	} else if item, ok := m.items[key]; ok {
+		out.Attributes = item
+	}
+
+	delete(m.written, key)
+	delete(m.items, key)
+
+	return out, nil
+}
+
+// testConditionHolds reports whether the condition of an update, if any, holds on current:
+// current is at the revision of the condition, or doesn't exist without it.
+func testConditionHolds(current map[string]*dynamodb.AttributeValue, input *dynamodb.UpdateItemInput) bool {
+	if input.ConditionExpression == nil {
+		return true
+	}
+
+	if revision, ok := input.ExpressionAttributeValues[":lastRevision"]; ok {
+		return current != nil && aws.StringValue(current[revisionAttribute].N) == aws.StringValue(revision.N)
+	}
+
+	return current == nil || !strings.HasPrefix(aws.StringValue(input.ConditionExpression), "attribute_not_exists("+keyNamePlaceholder+")")
+}
+
+// applyTestUpdate returns current updated by the update of a Put: its attributes set or removed,
+// its TTL and its timestamps set, and its revision incremented.
+func applyTestUpdate(current map[string]*dynamodb.AttributeValue, input *dynamodb.UpdateItemInput) map[string]*dynamodb.AttributeValue {
+	item := make(map[string]*dynamodb.AttributeValue, len(current))
+	for name, v := range current {
+		item[name] = v
+	}
+	for name, v := range input.Key {
+		item[name] = v
+	}
+
+	for placeholder, name := range input.ExpressionAttributeNames {
+		if !strings.HasPrefix(placeholder, "#attr") {
+			continue
+		}
+
+		if v, ok := input.ExpressionAttributeValues[":val"+strings.TrimPrefix(placeholder, "#attr")]; ok {
+			item[aws.StringValue(name)] = v
+		} else {
+			delete(item, aws.StringValue(name))
+		}
+	}
+
+	if ttl, ok := input.ExpressionAttributeValues[":ttl"]; ok {
+		item[ttlAttribute] = ttl
+	}
+
+	if now, ok := input.ExpressionAttributeValues[":now"]; ok {
+		item[updatedAtAttribute] = now
+		if _, ok := item[createdAtAttribute]; !ok {
+			item[createdAtAttribute] = now
+		}
+	}
+
+	var revision uint64
+	if v, ok := current[revisionAttribute]; ok {
+		revision, _ = strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+	} else if seed, ok := input.ExpressionAttributeValues[":revisionSeed"]; ok {
+		revision, _ = strconv.ParseUint(aws.StringValue(seed.N), 10, 64)
+	}
+	item[revisionAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatUint(revision+1, 10))}
+
+	return item
+}
+
+func (m *mockedBatchStore) BatchGetItemWithContext(_ aws.Context, input *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.afterRead != nil {
+		afterRead := m.afterRead
+		m.afterRead = nil
+
+		defer func() {
+			m.mu.Unlock()
+			afterRead()
+			m.mu.Lock()
+		}()
+	}
+
+	m.reads++
+
+	out := &dynamodb.BatchGetItemOutput{Responses: make(map[string][]map[string]*dynamodb.AttributeValue)}
+	for table, req := range input.RequestItems {
+		for _, key := range req.Keys {
+			if item, ok := m.written[itemKey(key)]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			} else if item, ok := m.items[itemKey(key)]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (m *mockedBatchStore) BatchWriteItemWithContext(_ aws.Context, input *dynamodb.BatchWriteItemInput, _ ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.batches++
+	if m.batches-1 == m.failBatch {
+		if m.failErr != nil {
+			return nil, m.failErr
+		}
+		return nil, errBatchFailed
+	}
+
+	if m.written == nil {
+		m.written = make(map[string]map[string]*dynamodb.AttributeValue)
+	}
+
+	out := &dynamodb.BatchWriteItemOutput{}
+
+	for table, requests := range input.RequestItems {
+		m.sizes = append(m.sizes, len(requests))
+
+		switch {
+		case m.throttled:
+			out.UnprocessedItems = map[string][]*dynamodb.WriteRequest{table: requests}
+			requests = nil
+		case m.unprocessed > 0:
+			m.unprocessed--
+			half := len(requests) / 2
+			out.UnprocessedItems = map[string][]*dynamodb.WriteRequest{table: requests[half:]}
+			requests = requests[:half]
+		}
+
+		for _, req := range requests {
+			if req.PutRequest != nil {
+				m.written[itemKey(req.PutRequest.Item)] = req.PutRequest.Item
+			} else {
+				key := itemKey(req.DeleteRequest.Key)
+				m.deleted = append(m.deleted, key)
+				delete(m.written, key)
+				delete(m.items, key)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// mockedUnprocessedGet leaves half of the keys of a batch unprocessed on their first read,
+// all of them at each read if throttled.
+type mockedUnprocessedGet struct {
+	dynamodbiface.DynamoDBAPI
+
+	items     map[string]map[string]*dynamodb.AttributeValue
+	sizes     []int
+	retry     bool
+	throttled bool
+}
+
+func (m *mockedUnprocessedGet) BatchGetItemWithContext(_ aws.Context, input *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	out := &dynamodb.BatchGetItemOutput{Responses: make(map[string][]map[string]*dynamodb.AttributeValue)}
+
+	for table, req := range input.RequestItems {
+		m.sizes = append(m.sizes, len(req.Keys))
+
+		keys := req.Keys
+		if m.throttled {
+			out.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{table: {Keys: keys}}
+			keys = nil
+		} else if !m.retry {
+			half := len(keys) / 2
+			out.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{table: {Keys: keys[half:]}}
+			keys = keys[:half]
+		}
+
+		for _, key := range keys {
+			if item, ok := m.items[itemKey(key)]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+
+	m.retry = !m.retry
+
+	return out, nil
+}
@@ -0,0 +1,174 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned by the DynamoDB calls failed fast by the open circuit breaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed the calls run.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen the calls fail fast with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen a single call probes the recovery, the others fail fast.
+	CircuitHalfOpen
+)
+
+// CircuitBreaker configures the circuit breaker of the DynamoDB calls of the store,
+// failing them fast while DynamoDB, or the network to it, is unavailable, instead of piling them up.
+// The breaker opens after FailureThreshold consecutive failures, the calls failed with a throttling
+// or a transient error after their retries, or timed out. After OpenTimeout, it half-opens:
+// a single call probes the recovery, closing the breaker if it succeeds, or opening it again.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures opening the breaker, defaults to 5.
+	FailureThreshold int
+
+	// OpenTimeout is the time the breaker stays open before probing the recovery, defaults to 30s.
+	OpenTimeout time.Duration
+
+	// OnStateChange is called with the new state of the breaker, such as to alert on its opening.
+	// It must not block.
+	OnStateChange func(state CircuitState)
+}
+
+// circuitBreaker is the state of a circuit breaker.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   *CircuitBreaker
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker returns a closed breaker configured by config.
+func newCircuitBreaker(config *CircuitBreaker) *circuitBreaker {
+	c := *config
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = defaultBreakerOpenTimeout
+	}
+
+	return &circuitBreaker{config: &c}
+}
+
+// allow reports whether a call can run, and whether it's the probe of the half-open breaker.
+func (b *circuitBreaker) allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.config.OpenTimeout {
+			return false, false
+		}
+
+		b.setState(CircuitHalfOpen)
+		b.probing = true
+
+		return true, true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false, false
+		}
+
+		b.probing = true
+
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// done records the result of a call.
+func (b *circuitBreaker) done(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	if !failed {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.setState(CircuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if probe || (b.state == CircuitClosed && b.failures >= b.config.FailureThreshold) {
+		b.openedAt = time.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// release ends the probe of a call canceled, leaving the state of the breaker unchanged.
+func (b *circuitBreaker) release(probe bool) {
+	if !probe {
+		return
+	}
+
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// setState changes the state of the breaker. It must be called with the lock held.
+func (b *circuitBreaker) setState(state CircuitState) {
+	b.state = state
+
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(state)
+	}
+}
+
+// isBreakerFailure reports whether err shows DynamoDB, or the network to it, unavailable.
+// The permanent errors, such as the conditional check failures, are answers of DynamoDB.
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || ClassifyError(err) != ErrorClassPermanent
+}
+
+// breakerInterceptor returns the interceptor failing the calls fast while breaker is open.
+func breakerInterceptor(breaker *circuitBreaker) callInterceptor {
+	return func(ctx aws.Context, _ *apiCall, next callFunc) (interface{}, error) {
+		ok, probe := breaker.allow()
+		if !ok {
+			return nil, ErrCircuitOpen
+		}
+
+		out, err := next(ctx)
+
+		// the canceled calls tell nothing of DynamoDB.
+		if errors.Is(err, context.Canceled) {
+			breaker.release(probe)
+			return out, err
+		}
+
+		breaker.done(probe, isBreakerFailure(err))
+
+		return out, err
+	}
+}
@@ -0,0 +1,70 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	mock := &mockedRegion{err: awserr.New(dynamodb.ErrCodeInternalServerError, "unavailable", nil)}
+
+	var states []CircuitState
+
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient: mock,
+		StreamsClient:  &mockedStreams{},
+		CircuitBreaker: &CircuitBreaker{
+			FailureThreshold: 2,
+			OpenTimeout:      20 * time.Millisecond,
+			OnStateChange:    func(state CircuitState) { states = append(states, state) },
+		},
+	}))
+
+	ctx := context.Background()
+
+	// the permanent errors don't open the breaker.
+	mock.err = awserr.New("ValidationException", "invalid", nil)
+	for i := 0; i < 3; i++ {
+		_, err := kv.Get(ctx, "key", nil)
+		require.Error(t, err)
+	}
+	assert.Empty(t, states)
+
+	mock.err = awserr.New(dynamodb.ErrCodeInternalServerError, "unavailable", nil)
+	for i := 0; i < 2; i++ {
+		_, err := kv.Get(ctx, "key", nil)
+		require.Error(t, err)
+	}
+	assert.Equal(t, []CircuitState{CircuitOpen}, states)
+
+	// the calls fail fast while open.
+	_, err := kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 5, mock.gets)
+
+	// the failed probe opens the breaker again.
+	time.Sleep(30 * time.Millisecond)
+	_, err = kv.Get(ctx, "key", nil)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen}, states)
+
+	// the successful probe closes it.
+	mock.err = nil
+	time.Sleep(30 * time.Millisecond)
+	_, err = kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.Equal(t, CircuitClosed, states[len(states)-1])
+
+	_, err = kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.Equal(t, 8, mock.gets)
+}
@@ -0,0 +1,116 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ErrConcurrencyLimitExceeded is returned by the DynamoDB calls which waited QueueTimeout for a slot.
+var ErrConcurrencyLimitExceeded = errors.New("concurrency limit exceeded")
+
+// ConcurrencyLimit caps the DynamoDB calls in flight of the store, so the bursts of calls don't exhaust
+// the connection pool of the HTTP client. The calls above the limit wait for a slot, in no particular order.
+// The bulk calls (Query, Scan, BatchGetItem, and BatchWriteItem) can be capped lower,
+// so the bursts of List, DeleteTree, or PutMany leave slots to the single item calls, such as Get.
+// The paginated reads hold their slot until their last page.
+type ConcurrencyLimit struct {
+	// MaxInFlight is the maximum number of calls in flight.
+	MaxInFlight int
+
+	// MaxBulkInFlight is the maximum number of bulk calls in flight, within MaxInFlight.
+	// Zero doesn't cap the bulk calls separately.
+	MaxBulkInFlight int
+
+	// QueueTimeout bounds the wait for a slot, the calls failing with ErrConcurrencyLimitExceeded after it.
+	// Zero waits for the context of the call.
+	QueueTimeout time.Duration
+}
+
+// bulkhead holds the slots of the calls in flight.
+type bulkhead struct {
+	slots        chan struct{}
+	bulkSlots    chan struct{}
+	queueTimeout time.Duration
+}
+
+// newBulkhead returns the bulkhead of limit.
+func newBulkhead(limit *ConcurrencyLimit) *bulkhead {
+	b := &bulkhead{slots: make(chan struct{}, limit.MaxInFlight), queueTimeout: limit.QueueTimeout}
+	if limit.MaxBulkInFlight > 0 {
+		b.bulkSlots = make(chan struct{}, limit.MaxBulkInFlight)
+	}
+
+	return b
+}
+
+// acquire waits for a slot of the call, and returns the function releasing it.
+func (b *bulkhead) acquire(ctx context.Context, operation string) (func(), error) {
+	var timeout <-chan time.Time
+	if b.queueTimeout > 0 {
+		timer := time.NewTimer(b.queueTimeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	var bulkSlots chan struct{}
+	if b.bulkSlots != nil && isBulkOperation(operation) {
+		bulkSlots = b.bulkSlots
+
+		if err := waitSlot(ctx, bulkSlots, timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := waitSlot(ctx, b.slots, timeout); err != nil {
+		if bulkSlots != nil {
+			<-bulkSlots
+		}
+		return nil, err
+	}
+
+	return func() {
+		<-b.slots
+		if bulkSlots != nil {
+			<-bulkSlots
+		}
+	}, nil
+}
+
+// waitSlot takes a slot of slots, waiting until timeout or ctx is done.
+func waitSlot(ctx context.Context, slots chan struct{}, timeout <-chan time.Time) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrConcurrencyLimitExceeded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isBulkOperation reports whether a DynamoDB operation reads or writes many items.
+func isBulkOperation(operation string) bool {
+	switch operation {
+	case "Query", "Scan", "BatchGetItem", "BatchWriteItem":
+		return true
+	default:
+		return false
+	}
+}
+
+// bulkheadInterceptor returns the interceptor running the calls within the slots of b.
+func bulkheadInterceptor(b *bulkhead) callInterceptor {
+	return func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error) {
+		release, err := b.acquire(ctx, call.operation)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return next(ctx)
+	}
+}
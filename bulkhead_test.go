@@ -0,0 +1,76 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	mock := &mockedBlockingScan{release: make(chan struct{}), started: make(chan struct{}, 1)}
+
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient:   mock,
+		StreamsClient:    &mockedStreams{},
+		ConcurrencyLimit: &ConcurrencyLimit{MaxInFlight: 2, MaxBulkInFlight: 1, QueueTimeout: 20 * time.Millisecond},
+	}))
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = kv.List(ctx, "", nil)
+	}()
+	<-mock.started
+
+	// the second scan waits for the slot of the first one.
+	_, err := kv.List(ctx, "", nil)
+	assert.ErrorIs(t, err, ErrConcurrencyLimitExceeded)
+
+	// the single item calls have their slots.
+	_, err = kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	close(mock.release)
+	wg.Wait()
+
+	_, err = kv.List(ctx, "", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+// mockedBlockingScan blocks the scans until release is closed, signaling started,
+// and finds no item.
+type mockedBlockingScan struct {
+	dynamodbiface.DynamoDBAPI
+
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *mockedBlockingScan) ScanPagesWithContext(_ aws.Context, _ *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	select {
+	case m.started <- struct{}{}:
+	default:
+	}
+
+	<-m.release
+	fn(&dynamodb.ScanOutput{}, true)
+
+	return nil
+}
+
+func (m *mockedBlockingScan) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{}, nil
+}
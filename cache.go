@@ -0,0 +1,235 @@
+package dynamodb
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	defaultCacheSize = 1000
+	defaultCacheTTL  = time.Minute
+)
+
+// CacheConfig configures the in-memory cache of the values read by Get and Exists.
+// The values may be served stale, whatever the consistency requested by the reads:
+// until they are invalidated, or at most for TTL.
+// The writes of the store evict the keys written, the batches and transactions evicting all the keys.
+type CacheConfig struct {
+	// Size is the maximum number of keys cached, the least recently used being evicted first, defaults to 1000.
+	Size int
+
+	// TTL is the maximum time a value is served from the cache, defaults to 1 minute.
+	TTL time.Duration
+
+	// Invalidate subscribes to the changes of all the keys with the Notifier of the store,
+	// so the keys written by the other clients of the table are evicted.
+	Invalidate bool
+}
+
+// cacheEntry a value cached, with its expiration time.
+type cacheEntry struct {
+	pair      *store.KVPair
+	expiresAt time.Time
+}
+
+// valueCache is a LRU cache of values.
+type valueCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List
+	// generation is incremented by the evictions,
+	// so the values read before an eviction are not cached after it.
+	generation uint64
+
+	// cancel stops the invalidation, if any.
+	cancel context.CancelFunc
+}
+
+func newValueCache(config *CacheConfig) *valueCache {
+	c := &valueCache{
+		size:    config.Size,
+		ttl:     config.TTL,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+
+	if c.size <= 0 {
+		c.size = defaultCacheSize
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultCacheTTL
+	}
+
+	return c
+}
+
+// get returns a copy of the value cached for key, if any.
+func (c *valueCache) get(key string) (*store.KVPair, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry, _ := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return copyPair(entry.pair), true
+}
+
+// add caches a copy of pair until expiresAt at the latest, unless a key was evicted since generation.
+func (c *valueCache) add(pair *store.KVPair, expiresAt time.Time, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if maxExpiresAt := time.Now().Add(c.ttl); expiresAt.IsZero() || expiresAt.After(maxExpiresAt) {
+		expiresAt = maxExpiresAt
+	}
+
+	entry := &cacheEntry{pair: copyPair(pair), expiresAt: expiresAt}
+
+	if elem, ok := c.entries[pair.Key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[pair.Key] = c.lru.PushFront(entry)
+
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		oldestEntry, _ := oldest.Value.(*cacheEntry)
+		c.lru.Remove(oldest)
+		delete(c.entries, oldestEntry.pair.Key)
+	}
+}
+
+// currentGeneration returns the generation of the cache, to pass to add.
+func (c *valueCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// evict removes key from the cache.
+func (c *valueCache) evict(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// purge removes all the keys from the cache.
+func (c *valueCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// close stops the invalidation, if any.
+func (c *valueCache) close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// cacheInterceptor returns the interceptor evicting the keys written from the cache,
+// whatever the result of the writes.
+func cacheInterceptor(cache *valueCache) callInterceptor {
+	return func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error) {
+		if !isWriteOperation(call.operation) {
+			return next(ctx)
+		}
+
+		out, err := next(ctx)
+
+		if call.key != "" {
+			cache.evict(call.key)
+		} else {
+			cache.purge()
+		}
+
+		return out, err
+	}
+}
+
+// startCacheInvalidation evicts the keys changed from the cache, until the cache is closed.
+func (ddb *Store) startCacheInvalidation() error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := ddb.getNotifier().Subscribe(ctx, "")
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	ddb.cache.cancel = cancel
+
+	go func() {
+		for event := range events {
+			ddb.cache.evict(event.Key)
+		}
+
+		if ctx.Err() == nil {
+			// the values are now only bounded by the TTL of the cache.
+			ddb.log().Error("cache invalidation stopped")
+		}
+	}()
+
+	return nil
+}
+
+// cachedGet returns the value of key from the cache, or reads and caches it.
+func (ddb *Store) cachedGet(ctx context.Context, key string, opts *store.ReadOptions) (*store.KVPair, error) {
+	if pair, ok := ddb.cache.get(key); ok {
+		return pair, nil
+	}
+
+	generation := ddb.cache.currentGeneration()
+
+	pair, meta, err := ddb.getWithMeta(ctx, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ddb.cache.add(pair, meta.ExpiresAt, generation)
+
+	return pair, nil
+}
+
+// copyPair returns a copy of pair, with a copy of its value, nil if pair is nil.
+func copyPair(pair *store.KVPair) *store.KVPair {
+	if pair == nil {
+		return nil
+	}
+
+	return &store.KVPair{Key: pair.Key, Value: append([]byte(nil), pair.Value...), LastIndex: pair.LastIndex}
+}
@@ -0,0 +1,96 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	mock := &mockedGetItem{items: map[string]string{"testCache": "d29ybGQ=", "testCacheOther": "b3RoZXI="}}
+	notifier := &chanNotifier{}
+
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		notifier:  notifier,
+		cache:     newValueCache(&CacheConfig{Size: 1}),
+	}
+	require.NoError(t, kv.startCacheInvalidation())
+
+	ctx := context.Background()
+
+	pair, err := kv.Get(ctx, "testCache", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("world"), pair.Value)
+
+	// the cached value is served, and not modified by the callers.
+	pair.Value[0] = 'W'
+	mock.set("testCache", "d29ybGQh")
+
+	pair, err = kv.Get(ctx, "testCache", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("world"), pair.Value)
+
+	exists, err := kv.Exists(ctx, "testCache", nil)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// the keys changed are evicted.
+	notifier.publish(&Event{Key: "testCache"})
+
+	assert.Eventually(t, func() bool {
+		pair, err = kv.Get(ctx, "testCache", nil)
+		return err == nil && string(pair.Value) == "world!"
+	}, time.Second, 10*time.Millisecond)
+
+	// the keys written are evicted.
+	mock.set("testCache", "d29ybGQ/")
+
+	_, err = cacheInterceptor(kv.cache)(ctx, &apiCall{operation: "UpdateItem", key: "testCache"}, func(aws.Context) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	pair, err = kv.Get(ctx, "testCache", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("world?"), pair.Value)
+
+	// the least recently used key is evicted.
+	_, err = kv.Get(ctx, "testCacheOther", nil)
+	require.NoError(t, err)
+
+	_, ok := kv.cache.get("testCache")
+	assert.False(t, ok)
+
+	require.NoError(t, kv.Close())
+}
+
+func TestValueCache(t *testing.T) {
+	cache := newValueCache(&CacheConfig{TTL: time.Hour})
+
+	// a value read before an eviction is not cached.
+	generation := cache.currentGeneration()
+	cache.evict("a")
+	cache.add(&store.KVPair{Key: "a", Value: []byte("a")}, time.Time{}, generation)
+
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+
+	// the values expire with their key.
+	cache.add(&store.KVPair{Key: "a", Value: []byte("a")}, time.Now().Add(-time.Second), cache.currentGeneration())
+
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+
+	cache.add(&store.KVPair{Key: "a", Value: []byte("a")}, time.Time{}, cache.currentGeneration())
+	cache.purge()
+
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,138 @@
+package dynamodb
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Stats are the capacity units consumed by the DynamoDB calls of the store, since its creation.
+// They are only collected with the ReturnConsumedCapacity option.
+type Stats struct {
+	// ReadCapacityUnits the capacity units consumed by the reads (GetItem, Query, Scan, and their batch and transaction forms).
+	ReadCapacityUnits float64
+	// WriteCapacityUnits the capacity units consumed by the writes.
+	WriteCapacityUnits float64
+	// Operations the capacity units consumed by each DynamoDB operation, such as "GetItem".
+	Operations map[string]float64
+}
+
+// capacityStats collects the consumed capacity of the calls.
+type capacityStats struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// Stats returns the capacity units consumed by the store.
+func (ddb *Store) Stats() Stats {
+	if ddb.capacity == nil {
+		return Stats{}
+	}
+
+	ddb.capacity.mu.Lock()
+	defer ddb.capacity.mu.Unlock()
+
+	stats := ddb.capacity.stats
+	stats.Operations = make(map[string]float64, len(ddb.capacity.stats.Operations))
+	for operation, units := range ddb.capacity.stats.Operations {
+		stats.Operations[operation] = units
+	}
+
+	return stats
+}
+
+// add records the capacity consumed by a call.
+func (c *capacityStats) add(operation string, units float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if isReadOperation(operation) {
+		c.stats.ReadCapacityUnits += units
+	} else {
+		c.stats.WriteCapacityUnits += units
+	}
+
+	if c.stats.Operations == nil {
+		c.stats.Operations = make(map[string]float64)
+	}
+	c.stats.Operations[operation] += units
+}
+
+// capacityInterceptor returns the interceptor requesting the consumed capacity of the calls,
+// and recording it in stats.
+func capacityInterceptor(stats *capacityStats) callInterceptor {
+	return func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error) {
+		returnConsumedCapacity(call.input)
+
+		out, err := next(ctx)
+
+		if units := consumedCapacity(out); units > 0 {
+			stats.add(call.operation, units)
+		}
+
+		return out, err
+	}
+}
+
+// isReadOperation reports whether a DynamoDB operation consumes read capacity.
+func isReadOperation(operation string) bool {
+	switch operation {
+	case "GetItem", "Query", "Scan", "BatchGetItem", "TransactGetItems":
+		return true
+	default:
+		return false
+	}
+}
+
+// isWriteOperation reports whether a DynamoDB operation consumes write capacity.
+func isWriteOperation(operation string) bool {
+	switch operation {
+	case "PutItem", "UpdateItem", "DeleteItem", "BatchWriteItem", "TransactWriteItems":
+		return true
+	default:
+		return false
+	}
+}
+
+// returnConsumedCapacity requests the total consumed capacity in the output of a call.
+func returnConsumedCapacity(input interface{}) {
+	total := aws.String(dynamodb.ReturnConsumedCapacityTotal)
+
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.PutItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.UpdateItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.DeleteItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.QueryInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.ScanInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.BatchGetItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.BatchWriteItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.TransactGetItemsInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.TransactWriteItemsInput:
+		in.ReturnConsumedCapacity = total
+	}
+}
+
+// addConsumedCapacity returns the sum of the consumed capacities of two calls on the same table.
+func addConsumedCapacity(sum, capacity *dynamodb.ConsumedCapacity) *dynamodb.ConsumedCapacity {
+	if capacity == nil {
+		return sum
+	}
+	if sum == nil {
+		sum = &dynamodb.ConsumedCapacity{TableName: capacity.TableName, CapacityUnits: aws.Float64(0)}
+	}
+
+	sum.CapacityUnits = aws.Float64(aws.Float64Value(sum.CapacityUnits) + aws.Float64Value(capacity.CapacityUnits))
+
+	return sum
+}
@@ -0,0 +1,84 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient:         &mockedCapacity{},
+		StreamsClient:          &mockedStreams{},
+		ReturnConsumedCapacity: true,
+	}))
+
+	ctx := context.Background()
+
+	_, err := kv.Get(ctx, "key", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, kv.Put(ctx, "key", []byte("value"), nil))
+
+	_, err = kv.List(ctx, "", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, Stats{
+		ReadCapacityUnits:  3.5,
+		WriteCapacityUnits: 1,
+		Operations:         map[string]float64{"GetItem": 1.5, "UpdateItem": 1, "Scan": 2},
+	}, kv.Stats())
+
+	// the stats are not collected by default.
+	assert.Equal(t, Stats{}, (&Store{}).Stats())
+}
+
+// mockedCapacity reports the consumed capacity of the calls requesting it,
+// Scan reading 2 pages of 1 unit each.
+type mockedCapacity struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockedCapacity) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{
+		Item: map[string]*dynamodb.AttributeValue{
+			partitionKey:          {S: aws.String("key")},
+			revisionAttribute:     {N: aws.String("1")},
+			encodedValueAttribute: {S: aws.String("dmFsdWU=")},
+		},
+		ConsumedCapacity: mockedConsumedCapacity(input.ReturnConsumedCapacity, 1.5),
+	}, nil
+}
+
+func (m *mockedCapacity) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	return &dynamodb.UpdateItemOutput{ConsumedCapacity: mockedConsumedCapacity(input.ReturnConsumedCapacity, 1)}, nil
+}
+
+func (m *mockedCapacity) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	for i, key := range []string{"a", "b"} {
+		fn(&dynamodb.ScanOutput{
+			Items: []map[string]*dynamodb.AttributeValue{{
+				partitionKey:          {S: aws.String(key)},
+				revisionAttribute:     {N: aws.String("1")},
+				encodedValueAttribute: {S: aws.String("dmFsdWU=")},
+			}},
+			ConsumedCapacity: mockedConsumedCapacity(input.ReturnConsumedCapacity, 1),
+		}, i == 1)
+	}
+
+	return nil
+}
+
+func mockedConsumedCapacity(returnConsumedCapacity *string, units float64) *dynamodb.ConsumedCapacity {
+	if aws.StringValue(returnConsumedCapacity) != dynamodb.ReturnConsumedCapacityTotal {
+		return nil
+	}
+	return &dynamodb.ConsumedCapacity{TableName: aws.String(TestTableName), CapacityUnits: aws.Float64(units)}
+}
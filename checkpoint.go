@@ -0,0 +1,95 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const sequenceNumberAttribute = "sequence_number"
+
+// checkpointer stores the position of a stream consumer in each shard.
+type checkpointer interface {
+	// get returns the sequence number of the last record handled in the shard, or an empty string.
+	get(ctx context.Context, shardKey string) (string, error)
+	// set records the sequence number of the last record handled in the shard.
+	set(ctx context.Context, shardKey, sequenceNumber string) error
+}
+
+// memoryCheckpointer keeps the positions for the lifetime of a consumer.
+type memoryCheckpointer struct {
+	mu        sync.Mutex
+	positions map[string]string
+}
+
+func newMemoryCheckpointer() *memoryCheckpointer {
+	return &memoryCheckpointer{positions: make(map[string]string)}
+}
+
+func (c *memoryCheckpointer) get(_ context.Context, shardKey string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.positions[shardKey], nil
+}
+
+func (c *memoryCheckpointer) set(_ context.Context, shardKey, sequenceNumber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.positions[shardKey] = sequenceNumber
+
+	return nil
+}
+
+// tableCheckpointer stores the positions in a side table,
+// with the same key schema as the store tables,
+// so a consumer resumes where the previous one stopped.
+type tableCheckpointer struct {
+	svc       dynamodbiface.DynamoDBAPI
+	tableName string
+	// consumerID prefixes the keys of the positions, so consumers can share the table.
+	consumerID string
+}
+
+// itemKey returns the key of the item holding the position in the shard.
+func (c *tableCheckpointer) itemKey(shardKey string) string {
+	if c.consumerID == "" {
+		return shardKey
+	}
+	return c.consumerID + "/" + shardKey
+}
+
+func (c *tableCheckpointer) get(ctx context.Context, shardKey string) (string, error) {
+	res, err := c.svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.tableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			partitionKey: {S: aws.String(c.itemKey(shardKey))},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if v, ok := res.Item[sequenceNumberAttribute]; ok {
+		return aws.StringValue(v.S), nil
+	}
+
+	return "", nil
+}
+
+func (c *tableCheckpointer) set(ctx context.Context, shardKey, sequenceNumber string) error {
+	_, err := c.svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			partitionKey:            {S: aws.String(c.itemKey(shardKey))},
+			sequenceNumberAttribute: {S: aws.String(sequenceNumber)},
+		},
+	})
+
+	return err
+}
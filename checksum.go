@@ -0,0 +1,115 @@
+package dynamodb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Checksum algorithms.
+const (
+	ChecksumCRC32C = "crc32c"
+	ChecksumSHA256 = "sha256"
+)
+
+const checksumAttribute = "checksum"
+
+var (
+	// ErrUnsupportedChecksum is returned when a checksum algorithm is unknown.
+	ErrUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+	// ErrChecksumMismatch is returned when a value read doesn't match its checksum.
+	ErrChecksumMismatch = errors.New("value checksum mismatch")
+)
+
+// ChecksumConfig the value checksum configuration.
+// The checksum of each value is written in an attribute of its item, and verified by the reads,
+// so the values corrupted, such as by another writer or an edit in the console, are detected.
+// The values written without checksum are not verified.
+type ChecksumConfig struct {
+	// Algorithm is the checksum algorithm of the values written: ChecksumCRC32C (default) or ChecksumSHA256.
+	// The values are verified with the algorithm they were written with.
+	Algorithm string
+
+	// LogMismatches logs the values not matching their checksum with the Logger of the store, and returns them,
+	// instead of failing their reads with ErrChecksumMismatch.
+	LogMismatches bool
+}
+
+// algorithm returns the checksum algorithm of the values written.
+func (c *ChecksumConfig) algorithm() string {
+	if c.Algorithm == "" {
+		return ChecksumCRC32C
+	}
+	return c.Algorithm
+}
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedChecksum, algorithm)
+	}
+}
+
+// checksum returns the checksum of value, prefixed by its algorithm, such as "crc32c:e3069283".
+func checksum(algorithm string, value []byte) (string, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	_, _ = h.Write(value)
+
+	return algorithm + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// valueChecksum returns the checksum to write with value, if enabled.
+func (ddb *Store) valueChecksum(value []byte) (string, error) {
+	if ddb.checksum == nil {
+		return "", nil
+	}
+
+	return checksum(ddb.checksum.algorithm(), value)
+}
+
+// verifyChecksum checks the value of the item of key matches its checksum, if any, when the checksums are enabled.
+func (ddb *Store) verifyChecksum(key string, item map[string]*dynamodb.AttributeValue, value []byte) error {
+	if ddb.checksum == nil {
+		return nil
+	}
+
+	v, ok := item[checksumAttribute]
+	if !ok || v == nil {
+		return nil
+	}
+
+	expected := aws.StringValue(v.S)
+
+	algorithm, _, _ := strings.Cut(expected, ":")
+
+	actual, err := checksum(algorithm, value)
+	if err != nil {
+		return err
+	}
+
+	if actual == expected {
+		return nil
+	}
+
+	if ddb.checksum.LogMismatches {
+		ddb.log().Error("value checksum mismatch", "key", key, "expected", expected, "actual", actual)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrChecksumMismatch, key)
+}
@@ -0,0 +1,56 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	sum, err := checksum(ChecksumCRC32C, []byte("123456789"))
+	require.NoError(t, err)
+	assert.Equal(t, "crc32c:e3069283", sum)
+
+	kv := &Store{checksum: &ChecksumConfig{}}
+	ctx := context.Background()
+
+	data, enc, err := kv.encodeValue(ctx, "key", []byte("value"))
+	require.NoError(t, err)
+
+	item := kv.valueAttributes(data, enc)
+	item[partitionKey] = &dynamodb.AttributeValue{S: aws.String("key")}
+	assert.Regexp(t, "^crc32c:", aws.StringValue(item[checksumAttribute].S))
+
+	pair, err := kv.decodeItem(item)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), pair.Value)
+
+	item[encodedValueAttribute] = kv.dataAttribute([]byte("edited"))
+	_, err = kv.decodeItem(item)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	logger := &recordingLogger{}
+	kv = &Store{checksum: &ChecksumConfig{Algorithm: ChecksumSHA256, LogMismatches: true}, logger: logger}
+
+	pair, err = kv.decodeItem(item)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("edited"), pair.Value)
+	require.Len(t, logger.errors, 1)
+	assert.Equal(t, "value checksum mismatch", logger.errors[0][0])
+
+	// the values written without checksum are not verified.
+	delete(item, checksumAttribute)
+	_, err = kv.decodeItem(item)
+	require.NoError(t, err)
+
+	_, enc, err = kv.encodeValue(ctx, "key", []byte("value"))
+	require.NoError(t, err)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", enc.checksum)
+
+	_, err = New(ctx, nil, &Config{Bucket: TestTableName, Checksum: &ChecksumConfig{Algorithm: "md5"}})
+	assert.ErrorIs(t, err, ErrUnsupportedChecksum)
+}
@@ -0,0 +1,396 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	chunksAttribute  = "chunks"
+	chunkIDAttribute = "chunk_id"
+
+	// chunkKeySeparator separates the key of a chunked item from the chunk suffix in chunk keys.
+	chunkKeySeparator = "#chunk#"
+
+	// maxTransactionItems the maximum number of items in a DynamoDB transaction.
+	maxTransactionItems = 100
+	// maxBatchWriteItems the maximum number of requests in a BatchWriteItem call.
+	maxBatchWriteItems = 25
+
+	maxChunkedWriteAttempts = 5
+)
+
+var (
+	// ErrValueTooLarge is returned when a value can't be stored, even split in chunks.
+	ErrValueTooLarge = errors.New("value too large to be stored")
+
+	// errChunkedWriteConflict the item was modified while writing a chunked value.
+	errChunkedWriteConflict = errors.New("item modified during chunked write")
+)
+
+// chunkKey returns the key of a chunk of a chunked item.
+func chunkKey(key, chunkID string, index int) string {
+	return fmt.Sprintf("%s%s%s/%d", key, chunkKeySeparator, chunkID, index)
+}
+
+// isChunkKey reports whether key is the key of a chunk item.
+func isChunkKey(key string) bool {
+	return strings.Contains(key, chunkKeySeparator)
+}
+
+// chunkInfo returns the chunk set referenced by an item, if any.
+func chunkInfo(item map[string]*dynamodb.AttributeValue) (string, int) {
+	id, ok := item[chunkIDAttribute]
+	if !ok {
+		return "", 0
+	}
+
+	var count int
+	if v, ok := item[chunksAttribute]; ok {
+		count, _ = strconv.Atoi(aws.StringValue(v.N))
+	}
+
+	return aws.StringValue(id.S), count
+}
+
+func isChunked(item map[string]*dynamodb.AttributeValue) bool {
+	_, count := chunkInfo(item)
+	return count > 0
+}
+
+func splitChunks(data []byte, size int) [][]byte {
+	var parts [][]byte
+	for len(data) > size {
+		parts = append(parts, data[:size])
+		data = data[size:]
+	}
+
+	return append(parts, data)
+}
+
+func newChunkID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// putChunked writes a value too large for a single item as chunk items, in a single transaction,
+// which also records the revision in the history if enabled.
+// The current item is read first, and passed to check if not nil.
+// The transaction is conditioned on the revision read,
+// errChunkedWriteConflict is returned if the item was modified concurrently.
+// It returns the new revision of the item.
+func (ddb *Store) putChunked(ctx context.Context, key string, data []byte, enc valueEncoding, opts *store.WriteOptions,
+	check func(current map[string]*dynamodb.AttributeValue) error,
+) (uint64, error) {
+	res, err := ddb.getKey(ctx, key, &store.ReadOptions{Consistent: true})
+	if err != nil {
+		return 0, err
+	}
+
+	current := res.Item
+	if check != nil {
+		if err = check(current); err != nil {
+			return 0, err
+		}
+	}
+
+	parts := splitChunks(data, ddb.chunkSize)
+	oldID, oldCount := chunkInfo(current)
+
+	var versions []*dynamodb.TransactWriteItem
+	revision := uint64(1)
+
+	if ddb.history != nil {
+		revision, _, err = ddb.nextRevision(ctx, key, current)
+		if err != nil {
+			return 0, err
+		}
+		versions = ddb.versionItems(key, revision, ddb.historyValue(key, data, enc))
+	} else if v, ok := current[ddb.revisionName()]; ok {
+		previous, err := strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		revision = previous + 1
+	}
+
+	if 1+len(parts)+oldCount+len(versions) > maxTransactionItems {
+		return 0, fmt.Errorf("%w: %d bytes", ErrValueTooLarge, len(data))
+	}
+
+	chunkID, err := newChunkID()
+	if err != nil {
+		return 0, err
+	}
+
+	exAttr := map[string]*dynamodb.AttributeValue{
+		":revision": {N: aws.String(strconv.FormatUint(revision, 10))},
+		":chunks":   {N: aws.String(strconv.Itoa(len(parts)))},
+		":chunkID":  {S: aws.String(chunkID)},
+	}
+
+	// the transaction being conditioned on the revision read, the revision is set rather than incremented,
+	// going on after the history for a new item.
+	setList := []string{
+		fmt.Sprintf("%s = :revision", revisionNamePlaceholder),
+		fmt.Sprintf("%s = :chunks", chunksAttribute),
+		fmt.Sprintf("%s = :chunkID", chunkIDAttribute),
+	}
+	exNames := map[string]*string{
+		revisionNamePlaceholder: aws.String(ddb.revisionName()),
+		keyNamePlaceholder:      aws.String(ddb.keyName()),
+		valueNamePlaceholder:    aws.String(ddb.valueName()),
+	}
+	removeList := []string{valueNamePlaceholder, s3ObjectAttribute}
+
+	if enc.codec != "" {
+		exAttr[":codec"] = &dynamodb.AttributeValue{S: aws.String(enc.codec)}
+		setList = append(setList, fmt.Sprintf("%s = :codec", compressionAttribute))
+	} else {
+		removeList = append(removeList, compressionAttribute)
+	}
+
+	if enc.dataKey != nil {
+		exAttr[":dataKey"] = &dynamodb.AttributeValue{B: enc.dataKey}
+		exAttr[":nonce"] = &dynamodb.AttributeValue{B: enc.nonce}
+		setList = append(setList,
+			fmt.Sprintf("%s = :dataKey", encryptionKeyAttribute), fmt.Sprintf("%s = :nonce", encryptionNonceAttribute))
+	} else {
+		removeList = append(removeList, encryptionKeyAttribute, encryptionNonceAttribute)
+	}
+
+	if enc.checksum != "" {
+		exAttr[":checksum"] = &dynamodb.AttributeValue{S: aws.String(enc.checksum)}
+		setList = append(setList, fmt.Sprintf("%s = :checksum", checksumAttribute))
+	} else {
+		removeList = append(removeList, checksumAttribute)
+	}
+
+	if enc.region != "" {
+		exAttr[":region"] = &dynamodb.AttributeValue{S: aws.String(enc.region)}
+		setList = append(setList, fmt.Sprintf("%s = :region", writerRegionAttribute))
+	}
+
+	if ddb.prefixIndex != "" {
+		exAttr[":prefix"] = &dynamodb.AttributeValue{S: aws.String(keyDirectory(ddb.storedKey(key), 1))}
+		setList = append(setList, fmt.Sprintf("%s = :prefix", prefixAttribute))
+	}
+
+	setList = appendTimestamps(setList, exAttr, exNames)
+
+	var ttlAttr *dynamodb.AttributeValue
+	if opts != nil && opts.TTL > 0 {
+		ttlAttr = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Add(opts.TTL).Unix(), 10))}
+		exAttr[":ttl"] = ttlAttr
+		exNames[ttlNamePlaceholder] = aws.String(ddb.ttlName())
+		setList = append(setList, fmt.Sprintf("%s = :ttl", ttlNamePlaceholder))
+	}
+
+	condExp := ddb.revisionCondition(current, exAttr, exNames)
+
+	items := []*dynamodb.TransactWriteItem{{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(ddb.tableName),
+			Key:                       ddb.keyAttributes(key),
+			UpdateExpression:          aws.String(fmt.Sprintf("SET %s REMOVE %s", strings.Join(setList, ","), strings.Join(removeList, ","))),
+			ConditionExpression:       aws.String(condExp),
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		},
+	}}
+
+	for i, part := range parts {
+		chunk := ddb.indexAttributes(key, ddb.keyAttributes(chunkKey(key, chunkID, i)))
+		chunk[ddb.valueName()] = ddb.dataAttribute(part)
+		if ttlAttr != nil {
+			chunk[ddb.ttlName()] = ttlAttr
+		}
+
+		items = append(items, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{TableName: aws.String(ddb.tableName), Item: chunk},
+		})
+	}
+
+	// the previous chunks are replaced atomically.
+	for i := 0; i < oldCount; i++ {
+		items = append(items, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				TableName: aws.String(ddb.tableName),
+				Key:       ddb.keyAttributes(chunkKey(key, oldID, i)),
+			},
+		})
+	}
+
+	items = append(items, versions...)
+
+	_, err = ddb.dynamoSvc.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		if isTransactionConditionFailed(err) {
+			return 0, errChunkedWriteConflict
+		}
+		return 0, err
+	}
+
+	if err = ddb.deleteS3Object(ctx, current); err != nil {
+		return 0, err
+	}
+
+	return revision, nil
+}
+
+// loadChunks returns a copy of a chunked item, with the value read from its chunks.
+// known contains the items already read, by key.
+func (ddb *Store) loadChunks(ctx context.Context, item map[string]*dynamodb.AttributeValue, consistent bool,
+	known map[string]map[string]*dynamodb.AttributeValue,
+) (map[string]*dynamodb.AttributeValue, error) {
+	key := ddb.itemKey(item)
+	chunkID, count := chunkInfo(item)
+
+	keys := make([]string, count)
+	var missing []string
+	for i := range keys {
+		keys[i] = chunkKey(key, chunkID, i)
+		if _, ok := known[keys[i]]; !ok {
+			missing = append(missing, keys[i])
+		}
+	}
+
+	fetched, err := ddb.batchGetItems(ctx, missing, consistent)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for _, k := range keys {
+		chunk, ok := known[k]
+		if !ok {
+			chunk, ok = fetched[k]
+		}
+		if !ok {
+			// the chunks were replaced since the item was read.
+			return nil, fmt.Errorf("%w: missing chunk %s", store.ErrKeyModified, k)
+		}
+
+		part, err := attributeData(chunk[ddb.valueName()])
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, part...)
+	}
+
+	loaded := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, v := range item {
+		loaded[name] = v
+	}
+	loaded[ddb.valueName()] = &dynamodb.AttributeValue{B: data}
+
+	return loaded, nil
+}
+
+// deleteChunks removes the chunk items referenced by the item of key, if any.
+func (ddb *Store) deleteChunks(ctx context.Context, key string, item map[string]*dynamodb.AttributeValue) error {
+	chunkID, count := chunkInfo(item)
+	if count == 0 {
+		return nil
+	}
+
+	requests := make([]*dynamodb.WriteRequest, count)
+	for i := range requests {
+		requests[i] = &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{
+				Key: ddb.keyAttributes(chunkKey(key, chunkID, i)),
+			},
+		}
+	}
+
+	return ddb.retryDeleteTree(ctx, map[string][]*dynamodb.WriteRequest{ddb.tableName: requests})
+}
+
+// putChunkedWithRetry writes a chunked value, retrying on concurrent modifications, and returns its revision.
+func (ddb *Store) putChunkedWithRetry(ctx context.Context, key string, data []byte, enc valueEncoding, opts *store.WriteOptions) (uint64, error) {
+	for i := 0; i < maxChunkedWriteAttempts; i++ {
+		revision, err := ddb.putChunked(ctx, key, data, enc, opts, nil)
+		if !errors.Is(err, errChunkedWriteConflict) {
+			return revision, err
+		}
+	}
+
+	return 0, store.ErrKeyModified
+}
+
+func isTransactionConditionFailed(err error) bool {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+
+	for _, reason := range canceled.CancellationReasons {
+		if aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isChunkSize reports whether data must be stored as chunks.
+func (ddb *Store) isChunkSize(data []byte) bool {
+	if ddb.s3Overflow != nil && len(data) > ddb.s3Overflow.Threshold {
+		return false
+	}
+
+	return ddb.chunkSize > 0 && len(data) > ddb.chunkSize
+}
+
+// hasExternalStorage reports whether values may be stored outside of their item.
+func (ddb *Store) hasExternalStorage() bool {
+	return ddb.chunkSize > 0 || ddb.s3Overflow != nil
+}
+
+// loadExternal returns the item with its value, if it's stored in chunks or in S3, decrypted if it's encrypted.
+// known contains the items already read, by key.
+func (ddb *Store) loadExternal(ctx context.Context, item map[string]*dynamodb.AttributeValue, consistent bool,
+	known map[string]map[string]*dynamodb.AttributeValue,
+) (map[string]*dynamodb.AttributeValue, error) {
+	var err error
+
+	switch {
+	case isChunked(item):
+		item, err = ddb.loadChunks(ctx, item, consistent, known)
+	case s3Object(item) != "":
+		if ddb.s3Overflow == nil {
+			return nil, ErrS3OverflowNotConfigured
+		}
+		item, err = ddb.loadS3Object(ctx, item)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.decryptItem(ctx, item)
+}
+
+// deleteExternal removes the chunks or the S3 object referenced by the item of key, if any.
+func (ddb *Store) deleteExternal(ctx context.Context, key string, item map[string]*dynamodb.AttributeValue) error {
+	if err := ddb.deleteChunks(ctx, key, item); err != nil {
+		return err
+	}
+
+	return ddb.deleteS3Object(ctx, item)
+}
@@ -0,0 +1,132 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStoreChunks(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+	ddbStore.chunkSize = 1024
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	key := "testChunks/large"
+	value := bytes.Repeat([]byte("0123456789"), 1000)
+
+	err := ddbStore.Put(ctx, key, value, nil)
+	require.NoError(t, err)
+
+	pair, err := ddbStore.Get(ctx, key, nil)
+	require.NoError(t, err)
+	assert.Equal(t, value, pair.Value)
+	assert.Equal(t, uint64(1), pair.LastIndex)
+
+	pairs, err := ddbStore.List(ctx, "testChunks", nil)
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, value, pairs[0].Value)
+
+	// replace the chunks with a smaller value.
+	ok, pair, err := ddbStore.AtomicPut(ctx, key, value[:3000], pair, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), pair.LastIndex)
+
+	_, _, err = ddbStore.AtomicPut(ctx, key, value, &store.KVPair{Key: key, LastIndex: 1}, nil)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	// back to a single item.
+	err = ddbStore.Put(ctx, key, []byte("small"), nil)
+	require.NoError(t, err)
+
+	items, err := ddbStore.scanPrefix(ctx, key, true)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	err = ddbStore.Delete(ctx, key)
+	require.NoError(t, err)
+}
+
+func TestSplitChunks(t *testing.T) {
+	assert.Equal(t, [][]byte{[]byte("abc"), []byte("def"), []byte("g")}, splitChunks([]byte("abcdefg"), 3))
+	assert.Equal(t, [][]byte{[]byte("abc")}, splitChunks([]byte("abc"), 3))
+}
+
+func TestLoadChunks(t *testing.T) {
+	kv := &Store{}
+
+	item := map[string]*dynamodb.AttributeValue{
+		partitionKey:      {S: aws.String("key")},
+		revisionAttribute: {N: aws.String("3")},
+		chunkIDAttribute:  {S: aws.String("abc")},
+		chunksAttribute:   {N: aws.String("2")},
+	}
+	assert.True(t, isChunked(item))
+
+	known := map[string]map[string]*dynamodb.AttributeValue{
+		"key#chunk#abc/0": {encodedValueAttribute: {S: aws.String("aGVsbG8g")}},
+		"key#chunk#abc/1": {encodedValueAttribute: {B: []byte("world")}},
+	}
+	for k := range known {
+		assert.True(t, isChunkKey(k))
+	}
+
+	loaded, err := kv.loadChunks(context.Background(), item, true, known)
+	require.NoError(t, err)
+
+	pair, err := kv.decodeItem(loaded)
+	require.NoError(t, err)
+	assert.Equal(t, &store.KVPair{Key: "key", Value: []byte("hello world"), LastIndex: 3}, pair)
+}
+
+func TestPutChunkedPrefix(t *testing.T) {
+	mock := &mockedChunkedPut{}
+	kv := &Store{
+		dynamoSvc:        mock,
+		tableName:        TestTableName,
+		chunkSize:        4,
+		prefixIndex:      "prefix-index",
+		keyPrefix:        "tenant/",
+		keyNormalization: &KeyNormalization{TrimLeadingSlash: true},
+	}
+
+	_, err := kv.putChunked(context.Background(), "/a/b", []byte("chunked value"), valueEncoding{}, nil, nil)
+	require.NoError(t, err)
+
+	// the item and its chunks are indexed under the directory of the stored key.
+	prefix := aws.StringValue(kv.indexAttributes("/a/b", nil)[prefixAttribute].S)
+	assert.Equal(t, "/tenant", prefix)
+
+	items := mock.input.TransactItems
+	require.Len(t, items, 5)
+	assert.Equal(t, prefix, aws.StringValue(items[0].Update.ExpressionAttributeValues[":prefix"].S))
+	for _, item := range items[1:] {
+		assert.Equal(t, prefix, aws.StringValue(item.Put.Item[prefixAttribute].S))
+	}
+}
+
+// mockedChunkedPut records the transaction of a chunked write of a new key.
+type mockedChunkedPut struct {
+	dynamodbiface.DynamoDBAPI
+	input *dynamodb.TransactWriteItemsInput
+}
+
+func (m *mockedChunkedPut) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m *mockedChunkedPut) TransactWriteItemsWithContext(_ aws.Context, input *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.input = input
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
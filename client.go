@@ -0,0 +1,117 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// NewFromClient creates a store on table with an already configured DynamoDB client.
+func NewFromClient(ctx context.Context, client dynamodbiface.DynamoDBAPI, table string) (*Store, error) {
+	return New(ctx, nil, &Config{Bucket: table, DynamoDBClient: client})
+}
+
+// readSvc returns the client of the reads: the DAX client if any, the DynamoDB client otherwise.
+func (ddb *Store) readSvc() dynamodbiface.DynamoDBAPI {
+	if ddb.daxSvc != nil {
+		return ddb.daxSvc
+	}
+	return ddb.dynamoSvc
+}
+
+// initClients sets the AWS clients of the store,
+// creating a session for the clients not provided in the configuration.
+func (ddb *Store) initClients(endpoints []string, options *Config) error {
+	ddb.dynamoSvc, ddb.streamsSvc, ddb.daxSvc = options.DynamoDBClient, options.StreamsClient, options.DAXClient
+	if options.S3Overflow != nil {
+		ddb.s3Svc = options.S3Client
+	}
+	if options.Encryption != nil {
+		ddb.kmsSvc = options.KMSClient
+	}
+	if options.AutoScaling != nil {
+		ddb.scalingSvc = options.AutoScalingClient
+	}
+
+	if ddb.dynamoSvc == nil || ddb.streamsSvc == nil || (options.S3Overflow != nil && ddb.s3Svc == nil) ||
+		(options.Encryption != nil && ddb.kmsSvc == nil) || (options.AutoScaling != nil && ddb.scalingSvc == nil) {
+		sess, err := newSession(endpoints, options)
+		if err != nil {
+			return err
+		}
+
+		if ddb.dynamoSvc == nil {
+			ddb.dynamoSvc = dynamodb.New(sess)
+		}
+		if ddb.streamsSvc == nil {
+			ddb.streamsSvc = dynamodbstreams.New(sess)
+		}
+		if ddb.s3Svc == nil && options.S3Overflow != nil {
+			ddb.s3Svc = s3.New(sess)
+		}
+		if ddb.kmsSvc == nil && options.Encryption != nil {
+			ddb.kmsSvc = kms.New(sess)
+		}
+		if ddb.scalingSvc == nil && options.AutoScaling != nil {
+			ddb.scalingSvc = applicationautoscaling.New(sess)
+		}
+	}
+
+	if options.GlobalTable != nil {
+		if err := ddb.initReplicas(options); err != nil {
+			return err
+		}
+	}
+
+	ddb.wrapClients(options)
+
+	return nil
+}
+
+// wrapClients runs the calls of the DynamoDB clients through the interceptors of the options.
+func (ddb *Store) wrapClients(options *Config) {
+	// the errors are wrapped once, for the operations of the store.
+	interceptors := []callInterceptor{errorInterceptor()}
+
+	// the keys written are evicted once, after all the attempts.
+	if ddb.cache != nil {
+		interceptors = append(interceptors, cacheInterceptor(ddb.cache))
+	}
+	// one span for all the attempts of a call.
+	if options.Tracer != nil {
+		interceptors = append(interceptors, traceInterceptor(options.Tracer))
+	}
+	// the latency of a call includes its retries.
+	if options.EMF != nil {
+		interceptors = append(interceptors, emfInterceptor(newEMFEmitter(options.EMF)))
+	}
+	// a call fails once its retries are exhausted.
+	if options.CircuitBreaker != nil {
+		interceptors = append(interceptors, breakerInterceptor(newCircuitBreaker(options.CircuitBreaker)))
+	}
+	if options.RetryPolicy != nil && (options.RetryPolicy.MaxAttempts > 1 || options.RetryPolicy.ThrottlingMaxAttempts > 1) {
+		interceptors = append(interceptors, retryInterceptor(options.RetryPolicy))
+	}
+	if options.RateLimit != nil {
+		interceptors = append(interceptors, rateLimitInterceptor(options.RateLimit))
+	}
+	// each attempt holds a slot, not while waiting for the retry or the rate limit.
+	if options.ConcurrencyLimit != nil && options.ConcurrencyLimit.MaxInFlight > 0 {
+		interceptors = append(interceptors, bulkheadInterceptor(newBulkhead(options.ConcurrencyLimit)))
+	}
+	// the capacity consumed by each attempt.
+	if options.ReturnConsumedCapacity {
+		ddb.capacity = &capacityStats{}
+		interceptors = append(interceptors, capacityInterceptor(ddb.capacity))
+	}
+
+	ddb.dynamoSvc = &wrappedDynamoDB{DynamoDBAPI: ddb.dynamoSvc, ddb: ddb, interceptors: interceptors}
+	if ddb.daxSvc != nil {
+		ddb.daxSvc = &wrappedDynamoDB{DynamoDBAPI: ddb.daxSvc, ddb: ddb, interceptors: interceptors}
+	}
+}
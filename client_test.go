@@ -0,0 +1,57 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromClient(t *testing.T) {
+	client := &mockedDescribeTable{}
+
+	kv, err := NewFromClient(context.Background(), client, TestTableName)
+	require.NoError(t, err)
+	// the client is wrapped to type its errors.
+	wrapped, ok := kv.dynamoSvc.(*wrappedDynamoDB)
+	require.True(t, ok)
+	assert.Same(t, client, wrapped.DynamoDBAPI)
+	assert.NotNil(t, kv.streamsSvc)
+	assert.Nil(t, kv.s3Svc)
+	assert.Equal(t, TestTableName, kv.tableName)
+
+	streams := &mockedStreams{}
+	kv, err = New(context.Background(), nil, &Config{Bucket: TestTableName, DynamoDBClient: client, StreamsClient: streams})
+	require.NoError(t, err)
+	assert.Same(t, streams, kv.streamsSvc)
+}
+
+func TestNewSessionAWSConfig(t *testing.T) {
+	base := aws.NewConfig().WithMaxRetries(7).WithRegion("us-east-1")
+
+	sess, err := newSession(nil, &Config{AWSConfig: base, Region: "eu-west-3"})
+	require.NoError(t, err)
+	assert.Equal(t, 7, aws.IntValue(sess.Config.MaxRetries))
+	assert.Equal(t, "eu-west-3", aws.StringValue(sess.Config.Region))
+
+	// the base configuration is not modified.
+	assert.Equal(t, "us-east-1", aws.StringValue(base.Region))
+}
+
+func TestDAXReads(t *testing.T) {
+	// GetItem isn't mocked on the DynamoDB client: the reads must go to DAX.
+	writes := &mockedConditionalUpdate{}
+	reads := &mockedGetItem{items: map[string]string{"key": "dmFsdWU="}}
+
+	kv := &Store{dynamoSvc: writes, daxSvc: reads, tableName: TestTableName}
+	ctx := context.Background()
+
+	pair, err := kv.Get(ctx, "key", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), pair.Value)
+
+	require.NoError(t, kv.Put(ctx, "key", []byte("value"), nil))
+	require.NotNil(t, writes.input)
+}
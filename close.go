@@ -0,0 +1,110 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrStoreClosed is returned when starting a watch, a lock, or a lease on a closed store,
+// and is the reason of the locks and the leases lost by closing the store.
+var ErrStoreClosed = errors.New("store closed")
+
+// backgroundTasks tracks the goroutines of the watches, the lock renewals, and the leases,
+// which Close cancels and waits for.
+type backgroundTasks struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+	// closeCh is closed by Close, created with the first task.
+	closeCh chan struct{}
+}
+
+// start registers a task, and returns its context, canceled when parent is done or the store is closed,
+// and the function ending the task, to call once the task has returned.
+func (t *backgroundTasks) start(parent context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, nil, ErrStoreClosed
+	}
+
+	if t.closeCh == nil {
+		t.closeCh = make(chan struct{})
+	}
+
+	t.wg.Add(1)
+
+	ctx, cancel := context.WithCancel(parent)
+
+	go func(closeCh <-chan struct{}) {
+		select {
+		case <-closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}(t.closeCh)
+
+	var once sync.Once
+
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			t.wg.Done()
+		})
+	}, nil
+}
+
+// isClosed reports whether the store was closed.
+func (t *backgroundTasks) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.closed
+}
+
+// close cancels the tasks, and waits for them to end.
+func (t *backgroundTasks) close() {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+
+		if t.closeCh != nil {
+			close(t.closeCh)
+		}
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+}
+
+// Close flushes and closes the open write buffers, then stops the watches, the renewals of the locks and the leases,
+// the background deletion of the expired items, the invalidation of the cache, and the notifier,
+// such as the consumer of the DynamoDB stream.
+// It returns once they have all stopped. The locks and the leases held are lost with ErrStoreClosed,
+// their items expiring with their TTL, and the watch channels are closed.
+// The pending writes are retried for up to 30 seconds, then dropped,
+// and the first error of the flushes is returned, naming the keys not written.
+func (ddb *Store) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeBufferCloseTimeout)
+	flushErr := ddb.writeBuffers.close(ctx)
+	cancel()
+
+	ddb.tasks.close()
+
+	if ddb.janitor != nil {
+		ddb.janitor.stop()
+	}
+	if ddb.cache != nil {
+		ddb.cache.close()
+	}
+
+	if ddb.notifier != nil {
+		if err := ddb.notifier.Close(); err != nil {
+			return err
+		}
+	}
+
+	return flushErr
+}
@@ -0,0 +1,55 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClose(t *testing.T) {
+	kv := &Store{
+		dynamoSvc: &mockedLockTable{},
+		tableName: TestTableName,
+		notifier:  &chanNotifier{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	events, err := kv.WatchWithOptions(ctx, "key", &WatchOptions{SkipInitial: true})
+	require.NoError(t, err)
+
+	locker, err := kv.NewLock(ctx, "lock", nil)
+	require.NoError(t, err)
+
+	lockHeld, err := locker.Lock(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, kv.Close())
+
+	// Close returns once the watches and the renewals have stopped.
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	default:
+		t.Fatal("watch channel not closed")
+	}
+
+	select {
+	case <-lockHeld:
+	default:
+		t.Fatal("lock not lost")
+	}
+
+	lock, ok := locker.(*Lock)
+	require.True(t, ok)
+	assert.ErrorIs(t, lock.Err(), ErrStoreClosed)
+
+	_, err = kv.Watch(ctx, "key", nil)
+	assert.ErrorIs(t, err, ErrStoreClosed)
+
+	_, err = locker.Lock(ctx)
+	assert.ErrorIs(t, err, ErrStoreClosed)
+}
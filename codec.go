@@ -0,0 +1,200 @@
+package dynamodb
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// ErrCodecConflict is returned when a Codec is configured with an option encoding the values itself.
+	ErrCodecConflict = errors.New("codec conflicts with the value encoding options")
+	// ErrCodecInvalidValue is returned when a Codec can't encode or decode a value.
+	ErrCodecInvalidValue = errors.New("invalid value for codec")
+)
+
+// Codec encodes the values into the attributes of their items, replacing the value attribute of the store.
+// The codecs are composed by wrapping one another, such as NewCompressionCodec(config, NewBinaryCodec("data")).
+type Codec interface {
+	// Encode returns the attributes storing value, a nil attribute value removing the attribute.
+	Encode(value []byte) (map[string]*dynamodb.AttributeValue, error)
+
+	// Decode returns the value stored in the attributes of an item.
+	Decode(attrs map[string]*dynamodb.AttributeValue) ([]byte, error)
+}
+
+// binaryCodec stores the values as raw bytes in a binary attribute.
+type binaryCodec struct {
+	attribute string
+}
+
+// NewBinaryCodec returns the Codec storing the values as raw bytes in the binary attribute.
+func NewBinaryCodec(attribute string) Codec {
+	return &binaryCodec{attribute: attribute}
+}
+
+func (c *binaryCodec) Encode(value []byte) (map[string]*dynamodb.AttributeValue, error) {
+	return map[string]*dynamodb.AttributeValue{c.attribute: {B: value}}, nil
+}
+
+func (c *binaryCodec) Decode(attrs map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	v, ok := attrs[c.attribute]
+	if !ok || v == nil {
+		return []byte{}, nil
+	}
+
+	return attributeData(v)
+}
+
+// stringCodec stores the values as text in a string attribute.
+type stringCodec struct {
+	attribute string
+}
+
+// NewStringCodec returns the Codec storing the values as is in the string attribute,
+// so text values, like JSON documents, are readable in the console.
+// The values must be valid UTF-8.
+func NewStringCodec(attribute string) Codec {
+	return &stringCodec{attribute: attribute}
+}
+
+func (c *stringCodec) Encode(value []byte) (map[string]*dynamodb.AttributeValue, error) {
+	if !utf8.Valid(value) {
+		return nil, fmt.Errorf("%w: not UTF-8", ErrCodecInvalidValue)
+	}
+
+	return map[string]*dynamodb.AttributeValue{c.attribute: {S: aws.String(string(value))}}, nil
+}
+
+func (c *stringCodec) Decode(attrs map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	v, ok := attrs[c.attribute]
+	if !ok || v == nil {
+		return []byte{}, nil
+	}
+
+	return []byte(aws.StringValue(v.S)), nil
+}
+
+// compressionCodec compresses the values above a size threshold, then encodes them with the next codec.
+type compressionCodec struct {
+	config CompressionConfig
+	next   Codec
+}
+
+// NewCompressionCodec returns the Codec compressing the values above the threshold of config,
+// and encoding the result with next, which must hold binary data.
+// The codec used is stored in the "compression" attribute.
+func NewCompressionCodec(config CompressionConfig, next Codec) (Codec, error) {
+	if !isCompressionSupported(config.Codec) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, config.Codec)
+	}
+
+	return &compressionCodec{config: config, next: next}, nil
+}
+
+func (c *compressionCodec) Encode(value []byte) (map[string]*dynamodb.AttributeValue, error) {
+	data, codec := value, ""
+
+	if len(value) >= c.config.Threshold {
+		compressed, err := compress(c.config.Codec, value)
+		if err != nil {
+			return nil, err
+		}
+
+		// only keep the compressed value if it's worth it.
+		if len(compressed) < len(value) {
+			data, codec = compressed, c.config.Codec
+		}
+	}
+
+	attrs, err := c.next.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs[compressionAttribute] = nil
+	if codec != "" {
+		attrs[compressionAttribute] = &dynamodb.AttributeValue{S: aws.String(codec)}
+	}
+
+	return attrs, nil
+}
+
+func (c *compressionCodec) Decode(attrs map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	data, err := c.next.Decode(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := attrs[compressionAttribute]; ok && v != nil {
+		return decompress(aws.StringValue(v.S), data)
+	}
+
+	return data, nil
+}
+
+// aeadCodec encrypts the values, then encodes them with the next codec.
+type aeadCodec struct {
+	aead cipher.AEAD
+	next Codec
+}
+
+// NewAEADCodec returns the Codec encrypting the values with aead, such as AES-GCM,
+// and encoding the nonce followed by the encrypted value with next, which must hold binary data.
+// Unlike the Encryption option, all the values are encrypted with the same key, held by the application.
+func NewAEADCodec(aead cipher.AEAD, next Codec) Codec {
+	return &aeadCodec{aead: aead, next: next}
+}
+
+func (c *aeadCodec) Encode(value []byte) (map[string]*dynamodb.AttributeValue, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(value)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.next.Encode(c.aead.Seal(nonce, nonce, value, nil))
+}
+
+func (c *aeadCodec) Decode(attrs map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	data, err := c.next.Decode(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: missing nonce", ErrValueDecryption)
+	}
+
+	value, err := c.aead.Open(nil, data[:c.aead.NonceSize()], data[c.aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrValueDecryption, err)
+	}
+
+	return value, nil
+}
+
+// checkCodec checks the options encoding the values are not combined with a Codec.
+func (c *Config) checkCodec() error {
+	if c.Codec == nil && !c.DocumentMode {
+		return nil
+	}
+
+	if c.Codec != nil && c.DocumentMode {
+		return fmt.Errorf("%w: DocumentMode is set", ErrCodecConflict)
+	}
+
+	if c.DocumentMode && c.Checksum != nil {
+		return fmt.Errorf("%w: Checksum is set with DocumentMode", ErrCodecConflict)
+	}
+
+	if c.Compression != nil || c.Encryption != nil || c.ChunkSize > 0 || c.S3Overflow != nil {
+		return fmt.Errorf("%w: Compression, Encryption, ChunkSize, or S3Overflow is set", ErrCodecConflict)
+	}
+
+	return nil
+}
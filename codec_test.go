@@ -0,0 +1,61 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	codec, err := NewCompressionCodec(CompressionConfig{Codec: CompressionZstd, Threshold: 100},
+		NewAEADCodec(aead, NewBinaryCodec("data")))
+	require.NoError(t, err)
+
+	kv := &Store{codec: codec}
+	ctx := context.Background()
+
+	value := bytes.Repeat([]byte("valkeyrie"), 100)
+
+	data, enc, err := kv.encodeValue(ctx, "key", value)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	attrs := kv.valueAttributes(data, enc)
+	assert.Contains(t, attrs, encodedValueAttribute)
+	assert.Nil(t, attrs[encodedValueAttribute])
+	assert.Equal(t, CompressionZstd, aws.StringValue(attrs[compressionAttribute].S))
+	assert.Less(t, len(attrs["data"].B), len(value))
+
+	attrs[partitionKey] = &dynamodb.AttributeValue{S: aws.String("key")}
+	pair, err := kv.decodeItem(attrs)
+	require.NoError(t, err)
+	assert.Equal(t, value, pair.Value)
+
+	attrs["data"].B[len(attrs["data"].B)-1] ^= 1
+	_, err = kv.decodeItem(attrs)
+	assert.ErrorIs(t, err, ErrValueDecryption)
+
+	kv.codec = NewStringCodec("doc")
+
+	_, enc, err = kv.encodeValue(ctx, "key", []byte(`{"a":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, aws.StringValue(enc.attrs["doc"].S))
+
+	_, _, err = kv.encodeValue(ctx, "key", []byte{0xff})
+	assert.ErrorIs(t, err, ErrCodecInvalidValue)
+
+	_, err = New(ctx, nil, &Config{Bucket: TestTableName, Codec: codec, ChunkSize: 1000})
+	assert.ErrorIs(t, err, ErrCodecConflict)
+}
@@ -0,0 +1,88 @@
+package dynamodb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+const compressionAttribute = "compression"
+
+// ErrUnsupportedCompression is returned when a compression codec is unknown.
+var ErrUnsupportedCompression = errors.New("unsupported compression codec")
+
+// CompressionConfig the value compression configuration.
+type CompressionConfig struct {
+	// Codec is the compression codec: CompressionGzip or CompressionZstd.
+	Codec string
+	// Threshold is the size, in bytes, from which values are compressed.
+	Threshold int
+}
+
+func isCompressionSupported(codec string) bool {
+	return codec == CompressionGzip || codec == CompressionZstd
+}
+
+func compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = enc.Close() }()
+
+		return enc.EncodeAll(data, nil), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, codec)
+	}
+}
+
+func decompress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = r.Close() }()
+
+		return io.ReadAll(r)
+
+	case CompressionZstd:
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+
+		return dec.DecodeAll(data, nil)
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, codec)
+	}
+}
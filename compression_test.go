@@ -0,0 +1,68 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	value := bytes.Repeat([]byte("valkeyrie"), 100)
+
+	for _, codec := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(codec, func(t *testing.T) {
+			kv := &Store{compression: &CompressionConfig{Codec: codec, Threshold: 100}}
+
+			data, enc, err := kv.encodeValue(context.Background(), "key", value)
+			require.NoError(t, err)
+			assert.Equal(t, codec, enc.codec)
+
+			attrs := kv.valueAttributes(data, enc)
+			assert.Equal(t, codec, aws.StringValue(attrs[compressionAttribute].S))
+
+			attrs[partitionKey] = &dynamodb.AttributeValue{S: aws.String("key")}
+			pair, err := kv.decodeItem(attrs)
+			require.NoError(t, err)
+			assert.Equal(t, value, pair.Value)
+
+			// small values are not compressed.
+			data, enc, err = kv.encodeValue(context.Background(), "key", []byte("small"))
+			require.NoError(t, err)
+			assert.Empty(t, enc.codec)
+			assert.Equal(t, []byte("small"), data)
+		})
+	}
+}
+
+func TestWriteUpdate(t *testing.T) {
+	kv := &Store{}
+
+	updateExp, exAttr, exNames := kv.writeUpdate(kv.valueAttributes([]byte("a"), valueEncoding{codec: CompressionGzip}), nil)
+	assert.Equal(t, "ADD #revision :incr SET #attr3 = :val3,#attr4 = :val4,"+
+		"#createdAt = if_not_exists(#createdAt, :now),#updatedAt = :now REMOVE #attr0,#attr1,#attr2,#attr5,#attr6,#attr7", updateExp)
+	assert.Len(t, exAttr, 4)
+	assert.Equal(t, "version", aws.StringValue(exNames["#revision"]))
+	assert.Equal(t, "encoded_value", aws.StringValue(exNames["#attr4"]))
+	assert.Equal(t, "chunk_id", aws.StringValue(exNames["#attr1"]))
+
+	updateExp, exAttr, _ = kv.writeUpdate(kv.valueAttributes([]byte("a"), valueEncoding{}), nil)
+	assert.Equal(t, "ADD #revision :incr SET #attr4 = :val4,"+
+		"#createdAt = if_not_exists(#createdAt, :now),#updatedAt = :now REMOVE #attr0,#attr1,#attr2,#attr3,#attr5,#attr6,#attr7", updateExp)
+	assert.Len(t, exAttr, 3)
+
+	updateExp, _, exNames = kv.writeUpdate(nil, &store.WriteOptions{TTL: time.Minute})
+	assert.Equal(t, "ADD #revision :incr SET #createdAt = if_not_exists(#createdAt, :now),#updatedAt = :now,#ttl = :ttl", updateExp)
+	assert.Equal(t, "expiration_time", aws.StringValue(exNames["#ttl"]))
+}
+
+func TestNewUnsupportedCompression(t *testing.T) {
+	_, err := New(context.Background(), nil, &Config{Bucket: TestTableName, Compression: &CompressionConfig{Codec: "lz4"}})
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+}
@@ -0,0 +1,36 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// PutIfNotExists creates a key, and returns store.ErrKeyExists if it already exists.
+// The expired keys don't exist.
+func (ddb *Store) PutIfNotExists(ctx context.Context, key string, value []byte, opts *store.WriteOptions) (*store.KVPair, error) {
+	_, pair, err := ddb.AtomicPut(ctx, key, value, nil, opts)
+
+	return pair, err
+}
+
+// PutIfValueEquals replaces the value of a key only if its current value equals expected,
+// and returns store.ErrKeyModified if it doesn't, or store.ErrKeyNotFound if the key doesn't exist.
+// The current value is read, then replaced conditionally on its revision:
+// a key written between the read and the replacement returns store.ErrKeyModified, even if its value is unchanged.
+func (ddb *Store) PutIfValueEquals(ctx context.Context, key string, value, expected []byte, opts *store.WriteOptions) (*store.KVPair, error) {
+	// not served from the cache.
+	current, _, err := ddb.GetWithMeta(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(current.Value, expected) {
+		return nil, store.ErrKeyModified
+	}
+
+	_, pair, err := ddb.AtomicPut(ctx, key, value, current, opts)
+
+	return pair, err
+}
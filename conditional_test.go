@@ -0,0 +1,37 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalPut(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLockTable{}, tableName: TestTableName}
+	ctx := context.Background()
+
+	_, err := kv.PutIfValueEquals(ctx, "testConditionalPut", []byte("b"), []byte("a"), nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	pair, err := kv.PutIfNotExists(ctx, "testConditionalPut", []byte("a"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), pair.Value)
+
+	_, err = kv.PutIfNotExists(ctx, "testConditionalPut", []byte("a"), nil)
+	assert.ErrorIs(t, err, store.ErrKeyExists)
+
+	_, err = kv.PutIfValueEquals(ctx, "testConditionalPut", []byte("c"), []byte("b"), nil)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	pair, err = kv.PutIfValueEquals(ctx, "testConditionalPut", []byte("b"), []byte("a"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), pair.Value)
+	assert.Equal(t, uint64(2), pair.LastIndex)
+
+	current, err := kv.Get(ctx, "testConditionalPut", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), current.Value)
+}
@@ -0,0 +1,127 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// writerRegionAttribute the attribute holding the region of the last write of a key.
+const writerRegionAttribute = "writer_region"
+
+// ConflictConfig configures the detection of the writes of a global table overwritten by the concurrent writes
+// of another region, which replicate with the last writer wins, even if they are conditional like AtomicPut.
+// The revision of a key serves as its Lamport clock: a write replicated over a write of the same or a later revision
+// overwrote it without seeing it.
+// The conflicts are detected on the records of the stream read by Watch and WatchTree, so only while the store watches,
+// and the stream must hold the new and the old images of the items (dynamodb.StreamViewTypeNewAndOldImages).
+type ConflictConfig struct {
+	// Region is the region written with the values, defaults to Config.Region.
+	Region string
+
+	// OnConflict is called with the conflicts detected, by the stream consumer: it must not block.
+	OnConflict func(conflict *Conflict)
+}
+
+// Conflict is a write overwritten by the concurrent write of another region.
+// The values stored in chunks or in S3 may not be readable anymore, and are then nil.
+type Conflict struct {
+	Key string
+
+	// Lost is the overwritten pair, written in LostRegion.
+	Lost       *store.KVPair
+	LostRegion string
+
+	// Won is the pair replacing it, written in WonRegion.
+	Won       *store.KVPair
+	WonRegion string
+}
+
+// conflictConfig returns the conflict detection configuration, with its region defaulted.
+func (c *Config) conflictConfig() *ConflictConfig {
+	if c.Conflicts == nil {
+		return nil
+	}
+
+	conflicts := *c.Conflicts
+	if conflicts.Region == "" {
+		conflicts.Region = c.Region
+	}
+
+	return &conflicts
+}
+
+// writerRegion returns the region written with the values, empty if the conflict detection is disabled.
+func (ddb *Store) writerRegion() string {
+	if ddb.conflicts == nil {
+		return ""
+	}
+
+	return ddb.conflicts.Region
+}
+
+// detectConflict reports the conflict of the update record, if its new revision doesn't follow its old one.
+func (ddb *Store) detectConflict(ctx context.Context, record *dynamodbstreams.Record) {
+	if ddb.conflicts == nil || ddb.conflicts.OnConflict == nil || record.Dynamodb == nil {
+		return
+	}
+
+	oldImage, newImage := record.Dynamodb.OldImage, record.Dynamodb.NewImage
+	if len(oldImage) == 0 || len(newImage) == 0 {
+		return
+	}
+
+	oldRevision, newRevision := itemRevision(oldImage, ddb.revisionName()), itemRevision(newImage, ddb.revisionName())
+	if newRevision == 0 || newRevision > oldRevision {
+		return
+	}
+
+	key := ddb.itemKey(record.Dynamodb.Keys)
+
+	ddb.conflicts.OnConflict(&Conflict{
+		Key:        key,
+		Lost:       ddb.imagePair(ctx, key, oldImage),
+		LostRegion: itemRegion(oldImage),
+		Won:        ddb.imagePair(ctx, key, newImage),
+		WonRegion:  itemRegion(newImage),
+	})
+}
+
+// imagePair returns the pair of a stream image, without value if it can't be read.
+func (ddb *Store) imagePair(ctx context.Context, key string, image map[string]*dynamodb.AttributeValue) *store.KVPair {
+	item, err := ddb.loadExternal(ctx, image, true, nil)
+	if err == nil {
+		var pair *store.KVPair
+		if pair, err = ddb.decodeItem(item); err == nil {
+			return pair
+		}
+	}
+
+	ddb.log().Info("stream image value unreadable", "key", key, "error", err)
+
+	return &store.KVPair{Key: key, LastIndex: itemRevision(image, ddb.revisionName())}
+}
+
+// itemRevision returns the revision of item, 0 if it has none.
+func itemRevision(item map[string]*dynamodb.AttributeValue, name string) uint64 {
+	v, ok := item[name]
+	if !ok || v == nil {
+		return 0
+	}
+
+	revision, _ := strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+
+	return revision
+}
+
+// itemRegion returns the region of the last write of item, empty if unknown.
+func itemRegion(item map[string]*dynamodb.AttributeValue) string {
+	if v, ok := item[writerRegionAttribute]; ok && v != nil {
+		return aws.StringValue(v.S)
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConflicts(t *testing.T) {
+	var conflicts []*Conflict
+
+	options := &Config{Region: "eu-west-1", Conflicts: &ConflictConfig{OnConflict: func(conflict *Conflict) {
+		conflicts = append(conflicts, conflict)
+	}}}
+	kv := &Store{tableName: TestTableName, conflicts: options.conflictConfig()}
+
+	ctx := context.Background()
+
+	// the writes hold their region.
+	data, enc, err := kv.encodeValue(ctx, "key", []byte("value"))
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", aws.StringValue(kv.valueAttributes(data, enc)[writerRegionAttribute].S))
+
+	image := func(value string, revision, region string) map[string]*dynamodb.AttributeValue {
+		item := newTestItem("key", value)
+		item[revisionAttribute] = &dynamodb.AttributeValue{N: aws.String(revision)}
+		item[writerRegionAttribute] = &dynamodb.AttributeValue{S: aws.String(region)}
+		return item
+	}
+	record := func(oldImage, newImage map[string]*dynamodb.AttributeValue) *dynamodbstreams.Record {
+		return &dynamodbstreams.Record{
+			EventName: aws.String(dynamodbstreams.OperationTypeModify),
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				Keys:     map[string]*dynamodb.AttributeValue{partitionKey: {S: aws.String("key")}},
+				OldImage: oldImage,
+				NewImage: newImage,
+			},
+		}
+	}
+
+	// a write following the previous one.
+	kv.detectConflict(ctx, record(image("dmFsdWUx", "2", "eu-west-1"), image("dmFsdWUy", "3", "us-east-1")))
+	assert.Empty(t, conflicts)
+
+	// a write of the same revision replicated over the local one.
+	kv.detectConflict(ctx, record(image("dmFsdWUx", "3", "eu-west-1"), image("dmFsdWUy", "3", "us-east-1")))
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "key", conflicts[0].Key)
+	assert.Equal(t, []byte("value1"), conflicts[0].Lost.Value)
+	assert.Equal(t, "eu-west-1", conflicts[0].LostRegion)
+	assert.Equal(t, []byte("value2"), conflicts[0].Won.Value)
+	assert.Equal(t, uint64(3), conflicts[0].Won.LastIndex)
+	assert.Equal(t, "us-east-1", conflicts[0].WonRegion)
+}
@@ -0,0 +1,124 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// ErrCopyTreeOverlap is returned when the destination prefix of CopyTree is under its source prefix.
+var ErrCopyTreeOverlap = errors.New("destination prefix under the source prefix")
+
+// CopyOptions configures Copy and CopyTree.
+type CopyOptions struct {
+	// KeepTTL keeps the remaining time to live of the keys copied, the copies don't expire otherwise.
+	KeepTTL bool
+}
+
+// Copy writes the value of the key src at dst, replacing its value if it exists.
+// It returns store.ErrKeyNotFound if src doesn't exist.
+// The write runs through the middlewares as an OperationCopy of dst.
+func (ddb *Store) Copy(ctx context.Context, src, dst string, opts *CopyOptions) error {
+	current, meta, err := ddb.GetWithMeta(ctx, src, nil)
+	if err != nil {
+		return err
+	}
+
+	writeOpts, err := copyWriteOptions(meta, opts)
+	if err != nil {
+		return err
+	}
+
+	op := &Operation{Name: OperationCopy, Key: dst, Value: current.Value}
+
+	return ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pair, err = ddb.putWithResult(ctx, op.Key, op.Value, writeOpts)
+		return err
+	})
+}
+
+// CopyTree copies the keys starting with srcPrefix to the keys starting with dstPrefix instead,
+// replacing their values if they exist, such as to promote a configuration from "staging/" to "prod/".
+// The keys are read page by page, and the keys of each page are written like PutMany,
+// each key running through the middlewares as an OperationCopyTree.
+// The copy isn't atomic: if some keys are not written, the others are, and a *BatchWriteError is returned.
+func (ddb *Store) CopyTree(ctx context.Context, srcPrefix, dstPrefix string, opts *CopyOptions) error {
+	// the keys read are normalized, so are the prefixes they are moved between.
+	srcPrefix, dstPrefix = ddb.normalizePrefix(srcPrefix), ddb.normalizePrefix(dstPrefix)
+
+	if strings.HasPrefix(dstPrefix, srcPrefix) {
+		return fmt.Errorf("%w: %s, %s", ErrCopyTreeOverlap, srcPrefix, dstPrefix)
+	}
+
+	failed := make(map[string]error)
+
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		items, lastKey, err := ddb.prefixPage(ctx, srcPrefix, true, nil, startKey)
+		if err != nil {
+			return err
+		}
+
+		if err = ddb.copyItems(ctx, items, srcPrefix, dstPrefix, opts, failed); err != nil {
+			return err
+		}
+
+		if len(lastKey) == 0 {
+			return batchError(failed)
+		}
+		startKey = lastKey
+	}
+}
+
+// copyItems writes the values of the items at the keys starting with dstPrefix instead of srcPrefix,
+// and adds the keys not written to failed.
+func (ddb *Store) copyItems(ctx context.Context, items []map[string]*dynamodb.AttributeValue, srcPrefix, dstPrefix string,
+	opts *CopyOptions, failed map[string]error,
+) error {
+	pairs, err := ddb.decodeItems(ctx, items, srcPrefix, true)
+	if err != nil {
+		return err
+	}
+
+	metas := make(map[string]*KeyMeta, len(items))
+	for _, item := range items {
+		metas[ddb.itemKey(item)] = ddb.itemMeta(ddb.itemKey(item), item)
+	}
+
+	copies := make([]*store.KVPair, 0, len(pairs))
+	writeOpts := make(map[string]*store.WriteOptions, len(pairs))
+
+	for _, pair := range pairs {
+		pairOpts, err := copyWriteOptions(metas[pair.Key], opts)
+		if err != nil {
+			// expired since it was read.
+			continue
+		}
+
+		dst := ddb.normalizeKey(dstPrefix + strings.TrimPrefix(pair.Key, srcPrefix))
+		copies = append(copies, &store.KVPair{Key: dst, Value: pair.Value})
+		writeOpts[dst] = pairOpts
+	}
+
+	if len(copies) == 0 {
+		return nil
+	}
+
+	ddb.putPairs(ctx, OperationCopyTree, copies, func(pair *store.KVPair) *store.WriteOptions { return writeOpts[pair.Key] }, failed)
+
+	return nil
+}
+
+// copyWriteOptions returns the write options of the copy of a key.
+func copyWriteOptions(meta *KeyMeta, opts *CopyOptions) (*store.WriteOptions, error) {
+	if opts == nil || !opts.KeepTTL {
+		return nil, nil
+	}
+
+	return remainingTTL(meta)
+}
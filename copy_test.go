@@ -0,0 +1,98 @@
+package dynamodb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyTree(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	mock := &mockedCopyTree{mockedBatchStore: mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"staging/a": newTestItem("staging/a", "dmFsdWUx"),
+			"staging/b": newTestItem("staging/b", "dmFsdWUy"),
+			"prod/b":    newTestItem("prod/b", "b2xk"),
+			"other/c":   newTestItem("other/c", "dmFsdWUz"),
+		},
+		failBatch: -1,
+	}}
+	mock.items["staging/b"][ttlAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt, 10))}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	err := kv.CopyTree(ctx, "staging/", "staging/copy/", nil)
+	assert.ErrorIs(t, err, ErrCopyTreeOverlap)
+
+	err = kv.CopyTree(ctx, "staging/", "prod/", &CopyOptions{KeepTTL: true})
+	require.NoError(t, err)
+
+	keys := make([]string, 0, len(mock.written))
+	for key := range mock.written {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"prod/a", "prod/b"}, keys)
+
+	assert.Equal(t, "dmFsdWUx", aws.StringValue(mock.written["prod/a"][encodedValueAttribute].S))
+	assert.Nil(t, mock.written["prod/a"][ttlAttribute])
+
+	// the replaced key is at its next revision, with the remaining time to live of the source.
+	assert.Equal(t, "2", aws.StringValue(mock.written["prod/b"][revisionAttribute].N))
+	ttl, err := strconv.ParseInt(aws.StringValue(mock.written["prod/b"][ttlAttribute].N), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, expiresAt, ttl, 1)
+}
+
+func TestCopyTreeNormalizedPrefixes(t *testing.T) {
+	mock := &mockedCopyTree{mockedBatchStore: mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"staging/a": newTestItem("staging/a", "dmFsdWUx"),
+		},
+		failBatch: -1,
+	}}
+	kv := &Store{
+		dynamoSvc:        mock,
+		tableName:        TestTableName,
+		keyNormalization: &KeyNormalization{TrimLeadingSlash: true, DirectorySuffix: true},
+	}
+
+	ctx := context.Background()
+
+	err := kv.CopyTree(ctx, "/a/", "a/b/", nil)
+	assert.ErrorIs(t, err, ErrCopyTreeOverlap)
+
+	err = kv.CopyTree(ctx, "/staging/", "/prod", nil)
+	require.NoError(t, err)
+	require.Len(t, mock.written, 1)
+	assert.Equal(t, "dmFsdWUx", aws.StringValue(mock.written["prod/a"][encodedValueAttribute].S))
+}
+
+// mockedCopyTree is a mockedBatchStore scanning its items by prefix, in a single page.
+type mockedCopyTree struct {
+	mockedBatchStore
+}
+
+func (m *mockedCopyTree) ScanWithContext(_ aws.Context, input *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+	prefix := aws.StringValue(input.ExpressionAttributeValues[":namePrefix"].S)
+
+	out := &dynamodb.ScanOutput{}
+	for key, item := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			out.Items = append(out.Items, item)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package dynamodb
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// ErrAssumeRoleARNMissing is returned when a web identity token file is configured without the role to assume.
+var ErrAssumeRoleARNMissing = errors.New("missing role ARN to assume with the web identity token")
+
+// CredentialsConfig the AWS credentials of the store,
+// replacing the default credential chain.
+//
+// The default chain reads the credentials from the environment, the shared configuration files,
+// the web identity token of IAM Roles for Service Accounts on EKS (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN),
+// the ECS task role, or the EC2 instance role.
+type CredentialsConfig struct {
+	// AccessKeyID, SecretAccessKey, and SessionToken (optional) are static credentials.
+	// They take precedence over Profile.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Profile is the shared configuration profile (~/.aws/credentials and ~/.aws/config) to use.
+	Profile string
+
+	// AssumeRoleARN is a role assumed with the credentials above, or the default ones.
+	AssumeRoleARN string
+	// ExternalID is the external ID required to assume the role, if any.
+	ExternalID string
+	// RoleSessionName is the session name of the assumed role, generated if empty.
+	RoleSessionName string
+
+	// WebIdentityTokenFile is an OIDC token file exchanged for the credentials of AssumeRoleARN
+	// with AssumeRoleWithWebIdentity, instead of assuming the role with the credentials above.
+	WebIdentityTokenFile string
+}
+
+// newSession creates the AWS session of the store.
+func newSession(endpoints []string, options *Config) (*session.Session, error) {
+	config := aws.NewConfig()
+	if options.AWSConfig != nil {
+		config = options.AWSConfig.Copy()
+	}
+	if len(endpoints) == 1 {
+		config.WithEndpoint(endpoints[0])
+	} else if options.Endpoint != "" {
+		config.WithEndpoint(options.Endpoint)
+	}
+	if options.Region != "" {
+		config.WithRegion(options.Region)
+	}
+
+	httpClient, err := options.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		config.WithHTTPClient(httpClient)
+	}
+
+	sessOptions := session.Options{
+		// the shared configuration holds the web identity, SSO, and credential process settings.
+		SharedConfigState: session.SharedConfigEnable,
+	}
+
+	creds := options.Credentials
+	if creds != nil {
+		if creds.AccessKeyID != "" {
+			config.WithCredentials(credentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken))
+		}
+		sessOptions.Profile = creds.Profile
+	}
+	sessOptions.Config = *config
+
+	sess, err := session.NewSessionWithOptions(sessOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds == nil {
+		return sess, nil
+	}
+
+	roleCreds, err := creds.roleCredentials(sess)
+	if err != nil || roleCreds == nil {
+		return sess, err
+	}
+
+	return sess.Copy(aws.NewConfig().WithCredentials(roleCreds)), nil
+}
+
+// roleCredentials returns the credentials of the role to assume, if any.
+func (c *CredentialsConfig) roleCredentials(sess *session.Session) (*credentials.Credentials, error) {
+	if c.AssumeRoleARN == "" {
+		if c.WebIdentityTokenFile != "" {
+			return nil, ErrAssumeRoleARNMissing
+		}
+		return nil, nil
+	}
+
+	if c.WebIdentityTokenFile != "" {
+		return stscreds.NewWebIdentityCredentials(sess, c.AssumeRoleARN, c.RoleSessionName, c.WebIdentityTokenFile), nil
+	}
+
+	return stscreds.NewCredentials(sess, c.AssumeRoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if c.ExternalID != "" {
+			p.ExternalID = aws.String(c.ExternalID)
+		}
+		p.RoleSessionName = c.RoleSessionName
+	}), nil
+}
@@ -0,0 +1,52 @@
+package dynamodb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSessionCredentials(t *testing.T) {
+	sess, err := newSession([]string{"http://localhost:8000"}, &Config{
+		Region:      "eu-west-3",
+		Credentials: &CredentialsConfig{AccessKeyID: "key", SecretAccessKey: "secret", SessionToken: "token"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://localhost:8000", aws.StringValue(sess.Config.Endpoint))
+	assert.Equal(t, "eu-west-3", aws.StringValue(sess.Config.Region))
+
+	value, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "key", value.AccessKeyID)
+	assert.Equal(t, "secret", value.SecretAccessKey)
+	assert.Equal(t, "token", value.SessionToken)
+
+	// the assumed role credentials replace the static ones, and are only fetched on use.
+	roleSess, err := newSession(nil, &Config{
+		Region:      "eu-west-3",
+		Credentials: &CredentialsConfig{AccessKeyID: "key", SecretAccessKey: "secret", AssumeRoleARN: "arn:aws:iam::123456789012:role/kv"},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, sess.Config.Credentials, roleSess.Config.Credentials)
+	assert.True(t, roleSess.Config.Credentials.IsExpired())
+}
+
+func TestNewSessionWebIdentity(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+
+	_, err := newSession(nil, &Config{Region: "eu-west-3", Credentials: &CredentialsConfig{WebIdentityTokenFile: tokenFile}})
+	assert.ErrorIs(t, err, ErrAssumeRoleARNMissing)
+
+	sess, err := newSession(nil, &Config{
+		Region:      "eu-west-3",
+		Credentials: &CredentialsConfig{WebIdentityTokenFile: tokenFile, AssumeRoleARN: "arn:aws:iam::123456789012:role/kv"},
+	})
+	require.NoError(t, err)
+	assert.True(t, sess.Config.Credentials.IsExpired())
+}
@@ -0,0 +1,122 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// documentCodec stores the JSON object values as maps.
+type documentCodec struct {
+	attribute string
+	encoder   *dynamodbattribute.Encoder
+	decoder   *dynamodbattribute.Decoder
+}
+
+// NewDocumentCodec returns the Codec storing the values, JSON objects, as maps in the attribute,
+// so their fields can be read in the console and by PartiQL, and updated one by one.
+// The values read are the documents encoded again: their key order and whitespace are not kept.
+// The values not stored as maps, such as written before the codec was used, are read as is.
+func NewDocumentCodec(attribute string) Codec {
+	return &documentCodec{
+		attribute: attribute,
+		encoder: dynamodbattribute.NewEncoder(func(e *dynamodbattribute.Encoder) {
+			e.NullEmptyString = false
+			e.EnableEmptyCollections = true
+		}),
+		decoder: dynamodbattribute.NewDecoder(func(d *dynamodbattribute.Decoder) {
+			d.UseNumber = true
+			d.EnableEmptyCollections = true
+		}),
+	}
+}
+
+func (c *documentCodec) Encode(value []byte) (map[string]*dynamodb.AttributeValue, error) {
+	doc, err := decodeJSONObject(value)
+	if err != nil {
+		return nil, err
+	}
+
+	av, err := c.encoder.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]*dynamodb.AttributeValue{c.attribute: av}, nil
+}
+
+func (c *documentCodec) Decode(attrs map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	v, ok := attrs[c.attribute]
+	if !ok || v == nil {
+		return []byte{}, nil
+	}
+
+	if v.M == nil {
+		return attributeData(v)
+	}
+
+	var doc interface{}
+	if err := c.decoder.Decode(v, &doc); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonNumbers(doc))
+}
+
+// decodeJSONObject returns the JSON object of value, with its numbers as dynamodbattribute.Number.
+func decodeJSONObject(value []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: not a JSON object: %v", ErrCodecInvalidValue, err)
+	}
+
+	if doc == nil || dec.More() {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrCodecInvalidValue)
+	}
+
+	attributeNumbers(doc)
+
+	return doc, nil
+}
+
+// attributeNumbers replaces the JSON numbers of v with dynamodbattribute.Number, so they are stored as is.
+func attributeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		return dynamodbattribute.Number(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = attributeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = attributeNumbers(e)
+		}
+	}
+
+	return v
+}
+
+// jsonNumbers replaces the dynamodbattribute.Number of v with JSON numbers, so they are encoded as is.
+func jsonNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case dynamodbattribute.Number:
+		return json.Number(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = jsonNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonNumbers(e)
+		}
+	}
+
+	return v
+}
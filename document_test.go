@@ -0,0 +1,51 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentCodec(t *testing.T) {
+	kv := &Store{codec: NewDocumentCodec(encodedValueAttribute)}
+	ctx := context.Background()
+
+	value := []byte(`{"name":"valkeyrie","replicas":3,"ratio":0.25,"big":12345678901234567890,` +
+		`"tags":["a",""],"limits":{},"enabled":true,"owner":null}`)
+
+	data, enc, err := kv.encodeValue(ctx, "key", value)
+	require.NoError(t, err)
+
+	attrs := kv.valueAttributes(data, enc)
+	doc := attrs[encodedValueAttribute].M
+	require.NotNil(t, doc)
+	assert.Equal(t, "valkeyrie", aws.StringValue(doc["name"].S))
+	assert.Equal(t, "12345678901234567890", aws.StringValue(doc["big"].N))
+	assert.Equal(t, "", aws.StringValue(doc["tags"].L[1].S))
+
+	attrs[partitionKey] = &dynamodb.AttributeValue{S: aws.String("key")}
+	pair, err := kv.decodeItem(attrs)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(value), string(pair.Value))
+
+	for _, invalid := range []string{`[1,2]`, `"text"`, `{"a":1} {}`, `{`, `null`} {
+		_, _, err = kv.encodeValue(ctx, "key", []byte(invalid))
+		assert.ErrorIs(t, err, ErrCodecInvalidValue, invalid)
+	}
+
+	// the values written before the document mode are read as is.
+	legacy := map[string]*dynamodb.AttributeValue{
+		partitionKey:          {S: aws.String("key")},
+		encodedValueAttribute: {S: aws.String("dmFsdWU=")},
+	}
+	pair, err = kv.decodeItem(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), pair.Value)
+
+	_, err = New(ctx, nil, &Config{Bucket: TestTableName, DocumentMode: true, Codec: NewBinaryCodec("data")})
+	assert.ErrorIs(t, err, ErrCodecConflict)
+}
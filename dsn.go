@@ -0,0 +1,126 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// dsnScheme the scheme of the connection strings.
+const dsnScheme = "dynamodb"
+
+// ErrInvalidDSN is returned when a connection string can't be parsed.
+var ErrInvalidDSN = errors.New("invalid dynamodb connection string")
+
+// ParseDSN returns the configuration and the endpoint, if any, of a connection string such as:
+//
+//	dynamodb://table-name?region=eu-central-1&endpoint=http://localhost:8000&consistent=false
+//
+// The parameters are:
+//   - region: Config.Region.
+//   - endpoint: the endpoint of DynamoDB, such as DynamoDB local.
+//   - consistent: false sets Config.EventuallyConsistentReads.
+//   - auto_create: Config.AutoCreateTable.
+//   - billing_mode: Config.BillingMode, such as PAY_PER_REQUEST.
+//   - key_prefix: Config.KeyPrefix.
+//   - ttl_attribute: Config.AttributeNames.ExpirationTime.
+//   - scan_segments: Config.ScanSegments.
+func ParseDSN(dsn string) (*Config, string, error) {
+	options := &Config{}
+
+	endpoint, err := options.applyDSN(dsn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return options, endpoint, nil
+}
+
+// NewFromDSN creates a store from a connection string, see ParseDSN.
+func NewFromDSN(ctx context.Context, dsn string) (*Store, error) {
+	options, endpoint, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []string
+	if endpoint != "" {
+		endpoints = []string{endpoint}
+	}
+
+	return New(ctx, endpoints, options)
+}
+
+// isDSN reports whether an endpoint is a connection string.
+func isDSN(endpoint string) bool {
+	return strings.HasPrefix(endpoint, dsnScheme+"://")
+}
+
+// applyDSN sets the options of the connection string dsn, and returns its endpoint.
+func (c *Config) applyDSN(dsn string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+
+	if u.Scheme != dsnScheme {
+		return "", fmt.Errorf("%w: scheme %q", ErrInvalidDSN, u.Scheme)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("%w: missing table name", ErrInvalidDSN)
+	}
+	c.Bucket = u.Host
+
+	var endpoint string
+
+	for name, values := range u.Query() {
+		value := values[len(values)-1]
+
+		if name == "endpoint" {
+			endpoint = value
+			continue
+		}
+
+		if err = c.setOption(name, value); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+		}
+	}
+
+	return endpoint, nil
+}
+
+// setOption sets the option of a connection string parameter, or of an environment variable.
+func (c *Config) setOption(name, value string) error {
+	var err error
+
+	switch name {
+	case "region":
+		c.Region = value
+	case "consistent":
+		var consistent bool
+		consistent, err = strconv.ParseBool(value)
+		c.EventuallyConsistentReads = !consistent
+	case "auto_create":
+		c.AutoCreateTable, err = strconv.ParseBool(value)
+	case "billing_mode":
+		c.BillingMode = value
+	case "key_prefix":
+		c.KeyPrefix = value
+	case "ttl_attribute":
+		c.AttributeNames.ExpirationTime = value
+	case "scan_segments":
+		c.ScanSegments, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSN(t *testing.T) {
+	options, endpoint, err := ParseDSN("dynamodb://my-table?region=eu-central-1&endpoint=http://localhost:8000&consistent=false" +
+		"&auto_create=true&billing_mode=PAY_PER_REQUEST&key_prefix=app/&ttl_attribute=ttl&scan_segments=4")
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://localhost:8000", endpoint)
+	assert.Equal(t, &Config{
+		Bucket:                    "my-table",
+		Region:                    "eu-central-1",
+		EventuallyConsistentReads: true,
+		AutoCreateTable:           true,
+		BillingMode:               dynamodb.BillingModePayPerRequest,
+		KeyPrefix:                 "app/",
+		AttributeNames:            AttributeNames{ExpirationTime: "ttl"},
+		ScanSegments:              4,
+	}, options)
+
+	for _, dsn := range []string{
+		"postgres://my-table",
+		"dynamodb://?region=eu-central-1",
+		"dynamodb://my-table?consistent=maybe",
+		"dynamodb://my-table?unknown=1",
+	} {
+		_, _, err = ParseDSN(dsn)
+		assert.ErrorIs(t, err, ErrInvalidDSN, dsn)
+	}
+
+	// the connection string can be the endpoint of the registry, over the configuration.
+	client := &mockedProjectedGet{}
+	kv, err := newStore(context.Background(), []string{"dynamodb://my-table?consistent=false"}, &Config{
+		Bucket:         "other-table",
+		DynamoDBClient: client,
+		StreamsClient:  &mockedStreams{},
+	})
+	require.NoError(t, err)
+
+	_, err = kv.Get(context.Background(), "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.Equal(t, "my-table", aws.StringValue(client.input.TableName))
+	assert.False(t, aws.BoolValue(client.input.ConsistentRead))
+}
@@ -6,15 +6,21 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/kvtools/valkeyrie"
 	"github.com/kvtools/valkeyrie/store"
 )
@@ -27,7 +33,7 @@ const (
 	DefaultReadCapacityUnits = 2
 	// DefaultWriteCapacityUnits default write capacity used to create table.
 	DefaultWriteCapacityUnits = 2
-	// DeleteTreeTimeoutSeconds the maximum time we retry a write batch.
+	// DeleteTreeTimeoutSeconds the default maximum time we retry a write batch.
 	DeleteTreeTimeoutSeconds = 30
 )
 
@@ -38,9 +44,17 @@ const (
 	ttlAttribute          = "expiration_time"
 )
 
+// The attributes holding the creation and last update times of the keys, in Unix milliseconds.
 const (
-	defaultLockTTL         = 20 * time.Second
-	dynamodbDefaultTimeout = 10 * time.Second
+	createdAtAttribute = "created_at"
+	updatedAtAttribute = "updated_at"
+)
+
+const (
+	defaultLockTTL = 20 * time.Second
+
+	// deleteTreeConcurrency the maximum number of delete batches written concurrently.
+	deleteTreeConcurrency = 4
 )
 
 var (
@@ -63,7 +77,296 @@ func init() {
 
 // Config the AWS DynamoDB configuration.
 type Config struct {
+	// Bucket is the name of the table.
+	// It may be a template with variables, such as "myapp-{env}-kv", resolved by New from TableNameVars,
+	// or else from the environment variable of the same name, or of its upper case name ("ENV" for "{env}").
 	Bucket string
+
+	// TableNameVars are the values of the variables of the table name templates, Bucket and StreamCheckpointTable.
+	TableNameVars map[string]string
+
+	// Endpoint is the endpoint of DynamoDB, such as DynamoDB local, used when no endpoint is passed to New.
+	Endpoint string
+
+	// Region is the AWS region of the table, defaults to the region of the environment or shared configuration.
+	Region string
+
+	// Credentials replaces the default AWS credential chain.
+	Credentials *CredentialsConfig
+
+	// AWSConfig is the base configuration of the AWS session (retryer, HTTP client, logger...).
+	// The endpoint, Region, and Credentials options override its settings.
+	AWSConfig *aws.Config
+
+	// HTTPClient is the HTTP client of the AWS clients created by the store.
+	HTTPClient *http.Client
+
+	// ProxyURL is the proxy of the HTTP requests, instead of the proxy of the environment (HTTPS_PROXY).
+	// Ignored if HTTPClient is set.
+	ProxyURL string
+
+	// CABundleFile is a PEM file with the certificates of additional certificate authorities
+	// trusted by the HTTP client, for TLS-intercepting gateways or self-signed endpoints.
+	// Ignored if HTTPClient is set.
+	CABundleFile string
+
+	// GlobalTable routes the calls to the replicas of a global table, failing over to another replica on sustained errors.
+	// It can't be combined with DAXClient.
+	GlobalTable *GlobalTableConfig
+
+	// Conflicts detects the writes of the global table overwritten by the concurrent writes of another region,
+	// writing the region of the writes with the values.
+	Conflicts *ConflictConfig
+
+	// DAXClient is a DynamoDB Accelerator cluster client serving the reads (GetItem, Query, Scan, and their batch
+	// and transaction forms), the writes going to DynamoDB directly, such as the client of github.com/aws/aws-dax-go.
+	// The eventually consistent reads are served from the DAX caches, and may not see the latest writes
+	// until the cached items expire. The consistent reads are passed through to DynamoDB by DAX.
+	DAXClient dynamodbiface.DynamoDBAPI
+
+	// Logger receives the errors of the background operations, discarded by default.
+	Logger Logger
+
+	// Lock configures the acquisition and the renewal of the locks.
+	Lock LockConfig
+
+	// Cache enables the in-memory cache of the values read by Get and Exists.
+	Cache *CacheConfig
+
+	// Janitor enables the background deletion of the expired items, stopped by Close.
+	Janitor *JanitorConfig
+
+	// DeleteTreeRetryTimeout is the maximum time DeleteTree retries the delete requests left unprocessed
+	// by DynamoDB, defaults to DeleteTreeTimeoutSeconds.
+	DeleteTreeRetryTimeout time.Duration
+
+	// Timeouts bounds the duration of the store operations, which are otherwise bounded by their context only.
+	Timeouts Timeouts
+
+	// RateLimit limits the capacity consumed by the reads and writes of the store.
+	RateLimit *RateLimit
+
+	// ConcurrencyLimit caps the DynamoDB calls in flight, and the bulk calls among them.
+	ConcurrencyLimit *ConcurrencyLimit
+
+	// ReturnConsumedCapacity requests the consumed capacity of the DynamoDB calls of the store (not the stream reads),
+	// reported by Stats and in the spans of the Tracer.
+	ReturnConsumedCapacity bool
+
+	// EMF emits the metrics of the DynamoDB calls in the CloudWatch Embedded Metric Format,
+	// for the serverless applications without metrics agent.
+	EMF *EMFConfig
+
+	// RetryPolicy retries the DynamoDB calls failed with a throttling or transient error,
+	// on top of the retries of the AWS SDK.
+	RetryPolicy *RetryPolicy
+
+	// CircuitBreaker fails the DynamoDB calls fast with ErrCircuitOpen while DynamoDB is unavailable.
+	CircuitBreaker *CircuitBreaker
+
+	// Tracer traces the DynamoDB calls of the store (not the stream reads), in spans children of the span of
+	// the context of the calls, with the table, the key of the single item operations, and the consumed capacity.
+	Tracer Tracer
+
+	// DynamoDBClient, StreamsClient, and S3Client are already configured clients used instead of
+	// the clients created by the store, with their own retryers, HTTP clients, and handlers.
+	DynamoDBClient dynamodbiface.DynamoDBAPI
+	StreamsClient  dynamodbstreamsiface.DynamoDBStreamsAPI
+	S3Client       s3iface.S3API
+
+	// KMSClient is an already configured KMS client used by the encryption, instead of the client created by the store.
+	KMSClient kmsiface.KMSAPI
+
+	// AutoScalingClient is an already configured Application Auto Scaling client used by AutoScaling,
+	// instead of the client created by the store.
+	AutoScalingClient applicationautoscalingiface.ApplicationAutoScalingAPI
+
+	// Notifier delivers the change notifications used by Watch and WatchTree.
+	// Defaults to a notifier reading the DynamoDB stream of the table.
+	Notifier Notifier
+
+	// WatchPollInterval enables polling-based Watch and WatchTree when no Notifier is set.
+	// Keys are polled at this interval and their revisions compared to detect changes.
+	WatchPollInterval time.Duration
+
+	// EventuallyConsistentReads makes the reads without ReadOptions eventually consistent, halving their cost.
+	// The reads with ReadOptions follow their Consistent option.
+	EventuallyConsistentReads bool
+
+	// BinaryValues stores the values as raw bytes in a binary (B) attribute,
+	// instead of base64 encoded strings.
+	// Values written as base64 strings are still read transparently.
+	BinaryValues bool
+
+	// Middlewares run around the operations Get, Put, Delete, Exists, List, DeleteTree, AtomicPut, and AtomicDelete,
+	// from the outermost, including when the locks or Watch use them,
+	// GetWithMeta running as a Get, GetMeta as a GetMeta, and ListPage as a List.
+	// ListStream, the batches, such as GetMany and BatchGet, and the transactions don't run through them.
+	Middlewares []Middleware
+
+	// Audit records the mutations of the store to a table, a log stream, or a callback.
+	Audit *AuditConfig
+
+	// DeleteNotFound makes Delete return store.ErrKeyNotFound when the key doesn't exist, or is expired,
+	// instead of succeeding silently.
+	DeleteNotFound bool
+
+	// Compression compresses the values above a size threshold.
+	// Compressed values are decompressed transparently, whatever this option.
+	Compression *CompressionConfig
+
+	// Codec encodes the values into the attributes of their items, instead of the value attribute.
+	// The items are decoded by the codec whatever their encoding, it must read the items already stored, if any.
+	// It can't be combined with Compression, Encryption, ChunkSize, or S3Overflow, which encode the values themselves.
+	Codec Codec
+
+	// DocumentMode stores the values, which must be JSON objects, as maps in the value attribute,
+	// with the Codec of NewDocumentCodec, so they can be read in the console and by PartiQL, and patched with PatchJSON.
+	// It can't be combined with Codec, nor with the options Codec can't be combined with.
+	DocumentMode bool
+
+	// Checksum writes a checksum of each value, verified by the reads.
+	// It can't be combined with DocumentMode, which doesn't keep the bytes of the values.
+	Checksum *ChecksumConfig
+
+	// History records the revisions of the keys in a history table, read by ListVersions and GetAt.
+	History *HistoryConfig
+
+	// Encryption encrypts the values client-side, after their compression, with data keys generated by KMS.
+	// The encrypted values are decrypted transparently, this option being required to read them.
+	Encryption *EncryptionConfig
+
+	// ChunkSize enables the chunked storage of large values:
+	// values larger than ChunkSize bytes (after compression) are split across multiple items,
+	// written in a single transaction.
+	// It must leave room for the base64 encoding (unless BinaryValues is set) under the 400KB item size limit.
+	ChunkSize int
+
+	// S3Overflow enables the storage of large values in S3:
+	// values larger than the threshold are stored in an S3 object,
+	// and only a pointer to the object is kept in DynamoDB.
+	// It takes precedence over ChunkSize.
+	S3Overflow *S3OverflowConfig
+
+	// StreamCheckpointTable is the table where the default stream notifier stores its position in each shard.
+	// It may be a template, like Bucket.
+	// The table must have a string "id" partition key.
+	// If empty, the positions are kept in memory and the notifier starts from the tip of the stream.
+	// A position is recorded once the events are queued to the subscribers, before they receive them:
+	// the events still queued when the process stops are not delivered on restart (at-most-once delivery).
+	StreamCheckpointTable string
+
+	// StreamConsumerID identifies the process in StreamCheckpointTable.
+	// Processes sharing the checkpoint table must have distinct consumer IDs,
+	// otherwise they overwrite each other's positions.
+	StreamConsumerID string
+
+	// DirectoryLayout selects the composite key table layout:
+	// the hash key is the "directory" attribute, holding the first DirectoryDepth segments of the parent directory,
+	// and the key is the "id" range key.
+	// List and DeleteTree then Query a single partition, instead of scanning the whole table,
+	// when the prefix holds at least DirectoryDepth complete directory segments.
+	// The table must have been created with this layout.
+	DirectoryLayout bool
+
+	// DirectoryDepth the number of directory segments in the hash key of the directory layout, defaults to 1.
+	DirectoryDepth int
+
+	// PartitionKeyName is the hash key of a pre-existing table with a composite primary key,
+	// the key being stored in its sort key (SortKeyName).
+	// With the directory layout, it names the "directory" hash key.
+	PartitionKeyName string
+
+	// SortKeyName is the sort key holding the key when PartitionKeyName is set, it overrides AttributeNames.Key.
+	SortKeyName string
+
+	// PartitionKeyValue is the hash key value of all the keys, unless PartitionKeyFunc is set.
+	// All the keys being in the same partition, List and DeleteTree Query it instead of scanning the table.
+	PartitionKeyValue string
+
+	// PartitionKeyFunc returns the hash key value of a key, to spread the keys across partitions.
+	// List and DeleteTree then scan the table, unless a PrefixIndex is configured.
+	// The directory layout takes precedence over PartitionKeyValue and PartitionKeyFunc.
+	PartitionKeyFunc func(key string) string
+
+	// PrefixIndex is the name of a global secondary index used by List and DeleteTree to Query the keys by prefix.
+	// The index has a "prefix" hash key, holding the first directory segment of the key maintained on write,
+	// and the "id" range key.
+	// Only the prefixes holding a complete directory segment use the index, and the reads are eventually consistent.
+	// The table is scanned when the index doesn't exist.
+	PrefixIndex string
+
+	// ScanSegments is the number of segments scanned concurrently when List or DeleteTree scan the table.
+	// Values lower than 2 disable the parallel scan.
+	ScanSegments int
+
+	// UnsortedList returns the pairs of List in the order DynamoDB reads them,
+	// instead of sorting them by key, which saves the sort on the large listings whose order doesn't matter.
+	UnsortedList bool
+
+	// AutoCreateTable creates the table in New if it doesn't exist, and waits for it to be active.
+	// The TTL attribute is enabled on the created table.
+	AutoCreateTable bool
+
+	// BillingMode is the billing mode of the created table:
+	// dynamodb.BillingModeProvisioned (default) or dynamodb.BillingModePayPerRequest.
+	BillingMode string
+
+	// ReadCapacityUnits is the provisioned read throughput of the created table and indexes,
+	// defaults to DefaultReadCapacityUnits.
+	ReadCapacityUnits int64
+
+	// WriteCapacityUnits is the provisioned write throughput of the created table and indexes,
+	// defaults to DefaultWriteCapacityUnits.
+	WriteCapacityUnits int64
+
+	// KMSKeyARN is the customer managed KMS key encrypting the created table.
+	// The table is encrypted with an AWS owned key if empty.
+	KMSKeyARN string
+
+	// StreamViewType enables the stream of the created table, read by the default notifier of Watch and WatchTree,
+	// such as dynamodb.StreamViewTypeNewAndOldImages, which the conflict detection requires.
+	// The stream of an existing table must have this view type. The stream is not enabled if empty.
+	StreamViewType string
+
+	// TableTags are the resource tags of the created table, such as for the cost allocation.
+	TableTags map[string]string
+
+	// TableClass is the class of the created table, dynamodb.TableClassStandard (default),
+	// or dynamodb.TableClassStandardInfrequentAccess for the data rarely read.
+	TableClass string
+
+	// AutoScaling registers the auto scaling of the capacity of the created table, with the provisioned billing mode,
+	// instead of throttling the calls above ReadCapacityUnits and WriteCapacityUnits.
+	AutoScaling *AutoScalingConfig
+
+	// DestructiveGuard allows DeleteTable and Truncate on the matching tables, which are denied without it.
+	DestructiveGuard *DestructiveGuard
+
+	// AttributeNames overrides the names of the key, revision, value, and expiration time attributes.
+	AttributeNames AttributeNames
+
+	// TenantID enables the tenant mode: the keys are stored in the partition of the tenant,
+	// PartitionKeyName being the hash key holding the tenant ID,
+	// so all the operations, including List, DeleteTree, and the locks, are scoped to the tenant.
+	// The isolation of the tenants can be enforced by the credentials of the store, with the policy of TenantPolicy.
+	// It can't be combined with PartitionKeyValue, PartitionKeyFunc, or DirectoryLayout.
+	TenantID string
+
+	// KeyPrefix is prepended to the keys written, and removed from the keys read,
+	// so several applications or environments can share a table, each with its own key prefix.
+	// The keys without the prefix are ignored by the store, such as by List, Watch, and the janitor.
+	KeyPrefix string
+
+	// PublishWrites publishes an event to the Notifier after each successful write of a single key,
+	// such as Put, AtomicPut, Delete, AtomicDelete, PatchJSON, or a key of PutMany or DeleteMany,
+	// so the stores sharing a notifier without DynamoDB stream, such as an SNS topic, watch each other's writes.
+	// The transactions, Move, and DeleteTree are not published.
+	// The stream notifier ignores the events, DynamoDB publishing the changes in the stream.
+	PublishWrites bool
+
+	// KeyNormalization normalizes the slashes of the keys and prefixes of all the operations, nil keeping them as given.
+	KeyNormalization *KeyNormalization
 }
 
 func newStore(ctx context.Context, endpoints []string, options valkeyrie.Config) (store.Store, error) {
@@ -72,17 +375,112 @@ func newStore(ctx context.Context, endpoints []string, options valkeyrie.Config)
 		return nil, &store.InvalidConfigurationError{Store: StoreName, Config: options}
 	}
 
+	if cfg == nil {
+		var err error
+		if cfg, err = ConfigFromEnv(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the endpoint can be a connection string, setting the options over the configuration.
+	if len(endpoints) == 1 && isDSN(endpoints[0]) {
+		dsnConfig := *cfg
+
+		endpoint, err := dsnConfig.applyDSN(endpoints[0])
+		if err != nil {
+			return nil, err
+		}
+
+		endpoints = nil
+		if endpoint != "" {
+			endpoints = []string{endpoint}
+		}
+
+		cfg = &dsnConfig
+	}
+
 	return New(ctx, endpoints, cfg)
 }
 
 // Store implements the store.Store interface.
 type Store struct {
-	dynamoSvc dynamodbiface.DynamoDBAPI
-	tableName string
+	dynamoSvc  dynamodbiface.DynamoDBAPI
+	streamsSvc dynamodbstreamsiface.DynamoDBStreamsAPI
+	tableName  string
+
+	// daxSvc the DAX client serving the reads, if any.
+	daxSvc dynamodbiface.DynamoDBAPI
+	// replicas the router of the calls to the replicas of the global table, if any.
+	replicas *replicatedDynamoDB
+	// conflicts the detection of the conflicting writes of the regions, if enabled.
+	conflicts *ConflictConfig
+
+	// middlewares the middlewares of the operations, from the outermost.
+	middlewares []Middleware
+
+	binaryValues         bool
+	deleteNotFound       bool
+	eventuallyConsistent bool
+	compression          *CompressionConfig
+	chunkSize            int
+	s3Overflow           *S3OverflowConfig
+	s3Svc                s3iface.S3API
+	encryption           *EncryptionConfig
+	codec                Codec
+	checksum             *ChecksumConfig
+	history              *HistoryConfig
+	kmsSvc               kmsiface.KMSAPI
+	// scalingSvc the Application Auto Scaling client, if the auto scaling is configured.
+	scalingSvc applicationautoscalingiface.ApplicationAutoScalingAPI
+	// destructiveGuard the guard of DeleteTable and Truncate, nil denying them.
+	destructiveGuard *DestructiveGuard
+	// keyNormalization the normalization of the keys and prefixes, nil keeping them as given.
+	keyNormalization *KeyNormalization
+
+	// directoryDepth the number of directory segments in the hash key, 0 for the flat layout.
+	directoryDepth int
+	// partitionKeyName the hash key of a composite primary key, if not the directory one.
+	partitionKeyName string
+	// partitionKeyValue or partitionKeyFunc, if not nil, gives the hash key value of a key.
+	partitionKeyValue string
+	partitionKeyFunc  func(key string) string
+	// prefixIndex the name of the prefix index, if any.
+	prefixIndex string
+	// scanSegments the number of segments of the parallel scans.
+	scanSegments int
+	// unsortedList keeps the pairs of List in the order of the reads.
+	unsortedList bool
+
+	// attributeNames the names of the item attributes, the empty names keep their default.
+	attributeNames AttributeNames
+	// keyPrefix the prefix of the keys stored in the table.
+	keyPrefix string
+
+	// lockConfig the configuration of the locks.
+	lockConfig LockConfig
+	// deleteTreeRetryTimeout the maximum time the unprocessed delete requests are retried, if not the default.
+	deleteTreeRetryTimeout time.Duration
+	// capacity the consumed capacity of the calls, if collected.
+	capacity *capacityStats
+	// timeouts the timeouts of the store operations.
+	timeouts Timeouts
+	// janitor the background deletion of the expired items, if enabled.
+	janitor *janitor
+	// cache the cache of the values read, if enabled.
+	cache *valueCache
+
+	logger Logger
+
+	notifier     Notifier
+	notifierOnce sync.Once
+	// tasks the goroutines stopped by Close.
+	tasks backgroundTasks
+	// writeBuffers the open write buffers, flushed by Close.
+	writeBuffers writeBuffers
 }
 
 // New creates a new AWS DynamoDB client.
-func New(_ context.Context, endpoints []string, options *Config) (*Store, error) {
+func New(ctx context.Context, endpoints []string, options *Config) (*Store, error) {
 	if len(endpoints) > 1 {
 		return nil, ErrMultipleEndpointsUnsupported
 	}
@@ -90,16 +488,139 @@ func New(_ context.Context, endpoints []string, options *Config) (*Store, error)
 	if options == nil || options.Bucket == "" {
 		return nil, ErrBucketOptionMissing
 	}
-	var config *aws.Config
-	if len(endpoints) == 1 {
-		config = &aws.Config{
-			Endpoint: aws.String(endpoints[0]),
+
+	if options.Compression != nil && !isCompressionSupported(options.Compression.Codec) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, options.Compression.Codec)
+	}
+
+	if options.Checksum != nil {
+		if _, err := newChecksumHash(options.Checksum.algorithm()); err != nil {
+			return nil, err
 		}
 	}
 
+	if err := options.checkCodec(); err != nil {
+		return nil, err
+	}
+
+	if err := options.checkGlobalTable(); err != nil {
+		return nil, err
+	}
+
+	attributeNames, err := options.attributeNames()
+	if err != nil {
+		return nil, err
+	}
+
+	partitionKeyValue, err := options.partitionKeyValue()
+	if err != nil {
+		return nil, err
+	}
+
+	tableName, err := expandTableName(options.Bucket, options.TableNameVars)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpointTable, err := expandTableName(options.StreamCheckpointTable, options.TableNameVars)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := options.historyConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	ddb := &Store{
-		dynamoSvc: dynamodb.New(session.Must(session.NewSession(config))),
-		tableName: options.Bucket,
+		tableName: tableName,
+
+		binaryValues:         options.BinaryValues,
+		deleteNotFound:       options.DeleteNotFound,
+		eventuallyConsistent: options.EventuallyConsistentReads,
+		middlewares:          options.Middlewares,
+		compression:          options.Compression,
+		chunkSize:            options.ChunkSize,
+		s3Overflow:           options.S3Overflow,
+		encryption:           options.Encryption,
+		codec:                options.Codec,
+		checksum:             options.Checksum,
+		history:              history,
+		conflicts:            options.conflictConfig(),
+		prefixIndex:          options.PrefixIndex,
+		scanSegments:         options.ScanSegments,
+		unsortedList:         options.UnsortedList,
+		destructiveGuard:     options.DestructiveGuard,
+		keyNormalization:     options.KeyNormalization,
+
+		partitionKeyName:  options.PartitionKeyName,
+		partitionKeyValue: partitionKeyValue,
+		partitionKeyFunc:  options.PartitionKeyFunc,
+		attributeNames:    attributeNames,
+		keyPrefix:         options.KeyPrefix,
+
+		lockConfig:             options.Lock,
+		deleteTreeRetryTimeout: options.DeleteTreeRetryTimeout,
+		timeouts:               options.Timeouts,
+		logger:                 options.Logger,
+		notifier:               options.Notifier,
+	}
+
+	if options.Audit != nil {
+		if options.Audit.Sink == nil {
+			return nil, ErrAuditSinkMissing
+		}
+
+		// the audit runs after the middlewares of the configuration.
+		ddb.middlewares = append(append([]Middleware(nil), ddb.middlewares...), ddb.auditMiddleware(options.Audit))
+	}
+
+	if options.PublishWrites {
+		// the publication is the innermost middleware, notifying the writes which succeeded.
+		ddb.middlewares = append(append([]Middleware(nil), ddb.middlewares...), ddb.publishMiddleware())
+	}
+
+	if options.DocumentMode {
+		ddb.codec = NewDocumentCodec(ddb.valueName())
+	}
+
+	if options.DirectoryLayout {
+		ddb.directoryDepth = options.DirectoryDepth
+		if ddb.directoryDepth <= 0 {
+			ddb.directoryDepth = 1
+		}
+	}
+
+	if options.Cache != nil {
+		ddb.cache = newValueCache(options.Cache)
+	}
+
+	if err = ddb.initClients(endpoints, options); err != nil {
+		return nil, err
+	}
+
+	if ddb.notifier == nil {
+		if options.WatchPollInterval > 0 {
+			ddb.notifier = &pollNotifier{ddb: ddb, interval: options.WatchPollInterval}
+		} else {
+			ddb.notifier = newStreamNotifier(ddb, checkpointTable, options.StreamConsumerID)
+		}
+	}
+
+	if options.AutoCreateTable {
+		if err := ddb.EnsureTable(ctx, options.tableSpec()); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Cache != nil && options.Cache.Invalidate {
+		if err := ddb.startCacheInvalidation(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Janitor != nil {
+		ddb.janitor = ddb.startJanitor(options.Janitor)
 	}
 
 	return ddb, nil
@@ -107,105 +628,267 @@ func New(_ context.Context, endpoints []string, options *Config) (*Store, error)
 
 // Put a value at the specified key.
 func (ddb *Store) Put(ctx context.Context, key string, value []byte, opts *store.WriteOptions) error {
-	keyAttr := make(map[string]*dynamodb.AttributeValue)
-	keyAttr[partitionKey] = &dynamodb.AttributeValue{S: aws.String(key)}
+	_, err := ddb.PutWithResult(ctx, key, value, opts)
+	return err
+}
 
-	exAttr := map[string]*dynamodb.AttributeValue{
-		":incr": {N: aws.String("1")},
+// PutWithResult puts a value at the specified key like Put,
+// and returns the written pair with its new revision, saving a Get.
+func (ddb *Store) PutWithResult(ctx context.Context, key string, value []byte, opts *store.WriteOptions) (*store.KVPair, error) {
+	op := &Operation{Name: OperationPut, Key: key, Value: value}
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pair, err = ddb.putWithResult(ctx, op.Key, op.Value, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var setList []string
+	return op.Pair, nil
+}
+
+// putWithResult writes the value of key, and returns the written pair.
+// With the history, the revision is recorded in the same transaction.
+func (ddb *Store) putWithResult(ctx context.Context, key string, value []byte, opts *store.WriteOptions) (*store.KVPair, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	keyAttr := ddb.keyAttributes(key)
+
+	var (
+		attrs map[string]*dynamodb.AttributeValue
+		data  []byte
+		enc   valueEncoding
+		err   error
+	)
 
 	// if a value was provided append it to the update expression.
 	if len(value) > 0 {
-		encodedValue := base64.StdEncoding.EncodeToString(value)
-		exAttr[":encv"] = &dynamodb.AttributeValue{S: aws.String(encodedValue)}
-		setList = append(setList, fmt.Sprintf("%s = :encv", encodedValueAttribute))
-	}
+		data, enc, err = ddb.encodeValue(ctx, key, value)
+		if err != nil {
+			return nil, err
+		}
 
-	// if a ttl was provided validate it and append it to the update expression.
-	if opts != nil && opts.TTL > 0 {
-		ttlVal := time.Now().Add(opts.TTL).Unix()
-		exAttr[":ttl"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(ttlVal, 10))}
-		setList = append(setList, fmt.Sprintf("%s = :ttl", ttlAttribute))
-	}
+		if ddb.isChunkSize(data) {
+			revision, err := ddb.putChunkedWithRetry(ctx, key, data, enc, opts)
+			if err != nil {
+				return nil, err
+			}
+			return &store.KVPair{Key: key, Value: value, LastIndex: revision}, nil
+		}
 
-	updateExp := fmt.Sprintf("ADD %s :incr", revisionAttribute)
+		attrs, err = ddb.storeAttributes(ctx, key, data, enc)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if len(setList) > 0 {
-		updateExp = fmt.Sprintf("%s SET %s", updateExp, strings.Join(setList, ","))
+	if ddb.history != nil {
+		return ddb.putRecorded(ctx, key, value, data, enc, attrs, opts, nil)
 	}
 
-	_, err := ddb.dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+	updateExp, exAttr, exNames := ddb.writeUpdate(ddb.indexAttributes(key, attrs), opts)
+
+	res, err := ddb.dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
 		TableName:                 aws.String(ddb.tableName),
 		Key:                       keyAttr,
+		ExpressionAttributeNames:  exNames,
 		ExpressionAttributeValues: exAttr,
 		UpdateExpression:          aws.String(updateExp),
+		// the previous revision, and the previous chunks or S3 object, if any, which must be removed.
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedOld),
 	})
 	if err != nil {
-		return err
+		ddb.discardS3Object(ctx, attrs)
+		return nil, err
 	}
 
-	return nil
+	if attrs != nil && ddb.hasExternalStorage() {
+		if err = ddb.deleteExternal(ctx, key, res.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	var previous uint64
+	if v, ok := res.Attributes[ddb.revisionName()]; ok {
+		previous, _ = strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+	}
+
+	return &store.KVPair{Key: key, Value: value, LastIndex: previous + 1}, nil
 }
 
 // Get a value given its key.
 func (ddb *Store) Get(ctx context.Context, key string, opts *store.ReadOptions) (*store.KVPair, error) {
+	op := &Operation{Name: OperationGet, Key: key}
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pair, err = ddb.get(ctx, op.Key, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return op.Pair, nil
+}
+
+// get reads the value of key.
+func (ddb *Store) get(ctx context.Context, key string, opts *store.ReadOptions) (*store.KVPair, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
 	if opts == nil {
 		opts = &store.ReadOptions{
-			Consistent: true, // default to enabling read consistency.
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
 		}
 	}
 
+	if ddb.cache != nil {
+		return ddb.cachedGet(ctx, key, opts)
+	}
+
+	pair, _, err := ddb.getWithMeta(ctx, key, opts)
+
+	return pair, err
+}
+
+// GetWithMeta returns a value given its key, and the metadata of the key,
+// so the keys about to expire can be renewed.
+// It runs through the middlewares as a Get.
+func (ddb *Store) GetWithMeta(ctx context.Context, key string, opts *store.ReadOptions) (*store.KVPair, *KeyMeta, error) {
+	if opts == nil {
+		opts = &store.ReadOptions{
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
+		}
+	}
+
+	op := &Operation{Name: OperationGet, Key: key}
+
+	var meta *KeyMeta
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+		defer cancel()
+
+		op.Pair, meta, err = ddb.getWithMeta(ctx, op.Key, opts)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return op.Pair, meta, nil
+}
+
+func (ddb *Store) getWithMeta(ctx context.Context, key string, opts *store.ReadOptions) (*store.KVPair, *KeyMeta, error) {
 	res, err := ddb.getKey(ctx, key, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if res.Item == nil {
-		return nil, store.ErrKeyNotFound
+		return nil, nil, store.ErrKeyNotFound
 	}
 
 	// is the item expired?
-	if isItemExpired(res.Item) {
-		return nil, store.ErrKeyNotFound
+	if ddb.isItemExpired(res.Item) {
+		return nil, nil, store.ErrKeyNotFound
 	}
 
-	return decodeItem(res.Item)
+	meta := ddb.itemMeta(key, res.Item)
+
+	item, err := ddb.loadExternal(ctx, res.Item, opts.Consistent, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := ddb.decodeItem(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pair, meta, nil
 }
 
 func (ddb *Store) getKey(ctx context.Context, key string, options *store.ReadOptions) (*dynamodb.GetItemOutput, error) {
-	return ddb.dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+	return ddb.readSvc().GetItemWithContext(ctx, &dynamodb.GetItemInput{
 		TableName:      aws.String(ddb.tableName),
 		ConsistentRead: aws.Bool(options.Consistent),
-		Key: map[string]*dynamodb.AttributeValue{
-			partitionKey: {S: aws.String(key)},
-		},
+		Key:            ddb.keyAttributes(key),
 	})
 }
 
 // Delete the value at the specified key.
+// The keys which don't exist are deleted silently, unless Config.DeleteNotFound is set.
 func (ddb *Store) Delete(ctx context.Context, key string) error {
-	_, err := ddb.dynamoSvc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(ddb.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			partitionKey: {S: aws.String(key)},
-		},
+	return ddb.runOperation(ctx, &Operation{Name: OperationDelete, Key: key}, func(ctx context.Context, op *Operation) error {
+		return ddb.deleteKey(ctx, op.Key)
 	})
+}
+
+// deleteKey deletes the item of key, and its value stored in chunks or in S3.
+// With the history, the deletion is recorded as a tombstone revision.
+func (ddb *Store) deleteKey(ctx context.Context, key string) error {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	if ddb.history != nil {
+		return ddb.deleteRecorded(ctx, key, nil)
+	}
+
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(ddb.tableName),
+		Key:       ddb.keyAttributes(key),
+	}
+
+	if ddb.hasExternalStorage() || ddb.deleteNotFound {
+		// the chunks or S3 object, if any, must be removed.
+		input.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
+	}
+
+	res, err := ddb.dynamoSvc.DeleteItemWithContext(ctx, input)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if ddb.deleteNotFound && (len(res.Attributes) == 0 || ddb.isItemExpired(res.Attributes)) {
+		return store.ErrKeyNotFound
+	}
+
+	return ddb.deleteExternal(ctx, key, res.Attributes)
 }
 
 // Exists if a Key exists in the store.
-func (ddb *Store) Exists(ctx context.Context, key string, _ *store.ReadOptions) (bool, error) {
-	res, err := ddb.dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(ddb.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			partitionKey: {
-				S: aws.String(key),
-			},
+// Only the key and the expiration time of the item are read, unless its value is cached.
+func (ddb *Store) Exists(ctx context.Context, key string, opts *store.ReadOptions) (bool, error) {
+	var exists bool
+
+	err := ddb.runOperation(ctx, &Operation{Name: OperationExists, Key: key}, func(ctx context.Context, op *Operation) (err error) {
+		exists, err = ddb.exists(ctx, op.Key, opts)
+		return err
+	})
+
+	return exists, err
+}
+
+// exists reports whether key exists, and is not expired.
+func (ddb *Store) exists(ctx context.Context, key string, _ *store.ReadOptions) (bool, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	if ddb.cache != nil {
+		if _, ok := ddb.cache.get(key); ok {
+			return true, nil
+		}
+	}
+
+	res, err := ddb.readSvc().GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(ddb.tableName),
+		Key:                  ddb.keyAttributes(key),
+		ProjectionExpression: aws.String(keyNamePlaceholder + ", " + ttlNamePlaceholder),
+		ExpressionAttributeNames: map[string]*string{
+			keyNamePlaceholder: aws.String(ddb.keyName()),
+			ttlNamePlaceholder: aws.String(ddb.ttlName()),
 		},
 	})
 	if err != nil {
@@ -217,47 +900,124 @@ func (ddb *Store) Exists(ctx context.Context, key string, _ *store.ReadOptions)
 	}
 
 	// is the item expired?
-	if isItemExpired(res.Item) {
+	if ddb.isItemExpired(res.Item) {
 		return false, nil
 	}
 
 	return true, nil
 }
 
-// List the content of a given prefix.
-func (ddb *Store) List(ctx context.Context, directory string, opts *store.ReadOptions) ([]*store.KVPair, error) {
+// KeyMeta is the metadata of a key.
+type KeyMeta struct {
+	Key       string
+	LastIndex uint64
+	// ExpiresAt is the expiration time of the key, zero if the key doesn't expire.
+	ExpiresAt time.Time
+	// CreatedAt and UpdatedAt are the times the key was created and last written,
+	// zero if it was last written by a version of the store not maintaining them.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GetMeta returns the metadata of a key, without reading its value.
+func (ddb *Store) GetMeta(ctx context.Context, key string, opts *store.ReadOptions) (*KeyMeta, error) {
 	if opts == nil {
 		opts = &store.ReadOptions{
-			Consistent: true, // default to enabling read consistency.
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
 		}
 	}
 
-	expAttr := make(map[string]*dynamodb.AttributeValue)
-	expAttr[":namePrefix"] = &dynamodb.AttributeValue{S: aws.String(directory)}
+	var meta *KeyMeta
 
-	filterExp := fmt.Sprintf("begins_with(%s, :namePrefix)", partitionKey)
+	err := ddb.runOperation(ctx, &Operation{Name: OperationGetMeta, Key: key}, func(ctx context.Context, op *Operation) (err error) {
+		meta, err = ddb.getMeta(ctx, op.Key, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	si := &dynamodb.ScanInput{
-		TableName:                 aws.String(ddb.tableName),
-		FilterExpression:          aws.String(filterExp),
-		ExpressionAttributeValues: expAttr,
-		ConsistentRead:            aws.Bool(opts.Consistent),
+	return meta, nil
+}
+
+// getMeta reads the metadata of key.
+func (ddb *Store) getMeta(ctx context.Context, key string, opts *store.ReadOptions) (*KeyMeta, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	res, err := ddb.readSvc().GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(ddb.tableName),
+		Key:                  ddb.keyAttributes(key),
+		ConsistentRead:       aws.Bool(opts.Consistent),
+		ProjectionExpression: aws.String(revisionNamePlaceholder + ", " + ttlNamePlaceholder + ", #createdAt, #updatedAt"),
+		ExpressionAttributeNames: map[string]*string{
+			revisionNamePlaceholder: aws.String(ddb.revisionName()),
+			ttlNamePlaceholder:      aws.String(ddb.ttlName()),
+			"#createdAt":            aws.String(createdAtAttribute),
+			"#updatedAt":            aws.String(updatedAtAttribute),
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var items []map[string]*dynamodb.AttributeValue
-	ctx, cancel := context.WithTimeout(ctx, dynamodbDefaultTimeout)
+	if res.Item == nil || ddb.isItemExpired(res.Item) {
+		return nil, store.ErrKeyNotFound
+	}
 
-	err := ddb.dynamoSvc.ScanPagesWithContext(ctx, si,
-		func(page *dynamodb.ScanOutput, lastPage bool) bool {
-			items = append(items, page.Items...)
+	return ddb.itemMeta(key, res.Item), nil
+}
 
-			if lastPage {
-				cancel()
-				return false
-			}
+// itemMeta returns the metadata of the item of key.
+func (ddb *Store) itemMeta(key string, item map[string]*dynamodb.AttributeValue) *KeyMeta {
+	meta := &KeyMeta{Key: key}
 
-			return true
-		})
+	if v, ok := item[ddb.revisionName()]; ok {
+		meta.LastIndex, _ = strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+	}
+	if v, ok := item[ddb.ttlName()]; ok {
+		ttl, _ := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+		meta.ExpiresAt = time.Unix(ttl, 0)
+	}
+	if v, ok := item[createdAtAttribute]; ok {
+		createdAt, _ := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+		meta.CreatedAt = time.UnixMilli(createdAt)
+	}
+	if v, ok := item[updatedAtAttribute]; ok {
+		updatedAt, _ := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+		meta.UpdatedAt = time.UnixMilli(updatedAt)
+	}
+
+	return meta
+}
+
+// List the content of a given prefix.
+func (ddb *Store) List(ctx context.Context, directory string, opts *store.ReadOptions) ([]*store.KVPair, error) {
+	op := &Operation{Name: OperationList, Key: directory}
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pairs, err = ddb.list(ctx, op.Key, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return op.Pairs, nil
+}
+
+// list reads the pairs with a key starting with directory.
+func (ddb *Store) list(ctx context.Context, directory string, opts *store.ReadOptions) ([]*store.KVPair, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.List)
+	defer cancel()
+
+	if opts == nil {
+		opts = &store.ReadOptions{
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
+		}
+	}
+
+	items, err := ddb.prefixItems(ctx, directory, opts.Consistent)
 	if err != nil {
 		return nil, err
 	}
@@ -266,424 +1026,774 @@ func (ddb *Store) List(ctx context.Context, directory string, opts *store.ReadOp
 		return nil, store.ErrKeyNotFound
 	}
 
-	var kvArray []*store.KVPair
-	var val *store.KVPair
+	pairs, err := ddb.decodeItems(ctx, items, directory, opts.Consistent)
+	if err != nil {
+		return nil, err
+	}
+
+	// the scans and the parallel scans read the items in an arbitrary order.
+	if !ddb.unsortedList {
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	}
+
+	return pairs, nil
+}
 
+// decodeItems returns the pairs of the items read under directory,
+// skipping the directory itself, the chunks, and the expired items.
+func (ddb *Store) decodeItems(ctx context.Context, items []map[string]*dynamodb.AttributeValue, directory string, consistent bool) ([]*store.KVPair, error) {
+	chunks := make(map[string]map[string]*dynamodb.AttributeValue)
 	for _, item := range items {
-		val, err = decodeItem(item)
-		if err != nil {
-			return nil, err
+		if key := ddb.itemKey(item); isChunkKey(key) {
+			chunks[key] = item
 		}
+	}
+
+	var kvArray []*store.KVPair
 
-		// skip the records which match the prefix.
-		if val.Key == directory {
+	for _, item := range items {
+		key := ddb.itemKey(item)
+
+		// skip the records which match the prefix, and the chunks of chunked records.
+		if key == ddb.normalizeKey(directory) || isChunkKey(key) {
 			continue
 		}
 		// skip records which are expired.
-		if isItemExpired(item) {
+		if ddb.isItemExpired(item) {
 			continue
 		}
 
+		item, err := ddb.loadExternal(ctx, item, consistent, chunks)
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := ddb.decodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+
 		kvArray = append(kvArray, val)
 	}
 
 	return kvArray, nil
 }
 
-// DeleteTree deletes a range of keys under a given directory.
-func (ddb *Store) DeleteTree(ctx context.Context, keyPrefix string) error {
-	expAttr := make(map[string]*dynamodb.AttributeValue)
+// scanPrefix returns all the items with a key starting with prefix.
+func (ddb *Store) scanPrefix(ctx context.Context, prefix string, consistent bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	return ddb.scanStoredPrefix(ctx, ddb.storedPrefix(prefix), consistent)
+}
 
-	expAttr[":namePrefix"] = &dynamodb.AttributeValue{S: aws.String(keyPrefix)}
+// scanStoredPrefix returns all the items with a stored key starting with prefix, used as is.
+func (ddb *Store) scanStoredPrefix(ctx context.Context, prefix string, consistent bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	si := ddb.storedPrefixScan(prefix, consistent)
 
-	res, err := ddb.dynamoSvc.ScanWithContext(ctx, &dynamodb.ScanInput{
+	if ddb.scanSegments > 1 {
+		return ddb.parallelScan(ctx, si, ddb.scanSegments)
+	}
+
+	return ddb.scanPages(ctx, si)
+}
+
+// prefixScan returns the Scan reading the items with a key starting with prefix, and not expired.
+func (ddb *Store) prefixScan(prefix string, consistent bool) *dynamodb.ScanInput {
+	return ddb.storedPrefixScan(ddb.storedPrefix(prefix), consistent)
+}
+
+// storedPrefixScan returns the Scan of prefixScan for a stored key prefix, used as is.
+func (ddb *Store) storedPrefixScan(prefix string, consistent bool) *dynamodb.ScanInput {
+	expAttr := make(map[string]*dynamodb.AttributeValue)
+	expAttr[":namePrefix"] = &dynamodb.AttributeValue{S: aws.String(prefix)}
+	expNames := map[string]*string{keyNamePlaceholder: aws.String(ddb.keyName())}
+
+	return &dynamodb.ScanInput{
 		TableName:                 aws.String(ddb.tableName),
-		FilterExpression:          aws.String(fmt.Sprintf("begins_with(%s, :namePrefix)", partitionKey)),
+		FilterExpression:          aws.String("begins_with(" + keyNamePlaceholder + ", :namePrefix) AND " + ddb.notExpiredFilter(expAttr, expNames)),
+		ExpressionAttributeNames:  expNames,
 		ExpressionAttributeValues: expAttr,
+		ConsistentRead:            aws.Bool(consistent),
+	}
+}
+
+// DeleteTree deletes a range of keys under a given directory.
+// The expired keys are left to the TTL deletion of DynamoDB.
+func (ddb *Store) DeleteTree(ctx context.Context, keyPrefix string) error {
+	return ddb.runOperation(ctx, &Operation{Name: OperationDeleteTree, Key: keyPrefix}, func(ctx context.Context, op *Operation) error {
+		return ddb.deleteTree(ctx, op.Key)
 	})
+}
+
+// deleteTree deletes the keys starting with keyPrefix.
+func (ddb *Store) deleteTree(ctx context.Context, keyPrefix string) error {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.DeleteTree)
+	defer cancel()
+
+	resItems, err := ddb.prefixItems(ctx, keyPrefix, false)
 	if err != nil {
 		return err
 	}
 
-	if len(res.Items) == 0 {
+	if len(resItems) == 0 {
 		return nil
 	}
 
+	if ddb.history != nil {
+		return ddb.deleteTreeRecorded(ctx, resItems)
+	}
+
 	items := make(map[string][]*dynamodb.WriteRequest)
 
-	items[ddb.tableName] = make([]*dynamodb.WriteRequest, len(res.Items))
+	items[ddb.tableName] = make([]*dynamodb.WriteRequest, len(resItems))
 
-	for n, item := range res.Items {
+	for n, item := range resItems {
 		items[ddb.tableName][n] = &dynamodb.WriteRequest{
 			DeleteRequest: &dynamodb.DeleteRequest{
-				Key: map[string]*dynamodb.AttributeValue{
-					partitionKey: item[partitionKey],
-				},
+				Key: ddb.keyAttributes(ddb.itemKey(item)),
 			},
 		}
 	}
 
-	return ddb.retryDeleteTree(ctx, items)
+	err = ddb.retryDeleteTree(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range resItems {
+		if err = ddb.deleteS3Object(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteTreeRecorded deletes the keys of items one by one, recording their tombstone revisions in the history.
+func (ddb *Store) deleteTreeRecorded(ctx context.Context, items []map[string]*dynamodb.AttributeValue) error {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = ddb.itemKey(item)
+	}
+
+	failed := make(map[string]error)
+
+	writeKeys(ctx, keys, func(ctx context.Context, key string) error {
+		if err := ddb.deleteRecorded(ctx, key, nil); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+			return err
+		}
+		return nil
+	}, failed)
+
+	return batchError(failed)
 }
 
 // AtomicPut Atomic CAS operation on a single value.
+// The expected state of the key is checked by the condition of the update,
+// only the values stored as chunks require reading the current item first.
 func (ddb *Store) AtomicPut(ctx context.Context, key string, value []byte, previous *store.KVPair, opts *store.WriteOptions) (bool, *store.KVPair, error) {
-	getRes, err := ddb.getKey(ctx, key, &store.ReadOptions{
-		Consistent: true, // enable the read consistent flag.
+	op := &Operation{Name: OperationAtomicPut, Key: key, Value: value, Previous: previous}
+
+	var ok bool
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		ok, op.Pair, err = ddb.atomicPut(ctx, op.Key, op.Value, op.Previous, opts)
+		return err
 	})
 	if err != nil {
-		return false, nil, err
+		return ok, nil, err
 	}
 
-	// AtomicPut is equivalent to Put if previous is nil and the Key exist in the DB or is not expired.
-	if previous == nil && getRes.Item != nil && !isItemExpired(getRes.Item) {
-		return false, nil, store.ErrKeyExists
-	}
+	return ok, op.Pair, nil
+}
 
-	keyAttr := make(map[string]*dynamodb.AttributeValue)
-	keyAttr[partitionKey] = &dynamodb.AttributeValue{S: aws.String(key)}
+// atomicPut writes the value of key if it's in the state of previous, and returns the written pair.
+// With the history, the state of previous is checked on the item read by putRecorded.
+func (ddb *Store) atomicPut(ctx context.Context, key string, value []byte, previous *store.KVPair, opts *store.WriteOptions) (bool, *store.KVPair, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
 
-	exAttr := make(map[string]*dynamodb.AttributeValue)
-	exAttr[":incr"] = &dynamodb.AttributeValue{N: aws.String("1")}
+	keyAttr := ddb.keyAttributes(key)
 
-	var setList []string
+	var (
+		attrs map[string]*dynamodb.AttributeValue
+		data  []byte
+		enc   valueEncoding
+		err   error
+	)
 
 	// if a value was provided append it to the update expression.
 	if len(value) > 0 {
-		encodedValue := base64.StdEncoding.EncodeToString(value)
-		exAttr[":encv"] = &dynamodb.AttributeValue{S: aws.String(encodedValue)}
-		setList = append(setList, fmt.Sprintf("%s = :encv", encodedValueAttribute))
-	}
-
-	// if a ttl was provided validate it and append it to the update expression.
-	if opts != nil && opts.TTL > 0 {
-		ttlVal := time.Now().Add(opts.TTL).Unix()
-		exAttr[":ttl"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(ttlVal, 10))}
-		setList = append(setList, fmt.Sprintf("%s = :ttl", ttlAttribute))
-	}
+		data, enc, err = ddb.encodeValue(ctx, key, value)
+		if err != nil {
+			return false, nil, err
+		}
 
-	updateExp := fmt.Sprintf("ADD %s :incr", revisionAttribute)
+		if ddb.isChunkSize(data) {
+			return ddb.atomicPutChunked(ctx, key, value, data, enc, previous, opts)
+		}
 
-	if len(setList) > 0 {
-		updateExp = fmt.Sprintf("%s SET %s", updateExp, strings.Join(setList, ","))
+		attrs, err = ddb.storeAttributes(ctx, key, data, enc)
+		if err != nil {
+			return false, nil, err
+		}
 	}
 
-	var condExp *string
+	if ddb.history != nil {
+		pair, err := ddb.putRecorded(ctx, key, value, data, enc, attrs, opts, ddb.atomicPutCheck(previous))
+		return err == nil, pair, err
+	}
 
-	if previous != nil {
-		exAttr[":lastRevision"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatUint(previous.LastIndex, 10))}
-		exAttr[":timeNow"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))}
+	updateExp, exAttr, exNames := ddb.writeUpdate(ddb.indexAttributes(key, attrs), opts)
+	condExp := ddb.atomicPutCondition(previous, exAttr, exNames)
 
-		// the previous kv is in the DB and is at the expected revision, also if it has a TTL set it is NOT expired.
-		condExp = aws.String(fmt.Sprintf("%s = :lastRevision AND (attribute_not_exists(%s) OR (attribute_exists(%s) AND %s > :timeNow))",
-			revisionAttribute, ttlAttribute, ttlAttribute, ttlAttribute))
+	returnValues := dynamodb.ReturnValueAllNew
+	if ddb.hasExternalStorage() {
+		// the previous chunks or S3 object, if any, must be removed.
+		returnValues = dynamodb.ReturnValueAllOld
 	}
 
 	res, err := ddb.dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
 		TableName:                 aws.String(ddb.tableName),
 		Key:                       keyAttr,
+		ExpressionAttributeNames:  exNames,
 		ExpressionAttributeValues: exAttr,
 		UpdateExpression:          aws.String(updateExp),
-		ConditionExpression:       condExp,
-		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+		ConditionExpression:       aws.String(condExp),
+		ReturnValues:              aws.String(returnValues),
 	})
 	if err != nil {
+		ddb.discardS3Object(ctx, attrs)
+
 		if awsErr, ok := err.(awserr.Error); ok {
 			if awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				if previous == nil {
+					return false, nil, store.ErrKeyExists
+				}
 				return false, nil, store.ErrKeyModified
 			}
 		}
 		return false, nil, err
 	}
 
-	item, err := decodeItem(res.Attributes)
+	if !ddb.hasExternalStorage() {
+		item, err := ddb.decryptItem(ctx, res.Attributes)
+		if err != nil {
+			return false, nil, err
+		}
+
+		pair, err := ddb.decodeItem(item)
+		if err != nil {
+			return false, nil, err
+		}
+
+		return true, pair, nil
+	}
+
+	if attrs != nil {
+		if err = ddb.deleteExternal(ctx, key, res.Attributes); err != nil {
+			return false, nil, err
+		}
+	}
+
+	// the previous value is not decoded, it may be encrypted.
+	var previousRevision uint64
+	if v, ok := res.Attributes[ddb.revisionName()]; ok {
+		previousRevision, _ = strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+	}
+
+	return true, &store.KVPair{Key: key, Value: value, LastIndex: previousRevision + 1}, nil
+}
+
+// atomicPutCondition returns the condition of an AtomicPut, adding its values to exAttr and its names to exNames.
+func (ddb *Store) atomicPutCondition(previous *store.KVPair, exAttr map[string]*dynamodb.AttributeValue, exNames map[string]*string) string {
+	exAttr[":timeNow"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))}
+	exNames[ttlNamePlaceholder] = aws.String(ddb.ttlName())
+
+	if previous == nil {
+		exNames[keyNamePlaceholder] = aws.String(ddb.keyName())
+
+		// the key doesn't exist, or is expired.
+		return fmt.Sprintf("attribute_not_exists(%s) OR (attribute_exists(%s) AND %s <= :timeNow)",
+			keyNamePlaceholder, ttlNamePlaceholder, ttlNamePlaceholder)
+	}
+
+	exAttr[":lastRevision"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatUint(previous.LastIndex, 10))}
+	exNames[revisionNamePlaceholder] = aws.String(ddb.revisionName())
+
+	// the previous kv is in the DB and is at the expected revision, also if it has a TTL set it is NOT expired.
+	return fmt.Sprintf("%s = :lastRevision AND (attribute_not_exists(%s) OR (attribute_exists(%s) AND %s > :timeNow))",
+		revisionNamePlaceholder, ttlNamePlaceholder, ttlNamePlaceholder, ttlNamePlaceholder)
+}
+
+// atomicPutCheck returns the check of the current item of an AtomicPut expecting the state of previous,
+// for the writes reading the item first.
+func (ddb *Store) atomicPutCheck(previous *store.KVPair) func(current map[string]*dynamodb.AttributeValue) error {
+	return func(current map[string]*dynamodb.AttributeValue) error {
+		exists := current != nil && !ddb.isItemExpired(current)
+
+		if previous == nil {
+			if exists {
+				return store.ErrKeyExists
+			}
+			return nil
+		}
+
+		if !exists || aws.StringValue(current[ddb.revisionName()].N) != strconv.FormatUint(previous.LastIndex, 10) {
+			return store.ErrKeyModified
+		}
+
+		return nil
+	}
+}
+
+// atomicPutChunked AtomicPut of a value stored as chunks.
+func (ddb *Store) atomicPutChunked(ctx context.Context, key string, value, data []byte, enc valueEncoding, previous *store.KVPair, opts *store.WriteOptions) (bool, *store.KVPair, error) {
+	revision, err := ddb.putChunked(ctx, key, data, enc, opts, ddb.atomicPutCheck(previous))
 	if err != nil {
+		if errors.Is(err, errChunkedWriteConflict) {
+			return false, nil, store.ErrKeyModified
+		}
 		return false, nil, err
 	}
 
-	return true, item, nil
+	return true, &store.KVPair{Key: key, Value: value, LastIndex: revision}, nil
 }
 
 // AtomicDelete delete of a single value.
+// The key is deleted only if it exists at the revision of previous, and is not expired.
 func (ddb *Store) AtomicDelete(ctx context.Context, key string, previous *store.KVPair) (bool, error) {
-	getRes, err := ddb.getKey(ctx, key, &store.ReadOptions{
-		Consistent: true, // enable the read consistent flag.
+	var ok bool
+
+	op := &Operation{Name: OperationAtomicDelete, Key: key, Previous: previous}
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		ok, err = ddb.atomicDelete(ctx, op.Key, op.Previous)
+		return err
 	})
-	if err != nil {
-		return false, err
+
+	return ok, err
+}
+
+// atomicDelete deletes key if it exists at the revision of previous.
+// With the history, the revision of previous is checked on the item read, and the deletion recorded like deleteKey.
+func (ddb *Store) atomicDelete(ctx context.Context, key string, previous *store.KVPair) (bool, error) {
+	if ddb.history != nil && previous != nil {
+		ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+		defer cancel()
+
+		err := ddb.deleteRecorded(ctx, key, func(current map[string]*dynamodb.AttributeValue) error {
+			if current == nil || ddb.isItemExpired(current) {
+				return store.ErrKeyNotFound
+			}
+			if aws.StringValue(current[ddb.revisionName()].N) != strconv.FormatUint(previous.LastIndex, 10) {
+				return store.ErrKeyModified
+			}
+			return nil
+		})
+
+		return err == nil, err
 	}
 
-	if previous == nil && getRes.Item != nil && !isItemExpired(getRes.Item) {
-		return false, store.ErrKeyExists
+	return ddb.atomicDeleteItem(ctx, key, previous)
+}
+
+// atomicDeleteItem deletes the item of key if it exists at the revision of previous, without recording it in the history.
+func (ddb *Store) atomicDeleteItem(ctx context.Context, key string, previous *store.KVPair) (bool, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	if previous == nil {
+		return false, store.ErrPreviousNotSpecified
 	}
 
-	expAttr := make(map[string]*dynamodb.AttributeValue)
-	if previous != nil {
-		expAttr[":lastRevision"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatUint(previous.LastIndex, 10))}
+	expAttr := map[string]*dynamodb.AttributeValue{
+		":lastRevision": {N: aws.String(strconv.FormatUint(previous.LastIndex, 10))},
+		":timeNow":      {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
 	}
 
 	req := &dynamodb.DeleteItemInput{
 		TableName: aws.String(ddb.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			partitionKey: {S: aws.String(key)},
+		Key:       ddb.keyAttributes(key),
+		// the revision condition fails if the key doesn't exist.
+		ConditionExpression: aws.String(fmt.Sprintf("%s = :lastRevision AND (attribute_not_exists(%s) OR %s > :timeNow)",
+			revisionNamePlaceholder, ttlNamePlaceholder, ttlNamePlaceholder)),
+		ExpressionAttributeNames: map[string]*string{
+			revisionNamePlaceholder: aws.String(ddb.revisionName()),
+			ttlNamePlaceholder:      aws.String(ddb.ttlName()),
 		},
-		ConditionExpression:       aws.String(fmt.Sprintf("%s = :lastRevision", revisionAttribute)),
 		ExpressionAttributeValues: expAttr,
 	}
 
-	_, err = ddb.dynamoSvc.DeleteItemWithContext(ctx, req)
+	if ddb.hasExternalStorage() {
+		// the chunks or S3 object, if any, must be removed.
+		req.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
+	}
+
+	res, err := ddb.dynamoSvc.DeleteItemWithContext(ctx, req)
 	if err != nil {
 		if awsErr, ok := err.(awserr.Error); ok {
 			if awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
-				return false, store.ErrKeyNotFound
+				return false, ddb.atomicDeleteFailure(ctx, key)
 			}
 		}
 		return false, err
 	}
 
-	return true, nil
-}
-
-// Close nothing to see here.
-func (ddb *Store) Close() error { return nil }
-
-// NewLock has to implemented at the library level since it's not supported by DynamoDB.
-func (ddb *Store) NewLock(_ context.Context, key string, opts *store.LockOptions) (store.Locker, error) {
-	ttl := defaultLockTTL
-	var value []byte
-	renewCh := make(chan struct{})
-
-	if opts != nil {
-		if opts.TTL != 0 {
-			ttl = opts.TTL
-		}
-
-		if len(opts.Value) != 0 {
-			value = opts.Value
-		}
-
-		if opts.RenewLock != nil {
-			renewCh = opts.RenewLock
-		}
+	if err = ddb.deleteExternal(ctx, key, res.Attributes); err != nil {
+		return false, err
 	}
 
-	return &dynamodbLock{
-		ddb:      ddb,
-		last:     nil,
-		key:      key,
-		value:    value,
-		ttl:      ttl,
-		renewCh:  renewCh,
-		unlockCh: make(chan struct{}),
-	}, nil
-}
-
-// Watch has to implemented at the library level since it's not supported by DynamoDB.
-func (ddb *Store) Watch(_ context.Context, _ string, _ *store.ReadOptions) (<-chan *store.KVPair, error) {
-	return nil, store.ErrCallNotSupported
-}
-
-// WatchTree has to implemented at the library level since it's not supported by DynamoDB.
-func (ddb *Store) WatchTree(_ context.Context, _ string, _ *store.ReadOptions) (<-chan []*store.KVPair, error) {
-	return nil, store.ErrCallNotSupported
+	return true, nil
 }
 
-func (ddb *Store) createTable() error {
-	_, err := ddb.dynamoSvc.CreateTable(&dynamodb.CreateTableInput{
-		AttributeDefinitions: []*dynamodb.AttributeDefinition{
-			{
-				AttributeName: aws.String(partitionKey),
-				AttributeType: aws.String("S"),
-			},
-		},
-		KeySchema: []*dynamodb.KeySchemaElement{
-			{
-				AttributeName: aws.String(partitionKey),
-				KeyType:       aws.String(dynamodb.KeyTypeHash),
-			},
-		},
-		// enable encryption of data by default.
-		SSESpecification: &dynamodb.SSESpecification{
-			Enabled: aws.Bool(true),
-			SSEType: aws.String(dynamodb.SSETypeAes256),
-		},
-		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(DefaultReadCapacityUnits),
-			WriteCapacityUnits: aws.Int64(DefaultWriteCapacityUnits),
-		},
-		TableName: aws.String(ddb.tableName),
-	})
+// atomicDeleteFailure returns the reason of the failed condition of an AtomicDelete:
+// ErrKeyNotFound if the key doesn't exist or is expired, ErrKeyModified otherwise.
+func (ddb *Store) atomicDeleteFailure(ctx context.Context, key string) error {
+	res, err := ddb.getKey(ctx, key, &store.ReadOptions{Consistent: true})
 	if err != nil {
-		if awsErr, ok := err.(awserr.Error); ok {
-			if awsErr.Code() == dynamodb.ErrCodeResourceInUseException {
-				return nil
-			}
-		}
 		return err
 	}
 
-	err = ddb.dynamoSvc.WaitUntilTableExists(&dynamodb.DescribeTableInput{
-		TableName: aws.String(ddb.tableName),
-	})
-	if err != nil {
-		return err
+	if res.Item == nil || ddb.isItemExpired(res.Item) {
+		return store.ErrKeyNotFound
 	}
 
-	return nil
+	return store.ErrKeyModified
 }
 
+// retryDeleteTree writes the delete requests in batches of maxBatchWriteItems,
+// with at most deleteTreeConcurrency batches in flight.
+// The unprocessed requests of all the batches are retried together once a second,
+// until they are all processed, ctx is done, or the retry deadline is reached.
 func (ddb *Store) retryDeleteTree(ctx context.Context, items map[string][]*dynamodb.WriteRequest) error {
-	batchResult, err := ddb.dynamoSvc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
-		RequestItems: items,
-	})
+	unprocessed, err := ddb.writeBatches(ctx, items)
 	if err != nil {
 		return err
 	}
 
-	if len(batchResult.UnprocessedItems) == 0 {
+	if len(unprocessed) == 0 {
 		return nil
 	}
 
-	timeout := make(chan bool, 1)
-	go func() {
-		time.Sleep(DeleteTreeTimeoutSeconds * time.Second)
-		timeout <- true
-	}()
+	timeout := time.NewTimer(ddb.deleteTreeRetryTimeoutOrDefault())
+	defer timeout.Stop()
 
 	ticker := time.NewTicker(1 * time.Second)
-
 	defer ticker.Stop()
 
-	// Poll once a second for table status,
-	// until the table is either active or the timeout deadline has been reached.
 	for {
 		select {
 		case <-ticker.C:
-			batchResult, err = ddb.dynamoSvc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
-				RequestItems: batchResult.UnprocessedItems,
-			})
+			unprocessed, err = ddb.writeBatches(ctx, unprocessed)
 			if err != nil {
 				return err
 			}
 
-			if len(batchResult.UnprocessedItems) == 0 {
+			if len(unprocessed) == 0 {
 				return nil
 			}
 
-		case <-timeout:
-			// polling for table status has taken more than the timeout.
+		case <-timeout.C:
+			// retrying the unprocessed requests has taken more than the timeout.
 			return ErrDeleteTreeTimeout
+
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
 
-type dynamodbLock struct {
-	ddb      *Store
-	last     *store.KVPair
-	renewCh  chan struct{}
-	unlockCh chan struct{}
-
-	key   string
-	value []byte
-	ttl   time.Duration
+// deleteTreeRetryTimeoutOrDefault returns the maximum time the unprocessed delete requests are retried.
+func (ddb *Store) deleteTreeRetryTimeoutOrDefault() time.Duration {
+	if ddb.deleteTreeRetryTimeout > 0 {
+		return ddb.deleteTreeRetryTimeout
+	}
+	return DeleteTreeTimeoutSeconds * time.Second
 }
 
-func (l *dynamodbLock) Lock(ctx context.Context) (<-chan struct{}, error) {
-	lockHeld := make(chan struct{})
+// writeBatches writes the requests in batches of maxBatchWriteItems, concurrently,
+// and returns the requests left unprocessed by all the batches.
+func (ddb *Store) writeBatches(ctx context.Context, items map[string][]*dynamodb.WriteRequest) (map[string][]*dynamodb.WriteRequest, error) {
+	var batches []map[string][]*dynamodb.WriteRequest
+	for table, requests := range items {
+		for start := 0; start < len(requests); start += maxBatchWriteItems {
+			end := start + maxBatchWriteItems
+			if end > len(requests) {
+				end = len(requests)
+			}
 
-	success, err := l.tryLock(ctx, lockHeld)
-	if err != nil {
-		return nil, err
-	}
-	if success {
-		return lockHeld, nil
+			batches = append(batches, map[string][]*dynamodb.WriteRequest{table: requests[start:end]})
+		}
 	}
 
-	// TODO: This really needs a jitter for backoff.
-	ticker := time.NewTicker(3 * time.Second)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		firstErr    error
+		unprocessed = make(map[string][]*dynamodb.WriteRequest)
+	)
+
+	sem := make(chan struct{}, deleteTreeConcurrency)
+
+	for _, batch := range batches {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(batch map[string][]*dynamodb.WriteRequest) {
+			defer func() { <-sem }()
+			defer wg.Done()
+
+			res, err := ddb.dynamoSvc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: batch,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
 
-	for {
-		select {
-		case <-ticker.C:
-			success, err := l.tryLock(ctx, lockHeld)
 			if err != nil {
-				return nil, err
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
 			}
-			if success {
-				return lockHeld, nil
+
+			for table, requests := range res.UnprocessedItems {
+				unprocessed[table] = append(unprocessed[table], requests...)
 			}
-		case <-ctx.Done():
-			return nil, ErrLockAcquireCancelled
+		}(batch)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return unprocessed, nil
+}
+
+// appendTimestamps appends the update of the creation and last update times to setList.
+func appendTimestamps(setList []string, exAttr map[string]*dynamodb.AttributeValue, exNames map[string]*string) []string {
+	exAttr[":now"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().UnixMilli(), 10))}
+	exNames["#createdAt"] = aws.String(createdAtAttribute)
+	exNames["#updatedAt"] = aws.String(updatedAtAttribute)
+
+	return append(setList, "#createdAt = if_not_exists(#createdAt, :now)", "#updatedAt = :now")
+}
+
+// writeUpdate builds the update expression incrementing the revision,
+// and writing the value attributes and the TTL if provided,
+// with its attribute values and names.
+// A nil attribute value means the attribute must be removed.
+func (ddb *Store) writeUpdate(attrs map[string]*dynamodb.AttributeValue, opts *store.WriteOptions) (string, map[string]*dynamodb.AttributeValue, map[string]*string) {
+	return ddb.seededWriteUpdate(attrs, opts, 0)
+}
+
+// seededWriteUpdate is writeUpdate, with the revision of a new item starting after revisionSeed if not 0.
+func (ddb *Store) seededWriteUpdate(attrs map[string]*dynamodb.AttributeValue, opts *store.WriteOptions, revisionSeed uint64) (string, map[string]*dynamodb.AttributeValue, map[string]*string) {
+	exAttr := map[string]*dynamodb.AttributeValue{
+		":incr": {N: aws.String("1")},
+	}
+	exNames := map[string]*string{
+		revisionNamePlaceholder: aws.String(ddb.revisionName()),
+	}
+
+	var setList, removeList []string
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		namePlaceholder := fmt.Sprintf("#attr%d", i)
+		exNames[namePlaceholder] = aws.String(name)
+
+		if attrs[name] == nil {
+			removeList = append(removeList, namePlaceholder)
+			continue
 		}
+
+		placeholder := fmt.Sprintf(":val%d", i)
+		exAttr[placeholder] = attrs[name]
+		setList = append(setList, fmt.Sprintf("%s = %s", namePlaceholder, placeholder))
+	}
+
+	setList = appendTimestamps(setList, exAttr, exNames)
+
+	// if a ttl was provided validate it and append it to the update expression.
+	if opts != nil && opts.TTL > 0 {
+		ttlVal := time.Now().Add(opts.TTL).Unix()
+		exAttr[":ttl"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(ttlVal, 10))}
+		exNames[ttlNamePlaceholder] = aws.String(ddb.ttlName())
+		setList = append(setList, fmt.Sprintf("%s = :ttl", ttlNamePlaceholder))
+	}
+
+	updateExp := fmt.Sprintf("ADD %s :incr", revisionNamePlaceholder)
+
+	if revisionSeed > 0 {
+		// a revision can't be both added to and set.
+		exAttr[":revisionSeed"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatUint(revisionSeed, 10))}
+		setList = append([]string{fmt.Sprintf("%s = if_not_exists(%s, :revisionSeed) + :incr",
+			revisionNamePlaceholder, revisionNamePlaceholder)}, setList...)
+		updateExp = ""
 	}
+
+	if len(setList) > 0 {
+		updateExp = strings.TrimSpace(fmt.Sprintf("%s SET %s", updateExp, strings.Join(setList, ",")))
+	}
+
+	if len(removeList) > 0 {
+		updateExp = fmt.Sprintf("%s REMOVE %s", updateExp, strings.Join(removeList, ","))
+	}
+
+	return updateExp, exAttr, exNames
 }
 
-func (l *dynamodbLock) Unlock(ctx context.Context) error {
-	l.unlockCh <- struct{}{}
+// valueEncoding describes how the data of a stored value is encoded.
+type valueEncoding struct {
+	// codec the compression codec, if the value is compressed.
+	codec string
+	// dataKey the encrypted data key, and nonce the nonce, if the value is encrypted.
+	dataKey []byte
+	nonce   []byte
+	// attrs the attributes encoded by the Codec of the store, if any, holding the value instead of the data.
+	attrs map[string]*dynamodb.AttributeValue
+	// checksum the checksum of the value, if computed.
+	checksum string
+	// region the region of the write, if the conflicts are detected.
+	region string
+}
 
-	_, err := l.ddb.AtomicDelete(ctx, l.key, l.last)
-	if err != nil {
-		return err
+// attributes returns the attributes describing the encoding.
+// A nil attribute value means the attribute must be removed.
+func (e valueEncoding) attributes() map[string]*dynamodb.AttributeValue {
+	attrs := map[string]*dynamodb.AttributeValue{
+		compressionAttribute:     nil,
+		encryptionKeyAttribute:   nil,
+		encryptionNonceAttribute: nil,
+		checksumAttribute:        nil,
 	}
 
-	l.last = nil
+	if e.checksum != "" {
+		attrs[checksumAttribute] = &dynamodb.AttributeValue{S: aws.String(e.checksum)}
+	}
 
-	return nil
+	// the region of the previous write is kept when the conflicts are not detected.
+	if e.region != "" {
+		attrs[writerRegionAttribute] = &dynamodb.AttributeValue{S: aws.String(e.region)}
+	}
+
+	if e.codec != "" {
+		attrs[compressionAttribute] = &dynamodb.AttributeValue{S: aws.String(e.codec)}
+	}
+
+	if e.dataKey != nil {
+		attrs[encryptionKeyAttribute] = &dynamodb.AttributeValue{B: e.dataKey}
+		attrs[encryptionNonceAttribute] = &dynamodb.AttributeValue{B: e.nonce}
+	}
+
+	return attrs
 }
 
-func (l *dynamodbLock) tryLock(ctx context.Context, lockHeld chan struct{}) (bool, error) {
-	success, item, err := l.ddb.AtomicPut(ctx, l.key, l.value, l.last, &store.WriteOptions{TTL: l.ttl})
-	if err != nil {
-		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, store.ErrKeyModified) || errors.Is(err, store.ErrKeyExists) {
-			return false, nil
+// valueAttributes returns the attributes storing the encoded value data.
+// A nil attribute value means the attribute must be removed.
+func (ddb *Store) valueAttributes(data []byte, enc valueEncoding) map[string]*dynamodb.AttributeValue {
+	attrs := enc.attributes()
+	attrs[ddb.valueName()] = ddb.dataAttribute(data)
+	attrs[chunksAttribute] = nil
+	attrs[chunkIDAttribute] = nil
+	attrs[s3ObjectAttribute] = nil
+
+	if enc.attrs != nil {
+		attrs[ddb.valueName()] = nil
+		for name, v := range enc.attrs {
+			attrs[name] = v
 		}
-		return false, err
-	}
-	if success {
-		l.last = item
-		// keep holding.
-		go l.holdLock(ctx, lockHeld)
-		return true, nil
 	}
 
-	return false, err
+	return attrs
 }
 
-func (l *dynamodbLock) holdLock(ctx context.Context, lockHeld chan struct{}) {
-	defer close(lockHeld)
+// encodeValue returns the bytes to store for the value of key, compressed then encrypted if configured,
+// and their encoding, or the attributes encoded by the Codec of the store.
+func (ddb *Store) encodeValue(ctx context.Context, key string, value []byte) ([]byte, valueEncoding, error) {
+	checksum, err := ddb.valueChecksum(value)
+	if err != nil {
+		return nil, valueEncoding{}, err
+	}
 
-	hold := func() error {
-		_, item, err := l.ddb.AtomicPut(ctx, l.key, l.value, l.last, &store.WriteOptions{TTL: l.ttl})
+	if ddb.codec != nil {
+		attrs, err := ddb.codec.Encode(value)
 		if err != nil {
-			return err
+			return nil, valueEncoding{}, err
 		}
 
-		l.last = item
-		return nil
+		return nil, valueEncoding{attrs: attrs, checksum: checksum, region: ddb.writerRegion()}, nil
 	}
 
-	// may need a floor of 1 second set.
-	heartbeat := time.NewTicker(l.ttl / 3)
-	defer heartbeat.Stop()
+	data, codec, err := ddb.compressValue(value)
+	if err != nil {
+		return nil, valueEncoding{}, err
+	}
 
-	for {
-		select {
-		case <-heartbeat.C:
-			if err := hold(); err != nil {
-				return
-			}
-		case <-l.renewCh:
-			return
-		case <-l.unlockCh:
-			return
-		case <-ctx.Done():
-			return
-		}
+	enc := valueEncoding{codec: codec, checksum: checksum, region: ddb.writerRegion()}
+	if ddb.encryption == nil {
+		return data, enc, nil
+	}
+
+	data, enc.dataKey, enc.nonce, err = ddb.encryptData(ctx, key, data)
+	if err != nil {
+		return nil, valueEncoding{}, err
+	}
+
+	return data, enc, nil
+}
+
+// compressValue returns the bytes to store for value, and the compression codec used if any.
+func (ddb *Store) compressValue(value []byte) ([]byte, string, error) {
+	if ddb.compression == nil || len(value) < ddb.compression.Threshold {
+		return value, "", nil
+	}
+
+	compressed, err := compress(ddb.compression.Codec, value)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// only keep the compressed value if it's worth it.
+	if len(compressed) >= len(value) {
+		return value, "", nil
+	}
+
+	return compressed, ddb.compression.Codec, nil
+}
+
+// dataAttribute returns the attribute value holding data.
+func (ddb *Store) dataAttribute(data []byte) *dynamodb.AttributeValue {
+	if ddb.binaryValues {
+		return &dynamodb.AttributeValue{B: data}
 	}
+
+	return &dynamodb.AttributeValue{S: aws.String(base64.StdEncoding.EncodeToString(data))}
+}
+
+// notExpiredFilter returns the filter expression skipping the expired items,
+// adding its values to exAttr and its names to exNames.
+// DynamoDB deletes the expired items up to a few days after their expiration time.
+func (ddb *Store) notExpiredFilter(exAttr map[string]*dynamodb.AttributeValue, exNames map[string]*string) string {
+	exAttr[":timeNow"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))}
+	exNames[ttlNamePlaceholder] = aws.String(ddb.ttlName())
+
+	return fmt.Sprintf("(attribute_not_exists(%s) OR %s > :timeNow)", ttlNamePlaceholder, ttlNamePlaceholder)
 }
 
-func isItemExpired(item map[string]*dynamodb.AttributeValue) bool {
-	v, ok := item[ttlAttribute]
+func (ddb *Store) isItemExpired(item map[string]*dynamodb.AttributeValue) bool {
+	v, ok := item[ddb.ttlName()]
 	if !ok {
 		return false
 	}
@@ -692,14 +1802,11 @@ func isItemExpired(item map[string]*dynamodb.AttributeValue) bool {
 	return time.Unix(ttl, 0).Before(time.Now())
 }
 
-func decodeItem(item map[string]*dynamodb.AttributeValue) (*store.KVPair, error) {
-	var key string
-	if v, ok := item[partitionKey]; ok {
-		key = aws.StringValue(v.S)
-	}
+func (ddb *Store) decodeItem(item map[string]*dynamodb.AttributeValue) (*store.KVPair, error) {
+	key := ddb.itemKey(item)
 
 	var revision int64
-	if v, ok := item[revisionAttribute]; ok {
+	if v, ok := item[ddb.revisionName()]; ok {
 		var err error
 		revision, err = strconv.ParseInt(aws.StringValue(v.N), 10, 64)
 		if err != nil {
@@ -707,19 +1814,51 @@ func decodeItem(item map[string]*dynamodb.AttributeValue) (*store.KVPair, error)
 		}
 	}
 
-	var encodedValue string
-	if v, ok := item[encodedValueAttribute]; ok {
-		encodedValue = aws.StringValue(v.S)
+	value, err := ddb.decodeValue(item)
+	if err != nil {
+		return nil, err
 	}
 
-	rawValue, err := base64.StdEncoding.DecodeString(encodedValue)
-	if err != nil {
+	if err = ddb.verifyChecksum(key, item, value); err != nil {
 		return nil, err
 	}
 
 	return &store.KVPair{
 		Key:       key,
-		Value:     rawValue,
+		Value:     value,
 		LastIndex: uint64(revision),
 	}, nil
 }
+
+// decodeValue returns the value stored in item.
+func (ddb *Store) decodeValue(item map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	if ddb.codec != nil {
+		return ddb.codec.Decode(item)
+	}
+
+	// the attributes of a write are nil when it removes them.
+	rawValue := []byte{}
+	if v, ok := item[ddb.valueName()]; ok && v != nil {
+		var err error
+		rawValue, err = attributeData(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := item[compressionAttribute]; ok && v != nil {
+		return decompress(aws.StringValue(v.S), rawValue)
+	}
+
+	return rawValue, nil
+}
+
+// attributeData returns the bytes held by a value attribute.
+func attributeData(v *dynamodb.AttributeValue) ([]byte, error) {
+	if v.B != nil {
+		// value stored as raw bytes.
+		return v.B, nil
+	}
+
+	return base64.StdEncoding.DecodeString(aws.StringValue(v.S))
+}
@@ -2,15 +2,20 @@ package dynamodb
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 	"github.com/kvtools/valkeyrie"
 	"github.com/kvtools/valkeyrie/store"
 	"github.com/kvtools/valkeyrie/testsuite"
@@ -38,7 +43,7 @@ func TestRegister(t *testing.T) {
 func TestSetup(t *testing.T) {
 	ddb := newDynamoDBStore(t)
 	// ensure this is idempotent.
-	err := ddb.createTable()
+	err := ddb.EnsureTable(context.Background(), nil)
 	require.NoError(t, err)
 }
 
@@ -51,6 +56,17 @@ func TestDynamoDBStore(t *testing.T) {
 	testsuite.RunTestTTL(t, ddbStore, backupStore)
 }
 
+func TestDynamoDBStoreBinaryValues(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+	ddbStore.binaryValues = true
+	backupStore := newDynamoDBStore(t)
+	backupStore.binaryValues = true
+
+	testsuite.RunTestCommon(t, ddbStore)
+	testsuite.RunTestAtomic(t, ddbStore)
+	testsuite.RunTestTTL(t, ddbStore, backupStore)
+}
+
 func TestDynamoDBStoreLock(t *testing.T) {
 	ddbStore := newDynamoDBStore(t)
 	backupStore := newDynamoDBStore(t)
@@ -59,17 +75,63 @@ func TestDynamoDBStoreLock(t *testing.T) {
 	testsuite.RunTestLockTTL(t, ddbStore, backupStore)
 }
 
-func TestDynamoDBStoreUnsupported(t *testing.T) {
+func TestDynamoDBStoreStreamDisabled(t *testing.T) {
 	ddbStore := newDynamoDBStore(t)
 
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
 	_, err := ddbStore.WatchTree(ctx, "test", nil)
-	assert.ErrorIs(t, err, store.ErrCallNotSupported)
+	assert.ErrorIs(t, err, ErrStreamNotEnabled)
 
 	_, err = ddbStore.Watch(ctx, "test", nil)
-	assert.ErrorIs(t, err, store.ErrCallNotSupported)
+	assert.ErrorIs(t, err, ErrStreamNotEnabled)
+}
+
+func TestDynamoDBStoreWatch(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+	enableStream(t, ddbStore)
+
+	testsuite.RunTestWatch(t, ddbStore)
+}
+
+func TestDynamoDBStoreWatchTree(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+	enableStream(t, ddbStore)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	err := ddbStore.Put(ctx, "testWatchTree/node1", []byte("node1"), nil)
+	require.NoError(t, err)
+	err = ddbStore.Put(ctx, "testWatchTree/node2", []byte("node2"), nil)
+	require.NoError(t, err)
+
+	events, err := ddbStore.WatchTree(ctx, "testWatchTree", nil)
+	require.NoError(t, err)
+
+	select {
+	case pairs := <-events:
+		assert.Len(t, pairs, 2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout reached")
+	}
+
+	err = ddbStore.Delete(ctx, "testWatchTree/node2")
+	require.NoError(t, err)
+
+	select {
+	case pairs := <-events:
+		require.Len(t, pairs, 1)
+		assert.Equal(t, "testWatchTree/node1", pairs[0].Key)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout reached")
+	}
+
+	cancel()
+
+	_, ok := <-events
+	assert.False(t, ok)
 }
 
 func TestBatchWrite(t *testing.T) {
@@ -117,6 +179,203 @@ func TestBatchWrite(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestRetryDeleteTree(t *testing.T) {
+	mock := &mockedBatchBatches{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	requests := make([]*dynamodb.WriteRequest, 60)
+	for i := range requests {
+		requests[i] = &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{Key: kv.keyAttributes(fmt.Sprintf("testDeleteTree/%d", i))},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	err := kv.retryDeleteTree(ctx, map[string][]*dynamodb.WriteRequest{TestTableName: requests})
+	require.NoError(t, err)
+
+	// 3 batches, then the unprocessed requests of each batch retried in a single batch.
+	assert.ElementsMatch(t, []int{25, 25, 10, 3}, mock.sizes)
+	assert.Len(t, mock.deleted, 60)
+}
+
+func TestRetryDeleteTreeCanceled(t *testing.T) {
+	// the first request of each batch is left unprocessed, and retried after a second.
+	kv := &Store{dynamoSvc: &mockedBatchBatches{}, tableName: TestTableName, deleteTreeRetryTimeout: time.Hour}
+
+	requests := []*dynamodb.WriteRequest{
+		{DeleteRequest: &dynamodb.DeleteRequest{Key: kv.keyAttributes("testDeleteTree/0")}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := kv.retryDeleteTree(ctx, map[string][]*dynamodb.WriteRequest{TestTableName: requests})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPutWithResult(t *testing.T) {
+	mock := &mockedPutRevision{revision: 4}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	pair, err := kv.PutWithResult(context.Background(), "testPut", []byte("value"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &store.KVPair{Key: "testPut", Value: []byte("value"), LastIndex: 5}, pair)
+
+	// the first revision of a new key.
+	mock.revision = 0
+
+	pair, err = kv.PutWithResult(context.Background(), "testPut", []byte("value"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), pair.LastIndex)
+}
+
+func TestGetMeta(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	mock := &mockedProjectedGet{item: map[string]*dynamodb.AttributeValue{
+		partitionKey:          {S: aws.String("testGetMeta")},
+		revisionAttribute:     {N: aws.String("3")},
+		encodedValueAttribute: {S: aws.String("dmFsdWU=")},
+		ttlAttribute:          {N: aws.String(strconv.FormatInt(expiresAt.Unix(), 10))},
+		createdAtAttribute:    {N: aws.String("1700000000000")},
+		updatedAtAttribute:    {N: aws.String("1700000001500")},
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+	ctx := context.Background()
+
+	meta, err := kv.GetMeta(ctx, "testGetMeta", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &KeyMeta{
+		Key:       "testGetMeta",
+		LastIndex: 3,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.UnixMilli(1700000000000),
+		UpdatedAt: time.UnixMilli(1700000001500),
+	}, meta)
+	assert.Equal(t, "#revision, #ttl, #createdAt, #updatedAt", aws.StringValue(mock.input.ProjectionExpression))
+
+	exists, err := kv.Exists(ctx, "testGetMeta", nil)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "#key, #ttl", aws.StringValue(mock.input.ProjectionExpression))
+
+	pair, meta, err := kv.GetWithMeta(ctx, "testGetMeta", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &store.KVPair{Key: "testGetMeta", Value: []byte("value"), LastIndex: 3}, pair)
+	assert.Equal(t, expiresAt, meta.ExpiresAt)
+
+	mock.item = nil
+
+	_, err = kv.GetMeta(ctx, "testGetMeta", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	_, _, err = kv.GetWithMeta(ctx, "testGetMeta", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+func TestAtomicPutCondition(t *testing.T) {
+	// GetItem isn't mocked: AtomicPut must not read the item.
+	mock := &mockedConditionalUpdate{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	ok, pair, err := kv.AtomicPut(ctx, "testAtomicPut", []byte("value"), nil, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &store.KVPair{Key: "testAtomicPut", Value: []byte("value"), LastIndex: 1}, pair)
+	assert.Contains(t, aws.StringValue(mock.input.ConditionExpression), "attribute_not_exists(#key)")
+	assert.Equal(t, "id", aws.StringValue(mock.input.ExpressionAttributeNames["#key"]))
+
+	mock.failed = true
+
+	_, _, err = kv.AtomicPut(ctx, "testAtomicPut", []byte("value"), nil, nil)
+	assert.ErrorIs(t, err, store.ErrKeyExists)
+
+	_, _, err = kv.AtomicPut(ctx, "testAtomicPut", []byte("value"), pair, nil)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+	assert.Equal(t, "1", aws.StringValue(mock.input.ExpressionAttributeValues[":lastRevision"].N))
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	mock := &mockedConditionalDelete{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	// the missing keys are deleted silently by default.
+	require.NoError(t, kv.Delete(ctx, "missing"))
+	assert.Nil(t, mock.input.ReturnValues)
+
+	kv.deleteNotFound = true
+
+	err := kv.Delete(ctx, "missing")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.Equal(t, dynamodb.ReturnValueAllOld, aws.StringValue(mock.input.ReturnValues))
+
+	mock.old = newTestItem("foo", "YmFy")
+	require.NoError(t, kv.Delete(ctx, "foo"))
+
+	// the expired keys were already gone.
+	mock.old[ttlAttribute] = &dynamodb.AttributeValue{N: aws.String("1")}
+	assert.ErrorIs(t, kv.Delete(ctx, "foo"), store.ErrKeyNotFound)
+}
+
+func TestAtomicDeleteCondition(t *testing.T) {
+	mock := &mockedConditionalDelete{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+	previous := &store.KVPair{Key: "testAtomicDelete", LastIndex: 2}
+
+	_, err := kv.AtomicDelete(ctx, "testAtomicDelete", nil)
+	assert.ErrorIs(t, err, store.ErrPreviousNotSpecified)
+
+	ok, err := kv.AtomicDelete(ctx, "testAtomicDelete", previous)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "2", aws.StringValue(mock.input.ExpressionAttributeValues[":lastRevision"].N))
+
+	mock.failed = true
+
+	_, err = kv.AtomicDelete(ctx, "testAtomicDelete", previous)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	mock.current = map[string]*dynamodb.AttributeValue{
+		partitionKey:      {S: aws.String("testAtomicDelete")},
+		revisionAttribute: {N: aws.String("3")},
+	}
+
+	_, err = kv.AtomicDelete(ctx, "testAtomicDelete", previous)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+}
+
+func TestCreateTable(t *testing.T) {
+	mock := &mockedCreateTable{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	err := kv.EnsureTable(context.Background(), &TableSpec{BillingMode: dynamodb.BillingModePayPerRequest})
+	require.NoError(t, err)
+	assert.Equal(t, dynamodb.BillingModePayPerRequest, aws.StringValue(mock.input.BillingMode))
+	assert.Nil(t, mock.input.ProvisionedThroughput)
+	assert.Equal(t, 1, mock.waits)
+	require.NotNil(t, mock.ttl)
+	assert.Equal(t, ttlAttribute, aws.StringValue(mock.ttl.TimeToLiveSpecification.AttributeName))
+
+	// the existing table is waited for, but left unchanged.
+	mock = &mockedCreateTable{exists: true}
+	kv = &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	err = kv.EnsureTable(context.Background(), &TableSpec{ReadCapacityUnits: 5})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), aws.Int64Value(mock.input.ProvisionedThroughput.ReadCapacityUnits))
+	assert.Equal(t, int64(DefaultWriteCapacityUnits), aws.Int64Value(mock.input.ProvisionedThroughput.WriteCapacityUnits))
+	assert.Equal(t, 1, mock.waits)
+	assert.Nil(t, mock.ttl)
+}
+
 func TestDecodeItem(t *testing.T) {
 	data := map[string]*dynamodb.AttributeValue{
 		partitionKey: {
@@ -130,12 +389,17 @@ func TestDecodeItem(t *testing.T) {
 		},
 	}
 
-	kv, err := decodeItem(data)
+	kv, err := (&Store{}).decodeItem(data)
 	require.NoError(t, err)
 	assert.Equal(t, &store.KVPair{Key: "abc123", Value: []uint8{0x61, 0x62, 0x63, 0x31, 0x32, 0x33, 0xa}, LastIndex: 0xa}, kv)
 
+	data[encodedValueAttribute] = &dynamodb.AttributeValue{B: []byte("abc123\n")}
+	kv, err = (&Store{}).decodeItem(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abc123\n"), kv.Value)
+
 	data[encodedValueAttribute] = &dynamodb.AttributeValue{S: aws.String("not base64")}
-	kv, err = decodeItem(data)
+	kv, err = (&Store{}).decodeItem(data)
 	assert.Error(t, err)
 	assert.Nil(t, kv)
 }
@@ -155,6 +419,146 @@ func (m *mockedBatchWrite) BatchWriteItem(_ *dynamodb.BatchWriteItemInput) (*dyn
 	return &dynamodb.BatchWriteItemOutput{}, nil
 }
 
+// mockedCreateTable records the table creation, failing if exists is set.
+type mockedCreateTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	exists bool
+	input  *dynamodb.CreateTableInput
+	// table the table created, described instead of the table of the last creation if set.
+	table          *dynamodb.CreateTableInput
+	waits          int
+	ttlDescription *dynamodb.TimeToLiveDescription
+	ttl            *dynamodb.UpdateTimeToLiveInput
+}
+
+func (m *mockedCreateTable) CreateTableWithContext(_ aws.Context, input *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	m.input = input
+
+	if m.exists {
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "Table already exists", nil)
+	}
+
+	m.table = input
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (m *mockedCreateTable) DescribeTableWithContext(_ aws.Context, input *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	table := m.table
+	if table == nil {
+		table = m.input
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableName:           input.TableName,
+		KeySchema:           table.KeySchema,
+		StreamSpecification: table.StreamSpecification,
+	}}, nil
+}
+
+func (m *mockedCreateTable) WaitUntilTableExistsWithContext(_ aws.Context, _ *dynamodb.DescribeTableInput, _ ...request.WaiterOption) error {
+	m.waits++
+	return nil
+}
+
+func (m *mockedCreateTable) DescribeTimeToLiveWithContext(_ aws.Context, _ *dynamodb.DescribeTimeToLiveInput, _ ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: m.ttlDescription}, nil
+}
+
+func (m *mockedCreateTable) UpdateTimeToLiveWithContext(_ aws.Context, input *dynamodb.UpdateTimeToLiveInput, _ ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	m.ttl = input
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+// mockedConditionalUpdate fails the updates on their condition if failed is set.
+type mockedConditionalUpdate struct {
+	dynamodbiface.DynamoDBAPI
+
+	failed bool
+	input  *dynamodb.UpdateItemInput
+}
+
+func (m *mockedConditionalUpdate) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.input = input
+
+	if m.failed {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+
+	return &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+		partitionKey:          input.Key[partitionKey],
+		revisionAttribute:     {N: aws.String("1")},
+		encodedValueAttribute: {S: aws.String("dmFsdWU=")},
+	}}, nil
+}
+
+// mockedConditionalDelete fails the deletes on their condition if failed is set,
+// returns current on reads, and old as the deleted item.
+type mockedConditionalDelete struct {
+	dynamodbiface.DynamoDBAPI
+
+	failed  bool
+	current map[string]*dynamodb.AttributeValue
+	old     map[string]*dynamodb.AttributeValue
+	input   *dynamodb.DeleteItemInput
+}
+
+func (m *mockedConditionalDelete) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	m.input = input
+
+	if m.failed {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+
+	return &dynamodb.DeleteItemOutput{Attributes: m.old}, nil
+}
+
+func (m *mockedConditionalDelete) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.current}, nil
+}
+
+// mockedBatchBatches records the size of the batches,
+// and leaves the first request of each batch unprocessed on its first attempt.
+type mockedBatchBatches struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu      sync.Mutex
+	sizes   []int
+	seen    map[string]struct{}
+	deleted []string
+}
+
+func (m *mockedBatchBatches) BatchWriteItemWithContext(_ aws.Context, input *dynamodb.BatchWriteItemInput, _ ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.seen == nil {
+		m.seen = make(map[string]struct{})
+	}
+
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: make(map[string][]*dynamodb.WriteRequest)}
+
+	for table, requests := range input.RequestItems {
+		m.sizes = append(m.sizes, len(requests))
+
+		for i, req := range requests {
+			key := aws.StringValue(req.DeleteRequest.Key[partitionKey].S)
+
+			_, seen := m.seen[key]
+			m.seen[key] = struct{}{}
+
+			if i == 0 && !seen {
+				out.UnprocessedItems[table] = append(out.UnprocessedItems[table], req)
+				continue
+			}
+
+			m.deleted = append(m.deleted, key)
+		}
+	}
+
+	return out, nil
+}
+
 func newDynamoDB() *dynamodb.DynamoDB {
 	creds := credentials.NewStaticCredentials("test", "test", "test")
 
@@ -167,19 +571,48 @@ func newDynamoDB() *dynamodb.DynamoDB {
 	return dynamodb.New(sess)
 }
 
+func newDynamoDBStreams() *dynamodbstreams.DynamoDBStreams {
+	creds := credentials.NewStaticCredentials("test", "test", "test")
+
+	config := aws.NewConfig().WithCredentials(creds)
+	config.Endpoint = aws.String("http://localhost:8000")
+	config.Region = aws.String("us-east-1")
+
+	sess := session.Must(session.NewSession(config))
+
+	return dynamodbstreams.New(sess)
+}
+
 func newDynamoDBStore(t *testing.T) *Store {
 	t.Helper()
 
+	return newDynamoDBStoreWith(t, nil)
+}
+
+// itemKey returns the key of an item with the default attribute names, for the mocks.
+func itemKey(item map[string]*dynamodb.AttributeValue) string {
+	return (&Store{}).itemKey(item)
+}
+
+// newDynamoDBStoreWith creates the test table after applying configure to the store.
+func newDynamoDBStoreWith(t *testing.T, configure func(ddbStore *Store)) *Store {
+	t.Helper()
+
 	ddb := newDynamoDB()
 
 	ddbStore := &Store{
-		dynamoSvc: ddb,
-		tableName: TestTableName,
+		dynamoSvc:  ddb,
+		streamsSvc: newDynamoDBStreams(),
+		tableName:  TestTableName,
+	}
+
+	if configure != nil {
+		configure(ddbStore)
 	}
 
 	err := deleteTable(ddb, TestTableName)
 	require.NoError(t, err)
-	err = ddbStore.createTable()
+	err = ddbStore.EnsureTable(context.Background(), nil)
 	require.NoError(t, err)
 
 	return ddbStore
@@ -202,3 +635,59 @@ func deleteTable(dynamoSvc *dynamodb.DynamoDB, tableName string) error {
 		TableName: aws.String(tableName),
 	})
 }
+
+func enableStream(t *testing.T, ddbStore *Store) {
+	t.Helper()
+
+	_, err := ddbStore.dynamoSvc.UpdateTable(&dynamodb.UpdateTableInput{
+		TableName: aws.String(ddbStore.tableName),
+		StreamSpecification: &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(dynamodb.StreamViewTypeNewAndOldImages),
+		},
+	})
+	require.NoError(t, err)
+}
+
+// mockedPutRevision returns the previous revision of the updated item, if any.
+type mockedPutRevision struct {
+	dynamodbiface.DynamoDBAPI
+	revision uint64
+}
+
+func (m *mockedPutRevision) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	out := &dynamodb.UpdateItemOutput{}
+	if m.revision > 0 && aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueUpdatedOld {
+		out.Attributes = map[string]*dynamodb.AttributeValue{revisionAttribute: {N: aws.String(strconv.FormatUint(m.revision, 10))}}
+	}
+
+	return out, nil
+}
+
+// mockedProjectedGet returns the attributes of item in the projection of the GetItem, if any.
+type mockedProjectedGet struct {
+	dynamodbiface.DynamoDBAPI
+	item  map[string]*dynamodb.AttributeValue
+	input *dynamodb.GetItemInput
+}
+
+func (m *mockedProjectedGet) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.input = input
+
+	if m.item == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	if input.ProjectionExpression == nil {
+		return &dynamodb.GetItemOutput{Item: m.item}, nil
+	}
+
+	item := make(map[string]*dynamodb.AttributeValue)
+	for _, name := range input.ExpressionAttributeNames {
+		if v, ok := m.item[aws.StringValue(name)]; ok {
+			item[aws.StringValue(name)] = v
+		}
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
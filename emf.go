@@ -0,0 +1,119 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const defaultEMFNamespace = "KVTools/DynamoDB"
+
+// The metrics emitted in the CloudWatch Embedded Metric Format, for each DynamoDB call.
+const (
+	MetricLatency          = "Latency"
+	MetricErrors           = "Errors"
+	MetricConsumedCapacity = "ConsumedCapacity"
+)
+
+// EMFConfig configures the emission of the metrics of the DynamoDB calls in the CloudWatch Embedded Metric Format:
+// one JSON line per call, with its latency (including its retries), its error, and its consumed capacity,
+// by table and operation. The lines written to stdout by a Lambda function are collected by CloudWatch Logs,
+// which extracts the metrics.
+type EMFConfig struct {
+	// Namespace is the CloudWatch namespace of the metrics, defaults to "KVTools/DynamoDB".
+	Namespace string
+
+	// Writer receives the lines, defaults to os.Stdout.
+	Writer io.Writer
+
+	// Dimensions are added to the TableName and Operation dimensions of the metrics, such as the service name.
+	Dimensions map[string]string
+}
+
+// emfEmitter writes the metrics of the calls.
+type emfEmitter struct {
+	mu         sync.Mutex
+	writer     io.Writer
+	namespace  string
+	dimensions map[string]string
+}
+
+// newEMFEmitter returns the emitter of the metrics configured by config.
+func newEMFEmitter(config *EMFConfig) *emfEmitter {
+	e := &emfEmitter{writer: config.Writer, namespace: config.Namespace, dimensions: config.Dimensions}
+	if e.writer == nil {
+		e.writer = os.Stdout
+	}
+	if e.namespace == "" {
+		e.namespace = defaultEMFNamespace
+	}
+
+	return e
+}
+
+// emfInterceptor returns the interceptor emitting the metrics of the calls with emitter.
+func emfInterceptor(emitter *emfEmitter) callInterceptor {
+	return func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error) {
+		returnConsumedCapacity(call.input)
+
+		start := time.Now()
+		out, err := next(ctx)
+
+		emitter.emit(call, time.Since(start), err, consumedCapacity(out))
+
+		return out, err
+	}
+}
+
+// emit writes the metrics of a call.
+func (e *emfEmitter) emit(call *apiCall, latency time.Duration, err error, units float64) {
+	now := time.Now()
+
+	extra := make([]string, 0, len(e.dimensions))
+	for name := range e.dimensions {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+
+	dimensions := append([]string{"TableName", "Operation"}, extra...)
+
+	failed := 0
+	if err != nil {
+		failed = 1
+	}
+
+	line := map[string]interface{}{
+		"_aws": map[string]interface{}{
+			"Timestamp": now.UnixMilli(),
+			"CloudWatchMetrics": []map[string]interface{}{{
+				"Namespace":  e.namespace,
+				"Dimensions": [][]string{dimensions},
+				"Metrics": []map[string]string{
+					{"Name": MetricLatency, "Unit": "Milliseconds"},
+					{"Name": MetricErrors, "Unit": "Count"},
+					{"Name": MetricConsumedCapacity, "Unit": "Count"},
+				},
+			}},
+		},
+		"TableName":            call.table,
+		"Operation":            call.operation,
+		MetricLatency:          float64(latency.Microseconds()) / 1000,
+		MetricErrors:           failed,
+		MetricConsumedCapacity: units,
+	}
+	for name, value := range e.dimensions {
+		line[name] = value
+	}
+
+	data, _ := json.Marshal(line)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, _ = e.writer.Write(append(data, '\n'))
+}
@@ -0,0 +1,58 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEMF(t *testing.T) {
+	var buf bytes.Buffer
+
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient: &mockedCapacity{},
+		StreamsClient:  &mockedStreams{},
+		EMF:            &EMFConfig{Writer: &buf, Dimensions: map[string]string{"Service": "config"}},
+	}))
+
+	_, err := kv.Get(context.Background(), "key", nil)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var line struct {
+		AWS struct {
+			CloudWatchMetrics []struct {
+				Namespace  string
+				Dimensions [][]string
+				Metrics    []struct{ Name, Unit string }
+			}
+		} `json:"_aws"`
+		TableName        string
+		Operation        string
+		Service          string
+		Errors           int
+		ConsumedCapacity float64
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+
+	require.Len(t, line.AWS.CloudWatchMetrics, 1)
+	metrics := line.AWS.CloudWatchMetrics[0]
+	assert.Equal(t, defaultEMFNamespace, metrics.Namespace)
+	assert.Equal(t, [][]string{{"TableName", "Operation", "Service"}}, metrics.Dimensions)
+	assert.Len(t, metrics.Metrics, 3)
+
+	assert.Equal(t, TestTableName, line.TableName)
+	assert.Equal(t, "GetItem", line.Operation)
+	assert.Equal(t, "config", line.Service)
+	assert.Equal(t, 0, line.Errors)
+	// the consumed capacity is requested for the metrics.
+	assert.InDelta(t, 1.5, line.ConsumedCapacity, 0)
+}
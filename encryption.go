@@ -0,0 +1,137 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+const (
+	encryptionKeyAttribute   = "encryption_key"
+	encryptionNonceAttribute = "encryption_nonce"
+
+	// encryptionContextKey the entry of the KMS encryption context holding the key of the item.
+	encryptionContextKey = "key"
+)
+
+var (
+	// ErrEncryptionNotConfigured is returned when reading an encrypted value without encryption configuration.
+	ErrEncryptionNotConfigured = errors.New("value encrypted but encryption is not configured")
+	// ErrValueDecryption is returned when an encrypted value can't be decrypted, such as if it was altered.
+	ErrValueDecryption = errors.New("value decryption failed")
+)
+
+// EncryptionConfig the client-side encryption configuration.
+// Each value is encrypted with AES-256-GCM by its own data key, generated by KMS,
+// and stored encrypted by the KMS key along with the nonce in attributes of the item.
+// The encryption is bound to the key of the item: a value copied as is to another key can't be decrypted.
+type EncryptionConfig struct {
+	// KeyID is the KMS key encrypting the data keys: its ID, ARN, alias name, or alias ARN.
+	// The encrypted values are decrypted with the KMS key which encrypted them, whatever this option.
+	KeyID string
+}
+
+// encryptData encrypts data stored at key with a new data key,
+// and returns the encrypted data, the data key encrypted by KMS, and the nonce.
+func (ddb *Store) encryptData(ctx context.Context, key string, data []byte) ([]byte, []byte, []byte, error) {
+	res, err := ddb.kmsSvc.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(ddb.encryption.KeyID),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: ddb.encryptionContext(key),
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	aead, err := newAEAD(res.Plaintext)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return aead.Seal(nil, nonce, data, []byte(ddb.storedKey(key))), res.CiphertextBlob, nonce, nil
+}
+
+// decryptItem returns a copy of item with its value decrypted, or item if its value is not encrypted.
+// The value must be loaded, if it's stored in chunks or in S3.
+func (ddb *Store) decryptItem(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	dataKey, ok := item[encryptionKeyAttribute]
+	if !ok || dataKey == nil {
+		return item, nil
+	}
+
+	if ddb.encryption == nil {
+		return nil, ErrEncryptionNotConfigured
+	}
+
+	key := ddb.itemKey(item)
+
+	res, err := ddb.kmsSvc.DecryptWithContext(ctx, &kms.DecryptInput{
+		CiphertextBlob:    dataKey.B,
+		EncryptionContext: ddb.encryptionContext(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(res.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if v, ok := item[ddb.valueName()]; ok {
+		if data, err = attributeData(v); err != nil {
+			return nil, err
+		}
+	}
+
+	var nonce []byte
+	if v, ok := item[encryptionNonceAttribute]; ok {
+		nonce = v.B
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce of %s", ErrValueDecryption, key)
+	}
+
+	data, err = aead.Open(nil, nonce, data, []byte(ddb.storedKey(key)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrValueDecryption, key, err)
+	}
+
+	decrypted := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, v := range item {
+		decrypted[name] = v
+	}
+	decrypted[ddb.valueName()] = &dynamodb.AttributeValue{B: data}
+	delete(decrypted, encryptionKeyAttribute)
+	delete(decrypted, encryptionNonceAttribute)
+
+	return decrypted, nil
+}
+
+// encryptionContext returns the KMS encryption context of the data key of key.
+func (ddb *Store) encryptionContext(key string) map[string]*string {
+	return map[string]*string{encryptionContextKey: aws.String(ddb.storedKey(key))}
+}
+
+func newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
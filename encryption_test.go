@@ -0,0 +1,91 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryption(t *testing.T) {
+	kmsMock := &mockedKMS{}
+	kv := &Store{
+		compression: &CompressionConfig{Codec: CompressionGzip, Threshold: 100},
+		encryption:  &EncryptionConfig{KeyID: "alias/kv"},
+		kmsSvc:      kmsMock,
+	}
+
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("valkeyrie"), 100)
+
+	data, enc, err := kv.encodeValue(ctx, "key", value)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionGzip, enc.codec)
+	assert.NotEmpty(t, enc.dataKey)
+	assert.Len(t, enc.nonce, 12)
+	assert.NotContains(t, string(data), "valkeyrie")
+
+	item := kv.valueAttributes(data, enc)
+	item[partitionKey] = &dynamodb.AttributeValue{S: aws.String("key")}
+
+	decrypted, err := kv.decryptItem(ctx, item)
+	require.NoError(t, err)
+	assert.NotContains(t, decrypted, encryptionKeyAttribute)
+
+	pair, err := kv.decodeItem(decrypted)
+	require.NoError(t, err)
+	assert.Equal(t, value, pair.Value)
+
+	// the value is bound to its key.
+	item[partitionKey] = &dynamodb.AttributeValue{S: aws.String("other")}
+	_, err = kv.decryptItem(ctx, item)
+	assert.Error(t, err)
+
+	item[partitionKey] = &dynamodb.AttributeValue{S: aws.String("key")}
+	item[encodedValueAttribute] = kv.dataAttribute(append([]byte{data[0] ^ 1}, data[1:]...))
+	_, err = kv.decryptItem(ctx, item)
+	assert.ErrorIs(t, err, ErrValueDecryption)
+
+	// the values not encrypted are read as is.
+	plain := kv.valueAttributes([]byte("plain"), valueEncoding{})
+	decrypted, err = kv.decryptItem(ctx, plain)
+	require.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+
+	_, err = (&Store{}).decryptItem(ctx, item)
+	assert.ErrorIs(t, err, ErrEncryptionNotConfigured)
+}
+
+// mockedKMS wraps the data keys with their encryption context, without encrypting them.
+type mockedKMS struct {
+	kmsiface.KMSAPI
+}
+
+func (m *mockedKMS) GenerateDataKeyWithContext(_ aws.Context, input *kms.GenerateDataKeyInput, _ ...request.Option) (*kms.GenerateDataKeyOutput, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	wrapped := append([]byte(aws.StringValue(input.EncryptionContext[encryptionContextKey])+":"), dataKey...)
+
+	return &kms.GenerateDataKeyOutput{Plaintext: dataKey, CiphertextBlob: wrapped, KeyId: input.KeyId}, nil
+}
+
+func (m *mockedKMS) DecryptWithContext(_ aws.Context, input *kms.DecryptInput, _ ...request.Option) (*kms.DecryptOutput, error) {
+	prefix := []byte(aws.StringValue(input.EncryptionContext[encryptionContextKey]) + ":")
+	if !bytes.HasPrefix(input.CiphertextBlob, prefix) {
+		return nil, errors.New(kms.ErrCodeInvalidCiphertextException)
+	}
+
+	return &kms.DecryptOutput{Plaintext: bytes.TrimPrefix(input.CiphertextBlob, prefix)}, nil
+}
@@ -0,0 +1,66 @@
+package dynamodb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The environment variables read by ConfigFromEnv.
+const (
+	EnvDSN      = "KVTOOLS_DYNAMODB_DSN"
+	EnvTable    = "KVTOOLS_DYNAMODB_TABLE"
+	EnvEndpoint = "KVTOOLS_DYNAMODB_ENDPOINT"
+	// envPrefix the prefix of the environment variables of the connection string parameters,
+	// such as KVTOOLS_DYNAMODB_REGION for region.
+	envPrefix = "KVTOOLS_DYNAMODB_"
+)
+
+// envOptions the connection string parameters read from the environment.
+func envOptions() []string {
+	return []string{"region", "consistent", "auto_create", "billing_mode", "key_prefix", "ttl_attribute", "scan_segments"}
+}
+
+// ConfigFromEnv returns the configuration of the environment variables:
+//   - KVTOOLS_DYNAMODB_DSN: a connection string, see ParseDSN, overridden by the other variables.
+//   - KVTOOLS_DYNAMODB_TABLE: Config.Bucket.
+//   - KVTOOLS_DYNAMODB_ENDPOINT: Config.Endpoint.
+//   - KVTOOLS_DYNAMODB_REGION, KVTOOLS_DYNAMODB_CONSISTENT, KVTOOLS_DYNAMODB_AUTO_CREATE,
+//     KVTOOLS_DYNAMODB_BILLING_MODE, KVTOOLS_DYNAMODB_KEY_PREFIX, KVTOOLS_DYNAMODB_TTL_ATTRIBUTE,
+//     and KVTOOLS_DYNAMODB_SCAN_SEGMENTS: the connection string parameters of the same name.
+//
+// The empty variables are ignored. The stores created from the valkeyrie registry without configuration use it.
+func ConfigFromEnv() (*Config, error) {
+	options := &Config{}
+
+	if dsn := os.Getenv(EnvDSN); dsn != "" {
+		endpoint, err := options.applyDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		options.Endpoint = endpoint
+	}
+
+	if table := os.Getenv(EnvTable); table != "" {
+		options.Bucket = table
+	}
+
+	if endpoint := os.Getenv(EnvEndpoint); endpoint != "" {
+		options.Endpoint = endpoint
+	}
+
+	for _, name := range envOptions() {
+		variable := envPrefix + strings.ToUpper(name)
+
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+
+		if err := options.setOption(name, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", variable, err)
+		}
+	}
+
+	return options, nil
+}
@@ -0,0 +1,45 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvDSN, "dynamodb://dsn-table?region=us-east-1&endpoint=http://localhost:8000&key_prefix=app/")
+	t.Setenv(EnvTable, "my-table")
+	t.Setenv("KVTOOLS_DYNAMODB_REGION", "eu-central-1")
+	t.Setenv("KVTOOLS_DYNAMODB_CONSISTENT", "false")
+	t.Setenv("KVTOOLS_DYNAMODB_TTL_ATTRIBUTE", "ttl")
+
+	options, err := ConfigFromEnv()
+	require.NoError(t, err)
+
+	assert.Equal(t, &Config{
+		Bucket:                    "my-table",
+		Endpoint:                  "http://localhost:8000",
+		Region:                    "eu-central-1",
+		KeyPrefix:                 "app/",
+		EventuallyConsistentReads: true,
+		AttributeNames:            AttributeNames{ExpirationTime: "ttl"},
+	}, options)
+
+	t.Setenv("KVTOOLS_DYNAMODB_SCAN_SEGMENTS", "many")
+	_, err = ConfigFromEnv()
+	assert.ErrorContains(t, err, "KVTOOLS_DYNAMODB_SCAN_SEGMENTS")
+
+	// the registry reads the environment without configuration, ignoring the empty variables.
+	t.Setenv(EnvDSN, "")
+	t.Setenv("KVTOOLS_DYNAMODB_SCAN_SEGMENTS", "")
+
+	kv, err := newStore(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	ddb, ok := kv.(*Store)
+	require.True(t, ok)
+	assert.Equal(t, "my-table", ddb.tableName)
+	assert.True(t, ddb.eventuallyConsistent)
+}
@@ -0,0 +1,108 @@
+package dynamodb
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// errCodeAccessDenied the error code of the calls denied by the IAM policies.
+const errCodeAccessDenied = "AccessDeniedException"
+
+var (
+	// ErrThrottled is matched by the errors of the calls throttled by DynamoDB,
+	// above the provisioned throughput or the account limits.
+	ErrThrottled = errors.New("throttled")
+	// ErrTableNotFound is matched by the errors of the calls to a table, or an index, which doesn't exist.
+	ErrTableNotFound = errors.New("table not found")
+	// ErrItemTooLarge is matched by the errors of the writes of an item above the 400KB limit of DynamoDB.
+	ErrItemTooLarge = errors.New("item too large")
+	// ErrAccessDenied is matched by the errors of the calls denied by the IAM policies of the credentials.
+	ErrAccessDenied = errors.New("access denied")
+)
+
+// AWSError is an error of a DynamoDB call of a known kind, returned by the operations of the store.
+// It matches its kind with errors.Is, such as ErrThrottled,
+// and remains an awserr.Error: errors.As finds the error of the AWS SDK it wraps.
+type AWSError struct {
+	// Kind is ErrThrottled, ErrTableNotFound, ErrItemTooLarge, or ErrAccessDenied.
+	Kind error
+	// Err is the error returned by the AWS SDK.
+	Err awserr.Error
+}
+
+func (e *AWSError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Code returns the error code of DynamoDB.
+func (e *AWSError) Code() string {
+	return e.Err.Code()
+}
+
+// Message returns the error message of DynamoDB.
+func (e *AWSError) Message() string {
+	return e.Err.Message()
+}
+
+// OrigErr returns the original error of the AWS SDK, if any.
+func (e *AWSError) OrigErr() error {
+	return e.Err.OrigErr()
+}
+
+// Is reports whether target is the kind of the error.
+func (e *AWSError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the error of the AWS SDK.
+func (e *AWSError) Unwrap() error {
+	return e.Err
+}
+
+// wrapAWSError returns err wrapped in an AWSError if it's of a known kind, unchanged otherwise.
+func wrapAWSError(err error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+
+	if kind := errorKind(awsErr); kind != nil {
+		return &AWSError{Kind: kind, Err: awsErr}
+	}
+
+	return err
+}
+
+// errorKind returns the kind of an error of the AWS SDK, nil if unknown.
+func errorKind(err awserr.Error) error {
+	switch {
+	case request.IsErrorThrottle(err):
+		return ErrThrottled
+	case err.Code() == dynamodb.ErrCodeResourceNotFoundException:
+		return ErrTableNotFound
+	case err.Code() == errCodeAccessDenied:
+		return ErrAccessDenied
+	// the writes above the limit fail their validation.
+	case err.Code() == "ValidationException" && strings.Contains(err.Message(), "Item size"):
+		return ErrItemTooLarge
+	default:
+		return nil
+	}
+}
+
+// errorInterceptor wraps the errors of the calls of a known kind in an AWSError.
+func errorInterceptor() callInterceptor {
+	return func(ctx aws.Context, _ *apiCall, next callFunc) (interface{}, error) {
+		out, err := next(ctx)
+		if err != nil {
+			return out, wrapAWSError(err)
+		}
+
+		return out, nil
+	}
+}
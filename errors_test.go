@@ -0,0 +1,48 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSErrors(t *testing.T) {
+	mock := &mockedRegion{}
+
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{DynamoDBClient: mock, StreamsClient: &mockedStreams{}}))
+
+	ctx := context.Background()
+
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throughput exceeded", nil), ErrThrottled},
+		{awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Requested resource not found", nil), ErrTableNotFound},
+		{awserr.New("AccessDeniedException", "not authorized", nil), ErrAccessDenied},
+		{awserr.New("ValidationException", "Item size has exceeded the maximum allowed size", nil), ErrItemTooLarge},
+	}
+
+	for _, test := range tests {
+		mock.err = test.err
+
+		err := kv.Put(ctx, "key", []byte("value"), nil)
+		assert.ErrorIs(t, err, test.kind)
+
+		var awsErr awserr.Error
+		require.ErrorAs(t, err, &awsErr)
+		assert.Equal(t, test.err.(awserr.Error).Code(), awsErr.Code())
+		assert.Equal(t, test.err, errors.Unwrap(err))
+	}
+
+	// the other errors are returned unchanged.
+	mock.err = awserr.New("ValidationException", "invalid expression", nil)
+	_, err := kv.Get(ctx, "key", nil)
+	assert.Equal(t, mock.err, err)
+}
@@ -0,0 +1,112 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// WatchEvents watches for changes on the keys starting with prefix,
+// and sends an event per change, with its type and the values before and after it.
+// The values are read from the images of the stream records,
+// so the stream must hold the new and the old images of the items (dynamodb.StreamViewTypeNewAndOldImages).
+// Without the new image, the new value is read with opts, and without the old image, the old value is nil.
+// If the notifier ends the subscription, the watch subscribes again with backoff, the changes made meanwhile being missed.
+func (ddb *Store) WatchEvents(ctx context.Context, prefix string, opts *store.ReadOptions) (<-chan *Event, error) {
+	return ddb.WatchEventsWithOptions(ctx, prefix, &WatchOptions{ReadOptions: opts})
+}
+
+// WatchEventsWithOptions watches for changes on the keys starting with prefix like WatchEvents,
+// with the read options of the new values, and the buffering options of the events.
+// SkipInitial and AfterRevision are ignored, only the changes being sent.
+func (ddb *Store) WatchEventsWithOptions(ctx context.Context, prefix string, opts *WatchOptions) (<-chan *Event, error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+
+	readOpts := opts.ReadOptions
+	if readOpts == nil {
+		readOpts = &store.ReadOptions{
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
+		}
+	}
+
+	ctx, done, err := ddb.tasks.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := ddb.subscribe(ctx, ddb.normalizePrefix(prefix), opts)
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	out := newWatchOutput[*Event](prefix, opts)
+
+	go func() {
+		defer done()
+		defer close(out.ch)
+
+		for {
+			event, err := sub.next(ctx)
+			if err != nil {
+				return
+			}
+
+			if event == nil {
+				// the events can't be read again after the renewal of the subscription.
+				ddb.log().Error("watch events missed", "prefix", prefix)
+				continue
+			}
+
+			event, err = ddb.eventValues(ctx, event, readOpts)
+			if err != nil {
+				if ctx.Err() == nil {
+					ddb.log().Error("watch stopped", "prefix", prefix, "error", err)
+				}
+				return
+			}
+
+			if !out.send(ctx, event) {
+				return
+			}
+		}
+	}()
+
+	return out.ch, nil
+}
+
+// eventValues returns a copy of event with its old and new values,
+// the event being shared by the subscribers of the notifier.
+func (ddb *Store) eventValues(ctx context.Context, event *Event, opts *store.ReadOptions) (*Event, error) {
+	out := &Event{Key: event.Key, Type: event.Type, Old: event.Old, New: event.New}
+
+	if out.Old == nil && event.oldImage != nil {
+		out.Old = ddb.imagePair(ctx, event.Key, event.oldImage)
+	}
+
+	if out.New == nil && event.newImage != nil {
+		out.New = ddb.imagePair(ctx, event.Key, event.newImage)
+	}
+
+	switch {
+	case out.New != nil, event.newImage != nil:
+		return out, nil
+	case out.Type == EventDelete, out.Type == EventExpire:
+		return out, nil
+	}
+
+	pair, err := ddb.Get(ctx, event.Key, opts)
+	switch {
+	case errors.Is(err, store.ErrKeyNotFound):
+		// the key was deleted since the change.
+	case err != nil:
+		return nil, err
+	default:
+		out.New = pair
+	}
+
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchEvents(t *testing.T) {
+	mock := &mockedGetItem{items: map[string]string{"a/2": "dmFsdWUy"}}
+	notifier := &chanNotifier{}
+
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		notifier:  notifier,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	events, err := kv.WatchEvents(ctx, "a/", nil)
+	require.NoError(t, err)
+
+	// the values are read from the images of the record.
+	notifier.publish(&Event{
+		Key:      "a/1",
+		Type:     EventUpdate,
+		oldImage: newTestItem("a/1", "dmFsdWUx"),
+		newImage: newTestItem("a/1", "dmFsdWUy"),
+	})
+
+	event := <-events
+	assert.Equal(t, EventUpdate, event.Type)
+	assert.Equal(t, []byte("value1"), event.Old.Value)
+	assert.Equal(t, []byte("value2"), event.New.Value)
+
+	// the new value is read without the new image.
+	notifier.publish(&Event{Key: "a/2", Type: EventCreate})
+
+	event = <-events
+	assert.Equal(t, "a/2", event.Key)
+	assert.Nil(t, event.Old)
+	assert.Equal(t, []byte("value2"), event.New.Value)
+
+	notifier.publish(&Event{Key: "a/3", Type: EventExpire, oldImage: newTestItem("a/3", "dmFsdWUz")})
+
+	event = <-events
+	assert.Equal(t, EventExpire, event.Type)
+	assert.Equal(t, []byte("value3"), event.Old.Value)
+	assert.Nil(t, event.New)
+}
+
+func TestRecordEventExpire(t *testing.T) {
+	kv := &Store{tableName: TestTableName}
+
+	record := newStreamRecord(dynamodbstreams.OperationTypeRemove, "a/1", "1")
+	record.Dynamodb.OldImage = newTestItem("a/1", "dmFsdWUx")
+
+	event := kv.recordEvent(record)
+	assert.Equal(t, EventDelete, event.Type)
+	assert.Equal(t, record.Dynamodb.OldImage, event.oldImage)
+
+	record.UserIdentity = &dynamodbstreams.Identity{
+		Type:        aws.String("Service"),
+		PrincipalId: aws.String("dynamodb.amazonaws.com"),
+	}
+
+	event = kv.recordEvent(record)
+	assert.Equal(t, EventExpire, event.Type)
+}
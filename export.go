@@ -0,0 +1,119 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ExportFormat is the format of the snapshots written by Export.
+type ExportFormat string
+
+// Export formats.
+const (
+	// ExportJSONL writes a SnapshotRecord per line, as a JSON object.
+	ExportJSONL ExportFormat = "jsonl"
+)
+
+// ErrUnsupportedExportFormat is returned when an export format is unknown.
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+// SnapshotRecord a key of a snapshot.
+type SnapshotRecord struct {
+	Key string `json:"key"`
+	// Value is encoded in base64 in JSON.
+	Value    []byte `json:"value"`
+	Revision uint64 `json:"revision"`
+	// ExpiresAt is the expiration time of the key in Unix seconds, 0 if the key doesn't expire.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// Export writes the keys starting with prefix to w, in format, such as for a backup or an offline analysis.
+// The keys are read page by page, so the snapshot is not a point in time:
+// the keys written during the export may be exported with their previous or their new value.
+// The expired keys are not exported.
+func (ddb *Store) Export(ctx context.Context, prefix string, w io.Writer, format ExportFormat) error {
+	if format != ExportJSONL {
+		return fmt.Errorf("%w: %q", ErrUnsupportedExportFormat, format)
+	}
+
+	enc := json.NewEncoder(w)
+
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		items, lastKey, err := ddb.prefixPage(ctx, prefix, true, nil, startKey)
+		if err != nil {
+			return err
+		}
+
+		if err = ddb.exportItems(ctx, items, prefix, enc); err != nil {
+			return err
+		}
+
+		if len(lastKey) == 0 {
+			return nil
+		}
+		startKey = lastKey
+	}
+}
+
+// exportItems writes the records of the items read under prefix to enc.
+func (ddb *Store) exportItems(ctx context.Context, items []map[string]*dynamodb.AttributeValue, prefix string, enc *json.Encoder) error {
+	pairs, err := ddb.decodeItems(ctx, items, prefix, true)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := make(map[string]int64, len(items))
+	for _, item := range items {
+		key := ddb.itemKey(item)
+		if meta := ddb.itemMeta(key, item); !meta.ExpiresAt.IsZero() {
+			expiresAt[key] = meta.ExpiresAt.Unix()
+		}
+	}
+
+	for _, pair := range pairs {
+		record := &SnapshotRecord{Key: pair.Key, Value: pair.Value, Revision: pair.LastIndex, ExpiresAt: expiresAt[pair.Key]}
+		if err = enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportToS3 starts the native export of the table to the S3 bucket, under prefix,
+// in the DynamoDB JSON format, and returns the ARN of the export.
+// The export holds the items of the table as they are stored, at the current point in time,
+// and doesn't consume the read capacity of the table.
+// The point-in-time recovery must be enabled on the table.
+func (ddb *Store) ExportToS3(ctx context.Context, bucket, prefix string) (string, error) {
+	desc, err := ddb.dynamoSvc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	input := &dynamodb.ExportTableToPointInTimeInput{
+		TableArn:     desc.Table.TableArn,
+		S3Bucket:     aws.String(bucket),
+		ExportFormat: aws.String(dynamodb.ExportFormatDynamodbJson),
+	}
+	if prefix != "" {
+		input.S3Prefix = aws.String(prefix)
+	}
+
+	res, err := ddb.dynamoSvc.ExportTableToPointInTimeWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(res.ExportDescription.ExportArn), nil
+}
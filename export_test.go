@@ -0,0 +1,65 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	mock := &mockedCopyTree{mockedBatchStore: mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"app/a":   newTestItem("app/a", "dmFsdWUx"),
+			"other/b": newTestItem("other/b", "dmFsdWUy"),
+		},
+	}}
+	mock.items["app/a"][ttlAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt, 10))}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	require.NoError(t, kv.Export(ctx, "app/", &buf, ExportJSONL))
+	assert.Equal(t, `{"key":"app/a","value":"dmFsdWUx","revision":1,"expires_at":`+strconv.FormatInt(expiresAt, 10)+"}\n", buf.String())
+
+	err := kv.Export(ctx, "app/", &buf, "csv")
+	assert.ErrorIs(t, err, ErrUnsupportedExportFormat)
+}
+
+func TestExportToS3(t *testing.T) {
+	mock := &mockedExport{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	arn, err := kv.ExportToS3(context.Background(), "backups", "kv/")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:export", arn)
+	assert.Equal(t, "arn:table", aws.StringValue(mock.input.TableArn))
+	assert.Equal(t, "backups", aws.StringValue(mock.input.S3Bucket))
+	assert.Equal(t, "kv/", aws.StringValue(mock.input.S3Prefix))
+}
+
+// mockedExport records the export of the table.
+type mockedExport struct {
+	dynamodbiface.DynamoDBAPI
+	input *dynamodb.ExportTableToPointInTimeInput
+}
+
+func (m *mockedExport) DescribeTableWithContext(_ aws.Context, _ *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{TableArn: aws.String("arn:table")}}, nil
+}
+
+func (m *mockedExport) ExportTableToPointInTimeWithContext(_ aws.Context, input *dynamodb.ExportTableToPointInTimeInput, _ ...request.Option) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	m.input = input
+	return &dynamodb.ExportTableToPointInTimeOutput{ExportDescription: &dynamodb.ExportDescription{ExportArn: aws.String("arn:export")}}, nil
+}
@@ -0,0 +1,324 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const (
+	defaultFailoverThreshold = 5
+	defaultFailbackDelay     = time.Minute
+)
+
+var (
+	// ErrGlobalTableDAX is returned when the global table configuration is combined with a DAX client.
+	ErrGlobalTableDAX = errors.New("global table can't be combined with DAX")
+	// ErrUnknownHomeRegion is returned when the home region of the writes is not a region of the global table.
+	ErrUnknownHomeRegion = errors.New("home region is not a region of the global table")
+)
+
+// GlobalTableConfig routes the calls of the store to the replicas of a global table.
+// The calls go to the local region, Config.Region, and fail over to the next replica
+// once FailoverThreshold consecutive calls failed with a transient error, the local region being tried again after FailbackDelay.
+// The consistent reads only see the writes of their region: the writes replicated from the other regions
+// are eventually consistent, as are the conditional writes racing with the writes of the other regions.
+// The streams, S3, and KMS clients are not failed over, Watch reading the stream of the local region.
+type GlobalTableConfig struct {
+	// Replicas are the regions of the other replicas of the table, in their failover order.
+	Replicas []string
+
+	// ReplicaClients are already configured clients of the replicas, by region,
+	// used instead of the clients created by the store.
+	ReplicaClients map[string]dynamodbiface.DynamoDBAPI
+
+	// HomeRegion pins the writes, and the table management calls, to a region, Config.Region or one of the Replicas:
+	// they are not failed over. The writes follow the reads if empty.
+	HomeRegion string
+
+	// FailoverThreshold is the number of consecutive calls failed with a transient error
+	// failing over to the next replica, defaults to 5.
+	FailoverThreshold int
+
+	// FailbackDelay is the time after a failover the local region is tried again, defaults to 1m.
+	FailbackDelay time.Duration
+}
+
+// checkGlobalTable checks the global table configuration, if any.
+func (c *Config) checkGlobalTable() error {
+	if c.GlobalTable == nil {
+		return nil
+	}
+
+	if c.DAXClient != nil {
+		return ErrGlobalTableDAX
+	}
+
+	home := c.GlobalTable.HomeRegion
+	if home == "" || home == c.Region {
+		return nil
+	}
+
+	for _, region := range c.GlobalTable.Replicas {
+		if region == home {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnknownHomeRegion, home)
+}
+
+// initReplicas routes the DynamoDB calls of the store to the replicas of the global table,
+// creating a session for the replicas without client.
+func (ddb *Store) initReplicas(options *Config) error {
+	config := options.GlobalTable
+
+	replicas := &replicatedDynamoDB{
+		DynamoDBAPI:   ddb.dynamoSvc,
+		ddb:           ddb,
+		regions:       append([]string{options.Region}, config.Replicas...),
+		clients:       []dynamodbiface.DynamoDBAPI{ddb.dynamoSvc},
+		home:          -1,
+		threshold:     config.FailoverThreshold,
+		failbackDelay: config.FailbackDelay,
+	}
+	if replicas.threshold <= 0 {
+		replicas.threshold = defaultFailoverThreshold
+	}
+	if replicas.failbackDelay <= 0 {
+		replicas.failbackDelay = defaultFailbackDelay
+	}
+
+	for _, region := range config.Replicas {
+		client := config.ReplicaClients[region]
+		if client == nil {
+			replicaOptions := *options
+			replicaOptions.Region = region
+
+			// the endpoint, if any, is the one of the local region.
+			sess, err := newSession(nil, &replicaOptions)
+			if err != nil {
+				return err
+			}
+			client = dynamodb.New(sess)
+		}
+
+		replicas.clients = append(replicas.clients, client)
+	}
+
+	for i, region := range replicas.regions {
+		if config.HomeRegion != "" && region == config.HomeRegion {
+			replicas.home = i
+			break
+		}
+	}
+
+	ddb.replicas = replicas
+	ddb.dynamoSvc = replicas
+
+	return nil
+}
+
+// ActiveRegion returns the region serving the reads of the global table, Config.Region if not failed over.
+// It returns an empty string without global table configuration.
+func (ddb *Store) ActiveRegion() string {
+	if ddb.replicas == nil {
+		return ""
+	}
+
+	ddb.replicas.mu.Lock()
+	defer ddb.replicas.mu.Unlock()
+
+	return ddb.replicas.regions[ddb.replicas.active]
+}
+
+// replicatedDynamoDB routes the calls to the client of the active replica, or of the home region for the writes.
+// The calls not used by the store go to the local region.
+type replicatedDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	ddb *Store
+	// regions and clients the regions and the clients of the replicas, the local region first.
+	regions []string
+	clients []dynamodbiface.DynamoDBAPI
+	// home the index of the home region of the writes, -1 if they follow the reads.
+	home          int
+	threshold     int
+	failbackDelay time.Duration
+
+	mu sync.Mutex
+	// active the index of the replica serving the calls.
+	active int
+	// failures the number of consecutive calls of the active replica failed with a transient error.
+	failures     int
+	failedOverAt time.Time
+}
+
+// pick returns the index of the replica of a call, failing back to the local region after the failback delay.
+func (r *replicatedDynamoDB) pick(write bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if write && r.home >= 0 {
+		return r.home
+	}
+
+	if r.active != 0 && time.Since(r.failedOverAt) >= r.failbackDelay {
+		r.active, r.failures = 0, 0
+		r.ddb.log().Info("failing back to the local region", "region", r.regions[0])
+	}
+
+	return r.active
+}
+
+// report counts the result of a call of the replica i, failing over to the next replica after threshold transient errors.
+func (r *replicatedDynamoDB) report(i int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i != r.active {
+		return
+	}
+
+	// the region answered, even with an error.
+	if ClassifyError(err) != ErrorClassTransient {
+		r.failures = 0
+		return
+	}
+
+	r.failures++
+	if r.failures < r.threshold {
+		return
+	}
+
+	r.active = (r.active + 1) % len(r.clients)
+	r.failures = 0
+	r.failedOverAt = time.Now()
+	r.ddb.log().Error("failing over to a replica", "region", r.regions[r.active], "error", err)
+}
+
+// routeCall runs invoke with the client of the replica of the call.
+func routeCall[O any](r *replicatedDynamoDB, write bool, invoke func(client dynamodbiface.DynamoDBAPI) (O, error)) (O, error) {
+	i := r.pick(write)
+
+	out, err := invoke(r.clients[i])
+	r.report(i, err)
+
+	return out, err
+}
+
+func (r *replicatedDynamoDB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.GetItemOutput, error) {
+		return client.GetItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.PutItemOutput, error) {
+		return client.PutItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.UpdateItemOutput, error) {
+		return client.UpdateItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.DeleteItemOutput, error) {
+		return client.DeleteItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.QueryOutput, error) {
+		return client.QueryWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	_, err := routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (struct{}, error) {
+		return struct{}{}, client.QueryPagesWithContext(ctx, input, fn, opts...)
+	})
+	return err
+}
+
+func (r *replicatedDynamoDB) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.ScanOutput, error) {
+		return client.ScanWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	_, err := routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (struct{}, error) {
+		return struct{}{}, client.ScanPagesWithContext(ctx, input, fn, opts...)
+	})
+	return err
+}
+
+func (r *replicatedDynamoDB) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.BatchGetItemOutput, error) {
+		return client.BatchGetItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.BatchWriteItemOutput, error) {
+		return client.BatchWriteItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) TransactGetItemsWithContext(ctx aws.Context, input *dynamodb.TransactGetItemsInput, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.TransactGetItemsOutput, error) {
+		return client.TransactGetItemsWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) TransactWriteItemsWithContext(ctx aws.Context, input *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.TransactWriteItemsOutput, error) {
+		return client.TransactWriteItemsWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.CreateTableOutput, error) {
+		return client.CreateTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.DescribeTableOutput, error) {
+		return client.DescribeTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) UpdateTableWithContext(ctx aws.Context, input *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.UpdateTableOutput, error) {
+		return client.UpdateTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) DeleteTableWithContext(ctx aws.Context, input *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.DeleteTableOutput, error) {
+		return client.DeleteTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) DescribeTimeToLiveWithContext(ctx aws.Context, input *dynamodb.DescribeTimeToLiveInput, opts ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return routeCall(r, false, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return client.DescribeTimeToLiveWithContext(ctx, input, opts...)
+	})
+}
+
+func (r *replicatedDynamoDB) UpdateTimeToLiveWithContext(ctx aws.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return routeCall(r, true, func(client dynamodbiface.DynamoDBAPI) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		return client.UpdateTimeToLiveWithContext(ctx, input, opts...)
+	})
+}
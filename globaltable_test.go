@@ -0,0 +1,95 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobalTable(t *testing.T) {
+	local := &mockedRegion{err: awserr.New(dynamodb.ErrCodeInternalServerError, "unavailable", nil)}
+	replica := &mockedRegion{}
+
+	options := &Config{
+		Region: "eu-west-1",
+		GlobalTable: &GlobalTableConfig{
+			Replicas:          []string{"us-east-1"},
+			ReplicaClients:    map[string]dynamodbiface.DynamoDBAPI{"us-east-1": replica},
+			HomeRegion:        "eu-west-1",
+			FailoverThreshold: 2,
+			FailbackDelay:     50 * time.Millisecond,
+		},
+	}
+	require.NoError(t, options.checkGlobalTable())
+
+	kv := &Store{dynamoSvc: local, tableName: TestTableName}
+	require.NoError(t, kv.initReplicas(options))
+	assert.Equal(t, "eu-west-1", kv.ActiveRegion())
+
+	ctx := context.Background()
+
+	// the reads fail over after 2 transient errors.
+	for i := 0; i < 2; i++ {
+		_, err := kv.Get(ctx, "key", nil)
+		require.Error(t, err)
+	}
+	assert.Equal(t, "us-east-1", kv.ActiveRegion())
+
+	_, err := kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.Equal(t, 1, replica.gets)
+
+	// the writes stay in the home region.
+	err = kv.Put(ctx, "key", []byte("value"), nil)
+	require.Error(t, err)
+	assert.Equal(t, 1, local.puts)
+	assert.Equal(t, 0, replica.puts)
+
+	// the reads fail back to the local region after the failback delay.
+	local.err = nil
+	time.Sleep(60 * time.Millisecond)
+
+	_, err = kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.Equal(t, "eu-west-1", kv.ActiveRegion())
+	assert.Equal(t, 1, replica.gets)
+
+	options.GlobalTable.HomeRegion = "ap-south-1"
+	assert.ErrorIs(t, options.checkGlobalTable(), ErrUnknownHomeRegion)
+}
+
+// mockedRegion is the client of a region failing its calls with err, if not nil, and counting them.
+type mockedRegion struct {
+	dynamodbiface.DynamoDBAPI
+
+	err  error
+	gets int
+	puts int
+}
+
+func (m *mockedRegion) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.gets++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m *mockedRegion) UpdateItemWithContext(_ aws.Context, _ *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.puts++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &dynamodb.UpdateItemOutput{}, nil
+}
@@ -4,6 +4,7 @@ go 1.18
 
 require (
 	github.com/aws/aws-sdk-go v1.44.91
+	github.com/klauspost/compress v1.15.9
 	github.com/kvtools/valkeyrie v1.0.0
 	github.com/stretchr/testify v1.8.0
 )
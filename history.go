@@ -0,0 +1,457 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	// historyAttributesSize the room left in the history items for their attributes other than the key and the value,
+	// the attributes of the items, and the revision sort key and the markers of the versions.
+	historyAttributesSize = itemAttributesSize + 64
+
+	// historyDeletedAttribute marks the tombstone revisions, recording the deletions of the keys.
+	historyDeletedAttribute = "deleted"
+	// historyOmittedAttribute marks the revisions recorded without their value, too large for the history.
+	historyOmittedAttribute = "value_omitted"
+
+	// maxHistoryWriteAttempts bounds the attempts of a write recorded in the history, retried on concurrent writes.
+	maxHistoryWriteAttempts = 5
+)
+
+var (
+	// ErrHistoryDisabled is returned when reading the history of a key without history configuration.
+	ErrHistoryDisabled = errors.New("history is disabled")
+	// ErrHistoryTableMissing is returned when the history is configured without its table.
+	ErrHistoryTableMissing = errors.New("missing history table")
+	// ErrHistoryValueOmitted is returned by GetAt for a revision recorded without its value.
+	ErrHistoryValueOmitted = errors.New("value not recorded in the history")
+)
+
+// HistoryConfig configures the history of the keys.
+// Each revision of a key is recorded in an item of the history table, written in the same transaction as the key:
+// the writes read the current revision of the key first, and are conditioned on it,
+// being retried when the key is modified concurrently.
+// The deletions are recorded as tombstone revisions, and the revisions of a key go on after its deletion.
+// The writes of the locks, and the deletions of the expired keys, are not recorded.
+// The values too large for a history item once encoded, base64 unless BinaryValues is set,
+// such as the values stored in chunks or in S3, are recorded without their value.
+// Each write of a transaction takes up to 3 of its 100 items: the key, its history item,
+// and the deletion of the revision no longer kept.
+type HistoryConfig struct {
+	// Table is the history table, it may be a template, like Config.Bucket.
+	// It must exist, with the key attribute of the store as string partition key ("id" by default),
+	// and its revision attribute as number sort key ("version" by default).
+	Table string
+
+	// MaxVersions is the number of revisions kept by key, the older ones being deleted by the writes, 0 for no limit.
+	// Only the revision preceding the kept ones is deleted by a write: CompactHistory deletes all the older ones.
+	MaxVersions int
+
+	// Retention is the time the revisions are kept, with the expiration time attribute of the history items,
+	// 0 for no limit. The TTL must be enabled on the history table for DynamoDB to delete the expired revisions.
+	Retention time.Duration
+}
+
+// KeyVersion a revision of a key in its history.
+type KeyVersion struct {
+	Revision uint64
+	// UpdatedAt is the time the revision was written.
+	UpdatedAt time.Time
+	// Deleted reports a tombstone revision, the key being deleted.
+	Deleted bool
+}
+
+// historyConfig returns the history configuration, with its table name expanded.
+func (c *Config) historyConfig() (*HistoryConfig, error) {
+	if c.History == nil {
+		return nil, nil
+	}
+
+	history := *c.History
+	if history.Table == "" {
+		return nil, ErrHistoryTableMissing
+	}
+
+	table, err := expandTableName(history.Table, c.TableNameVars)
+	if err != nil {
+		return nil, err
+	}
+	history.Table = table
+
+	return &history, nil
+}
+
+// putRecorded writes the value of key and records its revision in the history, in a single transaction,
+// and returns the written pair.
+// The value is encoded in data and enc, and its attributes are attrs, nil for an empty value.
+// The current item is read first, and passed to check if not nil.
+// The transaction is conditioned on the revision read, and retried if the key is modified concurrently.
+func (ddb *Store) putRecorded(ctx context.Context, key string, value, data []byte, enc valueEncoding,
+	attrs map[string]*dynamodb.AttributeValue, opts *store.WriteOptions, check func(current map[string]*dynamodb.AttributeValue) error,
+) (*store.KVPair, error) {
+	for i := 0; i < maxHistoryWriteAttempts; i++ {
+		res, err := ddb.getKey(ctx, key, &store.ReadOptions{Consistent: true})
+		if err != nil {
+			ddb.discardS3Object(ctx, attrs)
+			return nil, err
+		}
+
+		current := res.Item
+		if check != nil {
+			if err = check(current); err != nil {
+				ddb.discardS3Object(ctx, attrs)
+				return nil, err
+			}
+		}
+
+		revision, seed, err := ddb.nextRevision(ctx, key, current)
+		if err != nil {
+			ddb.discardS3Object(ctx, attrs)
+			return nil, err
+		}
+
+		updateExp, exAttr, exNames := ddb.seededWriteUpdate(ddb.indexAttributes(key, attrs), opts, seed)
+		condExp := ddb.revisionCondition(current, exAttr, exNames)
+
+		items := append([]*dynamodb.TransactWriteItem{{Update: &dynamodb.Update{
+			TableName:                 aws.String(ddb.tableName),
+			Key:                       ddb.keyAttributes(key),
+			UpdateExpression:          aws.String(updateExp),
+			ConditionExpression:       aws.String(condExp),
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		}}}, ddb.versionItems(key, revision, ddb.historyValue(key, data, enc))...)
+
+		_, err = ddb.dynamoSvc.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if isTransactionConditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			ddb.discardS3Object(ctx, attrs)
+			return nil, err
+		}
+
+		if attrs != nil && ddb.hasExternalStorage() {
+			if err = ddb.deleteExternal(ctx, key, current); err != nil {
+				return nil, err
+			}
+		}
+
+		return &store.KVPair{Key: key, Value: value, LastIndex: revision}, nil
+	}
+
+	ddb.discardS3Object(ctx, attrs)
+
+	return nil, store.ErrKeyModified
+}
+
+// deleteRecorded deletes key and records a tombstone revision in its history, in a single transaction.
+// The current item is read first, and passed to check if not nil.
+// The keys which don't exist are not recorded, nor are the expired keys, deleted without tombstone.
+// It returns store.ErrKeyNotFound for these keys if Config.DeleteNotFound is set.
+func (ddb *Store) deleteRecorded(ctx context.Context, key string, check func(current map[string]*dynamodb.AttributeValue) error) error {
+	for i := 0; i < maxHistoryWriteAttempts; i++ {
+		res, err := ddb.getKey(ctx, key, &store.ReadOptions{Consistent: true})
+		if err != nil {
+			return err
+		}
+
+		current := res.Item
+		if check != nil {
+			if err = check(current); err != nil {
+				return err
+			}
+		}
+
+		if current == nil {
+			if ddb.deleteNotFound {
+				return store.ErrKeyNotFound
+			}
+			return nil
+		}
+
+		exAttr := make(map[string]*dynamodb.AttributeValue)
+		exNames := make(map[string]*string)
+		condExp := ddb.revisionCondition(current, exAttr, exNames)
+
+		items := []*dynamodb.TransactWriteItem{{Delete: &dynamodb.Delete{
+			TableName:                 aws.String(ddb.tableName),
+			Key:                       ddb.keyAttributes(key),
+			ConditionExpression:       aws.String(condExp),
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		}}}
+
+		expired := ddb.isItemExpired(current)
+		if !expired {
+			revision, _, err := ddb.nextRevision(ctx, key, current)
+			if err != nil {
+				return err
+			}
+			items = append(items, ddb.versionItems(key, revision, nil)...)
+		}
+
+		_, err = ddb.dynamoSvc.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if isTransactionConditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = ddb.deleteExternal(ctx, key, current); err != nil {
+			return err
+		}
+
+		if expired && ddb.deleteNotFound {
+			return store.ErrKeyNotFound
+		}
+
+		return nil
+	}
+
+	return store.ErrKeyModified
+}
+
+// nextRevision returns the revision written after current, the item of key read before the write,
+// and the revision seed of a new item, so its revisions go on after the ones in the history.
+func (ddb *Store) nextRevision(ctx context.Context, key string, current map[string]*dynamodb.AttributeValue) (uint64, uint64, error) {
+	if v, ok := current[ddb.revisionName()]; ok {
+		revision, err := strconv.ParseUint(aws.StringValue(v.N), 10, 64)
+		return revision + 1, 0, err
+	}
+
+	seed, err := ddb.latestVersion(ctx, key)
+
+	return seed + 1, seed, err
+}
+
+// revisionCondition returns the condition of a write on the revision of current, the item read before the write,
+// adding its values to exAttr and its names to exNames.
+func (ddb *Store) revisionCondition(current map[string]*dynamodb.AttributeValue, exAttr map[string]*dynamodb.AttributeValue, exNames map[string]*string) string {
+	if v, ok := current[ddb.revisionName()]; ok {
+		exAttr[":currentRevision"] = v
+		exNames[revisionNamePlaceholder] = aws.String(ddb.revisionName())
+
+		return fmt.Sprintf("%s = :currentRevision", revisionNamePlaceholder)
+	}
+
+	exNames[keyNamePlaceholder] = aws.String(ddb.keyName())
+
+	return fmt.Sprintf("attribute_not_exists(%s)", keyNamePlaceholder)
+}
+
+// latestVersion returns the latest revision of key in its history, 0 if there is none.
+func (ddb *Store) latestVersion(ctx context.Context, key string) (uint64, error) {
+	res, err := ddb.dynamoSvc.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(ddb.history.Table),
+		KeyConditionExpression: aws.String(fmt.Sprintf("%s = :key", keyNamePlaceholder)),
+		ExpressionAttributeNames: map[string]*string{
+			keyNamePlaceholder:      aws.String(ddb.keyName()),
+			revisionNamePlaceholder: aws.String(ddb.revisionName()),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key": {S: aws.String(ddb.storedKey(key))},
+		},
+		ProjectionExpression: aws.String(revisionNamePlaceholder),
+		ScanIndexForward:     aws.Bool(false),
+		ConsistentRead:       aws.Bool(true),
+		Limit:                aws.Int64(1),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(res.Items) == 0 {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(aws.StringValue(res.Items[0][ddb.revisionName()].N), 10, 64)
+}
+
+// historyValue returns the attributes of the value of key encoded in data and enc, recorded in the history,
+// or the marker of a value too large to be recorded, with the key and the version attributes, in a history item.
+func (ddb *Store) historyValue(key string, data []byte, enc valueEncoding) map[string]*dynamodb.AttributeValue {
+	if ddb.storedDataSize(data)+len(ddb.storedKey(key))+historyAttributesSize > maxItemSize {
+		return map[string]*dynamodb.AttributeValue{historyOmittedAttribute: {BOOL: aws.Bool(true)}}
+	}
+
+	value := make(map[string]*dynamodb.AttributeValue)
+	for name, v := range ddb.valueAttributes(data, enc) {
+		if v != nil {
+			value[name] = v
+		}
+	}
+
+	return value
+}
+
+// versionItems returns the transaction items recording revision of key in the history, with the value attributes,
+// or a tombstone if value is nil, and deleting the revision no longer kept, if any.
+func (ddb *Store) versionItems(key string, revision uint64, value map[string]*dynamodb.AttributeValue) []*dynamodb.TransactWriteItem {
+	item := ddb.versionKey(key, revision)
+	for name, v := range value {
+		item[name] = v
+	}
+
+	if value == nil {
+		item[historyDeletedAttribute] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+	}
+
+	item[updatedAtAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().UnixMilli(), 10))}
+
+	if ddb.history.Retention > 0 {
+		expiresAt := time.Now().Add(ddb.history.Retention).Unix()
+		item[ddb.ttlName()] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt, 10))}
+	}
+
+	items := []*dynamodb.TransactWriteItem{{Put: &dynamodb.Put{
+		TableName: aws.String(ddb.history.Table),
+		Item:      item,
+	}}}
+
+	maxVersions := uint64(ddb.history.MaxVersions)
+	if maxVersions > 0 && revision > maxVersions {
+		items = append(items, &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+			TableName: aws.String(ddb.history.Table),
+			Key:       ddb.versionKey(key, revision-maxVersions),
+		}})
+	}
+
+	return items
+}
+
+// versionKey returns the primary key of the history item of key at revision.
+func (ddb *Store) versionKey(key string, revision uint64) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		ddb.keyName():      {S: aws.String(ddb.storedKey(key))},
+		ddb.revisionName(): {N: aws.String(strconv.FormatUint(revision, 10))},
+	}
+}
+
+// ListVersions returns the revisions of key recorded in its history, the latest first.
+// The expired revisions are skipped.
+func (ddb *Store) ListVersions(ctx context.Context, key string) ([]*KeyVersion, error) {
+	if ddb.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.List)
+	defer cancel()
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(ddb.history.Table),
+		KeyConditionExpression: aws.String(fmt.Sprintf("%s = :key", keyNamePlaceholder)),
+		ExpressionAttributeNames: map[string]*string{
+			keyNamePlaceholder:      aws.String(ddb.keyName()),
+			revisionNamePlaceholder: aws.String(ddb.revisionName()),
+			ttlNamePlaceholder:      aws.String(ddb.ttlName()),
+			"#updatedAt":            aws.String(updatedAtAttribute),
+			"#deleted":              aws.String(historyDeletedAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key": {S: aws.String(ddb.storedKey(key))},
+		},
+		ProjectionExpression: aws.String(fmt.Sprintf("%s, %s, #updatedAt, #deleted", revisionNamePlaceholder, ttlNamePlaceholder)),
+		ScanIndexForward:     aws.Bool(false),
+		ConsistentRead:       aws.Bool(true),
+	}
+
+	var versions []*KeyVersion
+
+	err := ddb.dynamoSvc.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, _ bool) bool {
+		for _, item := range page.Items {
+			if ddb.isItemExpired(item) {
+				continue
+			}
+
+			meta := ddb.itemMeta(key, item)
+			versions = append(versions, &KeyVersion{
+				Revision:  meta.LastIndex,
+				UpdatedAt: meta.UpdatedAt,
+				Deleted:   item[historyDeletedAttribute] != nil,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetAt returns the value of key at revision, from its history.
+// It returns store.ErrKeyNotFound if the revision is not in the history, is expired, or is a tombstone,
+// and ErrHistoryValueOmitted if the value was too large to be recorded.
+func (ddb *Store) GetAt(ctx context.Context, key string, revision uint64) (*store.KVPair, error) {
+	if ddb.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	res, err := ddb.dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(ddb.history.Table),
+		Key:            ddb.versionKey(key, revision),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Item) == 0 || ddb.isItemExpired(res.Item) || res.Item[historyDeletedAttribute] != nil {
+		return nil, store.ErrKeyNotFound
+	}
+
+	if res.Item[historyOmittedAttribute] != nil {
+		return nil, fmt.Errorf("%w: %s at revision %d", ErrHistoryValueOmitted, key, revision)
+	}
+
+	item, err := ddb.decryptItem(ctx, res.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.decodeItem(item)
+}
+
+// CompactHistory deletes the revisions of key older than the MaxVersions latest ones from its history,
+// and returns the number of revisions deleted.
+func (ddb *Store) CompactHistory(ctx context.Context, key string) (int, error) {
+	versions, err := ddb.ListVersions(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if ddb.history.MaxVersions <= 0 || len(versions) <= ddb.history.MaxVersions {
+		return 0, nil
+	}
+
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	deleted := 0
+
+	for _, version := range versions[ddb.history.MaxVersions:] {
+		_, err = ddb.dynamoSvc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(ddb.history.Table),
+			Key:       ddb.versionKey(key, version.Revision),
+		})
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
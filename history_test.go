@@ -0,0 +1,304 @@
+package dynamodb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	mock := newHistoryMock()
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	_, err := kv.ListVersions(ctx, "config")
+	assert.ErrorIs(t, err, ErrHistoryDisabled)
+
+	kv.history = &HistoryConfig{Table: "history", MaxVersions: 3, Retention: time.Hour}
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, kv.Put(ctx, "config", []byte("v"+strconv.Itoa(i)), nil))
+	}
+
+	// the revisions older than the 3 latest ones are deleted.
+	versions, err := kv.ListVersions(ctx, "config")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, uint64(5), versions[0].Revision)
+	assert.Equal(t, uint64(3), versions[2].Revision)
+	assert.WithinDuration(t, time.Now(), versions[0].UpdatedAt, time.Minute)
+
+	pair, err := kv.GetAt(ctx, "config", 4)
+	require.NoError(t, err)
+	assert.Equal(t, &store.KVPair{Key: "config", Value: []byte("v4"), LastIndex: 4}, pair)
+
+	_, err = kv.GetAt(ctx, "config", 1)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	// the writes conflicting with concurrent writes are retried.
+	mock.conflicts = 2
+
+	pair, err = kv.PutWithResult(ctx, "config", []byte("v6"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), pair.LastIndex)
+
+	_, _, err = kv.AtomicPut(ctx, "config", []byte("v7"), &store.KVPair{Key: "config", LastIndex: 5}, nil)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	kv.history.MaxVersions = 1
+
+	deleted, err := kv.CompactHistory(ctx, "config")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	versions, err = kv.ListVersions(ctx, "config")
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, uint64(6), versions[0].Revision)
+}
+
+func TestHistoryDelete(t *testing.T) {
+	mock := newHistoryMock()
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName, history: &HistoryConfig{Table: "history"}}
+
+	ctx := context.Background()
+
+	require.NoError(t, kv.Put(ctx, "config", []byte("v1"), nil))
+	require.NoError(t, kv.Delete(ctx, "config"))
+
+	// the deletion is a tombstone revision.
+	versions, err := kv.ListVersions(ctx, "config")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, uint64(2), versions[0].Revision)
+	assert.True(t, versions[0].Deleted)
+	assert.False(t, versions[1].Deleted)
+
+	_, err = kv.GetAt(ctx, "config", 2)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	// the revisions go on after the deletion.
+	_, pair, err := kv.AtomicPut(ctx, "config", []byte("v3"), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), pair.LastIndex)
+
+	ok, err := kv.AtomicDelete(ctx, "config", &store.KVPair{Key: "config", LastIndex: 2})
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	ok, err = kv.AtomicDelete(ctx, "config", pair)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	versions, err = kv.ListVersions(ctx, "config")
+	require.NoError(t, err)
+	require.Len(t, versions, 4)
+	assert.True(t, versions[0].Deleted)
+
+	// the keys which don't exist are not recorded.
+	require.NoError(t, kv.Delete(ctx, "missing"))
+	assert.Len(t, mock.versions, 4)
+}
+
+func TestHistoryTransaction(t *testing.T) {
+	mock := newHistoryMock()
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName, history: &HistoryConfig{Table: "history"}}
+
+	ctx := context.Background()
+
+	require.NoError(t, kv.Put(ctx, "a", []byte("a1"), nil))
+
+	err := kv.Transact(ctx).Put("b", []byte("b1"), nil).Delete("a").Commit()
+	require.NoError(t, err)
+
+	pair, err := kv.GetAt(ctx, "b", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b1"), pair.Value)
+
+	versions, err := kv.ListVersions(ctx, "a")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.True(t, versions[0].Deleted)
+
+	// the conditions are checked on the items read.
+	err = kv.Transact(ctx).CheckRevision("b", 2).Put("c", []byte("c1"), nil).Commit()
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	err = kv.Transact(ctx).CheckNotExists("b").Commit()
+	assert.ErrorIs(t, err, store.ErrKeyExists)
+
+	// a concurrent write is retried.
+	mock.conflicts = 1
+
+	err = kv.Transact(ctx).CheckRevision("b", 1).Put("c", []byte("c1"), nil).Commit()
+	require.NoError(t, err)
+
+	versions, err = kv.ListVersions(ctx, "c")
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+}
+
+func TestHistoryValueSize(t *testing.T) {
+	kv := &Store{tableName: TestTableName, history: &HistoryConfig{Table: "history"}}
+
+	data := make([]byte, 300*1024)
+
+	// base64 encoded, the value doesn't fit in a history item.
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{historyOmittedAttribute: {BOOL: aws.Bool(true)}},
+		kv.historyValue("key", data, valueEncoding{}))
+
+	kv.binaryValues = true
+
+	value := kv.historyValue("key", data, valueEncoding{})
+	assert.NotContains(t, value, historyOmittedAttribute)
+	assert.Equal(t, data, value[kv.valueName()].B)
+}
+
+// mockedHistory holds the items of the store and of the history table,
+// and applies the transactions conditioned on the revisions of the keys.
+type mockedHistory struct {
+	dynamodbiface.DynamoDBAPI
+
+	items map[string]map[string]*dynamodb.AttributeValue
+	// versions the history items, by key and revision.
+	versions map[string]map[string]*dynamodb.AttributeValue
+	// conflicts the number of transactions failing as if the keys were written concurrently.
+	conflicts int
+}
+
+func newHistoryMock() *mockedHistory {
+	return &mockedHistory{
+		items:    make(map[string]map[string]*dynamodb.AttributeValue),
+		versions: make(map[string]map[string]*dynamodb.AttributeValue),
+	}
+}
+
+func versionID(key map[string]*dynamodb.AttributeValue) string {
+	return aws.StringValue(key[partitionKey].S) + "@" + aws.StringValue(key[revisionAttribute].N)
+}
+
+func (m *mockedHistory) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if aws.StringValue(input.TableName) == "history" {
+		return &dynamodb.GetItemOutput{Item: m.versions[versionID(input.Key)]}, nil
+	}
+
+	return &dynamodb.GetItemOutput{Item: m.items[itemKey(input.Key)]}, nil
+}
+
+func (m *mockedHistory) TransactGetItemsWithContext(_ aws.Context, input *dynamodb.TransactGetItemsInput, _ ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	out := &dynamodb.TransactGetItemsOutput{}
+	for _, get := range input.TransactItems {
+		out.Responses = append(out.Responses, &dynamodb.ItemResponse{Item: m.items[itemKey(get.Get.Key)]})
+	}
+
+	return out, nil
+}
+
+func (m *mockedHistory) TransactWriteItemsWithContext(_ aws.Context, input *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	canceled := &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}},
+	}
+
+	if m.conflicts > 0 {
+		m.conflicts--
+		return nil, canceled
+	}
+
+	for _, item := range input.TransactItems {
+		switch {
+		case item.Update != nil:
+			if !m.revisionMatches(item.Update.Key, item.Update.ExpressionAttributeValues) {
+				return nil, canceled
+			}
+		case item.Delete != nil && aws.StringValue(item.Delete.TableName) != "history":
+			if !m.revisionMatches(item.Delete.Key, item.Delete.ExpressionAttributeValues) {
+				return nil, canceled
+			}
+		case item.ConditionCheck != nil:
+			if !m.revisionMatches(item.ConditionCheck.Key, item.ConditionCheck.ExpressionAttributeValues) {
+				return nil, canceled
+			}
+		}
+	}
+
+	for _, item := range input.TransactItems {
+		switch {
+		case item.Update != nil:
+			key := itemKey(item.Update.Key)
+			m.items[key] = applyTestUpdate(m.items[key], &dynamodb.UpdateItemInput{
+				Key:                       item.Update.Key,
+				ExpressionAttributeNames:  item.Update.ExpressionAttributeNames,
+				ExpressionAttributeValues: item.Update.ExpressionAttributeValues,
+			})
+		case item.Put != nil:
+			m.versions[versionID(item.Put.Item)] = item.Put.Item
+		case item.Delete != nil && aws.StringValue(item.Delete.TableName) == "history":
+			delete(m.versions, versionID(item.Delete.Key))
+		case item.Delete != nil:
+			delete(m.items, itemKey(item.Delete.Key))
+		}
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// revisionMatches reports whether the item of key is at the revision of the condition, or doesn't exist without it.
+func (m *mockedHistory) revisionMatches(key, exAttr map[string]*dynamodb.AttributeValue) bool {
+	current, exists := m.items[itemKey(key)]
+
+	revision, ok := exAttr[":currentRevision"]
+	if !ok {
+		return !exists
+	}
+
+	return exists && aws.StringValue(current[revisionAttribute].N) == aws.StringValue(revision.N)
+}
+
+func (m *mockedHistory) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	delete(m.versions, versionID(input.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (m *mockedHistory) QueryWithContext(_ aws.Context, input *dynamodb.QueryInput, _ ...request.Option) (*dynamodb.QueryOutput, error) {
+	items := m.keyVersions(aws.StringValue(input.ExpressionAttributeValues[":key"].S))
+	if input.Limit != nil && int64(len(items)) > *input.Limit {
+		items = items[:*input.Limit]
+	}
+
+	return &dynamodb.QueryOutput{Items: items}, nil
+}
+
+func (m *mockedHistory) QueryPagesWithContext(_ aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, _ ...request.Option) error {
+	fn(&dynamodb.QueryOutput{Items: m.keyVersions(aws.StringValue(input.ExpressionAttributeValues[":key"].S))}, true)
+
+	return nil
+}
+
+// keyVersions returns the history items of key, the latest revision first.
+func (m *mockedHistory) keyVersions(key string) []map[string]*dynamodb.AttributeValue {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, item := range m.versions {
+		if aws.StringValue(item[partitionKey].S) == key {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		ri, _ := strconv.Atoi(aws.StringValue(items[i][revisionAttribute].N))
+		rj, _ := strconv.Atoi(aws.StringValue(items[j][revisionAttribute].N))
+		return ri > rj
+	})
+
+	return items
+}
@@ -0,0 +1,58 @@
+package dynamodb
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	client, err := (&Config{CABundleFile: caFile}).httpClient()
+	require.NoError(t, err)
+
+	res, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+	_, err = (&Config{CABundleFile: caFile}).httpClient()
+	assert.ErrorIs(t, err, ErrInvalidCABundle)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	client, err := (&Config{ProxyURL: "http://proxy.example.com:3128"}).httpClient()
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "https://dynamodb.eu-west-3.amazonaws.com", http.NoBody)
+	require.NoError(t, err)
+
+	proxy, err := client.Transport.(*http.Transport).Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "proxy.example.com:3128", proxy.Host)
+
+	// the default client is kept without settings.
+	client, err = (&Config{}).httpClient()
+	require.NoError(t, err)
+	assert.Nil(t, client)
+
+	custom := &http.Client{}
+	client, err = (&Config{HTTPClient: custom, ProxyURL: "http://proxy.example.com:3128"}).httpClient()
+	require.NoError(t, err)
+	assert.Same(t, custom, client)
+}
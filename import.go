@@ -0,0 +1,196 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// ImportConflictPolicy is the policy of Import for the keys of a snapshot which already exist.
+type ImportConflictPolicy string
+
+// Import conflict policies.
+const (
+	// ImportOverwrite replaces the values of the existing keys, the default.
+	ImportOverwrite ImportConflictPolicy = "overwrite"
+	// ImportSkip keeps the values of the existing keys.
+	ImportSkip ImportConflictPolicy = "skip"
+	// ImportFail stops the import at the first existing key, with ErrImportConflict.
+	ImportFail ImportConflictPolicy = "fail"
+)
+
+var (
+	// ErrImportConflict is returned by Import when a key of the snapshot exists, with the ImportFail policy.
+	ErrImportConflict = errors.New("imported key already exists")
+	// ErrUnsupportedImportConflict is returned when an import conflict policy is unknown.
+	ErrUnsupportedImportConflict = errors.New("unsupported import conflict policy")
+)
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// Conflict is the policy for the keys which already exist, ImportOverwrite by default.
+	Conflict ImportConflictPolicy
+
+	// WritesPerSecond limits the rate of the keys written, 0 for no limit.
+	WritesPerSecond float64
+}
+
+// Import writes the keys of a snapshot written by Export in the JSONL format, in batches, and returns the number of keys written.
+// The keys of a batch are written like PutMany, running through the middlewares as OperationImport operations.
+// The keys are written at their next revision, not at the revision of the snapshot,
+// with the remaining time to live of the snapshot, the expired keys being skipped.
+// The existing keys are read before each batch: the keys created concurrently may be overwritten, whatever the conflict policy.
+// If some keys are not written, the others are, and a *BatchWriteError is returned.
+func (ddb *Store) Import(ctx context.Context, r io.Reader, opts *ImportOptions) (int, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	switch opts.Conflict {
+	case "", ImportOverwrite, ImportSkip, ImportFail:
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedImportConflict, opts.Conflict)
+	}
+
+	var limiter *tokenBucket
+	if opts.WritesPerSecond > 0 {
+		limiter = newTokenBucket(opts.WritesPerSecond, 1)
+	}
+
+	dec := json.NewDecoder(r)
+	failed := make(map[string]error)
+	written := 0
+
+	for {
+		records, err := decodeRecords(dec, maxBatchWriteItems)
+		if err != nil {
+			return written, err
+		}
+
+		if len(records) == 0 {
+			return written, batchError(failed)
+		}
+
+		if limiter != nil {
+			if err = limiter.wait(ctx); err != nil {
+				return written, err
+			}
+			limiter.take(float64(len(records)))
+		}
+
+		n, err := ddb.importRecords(ctx, records, opts.Conflict, failed)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// decodeRecords returns the next records of dec, at most max, none at the end of the snapshot.
+func decodeRecords(dec *json.Decoder, max int) ([]*SnapshotRecord, error) {
+	var records []*SnapshotRecord
+
+	for len(records) < max {
+		record := &SnapshotRecord{}
+
+		err := dec.Decode(record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot record: %w", err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// importRecords writes a batch of records, and returns the number of keys written.
+// The keys not written are added to failed.
+func (ddb *Store) importRecords(ctx context.Context, records []*SnapshotRecord, conflict ImportConflictPolicy,
+	failed map[string]error,
+) (int, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	for _, record := range records {
+		record.Key = ddb.normalizeKey(record.Key)
+	}
+
+	existing, err := ddb.existingKeys(ctx, records, conflict)
+	if err != nil {
+		return 0, err
+	}
+
+	pairs := make([]*store.KVPair, 0, len(records))
+	writeOpts := make(map[string]*store.WriteOptions, len(records))
+
+	for _, record := range records {
+		if existing[record.Key] {
+			if conflict == ImportFail {
+				return 0, fmt.Errorf("%w: %s", ErrImportConflict, record.Key)
+			}
+			continue
+		}
+
+		var recordOpts *store.WriteOptions
+		if record.ExpiresAt > 0 {
+			ttl := time.Until(time.Unix(record.ExpiresAt, 0))
+			if ttl <= 0 {
+				continue
+			}
+			recordOpts = &store.WriteOptions{TTL: ttl}
+		}
+
+		pairs = append(pairs, &store.KVPair{Key: record.Key, Value: record.Value})
+		writeOpts[record.Key] = recordOpts
+	}
+
+	if len(pairs) == 0 {
+		return 0, nil
+	}
+
+	optsOf := func(pair *store.KVPair) *store.WriteOptions { return writeOpts[pair.Key] }
+
+	batchFailed := make(map[string]error)
+	ddb.putPairs(ctx, OperationImport, pairs, optsOf, batchFailed)
+
+	for key, err := range batchFailed {
+		failed[key] = err
+	}
+
+	return len(pairs) - len(batchFailed), nil
+}
+
+// existingKeys returns the keys of records which exist and are not expired, unless they are overwritten.
+func (ddb *Store) existingKeys(ctx context.Context, records []*SnapshotRecord, conflict ImportConflictPolicy) (map[string]bool, error) {
+	if conflict != ImportSkip && conflict != ImportFail {
+		return nil, nil
+	}
+
+	keys := make([]string, len(records))
+	for i, record := range records {
+		keys[i] = record.Key
+	}
+
+	items, err := ddb.batchGetItems(ctx, uniqueKeys(keys), true)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(items))
+	for key, item := range items {
+		if !ddb.isItemExpired(item) {
+			existing[key] = true
+		}
+	}
+
+	return existing, nil
+}
@@ -0,0 +1,68 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	snapshot := `{"key":"app/a","value":"bmV3MQ==","revision":4}
+{"key":"app/b","value":"bmV3Mg==","revision":2,"expires_at":` + strconv.FormatInt(expiresAt, 10) + `}
+{"key":"app/c","value":"bmV3Mw==","revision":1,"expires_at":1700000000}
+`
+
+	newMock := func() *mockedBatchStore {
+		return &mockedBatchStore{
+			items: map[string]map[string]*dynamodb.AttributeValue{
+				"app/a": newTestItem("app/a", "b2xk"),
+			},
+			failBatch: -1,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	mock := newMock()
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	// the existing keys are overwritten by default, the expired ones are skipped.
+	n, err := kv.Import(ctx, strings.NewReader(snapshot), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "bmV3MQ==", aws.StringValue(mock.written["app/a"][encodedValueAttribute].S))
+	assert.Equal(t, "2", aws.StringValue(mock.written["app/a"][revisionAttribute].N))
+	assert.NotNil(t, mock.written["app/b"][ttlAttribute])
+	assert.NotContains(t, mock.written, "app/c")
+
+	mock = newMock()
+	kv.dynamoSvc = mock
+
+	n, err = kv.Import(ctx, strings.NewReader(snapshot), &ImportOptions{Conflict: ImportSkip, WritesPerSecond: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NotContains(t, mock.written, "app/a")
+	assert.Contains(t, mock.written, "app/b")
+
+	mock = newMock()
+	kv.dynamoSvc = mock
+
+	_, err = kv.Import(ctx, strings.NewReader(snapshot), &ImportOptions{Conflict: ImportFail})
+	assert.ErrorIs(t, err, ErrImportConflict)
+	assert.Empty(t, mock.written)
+
+	_, err = kv.Import(ctx, strings.NewReader(snapshot), &ImportOptions{Conflict: "merge"})
+	assert.ErrorIs(t, err, ErrUnsupportedImportConflict)
+
+	_, err = kv.Import(ctx, strings.NewReader("{not json"), nil)
+	assert.Error(t, err)
+}
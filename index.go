@@ -0,0 +1,69 @@
+package dynamodb
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// prefixAttribute the hash key of the prefix index:
+// the first segment of the parent directory of the key.
+const prefixAttribute = "prefix"
+
+// missingIndexMessage the message of the validation error of a query on an index missing from the table.
+const missingIndexMessage = "does not have the specified index"
+
+// indexAttributes adds the attributes maintained for the prefix index to attrs.
+func (ddb *Store) indexAttributes(key string, attrs map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if ddb.prefixIndex == "" {
+		return attrs
+	}
+
+	indexed := make(map[string]*dynamodb.AttributeValue, len(attrs)+1)
+	for name, v := range attrs {
+		indexed[name] = v
+	}
+	indexed[prefixAttribute] = &dynamodb.AttributeValue{S: aws.String(keyDirectory(ddb.storedKey(key), 1))}
+
+	return indexed
+}
+
+// indexSchema returns the definition of the prefix index, if any, with the provisioned throughput, and the attributes it requires.
+func (ddb *Store) indexSchema(throughput *dynamodb.ProvisionedThroughput) ([]*dynamodb.AttributeDefinition, []*dynamodb.GlobalSecondaryIndex) {
+	if ddb.prefixIndex == "" {
+		return nil, nil
+	}
+
+	return []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String(prefixAttribute), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	}, []*dynamodb.GlobalSecondaryIndex{{
+		IndexName: aws.String(ddb.prefixIndex),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(prefixAttribute), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String(ddb.keyName()), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		Projection:            &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		ProvisionedThroughput: throughput,
+	}}
+}
+
+// isIndexUnavailable reports whether err is caused by a missing index.
+func isIndexUnavailable(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+
+	switch awsErr.Code() {
+	case dynamodb.ErrCodeResourceNotFoundException:
+		return true
+	case "ValidationException":
+		// the other validation errors of the query are not caused by the index.
+		return strings.Contains(awsErr.Message(), missingIndexMessage)
+	default:
+		return false
+	}
+}
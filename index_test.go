@@ -0,0 +1,95 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStorePrefixIndex(t *testing.T) {
+	ddbStore := newDynamoDBStoreWith(t, func(ddbStore *Store) { ddbStore.prefixIndex = "prefix-index" })
+
+	testsuite.RunTestCommon(t, ddbStore)
+	testsuite.RunTestAtomic(t, ddbStore)
+}
+
+func TestIndexAttributes(t *testing.T) {
+	kv := &Store{}
+	assert.Nil(t, kv.indexAttributes("a/b", nil))
+
+	kv.prefixIndex = "prefix-index"
+	attrs := map[string]*dynamodb.AttributeValue{encodedValueAttribute: {S: aws.String("dmFsdWU=")}}
+
+	indexed := kv.indexAttributes("a/b/c", attrs)
+	assert.Len(t, indexed, 2)
+	assert.Equal(t, "/a", aws.StringValue(indexed[prefixAttribute].S))
+	assert.Len(t, attrs, 1)
+}
+
+func TestPrefixItemsIndex(t *testing.T) {
+	ctx := context.Background()
+
+	mock := &mockedPrefixQuery{items: []map[string]*dynamodb.AttributeValue{{partitionKey: {S: aws.String("a/1")}}}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName, prefixIndex: "prefix-index"}
+
+	items, err := kv.prefixItems(ctx, "a/", true)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "prefix-index", aws.StringValue(mock.query.IndexName))
+	assert.Equal(t, "/a", aws.StringValue(mock.query.ExpressionAttributeValues[":hashValue"].S))
+	assert.False(t, aws.BoolValue(mock.query.ConsistentRead))
+	assert.Equal(t, 0, mock.scans)
+
+	// prefixes without a complete directory segment are scanned.
+	_, err = kv.prefixItems(ctx, "a", true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, mock.scans)
+
+	// the table is scanned when the index doesn't exist.
+	mock.queryErr = awserr.New("ValidationException", "The table does not have the specified index", nil)
+	items, err = kv.prefixItems(ctx, "a/", true)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, 2, mock.scans)
+
+	// the other validation errors are returned.
+	mock.queryErr = awserr.New("ValidationException", "Invalid KeyConditionExpression", nil)
+	_, err = kv.prefixItems(ctx, "a/", true)
+	assert.Error(t, err)
+	assert.Equal(t, 2, mock.scans)
+}
+
+type mockedPrefixQuery struct {
+	dynamodbiface.DynamoDBAPI
+
+	items    []map[string]*dynamodb.AttributeValue
+	query    *dynamodb.QueryInput
+	queryErr error
+	scans    int
+}
+
+func (m *mockedPrefixQuery) QueryPagesWithContext(_ aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, _ ...request.Option) error {
+	m.query = input
+	if m.queryErr != nil {
+		return m.queryErr
+	}
+
+	fn(&dynamodb.QueryOutput{Items: m.items}, true)
+
+	return nil
+}
+
+func (m *mockedPrefixQuery) ScanPagesWithContext(_ aws.Context, _ *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	m.scans++
+	fn(&dynamodb.ScanOutput{Items: m.items}, true)
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	defaultJanitorInterval         = time.Hour
+	defaultJanitorDeletesPerSecond = 25
+)
+
+// JanitorConfig configures the background deletion of the expired items.
+// DynamoDB deletes the expired items up to a few days after their expiration time, and only when the TTL
+// of the table is enabled (see EnsureTTL), while the reads keep paying for the expired items until then.
+type JanitorConfig struct {
+	// Interval is the time between two sweeps of the expired items, defaults to 1 hour.
+	Interval time.Duration
+
+	// DeletesPerSecond limits the rate of the deletions, defaults to 25.
+	DeletesPerSecond float64
+}
+
+// janitor runs the sweeps of the expired items until stopped.
+type janitor struct {
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+// startJanitor starts the background sweeps of the expired items.
+func (ddb *Store) startJanitor(config *JanitorConfig) *janitor {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultJanitorInterval
+	}
+
+	deletesPerSecond := config.DeletesPerSecond
+	if deletesPerSecond <= 0 {
+		deletesPerSecond = defaultJanitorDeletesPerSecond
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &janitor{cancel: cancel, doneCh: make(chan struct{})}
+
+	go func() {
+		defer close(j.doneCh)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := ddb.DeleteExpired(ctx, deletesPerSecond)
+				if err != nil && ctx.Err() == nil {
+					// transient failure: the remaining items are deleted by the next sweep.
+					ddb.log().Info("expired items sweep failed", "deleted", deleted, "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+// stop stops the sweeps, and waits for the current one to end.
+func (j *janitor) stop() {
+	j.cancel()
+	<-j.doneCh
+}
+
+// DeleteExpired deletes the expired items of the store, at most deletesPerSecond items per second
+// (not limited if zero), and returns the number of items deleted.
+// The items of the table not managed by the store, such as the other partitions of a shared table,
+// or the keys without the key prefix of the store, are left untouched.
+func (ddb *Store) DeleteExpired(ctx context.Context, deletesPerSecond float64) (int, error) {
+	items, err := ddb.expiredItems(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var limiter *tokenBucket
+	if deletesPerSecond > 0 {
+		limiter = newTokenBucket(deletesPerSecond, 1)
+	}
+
+	var deleted int
+	for _, item := range items {
+		if limiter != nil {
+			if err = limiter.wait(ctx); err != nil {
+				return deleted, err
+			}
+			limiter.take(1)
+		}
+
+		ok, err := ddb.deleteExpiredItem(ctx, item)
+		if err != nil {
+			return deleted, err
+		}
+
+		if ok {
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// expiredItems returns the expired items of the store,
+// with a Query when the keys are all in the same partition, with a Scan otherwise.
+func (ddb *Store) expiredItems(ctx context.Context) ([]map[string]*dynamodb.AttributeValue, error) {
+	exNames := map[string]*string{ttlNamePlaceholder: aws.String(ddb.ttlName())}
+	exAttr := map[string]*dynamodb.AttributeValue{
+		":timeNow": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+	}
+	filter := ttlNamePlaceholder + " <= :timeNow"
+
+	if ddb.keyPrefix != "" {
+		exNames[keyNamePlaceholder] = aws.String(ddb.keyName())
+		exAttr[":namePrefix"] = &dynamodb.AttributeValue{S: aws.String(ddb.keyPrefix)}
+		filter = "begins_with(" + keyNamePlaceholder + ", :namePrefix) AND " + filter
+	}
+
+	if hashKey := ddb.hashKeyName(); hashKey != "" && ddb.partitionKeyFunc == nil && ddb.directoryDepth == 0 {
+		exNames["#hash"] = aws.String(hashKey)
+		exAttr[":hashValue"] = &dynamodb.AttributeValue{S: aws.String(ddb.partitionKeyValue)}
+
+		return ddb.queryPages(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(ddb.tableName),
+			KeyConditionExpression:    aws.String("#hash = :hashValue"),
+			FilterExpression:          aws.String(filter),
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		})
+	}
+
+	si := &dynamodb.ScanInput{
+		TableName:                 aws.String(ddb.tableName),
+		FilterExpression:          aws.String(filter),
+		ExpressionAttributeNames:  exNames,
+		ExpressionAttributeValues: exAttr,
+	}
+
+	if ddb.scanSegments > 1 {
+		return ddb.parallelScan(ctx, si, ddb.scanSegments)
+	}
+
+	return ddb.scanPages(ctx, si)
+}
+
+// deleteExpiredItem deletes an expired item, unless it was written again since it was read.
+func (ddb *Store) deleteExpiredItem(ctx context.Context, item map[string]*dynamodb.AttributeValue) (bool, error) {
+	_, err := ddb.dynamoSvc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(ddb.tableName),
+		Key:                      ddb.keyAttributes(ddb.itemKey(item)),
+		ConditionExpression:      aws.String(ttlNamePlaceholder + " <= :timeNow"),
+		ExpressionAttributeNames: map[string]*string{ttlNamePlaceholder: aws.String(ddb.ttlName())},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":timeNow": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+		},
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, ddb.deleteS3Object(ctx, item)
+}
@@ -0,0 +1,126 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteExpired(t *testing.T) {
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	mock := &mockedExpiredTable{
+		items: []map[string]*dynamodb.AttributeValue{
+			{ttlAttribute: {N: aws.String(expired)}, partitionKey: {S: aws.String("a")}},
+			{ttlAttribute: {N: aws.String(expired)}, partitionKey: {S: aws.String("rewritten")}},
+			{ttlAttribute: {N: aws.String(expired)}, partitionKey: {S: aws.String("b")}},
+		},
+	}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	deleted, err := kv.DeleteExpired(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, []string{"a", "b"}, mock.deleted)
+	assert.Equal(t, 1, mock.scans)
+	assert.Zero(t, mock.queries)
+
+	// the keys all in the same partition are queried.
+	kv.partitionKeyName, kv.partitionKeyValue = "pk", "kv"
+
+	_, err = kv.DeleteExpired(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, mock.queries)
+}
+
+func TestJanitor(t *testing.T) {
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	mock := &mockedExpiredTable{
+		items: []map[string]*dynamodb.AttributeValue{
+			{ttlAttribute: {N: aws.String(expired)}, partitionKey: {S: aws.String("a")}},
+		},
+	}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+	kv.janitor = kv.startJanitor(&JanitorConfig{Interval: 10 * time.Millisecond})
+
+	assert.Eventually(t, func() bool {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+		return len(mock.deleted) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, kv.Close())
+
+	mock.mu.Lock()
+	scans := mock.scans
+	mock.mu.Unlock()
+
+	// no sweep after Close.
+	time.Sleep(30 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.Equal(t, scans, mock.scans)
+}
+
+// mockedExpiredTable returns its items as the expired items, and fails the deletion of the "rewritten" key.
+type mockedExpiredTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu      sync.Mutex
+	items   []map[string]*dynamodb.AttributeValue
+	scans   int
+	queries int
+	deleted []string
+}
+
+func (m *mockedExpiredTable) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if aws.StringValue(input.FilterExpression) != "#ttl <= :timeNow" {
+		return awserr.New("ValidationException", "unexpected filter", nil)
+	}
+
+	m.scans++
+	fn(&dynamodb.ScanOutput{Items: m.items}, true)
+
+	return nil
+}
+
+func (m *mockedExpiredTable) QueryPagesWithContext(_ aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, _ ...request.Option) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if aws.StringValue(input.ExpressionAttributeValues[":hashValue"].S) != "kv" {
+		return awserr.New("ValidationException", "unexpected partition", nil)
+	}
+
+	m.queries++
+	fn(&dynamodb.QueryOutput{}, true)
+
+	return nil
+}
+
+func (m *mockedExpiredTable) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := itemKey(input.Key)
+	if key == "rewritten" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "not expired", nil)
+	}
+
+	m.deleted = append(m.deleted, key)
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
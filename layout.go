@@ -0,0 +1,228 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// directoryAttribute the default hash key of the tables using the directory layout.
+const directoryAttribute = "directory"
+
+// keyAttributes returns the primary key of the item of key.
+func (ddb *Store) keyAttributes(key string) map[string]*dynamodb.AttributeValue {
+	stored := ddb.storedKey(key)
+
+	attrs := map[string]*dynamodb.AttributeValue{
+		ddb.keyName(): {S: aws.String(stored)},
+	}
+
+	if hashKey := ddb.hashKeyName(); hashKey != "" {
+		attrs[hashKey] = &dynamodb.AttributeValue{S: aws.String(ddb.hashKeyValue(stored))}
+	}
+
+	return attrs
+}
+
+// storedKey returns the key stored in the table for key, normalized, with the key prefix of the store.
+func (ddb *Store) storedKey(key string) string {
+	return ddb.keyPrefix + ddb.normalizeKey(key)
+}
+
+// storedPrefix returns the prefix of the keys stored in the table for prefix, normalized, with the key prefix of the store.
+func (ddb *Store) storedPrefix(prefix string) string {
+	return ddb.keyPrefix + ddb.normalizePrefix(prefix)
+}
+
+// ownsItem reports whether an item belongs to the store:
+// its key has the key prefix of the store, and it's in the partition of the store if all the keys are in the same one.
+func (ddb *Store) ownsItem(item map[string]*dynamodb.AttributeValue) bool {
+	if partition, ok := ddb.prefixPartition(""); ok && ddb.directoryDepth == 0 {
+		if v, ok := item[ddb.partitionKeyName]; !ok || aws.StringValue(v.S) != partition {
+			return false
+		}
+	}
+
+	if ddb.keyPrefix == "" {
+		return true
+	}
+
+	v, ok := item[ddb.keyName()]
+
+	return ok && strings.HasPrefix(aws.StringValue(v.S), ddb.keyPrefix)
+}
+
+// hashKeyName returns the hash key of the tables with a composite primary key,
+// the key being their range key, or an empty string for the tables with the key as hash key.
+func (ddb *Store) hashKeyName() string {
+	switch {
+	case ddb.partitionKeyName != "":
+		return ddb.partitionKeyName
+	case ddb.directoryDepth > 0:
+		return directoryAttribute
+	default:
+		return ""
+	}
+}
+
+// hashKeyValue returns the hash key value of the item of key, with a composite primary key.
+func (ddb *Store) hashKeyValue(key string) string {
+	switch {
+	case ddb.directoryDepth > 0:
+		return keyDirectory(key, ddb.directoryDepth)
+	case ddb.partitionKeyFunc != nil:
+		return ddb.partitionKeyFunc(key)
+	default:
+		return ddb.partitionKeyValue
+	}
+}
+
+// prefixPartition returns the hash key value of the partition holding all the keys starting with prefix,
+// and false if they may be spread across several partitions.
+func (ddb *Store) prefixPartition(prefix string) (string, bool) {
+	switch {
+	case ddb.directoryDepth > 0:
+		return prefixDirectory(prefix, ddb.directoryDepth)
+	case ddb.partitionKeyName != "" && ddb.partitionKeyFunc == nil:
+		// all the keys are in the same partition.
+		return ddb.partitionKeyValue, true
+	default:
+		return "", false
+	}
+}
+
+// keyDirectory returns the partition of key:
+// the first depth segments of its parent directory, prefixed by a slash so it's never empty.
+func keyDirectory(key string, depth int) string {
+	var parent string
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		parent = key[:i]
+	}
+
+	return "/" + firstSegments(strings.TrimPrefix(parent, "/"), depth)
+}
+
+// prefixDirectory returns the partition holding all the keys starting with prefix,
+// and false if they may be spread across several partitions.
+func prefixDirectory(prefix string, depth int) (string, bool) {
+	trimmed := strings.TrimPrefix(prefix, "/")
+
+	// the segments of the partition must be complete in the prefix.
+	if depth == 0 || strings.Count(trimmed, "/") < depth {
+		return "", false
+	}
+
+	return "/" + firstSegments(trimmed, depth), true
+}
+
+func firstSegments(path string, count int) string {
+	segments := strings.SplitN(path, "/", count+1)
+	if len(segments) > count {
+		segments = segments[:count]
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// keySchema returns the key schema of the table, according to the layout.
+func (ddb *Store) keySchema() ([]*dynamodb.AttributeDefinition, []*dynamodb.KeySchemaElement) {
+	hashKey := ddb.hashKeyName()
+	if hashKey == "" {
+		return []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(ddb.keyName()), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		}, []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(ddb.keyName()), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		}
+	}
+
+	return []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String(hashKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		{AttributeName: aws.String(ddb.keyName()), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	}, []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(hashKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		{AttributeName: aws.String(ddb.keyName()), KeyType: aws.String(dynamodb.KeyTypeRange)},
+	}
+}
+
+// prefixItems returns all the items with a key starting with prefix,
+// with a Query when they are all in the same partition or a prefix index is configured,
+// with a Scan otherwise.
+func (ddb *Store) prefixItems(ctx context.Context, prefix string, consistent bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	return ddb.storedPrefixItems(ctx, ddb.storedPrefix(prefix), consistent)
+}
+
+// storedPrefixItems returns all the items with a stored key starting with prefix, used as is, like prefixItems.
+func (ddb *Store) storedPrefixItems(ctx context.Context, prefix string, consistent bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	if qi := ddb.storedPrefixQuery(prefix, consistent); qi != nil {
+		items, err := ddb.queryPages(ctx, qi)
+		if qi.IndexName == nil || !isIndexUnavailable(err) {
+			return items, err
+		}
+	}
+
+	return ddb.scanStoredPrefix(ctx, prefix, consistent)
+}
+
+// prefixQuery returns the Query reading the items with a key starting with prefix, and not expired,
+// or nil if the table must be scanned.
+func (ddb *Store) prefixQuery(prefix string, consistent bool) *dynamodb.QueryInput {
+	return ddb.storedPrefixQuery(ddb.storedPrefix(prefix), consistent)
+}
+
+// storedPrefixQuery returns the Query of prefixQuery for a stored key prefix, used as is.
+func (ddb *Store) storedPrefixQuery(prefix string, consistent bool) *dynamodb.QueryInput {
+	hashKey, hashValue, index := ddb.hashKeyName(), "", ""
+
+	if partition, ok := ddb.prefixPartition(prefix); ok {
+		hashValue = partition
+	} else if indexPrefix, ok := prefixDirectory(prefix, 1); ok && ddb.prefixIndex != "" {
+		hashKey, hashValue, index = prefixAttribute, indexPrefix, ddb.prefixIndex
+		// the global secondary indexes don't support consistent reads.
+		consistent = false
+	} else {
+		return nil
+	}
+
+	exNames := map[string]*string{
+		"#hash":            aws.String(hashKey),
+		keyNamePlaceholder: aws.String(ddb.keyName()),
+	}
+	exAttr := map[string]*dynamodb.AttributeValue{
+		":hashValue":  {S: aws.String(hashValue)},
+		":namePrefix": {S: aws.String(prefix)},
+	}
+
+	qi := &dynamodb.QueryInput{
+		TableName:                 aws.String(ddb.tableName),
+		KeyConditionExpression:    aws.String(fmt.Sprintf("#hash = :hashValue AND begins_with(%s, :namePrefix)", keyNamePlaceholder)),
+		FilterExpression:          aws.String(ddb.notExpiredFilter(exAttr, exNames)),
+		ExpressionAttributeNames:  exNames,
+		ExpressionAttributeValues: exAttr,
+		ConsistentRead:            aws.Bool(consistent),
+	}
+
+	if index != "" {
+		qi.IndexName = aws.String(index)
+	}
+
+	return qi
+}
+
+// queryPages returns the items of all the pages of a query.
+func (ddb *Store) queryPages(ctx context.Context, qi *dynamodb.QueryInput) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+
+	err := ddb.readSvc().QueryPagesWithContext(ctx, qi,
+		func(page *dynamodb.QueryOutput, _ bool) bool {
+			items = append(items, page.Items...)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,150 @@
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/kvtools/valkeyrie/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStoreDirectoryLayout(t *testing.T) {
+	ddbStore := newDynamoDBStoreWith(t, func(ddbStore *Store) { ddbStore.directoryDepth = 1 })
+	backupStore := &Store{dynamoSvc: ddbStore.dynamoSvc, tableName: TestTableName, directoryDepth: 1}
+
+	testsuite.RunTestCommon(t, ddbStore)
+	testsuite.RunTestAtomic(t, ddbStore)
+	testsuite.RunTestTTL(t, ddbStore, backupStore)
+}
+
+func TestDirectory(t *testing.T) {
+	testCases := []struct {
+		key      string
+		depth    int
+		expected string
+	}{
+		{key: "a", depth: 1, expected: "/"},
+		{key: "a/b", depth: 1, expected: "/a"},
+		{key: "a/b/c", depth: 1, expected: "/a"},
+		{key: "/a/b/c", depth: 1, expected: "/a"},
+		{key: "a/b/c", depth: 2, expected: "/a/b"},
+		{key: "a/b", depth: 2, expected: "/a"},
+		{key: "a/b/", depth: 2, expected: "/a/b"},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expected, keyDirectory(test.key, test.depth), test.key)
+	}
+}
+
+func TestPrefixDirectory(t *testing.T) {
+	testCases := []struct {
+		prefix    string
+		depth     int
+		directory string
+		ok        bool
+	}{
+		{prefix: "a", depth: 0},
+		{prefix: "a", depth: 1},
+		{prefix: "a/", depth: 1, directory: "/a", ok: true},
+		{prefix: "a/b", depth: 1, directory: "/a", ok: true},
+		{prefix: "/a/b/c", depth: 1, directory: "/a", ok: true},
+		{prefix: "a/b", depth: 2},
+		{prefix: "a/b/c", depth: 2, directory: "/a/b", ok: true},
+	}
+
+	for _, test := range testCases {
+		directory, ok := prefixDirectory(test.prefix, test.depth)
+		assert.Equal(t, test.ok, ok, test.prefix)
+		assert.Equal(t, test.directory, directory, test.prefix)
+
+		if ok {
+			// the keys starting with the prefix are in its partition.
+			assert.Equal(t, directory, keyDirectory(test.prefix+"x/y", test.depth), test.prefix)
+			assert.Equal(t, directory, keyDirectory(chunkKey(test.prefix, "id", 0), test.depth), test.prefix)
+		}
+	}
+}
+
+func TestKeyAttributes(t *testing.T) {
+	kv := &Store{}
+	assert.Len(t, kv.keyAttributes("a/b"), 1)
+
+	kv.directoryDepth = 1
+	attrs := kv.keyAttributes("a/b")
+	require.Len(t, attrs, 2)
+	assert.Equal(t, "a/b", aws.StringValue(attrs[partitionKey].S))
+	assert.Equal(t, "/a", aws.StringValue(attrs[directoryAttribute].S))
+}
+
+func TestCompositeKey(t *testing.T) {
+	kv := &Store{partitionKeyName: "pk", partitionKeyValue: "kv", attributeNames: AttributeNames{Key: "sk"}}
+
+	attrs := kv.keyAttributes("a/b")
+	require.Len(t, attrs, 2)
+	assert.Equal(t, "a/b", aws.StringValue(attrs["sk"].S))
+	assert.Equal(t, "kv", aws.StringValue(attrs["pk"].S))
+
+	_, schema := kv.keySchema()
+	require.Len(t, schema, 2)
+	assert.Equal(t, "pk", aws.StringValue(schema[0].AttributeName))
+	assert.Equal(t, "sk", aws.StringValue(schema[1].AttributeName))
+
+	// all the keys are in the same partition.
+	qi := kv.prefixQuery("a", true)
+	require.NotNil(t, qi)
+	assert.Equal(t, "kv", aws.StringValue(qi.ExpressionAttributeValues[":hashValue"].S))
+	assert.Equal(t, "pk", aws.StringValue(qi.ExpressionAttributeNames["#hash"]))
+	assert.True(t, aws.BoolValue(qi.ConsistentRead))
+	assert.Equal(t, "(attribute_not_exists(#ttl) OR #ttl > :timeNow)", aws.StringValue(qi.FilterExpression))
+	assert.Equal(t, ttlAttribute, aws.StringValue(qi.ExpressionAttributeNames["#ttl"]))
+	assert.NotNil(t, qi.ExpressionAttributeValues[":timeNow"])
+
+	kv.partitionKeyFunc = func(key string) string { return key[:1] }
+	assert.Equal(t, "a", aws.StringValue(kv.keyAttributes("a/b")["pk"].S))
+	assert.Nil(t, kv.prefixQuery("a/", true))
+}
+
+func TestKeyPrefix(t *testing.T) {
+	kv := &Store{keyPrefix: "app/", directoryDepth: 1}
+
+	attrs := kv.keyAttributes("a/b")
+	assert.Equal(t, "app/a/b", aws.StringValue(attrs[partitionKey].S))
+	assert.Equal(t, "/app", aws.StringValue(attrs[directoryAttribute].S))
+	assert.Equal(t, "a/b", kv.itemKey(attrs))
+
+	si := kv.prefixScan("a/", true)
+	assert.Equal(t, "app/a/", aws.StringValue(si.ExpressionAttributeValues[":namePrefix"].S))
+
+	qi := kv.prefixQuery("a/", true)
+	require.NotNil(t, qi)
+	assert.Equal(t, "/app", aws.StringValue(qi.ExpressionAttributeValues[":hashValue"].S))
+	assert.Equal(t, "app/a/", aws.StringValue(qi.ExpressionAttributeValues[":namePrefix"].S))
+
+	// the items of the other applications are ignored.
+	event := kv.recordEvent(&dynamodbstreams.Record{
+		EventName: aws.String(dynamodbstreams.OperationTypeInsert),
+		Dynamodb: &dynamodbstreams.StreamRecord{Keys: map[string]*dynamodb.AttributeValue{
+			partitionKey: {S: aws.String("other/a/b")},
+		}},
+	})
+	assert.Nil(t, event)
+}
+
+func TestConfigAttributeNames(t *testing.T) {
+	names, err := (&Config{PartitionKeyName: "pk", SortKeyName: "sk"}).attributeNames()
+	require.NoError(t, err)
+	assert.Equal(t, "sk", names.Key)
+
+	_, err = (&Config{PartitionKeyValue: "kv"}).attributeNames()
+	assert.ErrorIs(t, err, ErrPartitionKeyNameMissing)
+
+	_, err = (&Config{PartitionKeyName: "pk", SortKeyName: "sk", AttributeNames: AttributeNames{Key: "key"}}).attributeNames()
+	assert.ErrorIs(t, err, ErrAttributeNameConflict)
+
+	_, err = (&Config{PartitionKeyName: "version"}).attributeNames()
+	assert.ErrorIs(t, err, ErrAttributeNameConflict)
+}
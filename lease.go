@@ -0,0 +1,242 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	defaultLeaseTTL = 20 * time.Second
+	// minLeaseTTL leaves the lease at least two renewals, a second apart, before it expires.
+	minLeaseTTL = 3 * time.Second
+)
+
+var (
+	// ErrLeaseHeld is returned when the lease is already held by another owner.
+	ErrLeaseHeld = errors.New("lease is held by another owner")
+	// ErrLeaseExpired is reported when the lease could not be renewed before its TTL elapsed.
+	ErrLeaseExpired = errors.New("lease expired before it could be renewed")
+	// ErrLeaseLost is reported when the lease item was modified or removed by someone else.
+	ErrLeaseLost = errors.New("lease was taken over or removed")
+	// ErrLeaseReleased is returned when using a lease that has already been released.
+	ErrLeaseReleased = errors.New("lease already released")
+	// ErrLeaseTTLTooShort is returned when the lease TTL is too short to renew the lease before it expires.
+	ErrLeaseTTLTooShort = errors.New("lease TTL too short")
+)
+
+// LeaseOptions contains optional parameters for AcquireLease.
+type LeaseOptions struct {
+	// Value is stored in the lease item.
+	Value []byte
+	// TTL of the lease, renewed every TTL/3 in the background.
+	// It must be at least 3s.
+	TTL time.Duration
+	// OnExpire is called, at most once, as soon as the lease is lost.
+	// err is ErrLeaseExpired, ErrLeaseLost with the store.ErrKeyModified or store.ErrKeyNotFound of the renewal,
+	// or ErrStoreClosed if the store was closed.
+	OnExpire func(key string, err error)
+}
+
+// Lease is a named, auto-renewed lease.
+// Unlike a lock, acquiring a lease never waits:
+// the caller learns immediately whether it owns the lease,
+// and is notified (via Expired and OnExpire) the moment it doesn't anymore.
+type Lease struct {
+	ddb      *Store
+	key      string
+	value    []byte
+	ttl      time.Duration
+	onExpire func(key string, err error)
+
+	mu   sync.Mutex
+	last *store.KVPair
+	err  error
+
+	expiredCh chan struct{}
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+// AcquireLease tries once to acquire the lease named key.
+// It returns ErrLeaseHeld if the lease is owned by someone else.
+// On success, the lease is renewed in the background until Release is called or the lease is lost.
+// Like the lock items, the lease item is written without recording its revisions in the history.
+func (ddb *Store) AcquireLease(ctx context.Context, key string, opts *LeaseOptions) (*Lease, error) {
+	l := &Lease{
+		ddb:       ddb,
+		key:       key,
+		ttl:       defaultLeaseTTL,
+		expiredCh: make(chan struct{}),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+
+	if opts != nil {
+		if opts.TTL > 0 {
+			l.ttl = opts.TTL
+		}
+		if l.ttl < minLeaseTTL {
+			return nil, fmt.Errorf("%w: %s, the minimum is %s", ErrLeaseTTLTooShort, l.ttl, minLeaseTTL)
+		}
+		l.value = opts.Value
+		l.onExpire = opts.OnExpire
+	}
+
+	// the renewal is stopped by closing the store.
+	renewCtx, done, err := ddb.tasks.start(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := ddb.putLock(ctx, key, l.value, nil, l.ttl)
+	if err != nil {
+		done()
+
+		if errors.Is(err, store.ErrKeyExists) || errors.Is(err, store.ErrKeyModified) {
+			return nil, ErrLeaseHeld
+		}
+		return nil, err
+	}
+
+	l.last = item
+
+	go l.renew(renewCtx, done)
+
+	return l, nil
+}
+
+// Key returns the name of the lease.
+func (l *Lease) Key() string {
+	return l.key
+}
+
+// Expired returns a channel closed when the lease is lost.
+// It is not closed by Release.
+func (l *Lease) Expired() <-chan struct{} {
+	return l.expiredCh
+}
+
+// Err returns the reason the lease was lost, as passed to OnExpire, or nil if it is still held or was released.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Release stops renewing the lease and deletes the lease item.
+// If the lease was already lost, the loss reason is returned.
+func (l *Lease) Release(ctx context.Context) error {
+	released := false
+	l.stopOnce.Do(func() {
+		close(l.stopCh)
+		released = true
+	})
+	if !released {
+		return ErrLeaseReleased
+	}
+
+	<-l.doneCh
+
+	l.mu.Lock()
+	last, err := l.last, l.err
+	l.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	err = l.ddb.deleteLock(ctx, l.key, last)
+	switch {
+	case errors.Is(err, store.ErrKeyModified):
+		// the lease was taken over after it expired.
+		return fmt.Errorf("%w: %v", ErrLeaseLost, err)
+	case err != nil && !errors.Is(err, store.ErrKeyNotFound):
+		return err
+	default:
+		return nil
+	}
+}
+
+func (l *Lease) renew(ctx context.Context, done func()) {
+	err := l.renewLease(ctx)
+	if err != nil {
+		l.expire(err)
+	}
+
+	// the renewal ends before the OnExpire callback, which may Release.
+	close(l.doneCh)
+	done()
+
+	if err != nil && l.onExpire != nil {
+		l.onExpire(l.key, err)
+	}
+}
+
+// renewLease renews the lease until it is released, or returns the reason the lease was lost.
+func (l *Lease) renewLease(ctx context.Context) error {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	lastRenewal := time.Now()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := l.renewOnce(ctx)
+			if err == nil {
+				lastRenewal = time.Now()
+				continue
+			}
+
+			if errors.Is(err, store.ErrKeyModified) || errors.Is(err, store.ErrKeyNotFound) {
+				return fmt.Errorf("%w: %v", ErrLeaseLost, err)
+			}
+
+			// transient failure: keep trying until the lease would have expired server-side.
+			l.ddb.log().Info("lease renewal failed", "key", l.key, "error", err)
+			if time.Since(lastRenewal) >= l.ttl {
+				return ErrLeaseExpired
+			}
+		case <-l.stopCh:
+			return nil
+		case <-ctx.Done():
+			return ErrStoreClosed
+		}
+	}
+}
+
+func (l *Lease) renewOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, l.ttl/3)
+	defer cancel()
+
+	l.mu.Lock()
+	last := l.last
+	l.mu.Unlock()
+
+	item, err := l.ddb.putLock(ctx, l.key, l.value, last, l.ttl)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.last = item
+	l.mu.Unlock()
+
+	return nil
+}
+
+// expire records the reason the lease was lost.
+func (l *Lease) expire(err error) {
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+
+	close(l.expiredCh)
+}
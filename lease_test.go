@@ -0,0 +1,174 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLease(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	key := "testLease"
+
+	lease, err := ddbStore.AcquireLease(ctx, key, &LeaseOptions{TTL: 3 * time.Second, Value: []byte("owner-1")})
+	require.NoError(t, err)
+	require.NotNil(t, lease)
+
+	// a second owner cannot acquire the lease while it is renewed.
+	_, err = ddbStore.AcquireLease(ctx, key, nil)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+
+	time.Sleep(4 * time.Second)
+
+	_, err = ddbStore.AcquireLease(ctx, key, nil)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+
+	err = lease.Release(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, lease.Err())
+
+	err = lease.Release(ctx)
+	assert.ErrorIs(t, err, ErrLeaseReleased)
+
+	other, err := ddbStore.AcquireLease(ctx, key, nil)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+}
+
+func TestLeaseLost(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	key := "testLeaseLost"
+
+	lost := make(chan error, 1)
+	lease, err := ddbStore.AcquireLease(ctx, key, &LeaseOptions{
+		TTL:      3 * time.Second,
+		OnExpire: func(_ string, err error) { lost <- err },
+	})
+	require.NoError(t, err)
+
+	// someone else overwrites the lease item.
+	err = ddbStore.Put(ctx, key, []byte("intruder"), nil)
+	require.NoError(t, err)
+
+	select {
+	case <-lease.Expired():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease loss was not detected")
+	}
+
+	assert.ErrorIs(t, <-lost, ErrLeaseLost)
+	assert.ErrorIs(t, lease.Err(), ErrLeaseLost)
+	assert.ErrorIs(t, lease.Release(ctx), ErrLeaseLost)
+}
+
+func TestLeaseLostRelease(t *testing.T) {
+	mock := &mockedLeaseTable{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	released := make(chan error, 1)
+	var lease *Lease
+	lease, err := kv.AcquireLease(context.Background(), "lease", &LeaseOptions{
+		TTL: 3 * time.Second,
+		OnExpire: func(_ string, _ error) {
+			// releasing from the callback doesn't wait on the renewal calling it.
+			released <- lease.Release(context.Background())
+		},
+	})
+	require.NoError(t, err)
+
+	// the lease is taken over.
+	mock.mu.Lock()
+	mock.items["lease"][revisionAttribute].N = aws.String("42")
+	mock.mu.Unlock()
+
+	select {
+	case err := <-released:
+		assert.ErrorIs(t, err, ErrLeaseLost)
+		// the error of the renewal tells a takeover from a deletion.
+		assert.ErrorContains(t, err, store.ErrKeyModified.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("release from OnExpire blocked")
+	}
+}
+
+func TestLeaseRenewalHistory(t *testing.T) {
+	mock := &mockedLeaseHistory{mockedLeaseTable: &mockedLeaseTable{}}
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		history:   &HistoryConfig{Table: "history", MaxVersions: 3, Retention: time.Hour},
+	}
+
+	lease, err := kv.AcquireLease(context.Background(), "lease", &LeaseOptions{TTL: 3 * time.Second})
+	require.NoError(t, err)
+
+	// the lease item is renewed without recording its revisions in the history.
+	assert.Eventually(t, func() bool {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+
+		return aws.StringValue(mock.items["lease"][revisionAttribute].N) == "2"
+	}, 3*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, lease.Release(context.Background()))
+	assert.Zero(t, mock.transactions)
+}
+
+func TestLeaseStoreClosed(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLeaseTable{}, tableName: TestTableName, notifier: &chanNotifier{}}
+
+	expired := make(chan error, 1)
+	lease, err := kv.AcquireLease(context.Background(), "lease", &LeaseOptions{
+		TTL:      3 * time.Second,
+		OnExpire: func(_ string, err error) { expired <- err },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, kv.Close())
+
+	select {
+	case err := <-expired:
+		assert.ErrorIs(t, err, ErrStoreClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnExpire not called")
+	}
+	assert.ErrorIs(t, lease.Err(), ErrStoreClosed)
+}
+
+func TestLeaseTTLTooShort(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLeaseTable{}, tableName: TestTableName}
+
+	_, err := kv.AcquireLease(context.Background(), "lease", &LeaseOptions{TTL: time.Second})
+	assert.ErrorIs(t, err, ErrLeaseTTLTooShort)
+}
+
+// mockedLeaseHistory counts the transactions writing the history items, around a mockedLeaseTable.
+type mockedLeaseHistory struct {
+	*mockedLeaseTable
+
+	transactions int
+}
+
+func (m *mockedLeaseHistory) TransactWriteItemsWithContext(_ aws.Context, _ *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transactions++
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
@@ -0,0 +1,258 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// ErrInvalidPageToken is returned when a continuation token can't be decoded.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ListPage lists a page of the content of a given prefix, with consistent reads.
+// It returns the pairs of the page, and the continuation token of the next page, empty after the last page.
+// The token is opaque, and must be passed as is to read the next page, an empty token reads the first page.
+// As DynamoDB applies the page size before skipping the chunks and the expired items,
+// a page may hold fewer than pageSize pairs, even none, before the last page.
+// It runs through the middlewares as a List.
+func (ddb *Store) ListPage(ctx context.Context, prefix string, pageSize int, token string) ([]*store.KVPair, string, error) {
+	op := &Operation{Name: OperationList, Key: prefix}
+
+	var next string
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pairs, next, err = ddb.listPage(ctx, op.Key, pageSize, token)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return op.Pairs, next, nil
+}
+
+// listPage reads the page of the pairs with a key starting with prefix after token.
+func (ddb *Store) listPage(ctx context.Context, prefix string, pageSize int, token string) ([]*store.KVPair, string, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.List)
+	defer cancel()
+
+	startKey, err := decodePageToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var limit *int64
+	if pageSize > 0 {
+		limit = aws.Int64(int64(pageSize))
+	}
+
+	items, lastKey, err := ddb.prefixPage(ctx, prefix, true, limit, startKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pairs, err := ddb.decodeItems(ctx, items, prefix, true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := encodePageToken(lastKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return pairs, next, nil
+}
+
+// ListDirectory lists one level of the content of a given directory, like the listings without recursion of etcd and Consul,
+// so the keyspace can be browsed a level at a time.
+// The keys directly under the directory are returned as their pairs,
+// and the deeper keys are collapsed into an entry per child directory:
+// a pair without value, with the key of the child directory ending with a slash, such as "foo/bar/" for "foo/bar/baz".
+// The directory is the prefix of the keys followed by a slash: "foo" and "foo/" both list "foo/bar", but not "foobar".
+func (ddb *Store) ListDirectory(ctx context.Context, directory string, opts *store.ReadOptions) ([]*store.KVPair, error) {
+	directory = ddb.normalizeKey(directory)
+	if directory != "" && !strings.HasSuffix(directory, "/") {
+		directory += "/"
+	}
+
+	pairs, err := ddb.List(ctx, directory, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return collapseDirectories(pairs, directory), nil
+}
+
+// collapseDirectories returns the pairs directly under directory,
+// and an entry per child directory in place of the pairs under it, in the order of their first pair.
+func collapseDirectories(pairs []*store.KVPair, directory string) []*store.KVPair {
+	var entries []*store.KVPair
+	children := make(map[string]bool)
+
+	for _, pair := range pairs {
+		name := strings.TrimPrefix(pair.Key, directory)
+
+		i := strings.Index(name, "/")
+		if i < 0 {
+			entries = append(entries, pair)
+			continue
+		}
+
+		child := directory + name[:i+1]
+		if !children[child] {
+			children[child] = true
+			entries = append(entries, &store.KVPair{Key: child})
+		}
+	}
+
+	return entries
+}
+
+// ListStream lists the content of a given prefix, sending the pairs as the pages are read,
+// so the whole content is never held in memory.
+// The error channel receives at most one error, then both channels are closed.
+// The listing stops when ctx is done.
+// Unlike List, the listing doesn't run through the middlewares.
+func (ddb *Store) ListStream(ctx context.Context, prefix string, opts *store.ReadOptions) (<-chan *store.KVPair, <-chan error) {
+	if opts == nil {
+		opts = &store.ReadOptions{
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
+		}
+	}
+
+	pairsCh := make(chan *store.KVPair)
+	errCh := make(chan error, 1)
+
+	ctx, done, err := ddb.tasks.start(ctx)
+	if err != nil {
+		errCh <- err
+		close(errCh)
+		close(pairsCh)
+
+		return pairsCh, errCh
+	}
+
+	go func() {
+		defer done()
+		defer close(errCh)
+		defer close(pairsCh)
+
+		if err := ddb.streamPages(ctx, prefix, opts.Consistent, pairsCh); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return pairsCh, errCh
+}
+
+// streamPages reads the pages of the items with a key starting with prefix, and sends their pairs to pairsCh.
+func (ddb *Store) streamPages(ctx context.Context, prefix string, consistent bool, pairsCh chan<- *store.KVPair) error {
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		items, lastKey, err := ddb.prefixPage(ctx, prefix, consistent, nil, startKey)
+		if err != nil {
+			return err
+		}
+
+		pairs, err := ddb.decodeItems(ctx, items, prefix, consistent)
+		if err != nil {
+			return err
+		}
+
+		for _, pair := range pairs {
+			select {
+			case pairsCh <- pair:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if len(lastKey) == 0 {
+			return nil
+		}
+		startKey = lastKey
+	}
+}
+
+// prefixPage reads a page of the items with a key starting with prefix, after startKey if not nil.
+// It returns the key of the last item read, nil on the last page.
+func (ddb *Store) prefixPage(ctx context.Context, prefix string, consistent bool, limit *int64,
+	startKey map[string]*dynamodb.AttributeValue,
+) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+	if qi := ddb.prefixQuery(prefix, consistent); qi != nil {
+		qi.Limit = limit
+		qi.ExclusiveStartKey = startKey
+
+		res, err := ddb.readSvc().QueryWithContext(ctx, qi)
+		if err == nil {
+			return res.Items, res.LastEvaluatedKey, nil
+		}
+		if qi.IndexName == nil || !isIndexUnavailable(err) {
+			return nil, nil, err
+		}
+	}
+
+	si := ddb.prefixScan(prefix, consistent)
+	si.Limit = limit
+	si.ExclusiveStartKey = startKey
+
+	res, err := ddb.readSvc().ScanWithContext(ctx, si)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res.Items, res.LastEvaluatedKey, nil
+}
+
+// encodePageToken returns the continuation token for the key of the last item read.
+// All the key attributes are strings.
+func encodePageToken(lastKey map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(lastKey) == 0 {
+		return "", nil
+	}
+
+	key := make(map[string]string, len(lastKey))
+	for name, v := range lastKey {
+		key[name] = aws.StringValue(v.S)
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodePageToken returns the key of the last item read encoded in token, nil if token is empty.
+func decodePageToken(token string) (map[string]*dynamodb.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+
+	var key map[string]string
+	if err = json.Unmarshal(data, &key); err != nil || len(key) == 0 {
+		return nil, ErrInvalidPageToken
+	}
+
+	startKey := make(map[string]*dynamodb.AttributeValue, len(key))
+	for name, v := range key {
+		startKey[name] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+
+	return startKey, nil
+}
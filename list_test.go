@@ -0,0 +1,190 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStoreListPage(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		err := ddbStore.Put(ctx, fmt.Sprintf("testListPage/%d", i), []byte("value"), nil)
+		require.NoError(t, err)
+	}
+
+	keys := make(map[string]struct{})
+
+	var token string
+	for pages := 0; pages < 10; pages++ {
+		pairs, next, err := ddbStore.ListPage(ctx, "testListPage", 2, token)
+		require.NoError(t, err)
+
+		for _, pair := range pairs {
+			keys[pair.Key] = struct{}{}
+		}
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	assert.Len(t, keys, 5)
+}
+
+func TestPageToken(t *testing.T) {
+	token, err := encodePageToken(nil)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+
+	lastKey := map[string]*dynamodb.AttributeValue{
+		partitionKey:       {S: aws.String("a/b")},
+		directoryAttribute: {S: aws.String("/a")},
+	}
+
+	token, err = encodePageToken(lastKey)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	startKey, err := decodePageToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, lastKey, startKey)
+
+	startKey, err = decodePageToken("")
+	require.NoError(t, err)
+	assert.Nil(t, startKey)
+
+	_, err = decodePageToken("not a token")
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestListStream(t *testing.T) {
+	mock := &mockedPagedScan{pages: [][]map[string]*dynamodb.AttributeValue{
+		{newTestItem("a/1", "dmFsdWUx"), newTestItem("a/2", "dmFsdWUy")},
+		{},
+		{newTestItem("a/3", "dmFsdWUz")},
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	pairs, errs := kv.ListStream(ctx, "a", nil)
+
+	var keys []string
+	for pair := range pairs {
+		keys = append(keys, pair.Key)
+	}
+	assert.Equal(t, []string{"a/1", "a/2", "a/3"}, keys)
+	assert.NoError(t, <-errs)
+
+	// the listing stops when the context is canceled.
+	mock.next = 0
+	ctx, cancel = context.WithCancel(context.Background())
+
+	pairs, errs = kv.ListStream(ctx, "a", nil)
+	<-pairs
+	cancel()
+
+	assert.ErrorIs(t, <-errs, context.Canceled)
+}
+
+func TestListSorted(t *testing.T) {
+	mock := &mockedPagedScan{pages: [][]map[string]*dynamodb.AttributeValue{
+		{newTestItem("a/3", "dmFsdWUz"), newTestItem("a/1", "dmFsdWUx")},
+		{newTestItem("a/2", "dmFsdWUy")},
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	pairs, err := kv.List(context.Background(), "a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/1", "a/2", "a/3"}, pairKeys(pairs))
+
+	// the pairs are kept in the order of the scan.
+	mock.next = 0
+	kv.unsortedList = true
+
+	pairs, err = kv.List(context.Background(), "a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/3", "a/1", "a/2"}, pairKeys(pairs))
+}
+
+func pairKeys(pairs []*store.KVPair) []string {
+	keys := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		keys = append(keys, pair.Key)
+	}
+
+	return keys
+}
+
+func TestListDirectory(t *testing.T) {
+	mock := &mockedPagedScan{pages: [][]map[string]*dynamodb.AttributeValue{
+		{newTestItem("a/1", "dmFsdWUx"), newTestItem("a/b/2", "dmFsdWUy")},
+		{newTestItem("a/b/c/3", "dmFsdWUz"), newTestItem("a/d/4", "dmFsdWU0")},
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	pairs, err := kv.ListDirectory(context.Background(), "a", nil)
+	require.NoError(t, err)
+
+	require.Len(t, pairs, 3)
+	assert.Equal(t, "a/1", pairs[0].Key)
+	assert.Equal(t, []byte("value1"), pairs[0].Value)
+	assert.Equal(t, &store.KVPair{Key: "a/b/"}, pairs[1])
+	assert.Equal(t, &store.KVPair{Key: "a/d/"}, pairs[2])
+}
+
+func newTestItem(key, encodedValue string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		partitionKey:          {S: aws.String(key)},
+		revisionAttribute:     {N: aws.String("1")},
+		encodedValueAttribute: {S: aws.String(encodedValue)},
+	}
+}
+
+// mockedPagedScan returns a page per Scan call.
+type mockedPagedScan struct {
+	dynamodbiface.DynamoDBAPI
+
+	pages [][]map[string]*dynamodb.AttributeValue
+	next  int
+}
+
+func (m *mockedPagedScan) ScanWithContext(_ aws.Context, _ *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+	page := m.pages[m.next]
+	m.next++
+
+	out := &dynamodb.ScanOutput{Items: page}
+	if m.next < len(m.pages) {
+		out.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{partitionKey: {S: aws.String(fmt.Sprint(m.next))}}
+	}
+
+	return out, nil
+}
+
+func (m *mockedPagedScan) ScanPagesWithContext(_ aws.Context, _ *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	for m.next < len(m.pages) {
+		page := m.pages[m.next]
+		m.next++
+
+		if !fn(&dynamodb.ScanOutput{Items: page}, m.next == len(m.pages)) {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,596 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	defaultLockRetryInterval = 3 * time.Second
+	minLockHeartbeatInterval = time.Second
+)
+
+// The attributes of the lock items identifying their holder.
+const (
+	lockOwnerAttribute      = "lock_owner"
+	lockHostnameAttribute   = "lock_hostname"
+	lockPIDAttribute        = "lock_pid"
+	lockAcquiredAtAttribute = "lock_acquired_at"
+)
+
+var (
+	// ErrLockWaitTimeout is returned when the lock isn't acquired within the maximum wait.
+	ErrLockWaitTimeout = errors.New("lock wait timed out")
+	// ErrLockHeld is returned by TryLock when the lock is held by someone else.
+	ErrLockHeld = errors.New("lock is held by another owner")
+	// ErrLockLost is reported when the lock item was modified or removed by someone else.
+	ErrLockLost = errors.New("lock was taken over or removed")
+	// ErrLockExpired is reported when the lock could not be renewed before its TTL elapsed.
+	ErrLockExpired = errors.New("lock expired before it could be renewed")
+	// ErrLockNotHeld is returned by Unlock when the lock isn't held.
+	ErrLockNotHeld = errors.New("lock is not held")
+	// ErrLockAlreadyHeld is returned when locking a lock already held by the same Lock.
+	ErrLockAlreadyHeld = errors.New("lock is already held")
+)
+
+// LockConfig configures the acquisition and the renewal of the locks.
+type LockConfig struct {
+	// RetryInterval is the interval between the acquisition attempts of a lock held by someone else, defaults to 3s.
+	RetryInterval time.Duration
+
+	// RetryJitter is the maximum random delay added to RetryInterval, so the waiters don't retry in lockstep.
+	// Defaults to RetryInterval / 2, a negative value disables the jitter.
+	RetryJitter time.Duration
+
+	// MaxWait is the maximum time Lock waits for the lock, before returning ErrLockWaitTimeout.
+	// Zero waits until the context of Lock is done.
+	MaxWait time.Duration
+
+	// HeartbeatInterval is the interval between the renewals of a held lock, defaults to a third of the lock TTL.
+	// It can't be lower than 1s.
+	HeartbeatInterval time.Duration
+
+	// OnLost is called when a held lock is lost, with the reason returned by Lock.Err.
+	OnLost func(key string, err error)
+
+	// OwnerID identifies the holder of the locks in their LockInfo, along with its hostname and process ID.
+	OwnerID string
+}
+
+// LockInfo describes the holder of a lock.
+type LockInfo struct {
+	Key string
+	// Owner is the OwnerID of the LockConfig of the holder, if any.
+	Owner    string
+	Hostname string
+	PID      int
+	// AcquiredAt is the time the lock was acquired by the holder.
+	AcquiredAt time.Time
+	// ExpiresAt is the time the lock expires, unless renewed.
+	ExpiresAt time.Time
+	// Revision is the revision of the lock item, incremented by the renewals:
+	// the fencing token of the holder is the revision of the item when it acquired the lock.
+	Revision uint64
+}
+
+// retryDelay returns the delay before the next acquisition attempt.
+func (c LockConfig) retryDelay() time.Duration {
+	interval := c.RetryInterval
+	if interval <= 0 {
+		interval = defaultLockRetryInterval
+	}
+
+	jitter := c.RetryJitter
+	if jitter == 0 {
+		jitter = interval / 2
+	}
+	if jitter <= 0 {
+		return interval
+	}
+
+	//nolint:gosec // the jitter doesn't need a secure random source.
+	return interval + time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// heartbeatInterval returns the interval between the renewals of a lock.
+func (c LockConfig) heartbeatInterval(ttl time.Duration) time.Duration {
+	interval := c.HeartbeatInterval
+	if interval <= 0 {
+		interval = ttl / 3
+	}
+	if interval < minLockHeartbeatInterval {
+		interval = minLockHeartbeatInterval
+	}
+	return interval
+}
+
+// Lock is a distributed lock held by a TTL item, renewed in the background.
+// The store.Locker returned by NewLock is a *Lock.
+type Lock struct {
+	ddb     *Store
+	renewCh chan struct{}
+
+	key    string
+	value  []byte
+	ttl    time.Duration
+	config LockConfig
+
+	mu   sync.Mutex
+	last *store.KVPair
+	// token the fencing token of the current holding, 0 if the lock isn't held.
+	token uint64
+	// err the reason the last holding was lost, if any.
+	err error
+	// holding the current holding, nil if the lock isn't held.
+	holding *lockHolding
+}
+
+// lockHolding is a holding of a lock, renewed until it is stopped or lost.
+type lockHolding struct {
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// doneCh is closed when the renewal has stopped.
+	doneCh chan struct{}
+}
+
+func newLockHolding() *lockHolding {
+	return &lockHolding{stopCh: make(chan struct{}), doneCh: make(chan struct{})}
+}
+
+// ended reports whether the renewal has stopped.
+func (h *lockHolding) ended() bool {
+	select {
+	case <-h.doneCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop stops the renewal, and waits for it to return.
+func (h *lockHolding) stop() {
+	h.stopOnce.Do(func() { close(h.stopCh) })
+	<-h.doneCh
+}
+
+// NewLock has to implemented at the library level since it's not supported by DynamoDB.
+func (ddb *Store) NewLock(_ context.Context, key string, opts *store.LockOptions) (store.Locker, error) {
+	ttl := defaultLockTTL
+	var value []byte
+	renewCh := make(chan struct{})
+
+	if opts != nil {
+		if opts.TTL != 0 {
+			ttl = opts.TTL
+		}
+
+		if len(opts.Value) != 0 {
+			value = opts.Value
+		}
+
+		if opts.RenewLock != nil {
+			renewCh = opts.RenewLock
+		}
+	}
+
+	return &Lock{
+		ddb:     ddb,
+		key:     key,
+		value:   value,
+		ttl:     ttl,
+		config:  ddb.lockConfig,
+		renewCh: renewCh,
+	}, nil
+}
+
+// FencingToken returns the fencing token of the current holding of the lock, 0 if the lock isn't held.
+// The tokens of the successive holders increase, so the resources protected by the lock can reject
+// the writes of a holder which lost the lock, carrying a token lower than the last one they have seen.
+//
+// The token is the revision of the lock item when it was acquired.
+// As the revision of a new lock item starts after the current time in microseconds,
+// the tokens keep increasing when the lock item is deleted, provided the clocks of the holders are synchronized.
+func (l *Lock) FencingToken() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.token
+}
+
+// Holder returns the holder of the lock, or store.ErrKeyNotFound if the lock isn't held.
+func (l *Lock) Holder(ctx context.Context) (*LockInfo, error) {
+	return l.ddb.GetLockInfo(ctx, l.key)
+}
+
+// Err returns the reason the lock was lost, or nil if it is held or was released:
+// ErrLockLost if the lock item was modified or removed, ErrLockExpired if it couldn't be renewed before its TTL elapsed,
+// both wrapping the error of the renewal, the error of the context of Lock, or ErrStoreClosed once the store is closed.
+func (l *Lock) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Lock acquires the lock, retrying with jitter until ctx is done, or the maximum wait of the LockConfig.
+// The returned channel is closed when the lock is lost or released, Err returning the reason of the loss.
+func (l *Lock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, l.config.MaxWait)
+}
+
+// LockWithTimeout acquires the lock like Lock, waiting at most timeout, before returning ErrLockWaitTimeout.
+func (l *Lock) LockWithTimeout(ctx context.Context, timeout time.Duration) (<-chan struct{}, error) {
+	if timeout <= 0 {
+		return l.TryLock(ctx)
+	}
+	return l.lock(ctx, timeout)
+}
+
+// TryLock tries once to acquire the lock, and returns ErrLockHeld if it is held by someone else.
+// The returned channel is closed when the lock is lost or released.
+func (l *Lock) TryLock(ctx context.Context) (<-chan struct{}, error) {
+	lockHeld := make(chan struct{})
+
+	success, err := l.tryLock(ctx, lockHeld)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		return nil, ErrLockHeld
+	}
+
+	return lockHeld, nil
+}
+
+// lock acquires the lock, retrying until ctx is done or maxWait, if positive, elapsed.
+func (l *Lock) lock(ctx context.Context, maxWait time.Duration) (<-chan struct{}, error) {
+	lockHeld := make(chan struct{})
+
+	success, err := l.tryLock(ctx, lockHeld)
+	if err != nil {
+		return nil, err
+	}
+	if success {
+		return lockHeld, nil
+	}
+
+	var deadline <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+
+		deadline = timer.C
+	}
+
+	for {
+		retry := time.NewTimer(l.config.retryDelay())
+
+		select {
+		case <-retry.C:
+			success, err := l.tryLock(ctx, lockHeld)
+			if err != nil {
+				return nil, err
+			}
+			if success {
+				return lockHeld, nil
+			}
+		case <-deadline:
+			retry.Stop()
+			return nil, ErrLockWaitTimeout
+		case <-ctx.Done():
+			retry.Stop()
+			return nil, ErrLockAcquireCancelled
+		}
+	}
+}
+
+// Unlock releases the lock, deleting the lock item, and never blocks on the renewal, even if it already stopped.
+// It returns ErrLockNotHeld if the lock isn't held,
+// or the ErrLockLost or ErrLockExpired reason returned by Err if the lock was lost before.
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	holding := l.holding
+	l.holding = nil
+	l.mu.Unlock()
+
+	if holding == nil {
+		return ErrLockNotHeld
+	}
+
+	holding.stop()
+
+	l.mu.Lock()
+	last, lostErr := l.last, l.err
+	l.last = nil
+	l.token = 0
+	l.mu.Unlock()
+
+	if errors.Is(lostErr, ErrLockLost) || errors.Is(lostErr, ErrLockExpired) {
+		return lostErr
+	}
+
+	err := l.ddb.deleteLock(ctx, l.key, last)
+	switch {
+	case errors.Is(err, store.ErrKeyModified):
+		// the lock was taken over after it expired.
+		return fmt.Errorf("%w: %v", ErrLockLost, err)
+	case errors.Is(err, store.ErrKeyNotFound):
+		// the lock expired, the renewal having been stopped by the RenewLock channel.
+		return nil
+	default:
+		return err
+	}
+}
+
+func (l *Lock) tryLock(ctx context.Context, lockHeld chan struct{}) (bool, error) {
+	l.mu.Lock()
+	holding := l.holding
+	l.mu.Unlock()
+
+	if holding != nil && !holding.ended() {
+		return false, ErrLockAlreadyHeld
+	}
+
+	// the renewal is stopped by closing the store.
+	ctx, done, err := l.ddb.tasks.start(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// the lock is acquired if the lock item doesn't exist or is expired, whatever a previous holding.
+	item, err := l.ddb.putLock(ctx, l.key, l.value, nil, l.ttl)
+	if err != nil {
+		done()
+
+		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, store.ErrKeyModified) || errors.Is(err, store.ErrKeyExists) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	holding = newLockHolding()
+
+	l.mu.Lock()
+	l.last = item
+	l.token = item.LastIndex
+	l.err = nil
+	l.holding = holding
+	l.mu.Unlock()
+
+	// keep holding.
+	go l.holdLock(ctx, holding, lockHeld, done)
+
+	return true, nil
+}
+
+func (l *Lock) holdLock(ctx context.Context, holding *lockHolding, lockHeld chan struct{}, done func()) {
+	err := l.renewLock(ctx, holding)
+	if err != nil {
+		l.lost(err)
+	}
+
+	// the holding ends before the OnLost callback, which may Unlock.
+	close(lockHeld)
+	close(holding.doneCh)
+	done()
+
+	if err != nil && l.config.OnLost != nil {
+		l.config.OnLost(l.key, err)
+	}
+}
+
+// renewLock renews the lock until the holding is stopped, or returns the reason the lock was lost.
+func (l *Lock) renewLock(ctx context.Context, holding *lockHolding) error {
+	heartbeat := time.NewTicker(l.config.heartbeatInterval(l.ttl))
+	defer heartbeat.Stop()
+
+	lastRenewal := time.Now()
+
+	for {
+		select {
+		case <-heartbeat.C:
+			err := l.renew(ctx)
+			if err == nil {
+				lastRenewal = time.Now()
+				continue
+			}
+
+			if errors.Is(err, store.ErrKeyModified) || errors.Is(err, store.ErrKeyNotFound) {
+				return fmt.Errorf("%w: %v", ErrLockLost, err)
+			}
+
+			// transient failure: keep trying until the lock would have expired server-side.
+			l.ddb.log().Info("lock renewal failed", "key", l.key, "error", err)
+			if time.Since(lastRenewal) >= l.ttl {
+				return fmt.Errorf("%w: %v", ErrLockExpired, err)
+			}
+		case <-l.renewCh:
+			return nil
+		case <-holding.stopCh:
+			return nil
+		case <-ctx.Done():
+			if l.ddb.tasks.isClosed() {
+				return ErrStoreClosed
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// renew renews the lock item.
+func (l *Lock) renew(ctx context.Context) error {
+	l.mu.Lock()
+	last := l.last
+	l.mu.Unlock()
+
+	item, err := l.ddb.putLock(ctx, l.key, l.value, last, l.ttl)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.last = item
+	l.mu.Unlock()
+
+	return nil
+}
+
+// lost records the reason the lock was lost.
+func (l *Lock) lost(err error) {
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+
+	l.ddb.log().Error("lock lost", "key", l.key, "error", err)
+}
+
+// putLock writes the lock item, like AtomicPut, with the value stored inline.
+// The revision of a new lock item starts after the current time in microseconds,
+// so the fencing tokens keep increasing when the lock item is deleted.
+// It runs through the middlewares as an AtomicPut.
+func (ddb *Store) putLock(ctx context.Context, key string, value []byte, previous *store.KVPair, ttl time.Duration) (*store.KVPair, error) {
+	op := &Operation{Name: OperationAtomicPut, Key: key, Value: value, Previous: previous}
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pair, err = ddb.writeLock(ctx, op.Key, op.Value, op.Previous, ttl)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return op.Pair, nil
+}
+
+// deleteLock deletes the lock item, like AtomicDelete, without recording it in the history.
+// It runs through the middlewares as an AtomicDelete.
+func (ddb *Store) deleteLock(ctx context.Context, key string, previous *store.KVPair) error {
+	op := &Operation{Name: OperationAtomicDelete, Key: key, Previous: previous}
+
+	return ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) error {
+		_, err := ddb.atomicDeleteItem(ctx, op.Key, op.Previous)
+		return err
+	})
+}
+
+// writeLock writes the lock item of putLock.
+func (ddb *Store) writeLock(ctx context.Context, key string, value []byte, previous *store.KVPair, ttl time.Duration) (*store.KVPair, error) {
+	var attrs map[string]*dynamodb.AttributeValue
+	if len(value) > 0 {
+		data, enc, err := ddb.encodeValue(ctx, key, value)
+		if err != nil {
+			return nil, err
+		}
+		attrs = ddb.valueAttributes(data, enc)
+	}
+
+	attrs = ddb.lockOwnerAttributes(attrs)
+
+	var revisionSeed uint64
+	if previous == nil {
+		now := time.Now()
+		revisionSeed = uint64(now.UnixMicro())
+		attrs[lockAcquiredAtAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(now.UnixMilli(), 10))}
+	}
+
+	updateExp, exAttr, exNames := ddb.seededWriteUpdate(ddb.indexAttributes(key, attrs), &store.WriteOptions{TTL: ttl}, revisionSeed)
+	condExp := ddb.atomicPutCondition(previous, exAttr, exNames)
+
+	res, err := ddb.dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(ddb.tableName),
+		Key:                       ddb.keyAttributes(key),
+		ExpressionAttributeNames:  exNames,
+		ExpressionAttributeValues: exAttr,
+		UpdateExpression:          aws.String(updateExp),
+		ConditionExpression:       aws.String(condExp),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			if awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				if previous == nil {
+					return nil, store.ErrKeyExists
+				}
+				return nil, store.ErrKeyModified
+			}
+		}
+		return nil, err
+	}
+
+	item, err := ddb.decryptItem(ctx, res.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.decodeItem(item)
+}
+
+// lockOwnerAttributes returns attrs with the attributes identifying the holder of a lock.
+func (ddb *Store) lockOwnerAttributes(attrs map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	owned := make(map[string]*dynamodb.AttributeValue, len(attrs)+3)
+	for name, v := range attrs {
+		owned[name] = v
+	}
+
+	owned[lockPIDAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(os.Getpid()))}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		owned[lockHostnameAttribute] = &dynamodb.AttributeValue{S: aws.String(hostname)}
+	}
+	if ddb.lockConfig.OwnerID != "" {
+		owned[lockOwnerAttribute] = &dynamodb.AttributeValue{S: aws.String(ddb.lockConfig.OwnerID)}
+	}
+
+	return owned
+}
+
+// GetLockInfo returns the holder of the lock key, or store.ErrKeyNotFound if the lock isn't held.
+func (ddb *Store) GetLockInfo(ctx context.Context, key string) (*LockInfo, error) {
+	res, err := ddb.getKey(ctx, key, &store.ReadOptions{Consistent: true})
+	if err != nil {
+		return nil, err
+	}
+	if res.Item == nil || ddb.isItemExpired(res.Item) {
+		return nil, store.ErrKeyNotFound
+	}
+
+	item := res.Item
+	value := func(name string) string {
+		v, ok := item[name]
+		if !ok || v == nil {
+			return ""
+		}
+		if v.N != nil {
+			return aws.StringValue(v.N)
+		}
+		return aws.StringValue(v.S)
+	}
+
+	info := &LockInfo{
+		Key:      key,
+		Owner:    value(lockOwnerAttribute),
+		Hostname: value(lockHostnameAttribute),
+	}
+
+	info.PID, _ = strconv.Atoi(value(lockPIDAttribute))
+	info.Revision, _ = strconv.ParseUint(value(ddb.revisionName()), 10, 64)
+
+	if ms, err := strconv.ParseInt(value(lockAcquiredAtAttribute), 10, 64); err == nil {
+		info.AcquiredAt = time.UnixMilli(ms)
+	}
+	if ttl, err := strconv.ParseInt(value(ddb.ttlName()), 10, 64); err == nil {
+		info.ExpiresAt = time.Unix(ttl, 0)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,335 @@
+package dynamodb
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeededWriteUpdate(t *testing.T) {
+	kv := &Store{}
+
+	updateExp, exAttr, _ := kv.seededWriteUpdate(kv.valueAttributes([]byte("a"), valueEncoding{}), &store.WriteOptions{TTL: time.Minute}, 42)
+	assert.Equal(t, "SET #revision = if_not_exists(#revision, :revisionSeed) + :incr,#attr4 = :val4,"+
+		"#createdAt = if_not_exists(#createdAt, :now),#updatedAt = :now,#ttl = :ttl REMOVE #attr0,#attr1,#attr2,#attr3,#attr5,#attr6,#attr7", updateExp)
+	assert.Equal(t, "42", aws.StringValue(exAttr[":revisionSeed"].N))
+}
+
+func TestLockFencingToken(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLockTable{}, tableName: TestTableName}
+	ctx := context.Background()
+
+	locker, err := kv.NewLock(ctx, "lock", &store.LockOptions{TTL: time.Minute})
+	require.NoError(t, err)
+
+	lock, ok := locker.(*Lock)
+	require.True(t, ok)
+	assert.Zero(t, lock.FencingToken())
+
+	start := uint64(time.Now().UnixMicro())
+
+	_, err = lock.Lock(ctx)
+	require.NoError(t, err)
+
+	first := lock.FencingToken()
+	assert.Greater(t, first, start)
+
+	require.NoError(t, lock.Unlock(ctx))
+	assert.Zero(t, lock.FencingToken())
+
+	// the lock item was deleted, the token of the next holder is still greater.
+	_, err = lock.Lock(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, lock.FencingToken(), first)
+
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+func TestLockConfig(t *testing.T) {
+	config := LockConfig{RetryInterval: 100 * time.Millisecond}
+	for i := 0; i < 10; i++ {
+		delay := config.retryDelay()
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.Less(t, delay, 150*time.Millisecond)
+	}
+
+	assert.Equal(t, 100*time.Millisecond, LockConfig{RetryInterval: 100 * time.Millisecond, RetryJitter: -1}.retryDelay())
+
+	assert.Equal(t, 10*time.Second, LockConfig{}.heartbeatInterval(30*time.Second))
+	assert.Equal(t, 5*time.Second, LockConfig{HeartbeatInterval: 5 * time.Second}.heartbeatInterval(30*time.Second))
+	assert.Equal(t, time.Second, LockConfig{}.heartbeatInterval(time.Second))
+}
+
+func TestLockMaxWait(t *testing.T) {
+	// the lock is held by someone else.
+	kv := &Store{
+		dynamoSvc:  &mockedLockTable{revision: 1},
+		tableName:  TestTableName,
+		lockConfig: LockConfig{RetryInterval: 10 * time.Millisecond, MaxWait: 50 * time.Millisecond},
+	}
+
+	locker, err := kv.NewLock(context.Background(), "lock", nil)
+	require.NoError(t, err)
+
+	_, err = locker.Lock(context.Background())
+	assert.ErrorIs(t, err, ErrLockWaitTimeout)
+}
+
+func TestTryLock(t *testing.T) {
+	mock := &mockedLockTable{revision: 1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+	ctx := context.Background()
+
+	locker, err := kv.NewLock(ctx, "lock", nil)
+	require.NoError(t, err)
+
+	lock, ok := locker.(*Lock)
+	require.True(t, ok)
+
+	start := time.Now()
+	_, err = lock.TryLock(ctx)
+	assert.ErrorIs(t, err, ErrLockHeld)
+	assert.Less(t, time.Since(start), time.Second)
+
+	_, err = lock.LockWithTimeout(ctx, 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrLockWaitTimeout)
+
+	// the lock is released.
+	mock.mu.Lock()
+	mock.revision = 0
+	mock.mu.Unlock()
+
+	_, err = lock.TryLock(ctx)
+	require.NoError(t, err)
+	assert.NotZero(t, lock.FencingToken())
+
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+func TestLockLost(t *testing.T) {
+	mock := &mockedLockTable{}
+	lostCh := make(chan error, 1)
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		lockConfig: LockConfig{OnLost: func(key string, err error) {
+			assert.Equal(t, "lock", key)
+			lostCh <- err
+		}},
+	}
+
+	locker, err := kv.NewLock(context.Background(), "lock", &store.LockOptions{TTL: 3 * time.Second})
+	require.NoError(t, err)
+
+	lockHeld, err := locker.Lock(context.Background())
+	require.NoError(t, err)
+
+	// the lock is taken over.
+	mock.mu.Lock()
+	mock.revision++
+	mock.mu.Unlock()
+
+	select {
+	case <-lockHeld:
+	case <-time.After(3 * time.Second):
+		t.Fatal("lock not lost")
+	}
+
+	assert.ErrorIs(t, <-lostCh, ErrLockLost)
+
+	lock, ok := locker.(*Lock)
+	require.True(t, ok)
+	assert.ErrorIs(t, lock.Err(), ErrLockLost)
+
+	// unlocking a lost lock doesn't block.
+	assert.ErrorIs(t, lock.Unlock(context.Background()), ErrLockLost)
+	assert.ErrorIs(t, lock.Unlock(context.Background()), ErrLockNotHeld)
+}
+
+func TestLockLostUnlock(t *testing.T) {
+	mock := &mockedLockTable{}
+	unlockCh := make(chan error, 1)
+	var locker store.Locker
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		lockConfig: LockConfig{OnLost: func(_ string, _ error) {
+			// unlocking from the callback doesn't wait on the renewal calling it.
+			unlockCh <- locker.Unlock(context.Background())
+		}},
+	}
+
+	locker, err := kv.NewLock(context.Background(), "lock", &store.LockOptions{TTL: 3 * time.Second})
+	require.NoError(t, err)
+
+	_, err = locker.Lock(context.Background())
+	require.NoError(t, err)
+
+	mock.mu.Lock()
+	mock.revision++
+	mock.mu.Unlock()
+
+	select {
+	case err := <-unlockCh:
+		assert.ErrorIs(t, err, ErrLockLost)
+	case <-time.After(5 * time.Second):
+		t.Fatal("unlock from OnLost blocked")
+	}
+}
+
+func TestUnlockAfterCancel(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLockTable{}, tableName: TestTableName}
+
+	locker, err := kv.NewLock(context.Background(), "lock", nil)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, locker.Unlock(context.Background()), ErrLockNotHeld)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lockHeld, err := locker.Lock(ctx)
+	require.NoError(t, err)
+
+	_, err = locker.Lock(context.Background())
+	assert.ErrorIs(t, err, ErrLockAlreadyHeld)
+
+	// the renewal stops with the context of Lock.
+	cancel()
+	<-lockHeld
+
+	unlocked := make(chan error)
+	go func() { unlocked <- locker.Unlock(context.Background()) }()
+
+	select {
+	case err := <-unlocked:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Unlock blocked")
+	}
+
+	// the lock can be acquired again.
+	_, err = locker.Lock(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, locker.Unlock(context.Background()))
+}
+
+func TestLockHolder(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLockTable{}, tableName: TestTableName, lockConfig: LockConfig{OwnerID: "worker-1"}}
+	ctx := context.Background()
+
+	_, err := kv.GetLockInfo(ctx, "lock")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	locker, err := kv.NewLock(ctx, "lock", &store.LockOptions{Value: []byte("value"), TTL: time.Minute})
+	require.NoError(t, err)
+
+	lock, ok := locker.(*Lock)
+	require.True(t, ok)
+
+	start := time.Now().Truncate(time.Millisecond)
+
+	_, err = lock.Lock(ctx)
+	require.NoError(t, err)
+
+	info, err := lock.Holder(ctx)
+	require.NoError(t, err)
+
+	hostname, _ := os.Hostname()
+	assert.Equal(t, "lock", info.Key)
+	assert.Equal(t, "worker-1", info.Owner)
+	assert.Equal(t, hostname, info.Hostname)
+	assert.Equal(t, os.Getpid(), info.PID)
+	assert.Equal(t, lock.FencingToken(), info.Revision)
+	assert.False(t, info.AcquiredAt.Before(start))
+	assert.True(t, info.ExpiresAt.After(start))
+
+	// the renewals keep the acquisition time.
+	require.NoError(t, lock.renew(ctx))
+
+	renewed, err := kv.GetLockInfo(ctx, "lock")
+	require.NoError(t, err)
+	assert.Equal(t, info.AcquiredAt, renewed.AcquiredAt)
+	assert.Greater(t, renewed.Revision, info.Revision)
+
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+// mockedLockTable stores the revision of a single lock item, checking the revision conditions.
+type mockedLockTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu       sync.Mutex
+	revision uint64
+	item     map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockedLockTable) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if last, ok := input.ExpressionAttributeValues[":lastRevision"]; ok {
+		if aws.StringValue(last.N) != strconv.FormatUint(m.revision, 10) {
+			return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)
+		}
+	} else if m.revision != 0 {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)
+	}
+
+	if seed, ok := input.ExpressionAttributeValues[":revisionSeed"]; ok && m.revision == 0 {
+		m.revision, _ = strconv.ParseUint(aws.StringValue(seed.N), 10, 64)
+	}
+	m.revision++
+
+	// the attributes set by the update, the acquisition time being kept by the renewals.
+	item := map[string]*dynamodb.AttributeValue{
+		partitionKey:            input.Key[partitionKey],
+		revisionAttribute:       {N: aws.String(strconv.FormatUint(m.revision, 10))},
+		lockAcquiredAtAttribute: m.item[lockAcquiredAtAttribute],
+	}
+	for placeholder, name := range input.ExpressionAttributeNames {
+		if v, ok := input.ExpressionAttributeValues[":val"+strings.TrimPrefix(placeholder, "#attr")]; ok {
+			item[aws.StringValue(name)] = v
+		}
+	}
+	if ttl, ok := input.ExpressionAttributeValues[":ttl"]; ok {
+		item[ttlAttribute] = ttl
+	}
+	m.item = item
+
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+func (m *mockedLockTable) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.revision == 0 {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{Item: m.item}, nil
+}
+
+func (m *mockedLockTable) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if aws.StringValue(input.ExpressionAttributeValues[":lastRevision"].N) != strconv.FormatUint(m.revision, 10) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)
+	}
+	m.revision = 0
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
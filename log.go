@@ -0,0 +1,26 @@
+package dynamodb
+
+// Logger receives the errors the store can't return to its callers,
+// such as the failures of the watchers and of the lock heartbeats.
+// The arguments are alternating keys and values, so a *slog.Logger can be used.
+type Logger interface {
+	// Info logs a transient failure, retried by the store.
+	Info(msg string, args ...interface{})
+	// Error logs a failure ending a background operation.
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger discards the logs.
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...interface{}) {}
+
+func (nopLogger) Error(string, ...interface{}) {}
+
+// log returns the logger of the store.
+func (ddb *Store) log() Logger {
+	if ddb.logger == nil {
+		return nopLogger{}
+	}
+	return ddb.logger
+}
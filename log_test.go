@@ -0,0 +1,65 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	kv := &Store{
+		s3Overflow: &S3OverflowConfig{Bucket: "bucket"},
+		s3Svc:      &mockedFailingS3{},
+		logger:     logger,
+	}
+
+	kv.discardS3Object(context.Background(), map[string]*dynamodb.AttributeValue{s3ObjectAttribute: {S: aws.String("object")}})
+
+	require.Len(t, logger.errors, 1)
+	assert.Equal(t, []interface{}{"object", "object", "error", errS3Unavailable}, logger.errors[0][1:])
+
+	// the logs are discarded by default.
+	kv.logger = nil
+	kv.discardS3Object(context.Background(), map[string]*dynamodb.AttributeValue{s3ObjectAttribute: {S: aws.String("object")}})
+}
+
+var errS3Unavailable = errors.New("s3 unavailable")
+
+type mockedFailingS3 struct {
+	s3iface.S3API
+}
+
+func (m *mockedFailingS3) DeleteObjectWithContext(_ aws.Context, _ *s3.DeleteObjectInput, _ ...request.Option) (*s3.DeleteObjectOutput, error) {
+	return nil, errS3Unavailable
+}
+
+// recordingLogger records the messages and arguments of the logs.
+type recordingLogger struct {
+	mu     sync.Mutex
+	infos  [][]interface{}
+	errors [][]interface{}
+}
+
+func (l *recordingLogger) Info(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.infos = append(l.infos, append([]interface{}{msg}, args...))
+}
+
+func (l *recordingLogger) Error(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.errors = append(l.errors, append([]interface{}{msg}, args...))
+}
@@ -0,0 +1,98 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// The names of the operations run through the middlewares.
+const (
+	OperationGet          = "Get"
+	OperationGetMeta      = "GetMeta"
+	OperationPut          = "Put"
+	OperationDelete       = "Delete"
+	OperationExists       = "Exists"
+	OperationList         = "List"
+	OperationDeleteTree   = "DeleteTree"
+	OperationAtomicPut    = "AtomicPut"
+	OperationAtomicDelete = "AtomicDelete"
+
+	// The writes of PutMany, CopyTree, Import, and Migrate run as an operation by key.
+	// DeleteMany runs as an operation by batch, with Keys, or by key with the history.
+	OperationPutMany    = "PutMany"
+	OperationDeleteMany = "DeleteMany"
+	OperationCopyTree   = "CopyTree"
+	OperationImport     = "Import"
+	OperationMigrate    = "Migrate"
+
+	OperationCopy      = "Copy"
+	OperationPatchJSON = "PatchJSON"
+	// OperationMove is the Move of the key to the other key of Keys.
+	OperationMove = "Move"
+	// OperationCommit is the Commit of a transaction, writing the keys of Keys, without Key.
+	OperationCommit = "Commit"
+
+	// OperationTruncate and OperationDeleteTable delete all the keys of the table, without Key.
+	OperationTruncate    = "Truncate"
+	OperationDeleteTable = "DeleteTable"
+)
+
+// Operation is an operation of the store, run through the middlewares.
+// The middlewares can modify its key and value before calling the next one, such as to normalize the keys,
+// and read its results after.
+type Operation struct {
+	// Name is the name of the operation, such as OperationPut.
+	Name string
+	// Key is the key of the operation, or the prefix of List and DeleteTree.
+	Key string
+	// Keys are the keys written by the operations on several keys at once, Commit, Move, and the batches of DeleteMany.
+	// A batch runs with the keys of its requests, and ends with the keys it deleted.
+	Keys []string
+	// Value is the value written by Put, AtomicPut, Copy, and the writes of PutMany, CopyTree, Import, and Migrate,
+	// or the JSON value set by PatchJSON.
+	Value []byte
+	// Previous is the pair expected by AtomicPut and AtomicDelete, nil for AtomicPut creating the key.
+	Previous *store.KVPair
+
+	// Pair is the pair read by Get, or written by the operations writing a single value, once the operation succeeded.
+	Pair *store.KVPair
+	// Pairs are the pairs read by List, or the page read by ListPage, once the operation succeeded.
+	Pairs []*store.KVPair
+}
+
+// isBatch reports whether op is a batch of DeleteMany, with Keys instead of Key.
+func isBatch(op *Operation) bool {
+	return op.Name == OperationDeleteMany && op.Keys != nil
+}
+
+// OperationFunc runs an operation of the store.
+type OperationFunc func(ctx context.Context, op *Operation) error
+
+// Middleware returns an OperationFunc running next, adding a behavior around it,
+// such as auditing, metrics, or fault injection:
+//
+//	func timing(next dynamodb.OperationFunc) dynamodb.OperationFunc {
+//		return func(ctx context.Context, op *dynamodb.Operation) error {
+//			start := time.Now()
+//			err := next(ctx, op)
+//			observe(op.Name, time.Since(start), err)
+//			return err
+//		}
+//	}
+type Middleware func(next OperationFunc) OperationFunc
+
+// runOperation runs op with run through the middlewares of the store, from the outermost.
+// The key is checked after the middlewares, which may modify it.
+func (ddb *Store) runOperation(ctx context.Context, op *Operation, run OperationFunc) error {
+	// the middlewares see the normalized key, the prefix being ended with a slash by the reads of the table.
+	op.Key = ddb.normalizeKey(op.Key)
+
+	run = ddb.validateOperation(run)
+
+	for i := len(ddb.middlewares) - 1; i >= 0; i-- {
+		run = ddb.middlewares[i](run)
+	}
+
+	return run(ctx, op)
+}
@@ -0,0 +1,100 @@
+package dynamodb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewares(t *testing.T) {
+	var (
+		names  []string
+		errs   []error
+		pairs  []*store.KVPair
+		values []string
+	)
+
+	record := func(next OperationFunc) OperationFunc {
+		return func(ctx context.Context, op *Operation) error {
+			err := next(ctx, op)
+			names = append(names, op.Name+" "+op.Key)
+			errs = append(errs, err)
+			pairs = append(pairs, op.Pair)
+			return err
+		}
+	}
+	upper := func(next OperationFunc) OperationFunc {
+		return func(ctx context.Context, op *Operation) error {
+			op.Key = strings.ToUpper(op.Key)
+			values = append(values, string(op.Value))
+			return next(ctx, op)
+		}
+	}
+
+	kv := &Store{dynamoSvc: &mockedRegion{}, tableName: TestTableName, middlewares: []Middleware{record, upper}}
+
+	ctx := context.Background()
+
+	require.NoError(t, kv.Put(ctx, "key", []byte("value"), nil))
+
+	_, err := kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	deletes := &mockedConditionalDelete{}
+	kv.dynamoSvc = deletes
+	require.NoError(t, kv.Delete(ctx, "key"))
+	assert.Equal(t, "KEY", itemKey(deletes.input.Key))
+
+	// the outer middleware sees the key modified by the inner one.
+	assert.Equal(t, []string{"Put KEY", "Get KEY", "Delete KEY"}, names)
+	assert.Equal(t, []string{"value", "", ""}, values)
+	require.NotNil(t, pairs[0])
+	assert.Equal(t, "KEY", pairs[0].Key)
+	assert.Equal(t, uint64(1), pairs[0].LastIndex)
+	assert.Nil(t, pairs[1])
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], store.ErrKeyNotFound)
+}
+
+func TestMiddlewaresExtendedOperations(t *testing.T) {
+	var names []string
+	record := func(next OperationFunc) OperationFunc {
+		return func(ctx context.Context, op *Operation) error {
+			names = append(names, op.Name+" "+op.Key)
+			return next(ctx, op)
+		}
+	}
+
+	ctx := context.Background()
+
+	kv := &Store{dynamoSvc: &mockedRegion{}, tableName: TestTableName, middlewares: []Middleware{record}}
+
+	_, _, err := kv.GetWithMeta(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	_, err = kv.GetMeta(ctx, "key", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	kv.dynamoSvc = &mockedPagedScan{pages: [][]map[string]*dynamodb.AttributeValue{{newTestItem("dir/a", "dmFsdWU=")}}}
+
+	page, _, err := kv.ListPage(ctx, "dir/", 10, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir/a"}, pairKeys(page))
+
+	kv.dynamoSvc = &mockedLockTable{}
+
+	_, err = kv.putLock(ctx, "lock", nil, nil, time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Get key", "GetMeta key", "List dir/", "AtomicPut lock"}, names)
+
+	// the keys are validated after the middlewares.
+	_, err = kv.GetMeta(ctx, "", nil)
+	assert.Error(t, err)
+}
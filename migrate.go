@@ -0,0 +1,145 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// ErrMigrationWatchStopped is returned by Migrate when the watch of the source store stops before the context is done.
+var ErrMigrationWatchStopped = errors.New("migration source watch stopped")
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// Watch keeps copying the changes of the source keys after the initial copy, until the context is done,
+	// so the clients can be switched to the store without a write freeze.
+	Watch bool
+}
+
+// Migrate copies the keys starting with prefix from src, another store such as etcd, Consul, or Redis, to the store,
+// replacing their values if they exist.
+// The keys are written like PutMany, running through the middlewares as OperationMigrate operations,
+// at their next revision in the store, without expiration time, and the deletions are copied with DeleteMany.
+// With the Watch option, the source keys are watched before the initial copy,
+// then their changes and deletions are copied until the context is done, and Migrate returns nil.
+// If some keys are not written, the others are, and a *BatchWriteError is returned.
+func (ddb *Store) Migrate(ctx context.Context, src store.Store, prefix string, opts *MigrateOptions) error {
+	var events <-chan []*store.KVPair
+
+	if opts != nil && opts.Watch {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var err error
+		events, err = src.WatchTree(watchCtx, prefix, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	pairs, err := src.List(ctx, prefix, nil)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+
+	if err = ddb.migratePairs(ctx, pairs); err != nil {
+		return err
+	}
+
+	if events == nil {
+		return nil
+	}
+
+	return ddb.followTree(ctx, events, pairs)
+}
+
+// followTree copies the changes of the source trees received from events, compared to the pairs already copied,
+// until the context is done.
+func (ddb *Store) followTree(ctx context.Context, events <-chan []*store.KVPair, pairs []*store.KVPair) error {
+	copied := make(map[string]*store.KVPair, len(pairs))
+	for _, pair := range pairs {
+		copied[pair.Key] = pair
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case tree, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return ErrMigrationWatchStopped
+			}
+
+			if err := ddb.migrateTree(ctx, tree, copied); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// migrateTree copies the pairs of tree changed since copied, deletes the keys of copied no longer in tree,
+// and updates copied.
+func (ddb *Store) migrateTree(ctx context.Context, tree []*store.KVPair, copied map[string]*store.KVPair) error {
+	var changed []*store.KVPair
+
+	current := make(map[string]*store.KVPair, len(tree))
+	for _, pair := range tree {
+		current[pair.Key] = pair
+
+		previous, ok := copied[pair.Key]
+		if !ok || previous.LastIndex != pair.LastIndex || !bytes.Equal(previous.Value, pair.Value) {
+			changed = append(changed, pair)
+		}
+	}
+
+	var deleted []string
+	for key := range copied {
+		if _, ok := current[key]; !ok {
+			deleted = append(deleted, key)
+		}
+	}
+
+	if err := ddb.migratePairs(ctx, changed); err != nil {
+		return err
+	}
+
+	if len(deleted) > 0 {
+		if err := ddb.DeleteMany(ctx, deleted); err != nil {
+			return err
+		}
+	}
+
+	for key := range copied {
+		delete(copied, key)
+	}
+	for key, pair := range current {
+		copied[key] = pair
+	}
+
+	return nil
+}
+
+// migratePairs writes the values of the source pairs.
+func (ddb *Store) migratePairs(ctx context.Context, pairs []*store.KVPair) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	values := make([]*store.KVPair, len(pairs))
+	for i, pair := range pairs {
+		values[i] = &store.KVPair{Key: pair.Key, Value: pair.Value}
+	}
+
+	failed := make(map[string]error)
+	ddb.putPairs(ctx, OperationMigrate, values, func(*store.KVPair) *store.WriteOptions { return nil }, failed)
+
+	return batchError(failed)
+}
@@ -0,0 +1,86 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	src := &mockedSource{
+		pairs: []*store.KVPair{
+			{Key: "app/a", Value: []byte("value1"), LastIndex: 10},
+			{Key: "app/b", Value: []byte("value2"), LastIndex: 11},
+		},
+		events: make(chan []*store.KVPair),
+	}
+	mock := &mockedBatchStore{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"app/a": newTestItem("app/a", "b2xk"),
+		},
+		failBatch: -1,
+	}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	err := kv.Migrate(ctx, src, "app/", nil)
+	require.NoError(t, err)
+	assert.Len(t, mock.written, 2)
+	assert.Equal(t, "dmFsdWUx", aws.StringValue(mock.written["app/a"][encodedValueAttribute].S))
+	assert.Equal(t, "2", aws.StringValue(mock.written["app/a"][revisionAttribute].N))
+
+	mock.written = nil
+
+	watchCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- kv.Migrate(watchCtx, src, "app/", &MigrateOptions{Watch: true}) }()
+
+	// app/a is unchanged, app/b is updated, app/c is created.
+	src.events <- []*store.KVPair{
+		{Key: "app/a", Value: []byte("value1"), LastIndex: 10},
+		{Key: "app/b", Value: []byte("value3"), LastIndex: 12},
+		{Key: "app/c", Value: []byte("value4"), LastIndex: 13},
+	}
+	// app/a is deleted, the tree being sent again once it is copied.
+	tree := []*store.KVPair{
+		{Key: "app/b", Value: []byte("value3"), LastIndex: 12},
+		{Key: "app/c", Value: []byte("value4"), LastIndex: 13},
+	}
+	src.events <- tree
+	src.events <- tree
+
+	stop()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, "dmFsdWUz", aws.StringValue(mock.written["app/b"][encodedValueAttribute].S))
+	assert.Equal(t, "dmFsdWU0", aws.StringValue(mock.written["app/c"][encodedValueAttribute].S))
+	assert.Equal(t, []string{"app/a"}, mock.deleted)
+
+	close(src.events)
+
+	err = kv.Migrate(ctx, src, "app/", &MigrateOptions{Watch: true})
+	assert.ErrorIs(t, err, ErrMigrationWatchStopped)
+}
+
+// mockedSource is a source store listing pairs, and sending the trees of events to its watchers.
+type mockedSource struct {
+	store.Store
+
+	pairs  []*store.KVPair
+	events chan []*store.KVPair
+}
+
+func (m *mockedSource) List(_ context.Context, _ string, _ *store.ReadOptions) ([]*store.KVPair, error) {
+	return m.pairs, nil
+}
+
+func (m *mockedSource) WatchTree(_ context.Context, _ string, _ *store.ReadOptions) (<-chan []*store.KVPair, error) {
+	return m.events, nil
+}
@@ -0,0 +1,54 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// Move renames the key src to dst atomically, in a transaction deleting src and creating dst.
+// The remaining time to live of src is kept.
+// It returns store.ErrKeyNotFound if src doesn't exist, store.ErrKeyExists if dst exists,
+// or store.ErrKeyModified if src is written during the move.
+// The values stored in chunks or in S3 can't be moved.
+// The transaction runs through the middlewares as an OperationMove of src, with the Keys src and dst.
+func (ddb *Store) Move(ctx context.Context, src, dst string) error {
+	src, dst = ddb.normalizeKey(src), ddb.normalizeKey(dst)
+
+	current, meta, err := ddb.GetWithMeta(ctx, src, nil)
+	if err != nil {
+		return err
+	}
+
+	opts, err := remainingTTL(meta)
+	if err != nil {
+		return err
+	}
+
+	op := &Operation{Name: OperationMove, Key: src, Keys: []string{src, dst}, Value: current.Value}
+
+	return ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) error {
+		return ddb.Transact(ctx).
+			CheckRevision(src, current.LastIndex).
+			Delete(src).
+			CheckNotExists(dst).
+			Put(dst, op.Value, opts).
+			commit(ctx)
+	})
+}
+
+// remainingTTL returns the write options keeping the remaining time to live of a key, if it expires.
+func remainingTTL(meta *KeyMeta) (*store.WriteOptions, error) {
+	if meta.ExpiresAt.IsZero() {
+		return nil, nil
+	}
+
+	ttl := time.Until(meta.ExpiresAt)
+	if ttl <= 0 {
+		// expired since it was read.
+		return nil, store.ErrKeyNotFound
+	}
+
+	return &store.WriteOptions{TTL: ttl}, nil
+}
@@ -0,0 +1,59 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMove(t *testing.T) {
+	mock := &mockedMove{item: newTestItem("a", "dmFsdWU=")}
+	mock.item[ttlAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	err := kv.Move(ctx, "a", "b")
+	require.NoError(t, err)
+
+	items := mock.input.TransactItems
+	require.Len(t, items, 2)
+
+	require.NotNil(t, items[0].Delete)
+	assert.Equal(t, "a", itemKey(items[0].Delete.Key))
+	assert.Contains(t, aws.StringValue(items[0].Delete.ConditionExpression), ":lastRevision")
+
+	require.NotNil(t, items[1].Update)
+	assert.Equal(t, "b", itemKey(items[1].Update.Key))
+	assert.Contains(t, aws.StringValue(items[1].Update.ConditionExpression), "attribute_not_exists")
+	assert.NotNil(t, items[1].Update.ExpressionAttributeValues[":ttl"])
+
+	mock.failedIndex = aws.Int(1)
+
+	err = kv.Move(ctx, "a", "b")
+	assert.ErrorIs(t, err, store.ErrKeyExists)
+
+	mock.item = nil
+
+	err = kv.Move(ctx, "a", "b")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+// mockedMove returns item on reads, and records the transactions.
+type mockedMove struct {
+	mockedTransactWrite
+
+	item map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockedMove) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.item}, nil
+}
@@ -0,0 +1,82 @@
+package dynamodb
+
+import (
+	"strings"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// KeyNormalization configures the normalization of the keys and prefixes given to the store,
+// so the different spellings of a key, such as "/foo//bar" and "foo/bar", address the same item.
+// The keys are normalized before being stored, so it must be enabled before writing to the table,
+// the keys written without normalization being unreachable by their normalized spelling.
+type KeyNormalization struct {
+	// TrimLeadingSlash removes the leading slashes of the keys.
+	TrimLeadingSlash bool
+	// CollapseSlashes replaces the consecutive slashes of the keys by a single one.
+	CollapseSlashes bool
+	// DirectorySuffix removes the trailing slashes of the keys,
+	// and ends the prefixes of List, WatchTree, and DeleteTree with a slash,
+	// so a prefix only matches the keys under its directory: "foo" and "foo/" match "foo/bar", but not "foobar".
+	DirectorySuffix bool
+}
+
+// normalizeKey returns key normalized by the key normalization of the store.
+func (ddb *Store) normalizeKey(key string) string {
+	n := ddb.keyNormalization
+	if n == nil {
+		return key
+	}
+
+	if n.CollapseSlashes {
+		for strings.Contains(key, "//") {
+			key = strings.ReplaceAll(key, "//", "/")
+		}
+	}
+
+	if n.TrimLeadingSlash {
+		key = strings.TrimLeft(key, "/")
+	}
+
+	if n.DirectorySuffix {
+		key = strings.TrimRight(key, "/")
+	}
+
+	return key
+}
+
+// normalizePrefix returns prefix normalized by the key normalization of the store,
+// ending with a slash with the directory suffix convention, unless it's the empty prefix of all the keys.
+func (ddb *Store) normalizePrefix(prefix string) string {
+	prefix = ddb.normalizeKey(prefix)
+
+	if ddb.keyNormalization != nil && ddb.keyNormalization.DirectorySuffix && prefix != "" {
+		prefix += "/"
+	}
+
+	return prefix
+}
+
+// normalizeKeys returns keys normalized by the key normalization of the store.
+func (ddb *Store) normalizeKeys(keys []string) []string {
+	normalized := make([]string, len(keys))
+	for i, key := range keys {
+		normalized[i] = ddb.normalizeKey(key)
+	}
+
+	return normalized
+}
+
+// normalizePairs returns pairs with their keys normalized by the key normalization of the store,
+// copying the pairs whose key changes.
+func (ddb *Store) normalizePairs(pairs []*store.KVPair) []*store.KVPair {
+	normalized := make([]*store.KVPair, len(pairs))
+	for i, pair := range pairs {
+		normalized[i] = pair
+		if key := ddb.normalizeKey(pair.Key); key != pair.Key {
+			normalized[i] = &store.KVPair{Key: key, Value: pair.Value, LastIndex: pair.LastIndex}
+		}
+	}
+
+	return normalized
+}
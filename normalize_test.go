@@ -0,0 +1,47 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyNormalization(t *testing.T) {
+	kv := &Store{tableName: TestTableName}
+
+	// the keys are kept as given without normalization.
+	assert.Equal(t, "/foo//bar/", kv.normalizeKey("/foo//bar/"))
+	assert.Equal(t, "foo", kv.normalizePrefix("foo"))
+
+	kv.keyNormalization = &KeyNormalization{TrimLeadingSlash: true, CollapseSlashes: true}
+	assert.Equal(t, "foo/bar/", kv.normalizeKey("//foo///bar/"))
+	assert.Equal(t, "foo", kv.normalizePrefix("/foo"))
+
+	kv.keyNormalization.DirectorySuffix = true
+	assert.Equal(t, "foo/bar", kv.normalizeKey("/foo//bar//"))
+	assert.Equal(t, "foo/", kv.normalizePrefix("foo"))
+	assert.Equal(t, "foo/", kv.normalizePrefix("/foo/"))
+	assert.Equal(t, "", kv.normalizePrefix("/"))
+
+	kv.keyPrefix = "app/"
+	assert.Equal(t, "app/foo/bar", kv.storedKey("/foo/bar"))
+	assert.Equal(t, "app/foo/", kv.storedPrefix("foo"))
+
+	scan := kv.prefixScan("/foo", true)
+	assert.Equal(t, "app/foo/", aws.StringValue(scan.ExpressionAttributeValues[":namePrefix"].S))
+
+	// the operations address the normalized key, and return it.
+	mock := &mockedProjectedGet{item: newTestItem("app/foo/bar", "dmFsdWU=")}
+	kv.dynamoSvc = mock
+
+	pair, err := kv.Get(context.Background(), "//foo/bar/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "app/foo/bar", aws.StringValue(mock.input.Key[partitionKey].S))
+	assert.Equal(t, "foo/bar", pair.Key)
+
+	err = kv.Put(context.Background(), "/", []byte("value"), nil)
+	assert.ErrorIs(t, err, ErrEmptyKey)
+}
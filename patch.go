@@ -0,0 +1,334 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+var (
+	// ErrDocumentModeDisabled is returned when patching a document without the document mode.
+	ErrDocumentModeDisabled = errors.New("document mode is disabled")
+	// ErrInvalidDocumentPath is returned when the path of a patch can't be parsed.
+	ErrInvalidDocumentPath = errors.New("invalid document path")
+
+	// errPatchConflict the document was modified while patching it with the history.
+	errPatchConflict = errors.New("document modified during patch")
+)
+
+// PatchJSON sets the field at path of the document of key to the JSON value, in a single update of the document mode,
+// so the concurrent patches of different fields don't overwrite each other, and returns the patched pair.
+// The path holds the field names separated by dots, with the list indexes in brackets, such as "limits.cpu" or "tags[0]";
+// the field names with dots or brackets can't be patched.
+// The parent of the field must exist, the field is created if it doesn't.
+// It returns store.ErrKeyNotFound if the key doesn't exist, is expired, or is not stored as a document.
+// With the history, the document is read and patched client-side, then written conditioned on its revision.
+// It runs through the middlewares as an OperationPatchJSON, with the JSON value.
+func (ddb *Store) PatchJSON(ctx context.Context, key, path string, value []byte) (*store.KVPair, error) {
+	op := &Operation{Name: OperationPatchJSON, Key: key, Value: value}
+
+	err := ddb.runOperation(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Pair, err = ddb.patchJSON(ctx, op.Key, path, op.Value)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return op.Pair, nil
+}
+
+// patchJSON sets the field at path of the document of key to the JSON value, and returns the patched pair.
+func (ddb *Store) patchJSON(ctx context.Context, key, path string, value []byte) (*store.KVPair, error) {
+	codec, ok := ddb.codec.(*documentCodec)
+	if !ok {
+		return nil, ErrDocumentModeDisabled
+	}
+
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Write)
+	defer cancel()
+
+	exNames := map[string]*string{
+		revisionNamePlaceholder: aws.String(ddb.revisionName()),
+		valueNamePlaceholder:    aws.String(ddb.valueName()),
+		ttlNamePlaceholder:      aws.String(ddb.ttlName()),
+	}
+
+	fieldPath, err := documentPath(path, exNames)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := codec.encodeJSON(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if ddb.history != nil {
+		return ddb.patchRecorded(ctx, key, path, value)
+	}
+
+	exAttr := map[string]*dynamodb.AttributeValue{
+		":incr":    {N: aws.String("1")},
+		":patch":   patch,
+		":map":     {S: aws.String("M")},
+		":timeNow": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+	}
+
+	setList := appendTimestamps([]string{fmt.Sprintf("%s.%s = :patch", valueNamePlaceholder, fieldPath)}, exAttr, exNames)
+
+	res, err := ddb.dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(ddb.tableName),
+		Key:                       ddb.keyAttributes(key),
+		ExpressionAttributeNames:  exNames,
+		ExpressionAttributeValues: exAttr,
+		UpdateExpression: aws.String(fmt.Sprintf("ADD %s :incr SET %s",
+			revisionNamePlaceholder, strings.Join(setList, ","))),
+		// the document exists, and is not expired.
+		ConditionExpression: aws.String(fmt.Sprintf("attribute_type(%s, :map) AND (attribute_not_exists(%s) OR %s > :timeNow)",
+			valueNamePlaceholder, ttlNamePlaceholder, ttlNamePlaceholder)),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			if awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				return nil, store.ErrKeyNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return ddb.decodeItem(res.Attributes)
+}
+
+// patchRecorded patches the document of key client-side, and writes it with its revision recorded in the history,
+// conditioned on the revision of the document patched, the patch being applied again to the new document on conflict.
+func (ddb *Store) patchRecorded(ctx context.Context, key, path string, value []byte) (*store.KVPair, error) {
+	var patch interface{}
+	if err := decodeJSONNumbers(value, &patch); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxHistoryWriteAttempts; i++ {
+		res, err := ddb.getKey(ctx, key, &store.ReadOptions{Consistent: true})
+		if err != nil {
+			return nil, err
+		}
+
+		current := res.Item
+		if current == nil || ddb.isItemExpired(current) || current[ddb.valueName()] == nil || current[ddb.valueName()].M == nil {
+			return nil, store.ErrKeyNotFound
+		}
+
+		pair, err := ddb.decodeItem(current)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc interface{}
+		if err = decodeJSONNumbers(pair.Value, &doc); err != nil {
+			return nil, err
+		}
+
+		if doc, err = setDocumentPath(doc, path, patch); err != nil {
+			return nil, err
+		}
+
+		patched, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		data, enc, err := ddb.encodeValue(ctx, key, patched)
+		if err != nil {
+			return nil, err
+		}
+
+		attrs, err := ddb.storeAttributes(ctx, key, data, enc)
+		if err != nil {
+			return nil, err
+		}
+
+		written, err := ddb.putRecorded(ctx, key, patched, data, enc, attrs, nil, func(latest map[string]*dynamodb.AttributeValue) error {
+			if aws.StringValue(latest[ddb.revisionName()].N) != aws.StringValue(current[ddb.revisionName()].N) {
+				return errPatchConflict
+			}
+			return nil
+		})
+		if errors.Is(err, errPatchConflict) {
+			continue
+		}
+
+		return written, err
+	}
+
+	return nil, store.ErrKeyModified
+}
+
+// decodeJSONNumbers decodes the JSON data into v, with its numbers kept as is.
+func decodeJSONNumbers(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: not JSON: %v", ErrCodecInvalidValue, err)
+	}
+
+	return nil
+}
+
+// setDocumentPath sets the field at path of doc, a decoded JSON document, to v, like the update of PatchJSON:
+// the parent of the field must exist, and an index past the end of a list appends v to the list.
+// The path must have been checked by documentPath.
+func setDocumentPath(doc interface{}, path string, v interface{}) (interface{}, error) {
+	var steps []interface{}
+
+	for _, field := range strings.Split(path, ".") {
+		name, indexes := field, ""
+		if pos := strings.IndexByte(field, '['); pos >= 0 {
+			name, indexes = field[:pos], field[pos:]
+		}
+
+		steps = append(steps, name)
+
+		for indexes != "" {
+			end := strings.IndexByte(indexes, ']')
+			index, _ := strconv.Atoi(indexes[1:end])
+			steps = append(steps, index)
+			indexes = indexes[end+1:]
+		}
+	}
+
+	doc, ok := setDocumentSteps(doc, steps, v)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q not in the document", ErrInvalidDocumentPath, path)
+	}
+
+	return doc, nil
+}
+
+// setDocumentSteps sets the element of node at the field names and list indexes of steps to v,
+// and reports whether the parent of the element exists.
+func setDocumentSteps(node interface{}, steps []interface{}, v interface{}) (interface{}, bool) {
+	if len(steps) == 0 {
+		return v, true
+	}
+
+	switch step := steps[0].(type) {
+	case string:
+		fields, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		child, exists := fields[step]
+		if !exists && len(steps) > 1 {
+			return nil, false
+		}
+
+		if fields[step], ok = setDocumentSteps(child, steps[1:], v); !ok {
+			return nil, false
+		}
+
+		return fields, true
+
+	default:
+		index := step.(int)
+
+		list, ok := node.([]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if index >= len(list) {
+			if len(steps) > 1 {
+				return nil, false
+			}
+			return append(list, v), true
+		}
+
+		if list[index], ok = setDocumentSteps(list[index], steps[1:], v); !ok {
+			return nil, false
+		}
+
+		return list, true
+	}
+}
+
+// encodeJSON returns the attribute value of the JSON value, with its numbers stored as is.
+func (c *documentCodec) encodeJSON(value []byte) (*dynamodb.AttributeValue, error) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: not JSON: %v", ErrCodecInvalidValue, err)
+	}
+
+	if dec.More() {
+		return nil, fmt.Errorf("%w: not JSON", ErrCodecInvalidValue)
+	}
+
+	return c.encoder.Encode(attributeNumbers(v))
+}
+
+// documentPath returns the document path expression of path, adding the placeholders of its field names to exNames.
+func documentPath(path string, exNames map[string]*string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("%w: empty path", ErrInvalidDocumentPath)
+	}
+
+	fields := strings.Split(path, ".")
+	elements := make([]string, 0, len(fields))
+
+	for i, field := range fields {
+		name := field
+		var indexes string
+
+		if pos := strings.IndexByte(field, '['); pos >= 0 {
+			name, indexes = field[:pos], field[pos:]
+		}
+
+		if name == "" || strings.ContainsRune(name, ']') {
+			return "", fmt.Errorf("%w: %q", ErrInvalidDocumentPath, path)
+		}
+
+		if err := checkListIndexes(indexes); err != nil {
+			return "", fmt.Errorf("%w: %q", err, path)
+		}
+
+		placeholder := fmt.Sprintf("#field%d", i)
+		exNames[placeholder] = aws.String(name)
+		elements = append(elements, placeholder+indexes)
+	}
+
+	return strings.Join(elements, "."), nil
+}
+
+// checkListIndexes checks indexes is a sequence of list indexes in brackets, such as "[0][2]".
+func checkListIndexes(indexes string) error {
+	for indexes != "" {
+		end := strings.IndexByte(indexes, ']')
+		if indexes[0] != '[' || end < 0 {
+			return ErrInvalidDocumentPath
+		}
+
+		if _, err := strconv.ParseUint(indexes[1:end], 10, 32); err != nil {
+			return ErrInvalidDocumentPath
+		}
+
+		indexes = indexes[end+1:]
+	}
+
+	return nil
+}
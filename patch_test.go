@@ -0,0 +1,85 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchJSON(t *testing.T) {
+	mock := &mockedPatch{item: map[string]*dynamodb.AttributeValue{
+		partitionKey:      {S: aws.String("config")},
+		revisionAttribute: {N: aws.String("1")},
+		encodedValueAttribute: {M: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String("valkeyrie")},
+		}},
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	_, err := kv.PatchJSON(ctx, "config", "replicas", []byte("3"))
+	assert.ErrorIs(t, err, ErrDocumentModeDisabled)
+
+	kv.codec = NewDocumentCodec(encodedValueAttribute)
+
+	pair, err := kv.PatchJSON(ctx, "config", "replicas", []byte("3"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"valkeyrie","replicas":3}`, string(pair.Value))
+	assert.Equal(t, uint64(2), pair.LastIndex)
+	assert.Equal(t, "ADD #revision :incr SET #value.#field0 = :patch,"+
+		"#createdAt = if_not_exists(#createdAt, :now),#updatedAt = :now", aws.StringValue(mock.input.UpdateExpression))
+
+	_, err = kv.PatchJSON(ctx, "config", "limits.cpu", []byte(`{"max":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, "ADD #revision :incr SET #value.#field0.#field1 = :patch,"+
+		"#createdAt = if_not_exists(#createdAt, :now),#updatedAt = :now", aws.StringValue(mock.input.UpdateExpression))
+	assert.Equal(t, "cpu", aws.StringValue(mock.input.ExpressionAttributeNames["#field1"]))
+	assert.Equal(t, "2", aws.StringValue(mock.input.ExpressionAttributeValues[":patch"].M["max"].N))
+
+	_, err = kv.PatchJSON(ctx, "config", "tags[1][0].name", []byte(`"a"`))
+	require.NoError(t, err)
+	assert.Contains(t, aws.StringValue(mock.input.UpdateExpression), "SET #value.#field0[1][0].#field1 = :patch,")
+
+	for _, path := range []string{"", "a..b", "[0]", "a[x]", "a[1", "a]"} {
+		_, err = kv.PatchJSON(ctx, "config", path, []byte("1"))
+		assert.ErrorIs(t, err, ErrInvalidDocumentPath, path)
+	}
+
+	_, err = kv.PatchJSON(ctx, "config", "name", []byte("{"))
+	assert.ErrorIs(t, err, ErrCodecInvalidValue)
+
+	_, err = kv.PatchJSON(ctx, "missing", "name", []byte(`"a"`))
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+// mockedPatch holds a single document, and applies the patches of its top level fields.
+type mockedPatch struct {
+	dynamodbiface.DynamoDBAPI
+	item  map[string]*dynamodb.AttributeValue
+	input *dynamodb.UpdateItemInput
+}
+
+func (m *mockedPatch) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.input = input
+
+	if aws.StringValue(input.Key[partitionKey].S) != aws.StringValue(m.item[partitionKey].S) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	}
+
+	if _, ok := input.ExpressionAttributeNames["#field1"]; !ok {
+		field := aws.StringValue(input.ExpressionAttributeNames["#field0"])
+		m.item[encodedValueAttribute].M[field] = input.ExpressionAttributeValues[":patch"]
+		m.item[revisionAttribute] = &dynamodb.AttributeValue{N: aws.String("2")}
+	}
+
+	return &dynamodb.UpdateItemOutput{Attributes: m.item}, nil
+}
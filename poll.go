@@ -0,0 +1,120 @@
+package dynamodb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// pollNotifier is a Notifier polling the keys under the watched prefix.
+// It's not real-time, but works with any table.
+type pollNotifier struct {
+	ddb      *Store
+	interval time.Duration
+}
+
+// Subscribe returns a channel receiving the events on keys starting with prefix.
+func (n *pollNotifier) Subscribe(ctx context.Context, prefix string) (<-chan *Event, error) {
+	revisions, err := n.revisions(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan *Event)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				current, err := n.revisions(ctx, prefix)
+				if err != nil {
+					// transient failure: try again at the next tick.
+					n.ddb.log().Info("watch poll failed", "prefix", prefix, "error", err)
+					continue
+				}
+
+				for _, event := range diffRevisions(revisions, current) {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				revisions = current
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Publish does nothing: changes are detected by polling.
+func (n *pollNotifier) Publish(_ context.Context, _ *Event) error {
+	return nil
+}
+
+// Close does nothing: subscriptions end with their context.
+func (n *pollNotifier) Close() error {
+	return nil
+}
+
+// revisions returns the revision of each non-expired key starting with prefix.
+// The prefix is already normalized by Watch or WatchTree: normalizing it again would end the key of a Watch with a slash.
+func (n *pollNotifier) revisions(ctx context.Context, prefix string) (map[string]string, error) {
+	items, err := n.ddb.storedPrefixItems(ctx, n.ddb.keyPrefix+prefix, true)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make(map[string]string, len(items))
+	for _, item := range items {
+		if n.ddb.isItemExpired(item) || isChunkKey(n.ddb.itemKey(item)) {
+			continue
+		}
+
+		var revision string
+		if v, ok := item[n.ddb.revisionName()]; ok {
+			revision = aws.StringValue(v.N)
+		}
+
+		revisions[n.ddb.itemKey(item)] = revision
+	}
+
+	return revisions, nil
+}
+
+// diffRevisions returns the events on the keys created, updated, or deleted between two polls.
+func diffRevisions(previous, current map[string]string) []*Event {
+	var events []*Event
+
+	for key, revision := range current {
+		previousRevision, ok := previous[key]
+		switch {
+		case !ok:
+			events = append(events, &Event{Key: key, Type: EventCreate})
+		case previousRevision != revision:
+			events = append(events, &Event{Key: key, Type: EventUpdate})
+		}
+	}
+
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			events = append(events, &Event{Key: key, Type: EventDelete})
+		}
+	}
+
+	// keep the events in a stable order.
+	sort.Slice(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+
+	return events
+}
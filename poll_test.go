@@ -0,0 +1,127 @@
+package dynamodb
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStorePollWatch(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+	ddbStore.notifier = &pollNotifier{ddb: ddbStore, interval: 100 * time.Millisecond}
+
+	testsuite.RunTestWatch(t, ddbStore)
+}
+
+func TestDiffRevisions(t *testing.T) {
+	previous := map[string]string{
+		"a": "1",
+		"b": "2",
+		"c": "1",
+	}
+	current := map[string]string{
+		"a": "1",
+		"b": "3",
+		"d": "1",
+	}
+
+	expected := []*Event{
+		{Key: "b", Type: EventUpdate},
+		{Key: "c", Type: EventDelete},
+		{Key: "d", Type: EventCreate},
+	}
+	assert.Equal(t, expected, diffRevisions(previous, current))
+	assert.Empty(t, diffRevisions(current, current))
+}
+
+func TestPollWatchDirectorySuffix(t *testing.T) {
+	mock := &mockedPollTable{items: map[string]map[string]*dynamodb.AttributeValue{
+		"foo/bar": newTestItem("foo/bar", "dmFsdWUx"),
+	}}
+	kv := &Store{
+		dynamoSvc:        mock,
+		tableName:        TestTableName,
+		keyNormalization: &KeyNormalization{DirectorySuffix: true},
+	}
+	kv.notifier = &pollNotifier{ddb: kv, interval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	pairs, err := kv.Watch(ctx, "foo/bar/", nil)
+	require.NoError(t, err)
+
+	pair := <-pairs
+	assert.Equal(t, []byte("value1"), pair.Value)
+
+	mock.set(newTestItem("foo/bar", "dmFsdWUy"), "2")
+
+	select {
+	case pair := <-pairs:
+		assert.Equal(t, []byte("value2"), pair.Value)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change of the watched key not detected")
+	}
+
+	// the key of the watch is polled as is, not as a directory.
+	assert.Equal(t, "foo/bar", mock.lastPrefix())
+}
+
+// mockedPollTable serves the items by key, and the scans of the items starting with the scanned prefix.
+type mockedPollTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu       sync.Mutex
+	items    map[string]map[string]*dynamodb.AttributeValue
+	prefixes []string
+}
+
+func (m *mockedPollTable) set(item map[string]*dynamodb.AttributeValue, revision string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item[revisionAttribute] = &dynamodb.AttributeValue{N: aws.String(revision)}
+	m.items[itemKey(item)] = item
+}
+
+func (m *mockedPollTable) lastPrefix() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.prefixes[len(m.prefixes)-1]
+}
+
+func (m *mockedPollTable) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &dynamodb.GetItemOutput{Item: m.items[itemKey(input.Key)]}, nil
+}
+
+func (m *mockedPollTable) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix := aws.StringValue(input.ExpressionAttributeValues[":namePrefix"].S)
+	m.prefixes = append(m.prefixes, prefix)
+
+	page := &dynamodb.ScanOutput{}
+	for key, item := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			page.Items = append(page.Items, item)
+		}
+	}
+	fn(page, true)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package dynamodb
+
+import "context"
+
+// publishMiddleware returns the middleware publishing the successful writes to the notifier.
+// A failed publication is logged, the write having succeeded.
+func (ddb *Store) publishMiddleware() Middleware {
+	return func(next OperationFunc) OperationFunc {
+		return func(ctx context.Context, op *Operation) error {
+			err := next(ctx, op)
+			if err != nil {
+				return err
+			}
+
+			for _, event := range writeEvents(op) {
+				if err := ddb.getNotifier().Publish(ctx, event); err != nil {
+					ddb.log().Error("write event publication failed", "key", event.Key, "operation", op.Name, "error", err)
+				}
+			}
+
+			return nil
+		}
+	}
+}
+
+// writeEvents returns the events of a successful write, an event by key for the batches of DeleteMany.
+func writeEvents(op *Operation) []*Event {
+	if !isBatch(op) {
+		if event := writeEvent(op); event != nil {
+			return []*Event{event}
+		}
+		return nil
+	}
+
+	events := make([]*Event, len(op.Keys))
+	for i, key := range op.Keys {
+		events[i] = writeEvent(&Operation{Name: op.Name, Key: key})
+	}
+
+	return events
+}
+
+// writeEvent returns the event of a successful write, nil if the operation doesn't write a single key.
+// The type of a Put is unknown, as it creates or updates the key.
+// The pairs are copied, so the subscribers don't share the pairs of the writer.
+func writeEvent(op *Operation) *Event {
+	switch op.Name {
+	case OperationPut, OperationPutMany, OperationCopy, OperationCopyTree, OperationImport, OperationMigrate:
+		return &Event{Key: op.Key, New: copyPair(op.Pair)}
+	case OperationPatchJSON:
+		return &Event{Key: op.Key, Type: EventUpdate, New: copyPair(op.Pair)}
+	case OperationAtomicPut:
+		if op.Pair == nil {
+			return nil
+		}
+
+		event := &Event{Key: op.Key, Type: EventUpdate, Old: copyPair(op.Previous), New: copyPair(op.Pair)}
+		if op.Previous == nil {
+			event.Type = EventCreate
+		}
+
+		return event
+	case OperationDelete, OperationDeleteMany:
+		return &Event{Key: op.Key, Type: EventDelete}
+	case OperationAtomicDelete:
+		return &Event{Key: op.Key, Type: EventDelete, Old: copyPair(op.Previous)}
+	default:
+		return nil
+	}
+}
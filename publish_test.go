@@ -0,0 +1,104 @@
+package dynamodb
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishWrites(t *testing.T) {
+	notifier := &recordingNotifier{}
+
+	kv := &Store{
+		dynamoSvc: &mockedLockTable{},
+		tableName: TestTableName,
+		notifier:  notifier,
+	}
+	kv.middlewares = []Middleware{kv.publishMiddleware()}
+
+	ctx := context.Background()
+
+	_, created, err := kv.AtomicPut(ctx, "key", []byte("value1"), nil, nil)
+	require.NoError(t, err)
+
+	// the failed writes aren't published.
+	_, _, err = kv.AtomicPut(ctx, "key", []byte("value1"), nil, nil)
+	require.Error(t, err)
+
+	_, updated, err := kv.AtomicPut(ctx, "key", []byte("value2"), created, nil)
+	require.NoError(t, err)
+
+	_, err = kv.AtomicDelete(ctx, "key", updated)
+	require.NoError(t, err)
+
+	events := notifier.recorded()
+	require.Len(t, events, 3)
+
+	// the keys of PutMany are written concurrently.
+	sort.Slice(events[:2], func(i, j int) bool { return events[i].Key < events[j].Key })
+
+	assert.Equal(t, &Event{Key: "key", Type: EventCreate, New: created}, events[0])
+	assert.Equal(t, &Event{Key: "key", Type: EventUpdate, Old: created, New: updated}, events[1])
+	assert.Equal(t, &Event{Key: "key", Type: EventDelete, Old: updated}, events[2])
+}
+
+func TestPublishBatch(t *testing.T) {
+	notifier := &recordingNotifier{}
+
+	kv := &Store{
+		dynamoSvc: &mockedBatchStore{failBatch: -1},
+		tableName: TestTableName,
+		notifier:  notifier,
+	}
+	kv.middlewares = []Middleware{kv.publishMiddleware()}
+
+	ctx := context.Background()
+
+	// an event is published for each key written.
+	err := kv.PutMany(ctx, []*store.KVPair{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, kv.DeleteMany(ctx, []string{"a"}))
+
+	events := notifier.recorded()
+	require.Len(t, events, 3)
+
+	// the keys of PutMany are written concurrently.
+	sort.Slice(events[:2], func(i, j int) bool { return events[i].Key < events[j].Key })
+
+	assert.Equal(t, &Event{Key: "a", New: &store.KVPair{Key: "a", Value: []byte("1"), LastIndex: 1}}, events[0])
+	assert.Equal(t, &Event{Key: "b", New: &store.KVPair{Key: "b", Value: []byte("2"), LastIndex: 1}}, events[1])
+	assert.Equal(t, &Event{Key: "a", Type: EventDelete}, events[2])
+}
+
+// recordingNotifier records the published events.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (n *recordingNotifier) Subscribe(_ context.Context, _ string) (<-chan *Event, error) {
+	return make(chan *Event), nil
+}
+
+func (n *recordingNotifier) Publish(_ context.Context, event *Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.events = append(n.events, event)
+
+	return nil
+}
+
+func (n *recordingNotifier) recorded() []*Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.events
+}
+
+func (n *recordingNotifier) Close() error { return nil }
@@ -0,0 +1,125 @@
+package dynamodb
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// RateLimit limits the capacity consumed by the store, so bulk operations such as List, DeleteTree, and PutMany
+// don't starve the other clients of the table, or cause sustained throttling.
+// The calls wait for the budget to be positive, then the capacity they consumed is taken from the budget.
+// The calls not reporting their consumed capacity, such as the calls served by DAX, take one unit.
+type RateLimit struct {
+	// ReadCapacityUnits is the budget of the reads, in capacity units per second. Zero doesn't limit the reads.
+	ReadCapacityUnits float64
+
+	// WriteCapacityUnits is the budget of the writes, in capacity units per second. Zero doesn't limit the writes.
+	WriteCapacityUnits float64
+
+	// Burst is the budget accumulated while idle, in seconds of budget, defaults to 1.
+	Burst float64
+}
+
+// tokenBucket is a token bucket of capacity units, allowing a negative balance paid back before the next call.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burstSeconds float64) *tokenBucket {
+	if burstSeconds <= 0 {
+		burstSeconds = 1
+	}
+
+	burst := rate * burstSeconds
+
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// refill adds the tokens accumulated since the last refill. It must be called with the lock held.
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait waits for the balance to be positive, or ctx to be done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		b.refill()
+		tokens := b.tokens
+		b.mu.Unlock()
+
+		if tokens > 0 {
+			return nil
+		}
+
+		delay := time.Duration((-tokens/b.rate)*float64(time.Second)) + time.Millisecond
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take takes units from the balance.
+func (b *tokenBucket) take(units float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens -= units
+}
+
+// rateLimitInterceptor returns the interceptor limiting the capacity consumed by the reads and writes.
+func rateLimitInterceptor(limit *RateLimit) callInterceptor {
+	var reads, writes *tokenBucket
+	if limit.ReadCapacityUnits > 0 {
+		reads = newTokenBucket(limit.ReadCapacityUnits, limit.Burst)
+	}
+	if limit.WriteCapacityUnits > 0 {
+		writes = newTokenBucket(limit.WriteCapacityUnits, limit.Burst)
+	}
+
+	return func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error) {
+		var bucket *tokenBucket
+		switch {
+		case isReadOperation(call.operation):
+			bucket = reads
+		case isWriteOperation(call.operation):
+			bucket = writes
+		}
+
+		if bucket == nil {
+			return next(ctx)
+		}
+
+		if err := bucket.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		returnConsumedCapacity(call.input)
+
+		out, err := next(ctx)
+
+		units := consumedCapacity(out)
+		if units == 0 && err == nil {
+			units = 1
+		}
+		bucket.take(units)
+
+		return out, err
+	}
+}
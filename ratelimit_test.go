@@ -0,0 +1,54 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(100, 0.01)
+	require.NoError(t, bucket.wait(context.Background()))
+
+	// the 2 units of debt are paid back in 20ms.
+	bucket.take(3)
+
+	start := time.Now()
+	require.NoError(t, bucket.wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+
+	bucket.take(100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, bucket.wait(ctx), context.DeadlineExceeded)
+}
+
+func TestRateLimit(t *testing.T) {
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient: &mockedCapacity{},
+		StreamsClient:  &mockedStreams{},
+		// a budget of 1 unit, the reads consuming 1.5 units.
+		RateLimit: &RateLimit{ReadCapacityUnits: 10, Burst: 0.1},
+	}))
+
+	ctx := context.Background()
+
+	_, err := kv.Get(ctx, "key", nil)
+	require.NoError(t, err)
+
+	// the writes are not limited.
+	start := time.Now()
+	require.NoError(t, kv.Put(ctx, "key", []byte("value"), nil))
+	assert.Less(t, time.Since(start), 40*time.Millisecond)
+
+	// the read waits for the 0.5 unit of debt to be paid back.
+	_, err = kv.Get(ctx, "key", nil)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
@@ -0,0 +1,209 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	defaultRetryBaseDelay = 50 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+	// defaultThrottlingBackoff the suggested delay before retrying a throttled call,
+	// leaving DynamoDB time to refill the capacity of the partition.
+	defaultThrottlingBackoff = 500 * time.Millisecond
+)
+
+// ErrorClass is the class of an error of a DynamoDB call, selecting its retry policy.
+type ErrorClass int
+
+const (
+	// ErrorClassPermanent the call can't succeed if retried.
+	ErrorClassPermanent ErrorClass = iota
+	// ErrorClassTransient the call failed on a transient server or network error.
+	ErrorClassTransient
+	// ErrorClassThrottling the call was throttled.
+	ErrorClassThrottling
+)
+
+// RetryPolicy is the retry policy of the DynamoDB calls of the store,
+// applied on top of the retries of the AWS SDK.
+// The paginated reads are resumed after the last page read.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call failed with a transient error,
+	// including the first one. Values lower than 2 disable the retries.
+	MaxAttempts int
+
+	// ThrottlingMaxAttempts is the maximum number of attempts of a throttled call, defaults to MaxAttempts.
+	ThrottlingMaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubled at each attempt with full jitter, defaults to 50ms.
+	BaseDelay time.Duration
+
+	// ThrottlingBaseDelay is the BaseDelay of the throttled calls, defaults to BaseDelay.
+	ThrottlingBaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts, defaults to 5s.
+	MaxDelay time.Duration
+
+	// Classify returns the class of an error, defaults to ClassifyError.
+	Classify func(err error) ErrorClass
+}
+
+// ClassifyError returns the class of an error of a DynamoDB call:
+// the throttling and the retryable errors of the AWS SDK, the server errors, or permanent.
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassPermanent
+	case request.IsErrorThrottle(err) || errors.Is(err, ErrThrottled):
+		return ErrorClassThrottling
+	case request.IsErrorRetryable(err) || isServerError(err):
+		return ErrorClassTransient
+	default:
+		return ErrorClassPermanent
+	}
+}
+
+// IsRetryable reports whether a call failed with err may succeed if retried: a throttling or a transient error.
+func IsRetryable(err error) bool {
+	return ClassifyError(err) != ErrorClassPermanent
+}
+
+// ErrorInfo describes an error of a DynamoDB call to the retry loops built around the store.
+type ErrorInfo struct {
+	// Class is the class of the error, returned by ClassifyError.
+	Class ErrorClass
+	// Retryable reports whether the call may succeed if retried.
+	Retryable bool
+	// Throttled reports whether the call was throttled, and must be retried at a lower rate.
+	Throttled bool
+	// Network reports whether the call failed to reach DynamoDB, or to read its response.
+	Network bool
+	// Backoff is the suggested delay before the first retry, 0 for the permanent errors.
+	// The later retries should double it, with jitter.
+	Backoff time.Duration
+}
+
+// DescribeError returns the retry metadata of an error of a DynamoDB call.
+func DescribeError(err error) ErrorInfo {
+	info := ErrorInfo{Class: ClassifyError(err)}
+
+	switch info.Class {
+	case ErrorClassThrottling:
+		info.Retryable, info.Throttled, info.Backoff = true, true, defaultThrottlingBackoff
+	case ErrorClassTransient:
+		info.Retryable, info.Network, info.Backoff = true, isNetworkError(err), defaultRetryBaseDelay
+	case ErrorClassPermanent:
+	}
+
+	return info
+}
+
+// isNetworkError reports whether err is a failure to send a request, or to read its response.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	switch aerr.Code() {
+	case request.ErrCodeRequestError, request.ErrCodeResponseTimeout, request.ErrCodeRead:
+		return true
+	default:
+		return false
+	}
+}
+
+// isServerError reports whether err is an internal error of DynamoDB.
+func isServerError(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeInternalServerError
+}
+
+// attempts returns the maximum number of attempts and the base delay of the calls failed with an error of class.
+func (p *RetryPolicy) attempts(class ErrorClass) (int, time.Duration) {
+	maxAttempts, baseDelay := p.MaxAttempts, p.BaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+
+	switch class {
+	case ErrorClassThrottling:
+		if p.ThrottlingMaxAttempts > 0 {
+			maxAttempts = p.ThrottlingMaxAttempts
+		}
+		if p.ThrottlingBaseDelay > 0 {
+			baseDelay = p.ThrottlingBaseDelay
+		}
+	case ErrorClassTransient:
+	default:
+		return 1, 0
+	}
+
+	return maxAttempts, baseDelay
+}
+
+// delay returns the delay before the retry following attempt, with full jitter.
+func (p *RetryPolicy) delay(attempt int, baseDelay time.Duration) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	backoff := maxDelay
+	if attempt < 32 && baseDelay<<attempt < maxDelay {
+		backoff = baseDelay << attempt
+	}
+
+	//nolint:gosec // the jitter doesn't need a secure random source.
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryInterceptor returns the interceptor retrying the failed calls according to policy.
+func retryInterceptor(policy *RetryPolicy) callInterceptor {
+	classify := policy.Classify
+	if classify == nil {
+		classify = ClassifyError
+	}
+
+	return func(ctx aws.Context, _ *apiCall, next callFunc) (interface{}, error) {
+		for attempt := 1; ; attempt++ {
+			out, err := next(ctx)
+			if err == nil {
+				return out, nil
+			}
+
+			maxAttempts, baseDelay := policy.attempts(classify(err))
+			if attempt >= maxAttempts {
+				return out, err
+			}
+
+			timer := time.NewTimer(policy.delay(attempt-1, baseDelay))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return out, err
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	throttled := awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil)
+	transient := awserr.New(dynamodb.ErrCodeInternalServerError, "internal", nil)
+	invalid := awserr.New("ValidationException", "invalid", nil)
+
+	assert.Equal(t, ErrorClassThrottling, ClassifyError(throttled))
+	assert.Equal(t, ErrorClassTransient, ClassifyError(transient))
+	assert.Equal(t, ErrorClassPermanent, ClassifyError(invalid))
+	assert.Equal(t, ErrorClassPermanent, ClassifyError(context.Canceled))
+}
+
+func TestDescribeError(t *testing.T) {
+	throttled := &AWSError{Kind: ErrThrottled, Err: awserr.New(dynamodb.ErrCodeRequestLimitExceeded, "throttled", nil)}
+	network := awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("connection reset"))
+	invalid := awserr.New("ValidationException", "invalid", nil)
+
+	info := DescribeError(throttled)
+	assert.True(t, info.Retryable)
+	assert.True(t, info.Throttled)
+	assert.Equal(t, defaultThrottlingBackoff, info.Backoff)
+
+	info = DescribeError(network)
+	assert.Equal(t, ErrorClassTransient, info.Class)
+	assert.True(t, info.Network)
+	assert.False(t, info.Throttled)
+	assert.Equal(t, defaultRetryBaseDelay, info.Backoff)
+
+	assert.Equal(t, ErrorInfo{Class: ErrorClassPermanent}, DescribeError(invalid))
+
+	assert.True(t, IsRetryable(network))
+	assert.False(t, IsRetryable(invalid))
+	assert.False(t, IsRetryable(context.DeadlineExceeded))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	throttled := awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil)
+	policy := &RetryPolicy{MaxAttempts: 2, ThrottlingMaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	mock := &mockedFailingGetItem{errs: []error{throttled, throttled}}
+	kv := newRetryStore(t, mock, policy)
+
+	_, err := kv.Get(context.Background(), "key", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, mock.calls)
+
+	// exhausted attempts.
+	mock = &mockedFailingGetItem{errs: []error{throttled, throttled, throttled}}
+	kv = newRetryStore(t, mock, policy)
+
+	_, err = kv.Get(context.Background(), "key", nil)
+	assert.True(t, request.IsErrorThrottle(err))
+	assert.Equal(t, 3, mock.calls)
+
+	// the permanent errors are not retried.
+	invalid := awserr.New("ValidationException", "invalid", nil)
+	mock = &mockedFailingGetItem{errs: []error{invalid}}
+	kv = newRetryStore(t, mock, policy)
+
+	_, err = kv.Get(context.Background(), "key", nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, mock.calls)
+
+	// the cancellation of the context stops the retries.
+	mock = &mockedFailingGetItem{errs: []error{throttled, throttled}}
+	kv = newRetryStore(t, mock, &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = kv.Get(ctx, "key", nil)
+	assert.True(t, request.IsErrorThrottle(err))
+	assert.Equal(t, 1, mock.calls)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for attempt := 0; attempt < 40; attempt++ {
+		delay := policy.delay(attempt, policy.BaseDelay)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, policy.MaxDelay)
+	}
+}
+
+func TestRetryScanPages(t *testing.T) {
+	transient := awserr.New(dynamodb.ErrCodeInternalServerError, "internal", nil)
+	mock := &mockedFailingScan{failAfter: 1, err: transient}
+
+	w := &wrappedDynamoDB{
+		DynamoDBAPI:  mock,
+		ddb:          &Store{},
+		interceptors: []callInterceptor{retryInterceptor(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})},
+	}
+
+	var keys []string
+	err := w.ScanPagesWithContext(context.Background(), &dynamodb.ScanInput{}, func(page *dynamodb.ScanOutput, _ bool) bool {
+		for _, item := range page.Items {
+			keys = append(keys, itemKey(item))
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	// the second attempt resumes after the first page.
+	assert.Equal(t, []string{"a", "b"}, keys)
+	assert.Equal(t, []string{"", "a"}, mock.startKeys)
+}
+
+func newRetryStore(t *testing.T, client dynamodbiface.DynamoDBAPI, policy *RetryPolicy) *Store {
+	t.Helper()
+
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient: client,
+		StreamsClient:  &mockedStreams{},
+		RetryPolicy:    policy,
+	}))
+
+	return kv
+}
+
+type mockedFailingGetItem struct {
+	dynamodbiface.DynamoDBAPI
+	errs  []error
+	calls int
+}
+
+func (m *mockedFailingGetItem) GetItemWithContext(_ aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.calls++
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		partitionKey:          {S: aws.String("key")},
+		revisionAttribute:     {N: aws.String("1")},
+		encodedValueAttribute: {S: aws.String("dmFsdWU=")},
+	}}, nil
+}
+
+// mockedFailingScan scans the items "a" and "b" in pages of one item, failing with err after failAfter pages once.
+type mockedFailingScan struct {
+	dynamodbiface.DynamoDBAPI
+	failAfter int
+	err       error
+	startKeys []string
+}
+
+func (m *mockedFailingScan) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	start := itemKey(input.ExclusiveStartKey)
+	m.startKeys = append(m.startKeys, start)
+
+	for i, key := range []string{"a", "b"} {
+		if key <= start {
+			continue
+		}
+		if m.err != nil && i == m.failAfter {
+			err := m.err
+			m.err = nil
+			return err
+		}
+
+		item := map[string]*dynamodb.AttributeValue{partitionKey: {S: aws.String(key)}}
+		if !fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}, LastEvaluatedKey: item}, key == "b") {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const s3ObjectAttribute = "s3_object"
+
+// ErrS3OverflowNotConfigured is returned when reading a value stored in S3 without S3 overflow configuration.
+var ErrS3OverflowNotConfigured = errors.New("value stored in S3 but S3 overflow is not configured")
+
+// S3OverflowConfig the S3 overflow storage configuration.
+// The S3 objects don't expire with their items:
+// a lifecycle rule should be set on the bucket if TTLs are used.
+type S3OverflowConfig struct {
+	// Bucket is the S3 bucket where the large values are stored.
+	Bucket string
+	// Prefix is prepended to the S3 object keys.
+	Prefix string
+	// Threshold is the size, in bytes (after compression), from which values are stored in S3.
+	Threshold int
+}
+
+// putS3Object stores data in a new S3 object, and returns the object key.
+func (ddb *Store) putS3Object(ctx context.Context, key string, data []byte) (string, error) {
+	id, err := newChunkID()
+	if err != nil {
+		return "", err
+	}
+
+	objectKey := ddb.s3Overflow.Prefix + ddb.storedKey(key) + "/" + id
+
+	_, err = ddb.s3Svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(ddb.s3Overflow.Bucket),
+		Key:    aws.String(objectKey),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return objectKey, nil
+}
+
+// loadS3Object returns a copy of an item stored in S3, with the value read from S3.
+func (ddb *Store) loadS3Object(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	res, err := ddb.s3Svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(ddb.s3Overflow.Bucket),
+		Key:    aws.String(s3Object(item)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, v := range item {
+		loaded[name] = v
+	}
+	loaded[ddb.valueName()] = &dynamodb.AttributeValue{B: data}
+
+	return loaded, nil
+}
+
+// deleteS3Object removes the S3 object referenced by item, if any.
+func (ddb *Store) deleteS3Object(ctx context.Context, item map[string]*dynamodb.AttributeValue) error {
+	objectKey := s3Object(item)
+	if objectKey == "" || ddb.s3Overflow == nil {
+		return nil
+	}
+
+	_, err := ddb.s3Svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(ddb.s3Overflow.Bucket),
+		Key:    aws.String(objectKey),
+	})
+
+	return err
+}
+
+// s3Attributes returns the attributes of an item with its value stored in S3.
+func (ddb *Store) s3Attributes(objectKey string, enc valueEncoding) map[string]*dynamodb.AttributeValue {
+	attrs := enc.attributes()
+	attrs[s3ObjectAttribute] = &dynamodb.AttributeValue{S: aws.String(objectKey)}
+	attrs[ddb.valueName()] = nil
+	attrs[chunksAttribute] = nil
+	attrs[chunkIDAttribute] = nil
+
+	return attrs
+}
+
+// s3Object returns the key of the S3 object holding the value of item, if any.
+func s3Object(item map[string]*dynamodb.AttributeValue) string {
+	// the attribute is nil in the attributes of a write removing it.
+	if v, ok := item[s3ObjectAttribute]; ok && v != nil {
+		return aws.StringValue(v.S)
+	}
+	return ""
+}
+
+// storeAttributes returns the attributes storing the encoded value data,
+// after uploading it to S3 if it's above the overflow threshold.
+func (ddb *Store) storeAttributes(ctx context.Context, key string, data []byte, enc valueEncoding) (map[string]*dynamodb.AttributeValue, error) {
+	if ddb.s3Overflow == nil || len(data) <= ddb.s3Overflow.Threshold {
+		if err := ddb.checkValueSize(key, data, enc); err != nil {
+			return nil, err
+		}
+
+		return ddb.valueAttributes(data, enc), nil
+	}
+
+	objectKey, err := ddb.putS3Object(ctx, key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.s3Attributes(objectKey, enc), nil
+}
+
+// discardS3Object removes the S3 object uploaded for a write that failed.
+func (ddb *Store) discardS3Object(ctx context.Context, attrs map[string]*dynamodb.AttributeValue) {
+	// best effort: the object is not referenced by any item.
+	if err := ddb.deleteS3Object(ctx, attrs); err != nil {
+		ddb.log().Error("failed to remove an orphan S3 object", "object", s3Object(attrs), "error", err)
+	}
+}
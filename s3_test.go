@@ -0,0 +1,102 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Overflow(t *testing.T) {
+	s3Mock := &mockedS3{objects: make(map[string][]byte)}
+
+	kv := &Store{
+		s3Overflow: &S3OverflowConfig{Bucket: "bucket", Prefix: "values/", Threshold: 10},
+		s3Svc:      s3Mock,
+	}
+
+	ctx := context.Background()
+
+	// small values stay in DynamoDB.
+	attrs, err := kv.storeAttributes(ctx, "key", []byte("small"), valueEncoding{})
+	require.NoError(t, err)
+	assert.NotNil(t, attrs[encodedValueAttribute])
+	assert.Empty(t, s3Mock.objects)
+
+	value := bytes.Repeat([]byte("large"), 10)
+
+	attrs, err = kv.storeAttributes(ctx, "key", value, valueEncoding{})
+	require.NoError(t, err)
+	assert.Nil(t, attrs[encodedValueAttribute])
+	require.Len(t, s3Mock.objects, 1)
+
+	objectKey := s3Object(attrs)
+	assert.Contains(t, s3Mock.objects, objectKey)
+	assert.Regexp(t, "^values/key/", objectKey)
+
+	item := map[string]*dynamodb.AttributeValue{partitionKey: {S: aws.String("key")}}
+	for name, v := range attrs {
+		if v != nil {
+			item[name] = v
+		}
+	}
+
+	loaded, err := kv.loadExternal(ctx, item, true, nil)
+	require.NoError(t, err)
+
+	pair, err := kv.decodeItem(loaded)
+	require.NoError(t, err)
+	assert.Equal(t, value, pair.Value)
+
+	err = kv.deleteExternal(ctx, "key", item)
+	require.NoError(t, err)
+	assert.Empty(t, s3Mock.objects)
+
+	_, err = (&Store{}).loadExternal(ctx, item, true, nil)
+	assert.ErrorIs(t, err, ErrS3OverflowNotConfigured)
+}
+
+type mockedS3 struct {
+	s3iface.S3API
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *mockedS3) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[aws.StringValue(input.Key)] = data
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockedS3) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(m.objects[aws.StringValue(input.Key)]))}, nil
+}
+
+func (m *mockedS3) DeleteObjectWithContext(_ aws.Context, input *s3.DeleteObjectInput, _ ...request.Option) (*s3.DeleteObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects, aws.StringValue(input.Key))
+
+	return &s3.DeleteObjectOutput{}, nil
+}
@@ -0,0 +1,75 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// scanPages returns the items of all the pages of a scan.
+func (ddb *Store) scanPages(ctx context.Context, si *dynamodb.ScanInput) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+
+	err := ddb.readSvc().ScanPagesWithContext(ctx, si,
+		func(page *dynamodb.ScanOutput, _ bool) bool {
+			items = append(items, page.Items...)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// parallelScan runs a scan with concurrent segment scanners, and merges their items in segment order.
+// The first error stops all the scanners.
+func (ddb *Store) parallelScan(ctx context.Context, si *dynamodb.ScanInput, segments int) ([]map[string]*dynamodb.AttributeValue, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]map[string]*dynamodb.AttributeValue, segments)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for i := 0; i < segments; i++ {
+		input := *si
+		input.Segment = aws.Int64(int64(i))
+		input.TotalSegments = aws.Int64(int64(segments))
+
+		wg.Add(1)
+		go func(segment int, input *dynamodb.ScanInput) {
+			defer wg.Done()
+
+			items, err := ddb.scanPages(ctx, input)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+
+			results[segment] = items
+		}(i, &input)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, segmentItems := range results {
+		items = append(items, segmentItems...)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,70 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStoreParallelScan(t *testing.T) {
+	ddbStore := newDynamoDBStoreWith(t, func(ddbStore *Store) { ddbStore.scanSegments = 4 })
+
+	testsuite.RunTestCommon(t, ddbStore)
+}
+
+func TestParallelScan(t *testing.T) {
+	mock := &mockedSegmentScan{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName, scanSegments: 3}
+
+	items, err := kv.scanPrefix(context.Background(), "a/", true)
+	require.NoError(t, err)
+
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, itemKey(item))
+	}
+	assert.Equal(t, []string{"a/0", "a/1", "a/2"}, keys)
+	assert.Equal(t, []int64{3, 3, 3}, mock.totals)
+
+	mock.failSegment = aws.Int64(1)
+	_, err = kv.scanPrefix(context.Background(), "a/", true)
+	assert.Error(t, err)
+}
+
+var errSegmentFailed = errors.New("segment failed")
+
+// mockedSegmentScan returns one item per segment.
+type mockedSegmentScan struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu          sync.Mutex
+	totals      []int64
+	failSegment *int64
+}
+
+func (m *mockedSegmentScan) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	m.mu.Lock()
+	m.totals = append(m.totals, aws.Int64Value(input.TotalSegments))
+	m.mu.Unlock()
+
+	segment := aws.Int64Value(input.Segment)
+	if m.failSegment != nil && *m.failSegment == segment {
+		return errSegmentFailed
+	}
+
+	fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+		{partitionKey: {S: aws.String(fmt.Sprintf("a/%d", segment))}},
+	}}, true)
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrSemaphoreFull is returned by TryAcquire when all the permits of the semaphore are held.
+	ErrSemaphoreFull = errors.New("all the semaphore permits are held")
+	// ErrInvalidSemaphoreLimit is returned when the limit of a semaphore is lower than 1.
+	ErrInvalidSemaphoreLimit = errors.New("invalid semaphore limit")
+)
+
+// Semaphore is a distributed counting semaphore, limiting the number of concurrent holders across processes.
+// Each permit is a lease on one of limit slot items, "<key>/<slot>",
+// renewed in the background by its holder, and reclaimed when its TTL elapses if the holder is gone.
+type Semaphore struct {
+	ddb   *Store
+	key   string
+	limit int
+	opts  *LeaseOptions
+}
+
+// NewSemaphore returns the semaphore key, with at most limit holders.
+// opts configures the leases of the permits, the default TTL being 20s.
+// All the users of a semaphore must use the same limit.
+func (ddb *Store) NewSemaphore(key string, limit int, opts *LeaseOptions) (*Semaphore, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSemaphoreLimit, limit)
+	}
+
+	return &Semaphore{ddb: ddb, key: key, limit: limit, opts: opts}, nil
+}
+
+// TryAcquire tries once to acquire a permit, and returns ErrSemaphoreFull if all the permits are held.
+// The permit is held until its lease is released or lost.
+func (s *Semaphore) TryAcquire(ctx context.Context) (*Lease, error) {
+	// the slots are tried in a random order, so the holders don't contend on the first ones.
+	//nolint:gosec // the order doesn't need a secure random source.
+	for _, slot := range rand.Perm(s.limit) {
+		lease, err := s.ddb.AcquireLease(ctx, s.slotKey(slot), s.opts)
+		if err == nil {
+			return lease, nil
+		}
+		if !errors.Is(err, ErrLeaseHeld) {
+			return nil, err
+		}
+	}
+
+	return nil, ErrSemaphoreFull
+}
+
+// Acquire acquires a permit, retrying at the retry interval of the LockConfig until ctx is done.
+func (s *Semaphore) Acquire(ctx context.Context) (*Lease, error) {
+	for {
+		lease, err := s.TryAcquire(ctx)
+		if !errors.Is(err, ErrSemaphoreFull) {
+			return lease, err
+		}
+
+		retry := time.NewTimer(s.ddb.lockConfig.retryDelay())
+
+		select {
+		case <-retry.C:
+		case <-ctx.Done():
+			retry.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Holders returns the number of permits held.
+func (s *Semaphore) Holders(ctx context.Context) (int, error) {
+	keys := make([]string, s.limit)
+	for slot := range keys {
+		keys[slot] = s.slotKey(slot)
+	}
+
+	// the expired slots are skipped.
+	pairs, err := s.ddb.BatchGet(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(pairs), nil
+}
+
+func (s *Semaphore) slotKey(slot int) string {
+	return s.key + "/" + strconv.Itoa(slot)
+}
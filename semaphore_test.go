@@ -0,0 +1,132 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedLeaseTable{}, tableName: TestTableName}
+	ctx := context.Background()
+
+	_, err := kv.NewSemaphore("migration", 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidSemaphoreLimit)
+
+	sem, err := kv.NewSemaphore("migration", 2, &LeaseOptions{TTL: time.Minute})
+	require.NoError(t, err)
+
+	first, err := sem.TryAcquire(ctx)
+	require.NoError(t, err)
+
+	second, err := sem.Acquire(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Key(), second.Key())
+
+	_, err = sem.TryAcquire(ctx)
+	assert.ErrorIs(t, err, ErrSemaphoreFull)
+
+	holders, err := sem.Holders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, holders)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, err = sem.Acquire(timeoutCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// a released permit can be acquired again.
+	require.NoError(t, first.Release(ctx))
+
+	third, err := sem.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, first.Key(), third.Key())
+
+	require.NoError(t, second.Release(ctx))
+	require.NoError(t, third.Release(ctx))
+}
+
+// mockedLeaseTable stores the revision and expiration time of items, checking the conditions of AtomicPut and AtomicDelete.
+type mockedLeaseTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockedLeaseTable) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[string]map[string]*dynamodb.AttributeValue)
+	}
+
+	key := itemKey(input.Key)
+	current, exists := m.items[key]
+	if exists && (&Store{}).isItemExpired(current) {
+		exists = false
+	}
+
+	var revision uint64
+	if exists {
+		revision, _ = strconv.ParseUint(aws.StringValue(current[revisionAttribute].N), 10, 64)
+	}
+
+	last, ok := input.ExpressionAttributeValues[":lastRevision"]
+	if (ok && (!exists || aws.StringValue(last.N) != strconv.FormatUint(revision, 10))) || (!ok && exists) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)
+	}
+
+	item := map[string]*dynamodb.AttributeValue{
+		partitionKey:      input.Key[partitionKey],
+		revisionAttribute: {N: aws.String(strconv.FormatUint(revision+1, 10))},
+	}
+	if ttl, ok := input.ExpressionAttributeValues[":ttl"]; ok {
+		item[ttlAttribute] = ttl
+	}
+	m.items[key] = item
+
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+func (m *mockedLeaseTable) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := itemKey(input.Key)
+	current, ok := m.items[key]
+	if !ok || aws.StringValue(current[revisionAttribute].N) != aws.StringValue(input.ExpressionAttributeValues[":lastRevision"].N) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)
+	}
+	delete(m.items, key)
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (m *mockedLeaseTable) BatchGetItemWithContext(_ aws.Context, input *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &dynamodb.BatchGetItemOutput{Responses: make(map[string][]map[string]*dynamodb.AttributeValue)}
+	for table, req := range input.RequestItems {
+		for _, key := range req.Keys {
+			if item, ok := m.items[itemKey(key)]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,370 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const (
+	defaultQueueNamePrefix = "kvtools-watch-"
+	// sqsWaitTimeSeconds the long polling of the queue.
+	sqsWaitTimeSeconds = 20
+	// sqsMaxMessages the maximum number of messages received, and deleted, at once.
+	sqsMaxMessages = 10
+)
+
+// ErrSNSNotifierConfig is returned by NewSNSNotifier when the clients or the topic are missing.
+var ErrSNSNotifierConfig = errors.New("sns notifier requires the SNS and SQS clients and the topic ARN")
+
+// SNSNotifierConfig configures the notifier delivering the events through an SNS topic and SQS queues.
+type SNSNotifierConfig struct {
+	// SNS is the client of the topic, such as sns.New(session).
+	SNS snsiface.SNSAPI
+	// SQS is the client of the queues, such as sqs.New(session).
+	SQS sqsiface.SQSAPI
+	// TopicArn is the ARN of the topic the events are published to.
+	TopicArn string
+
+	// QueueURL is an existing queue subscribed to the topic, for a delivery surviving the restarts of the process,
+	// the events published meanwhile being delivered after the restart.
+	// If empty, a queue is created and subscribed to the topic for the process on the first subscription,
+	// and deleted by Close.
+	QueueURL string
+	// QueueNamePrefix is the prefix of the names of the queues created, defaults to "kvtools-watch-".
+	QueueNamePrefix string
+
+	// Logger receives the failures of the delivery, defaults to discarding them.
+	Logger Logger
+}
+
+// snsMessage is the message of an event published to the topic.
+type snsMessage struct {
+	Key  string    `json:"key"`
+	Type EventType `json:"type,omitempty"`
+}
+
+// snsEnvelope is the message delivered to a queue subscribed to the topic without the raw message delivery.
+type snsEnvelope struct {
+	Message string `json:"Message"`
+}
+
+// snsNotifier is a Notifier publishing the events to an SNS topic, and reading them from an SQS queue of the process,
+// so the stores of several processes sharing the topic watch each other's writes, with Config.PublishWrites.
+// A single receiver is shared by all the subscribers, it runs from the first subscription until Close.
+type snsNotifier struct {
+	config SNSNotifierConfig
+
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	// cancel stops the running receiver, nil if the receiver is not running.
+	cancel context.CancelFunc
+	// queueURL the queue read by the receiver, empty until the first subscription.
+	queueURL string
+	// subscriptionArn the subscription of the queue created by the notifier to the topic, empty if the queue was given.
+	subscriptionArn string
+}
+
+// NewSNSNotifier returns a Notifier publishing the events to an SNS topic,
+// and delivering them to the watchers of the process through an SQS queue subscribed to the topic.
+// The stores sharing the topic must share the table and the key prefix,
+// and publish their writes with Config.PublishWrites.
+func NewSNSNotifier(config SNSNotifierConfig) (Notifier, error) {
+	if config.SNS == nil || config.SQS == nil || config.TopicArn == "" {
+		return nil, ErrSNSNotifierConfig
+	}
+
+	if config.QueueNamePrefix == "" {
+		config.QueueNamePrefix = defaultQueueNamePrefix
+	}
+	if config.Logger == nil {
+		config.Logger = nopLogger{}
+	}
+
+	return &snsNotifier{
+		config:      config,
+		subscribers: make(map[*streamSubscriber]struct{}),
+		queueURL:    config.QueueURL,
+	}, nil
+}
+
+// Subscribe returns a channel receiving the events on keys starting with prefix.
+func (n *snsNotifier) Subscribe(ctx context.Context, prefix string) (<-chan *Event, error) {
+	return n.subscribeQueued(ctx, prefix, defaultWatchQueue)
+}
+
+func (n *snsNotifier) subscribeQueued(ctx context.Context, prefix string, queue watchQueue) (<-chan *Event, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cancel == nil {
+		if err := n.createQueue(ctx); err != nil {
+			return nil, err
+		}
+
+		runCtx, cancel := context.WithCancel(context.Background())
+		n.cancel = cancel
+
+		go n.receive(runCtx, n.queueURL)
+	}
+
+	sub := newStreamSubscriber(ctx, prefix, queue)
+	n.subscribers[sub] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		n.unsubscribe(sub)
+	}()
+
+	return sub.events, nil
+}
+
+// Publish publishes the event to the topic.
+func (n *snsNotifier) Publish(ctx context.Context, event *Event) error {
+	message, err := json.Marshal(&snsMessage{Key: event.Key, Type: event.Type})
+	if err != nil {
+		return err
+	}
+
+	_, err = n.config.SNS.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn: aws.String(n.config.TopicArn),
+		Message:  aws.String(string(message)),
+	})
+
+	return err
+}
+
+// Close stops the receiver, closes all the subscriptions,
+// and deletes the queue created by the notifier, with its subscription to the topic.
+func (n *snsNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.stop()
+
+	if n.subscriptionArn == "" {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	_, err := n.config.SNS.UnsubscribeWithContext(ctx, &sns.UnsubscribeInput{SubscriptionArn: aws.String(n.subscriptionArn)})
+	if err != nil {
+		return err
+	}
+
+	if _, err = n.config.SQS.DeleteQueueWithContext(ctx, &sqs.DeleteQueueInput{QueueUrl: aws.String(n.queueURL)}); err != nil {
+		return err
+	}
+
+	n.queueURL, n.subscriptionArn = "", ""
+
+	return nil
+}
+
+// createQueue creates the queue of the process and subscribes it to the topic, unless there is already a queue.
+// n.mu must be held.
+func (n *snsNotifier) createQueue(ctx context.Context) error {
+	if n.queueURL != "" {
+		return nil
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	created, err := n.config.SQS.CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String(n.config.QueueNamePrefix + hex.EncodeToString(id)),
+	})
+	if err != nil {
+		return err
+	}
+
+	subscriptionArn, err := n.subscribeQueue(ctx, aws.StringValue(created.QueueUrl))
+	if err != nil {
+		// the queue is useless without its subscription.
+		_, _ = n.config.SQS.DeleteQueueWithContext(ctx, &sqs.DeleteQueueInput{QueueUrl: created.QueueUrl})
+		return err
+	}
+
+	n.queueURL, n.subscriptionArn = aws.StringValue(created.QueueUrl), subscriptionArn
+
+	return nil
+}
+
+// subscribeQueue allows the topic to send messages to the queue, and subscribes the queue to the topic,
+// with the raw message delivery. It returns the ARN of the subscription.
+func (n *snsNotifier) subscribeQueue(ctx context.Context, queueURL string) (string, error) {
+	attrs, err := n.config.SQS.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	queueArn := aws.StringValue(attrs.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "sns.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueArn,
+			"Condition": map[string]interface{}{"ArnEquals": map[string]string{"aws:SourceArn": n.config.TopicArn}},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = n.config.SQS.SetQueueAttributesWithContext(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]*string{sqs.QueueAttributeNamePolicy: aws.String(string(policy))},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	res, err := n.config.SNS.SubscribeWithContext(ctx, &sns.SubscribeInput{
+		TopicArn:              aws.String(n.config.TopicArn),
+		Protocol:              aws.String("sqs"),
+		Endpoint:              aws.String(queueArn),
+		Attributes:            map[string]*string{"RawMessageDelivery": aws.String("true")},
+		ReturnSubscriptionArn: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(res.SubscriptionArn), nil
+}
+
+// receive reads the messages of the queue until ctx is done, dispatching and deleting them.
+func (n *snsNotifier) receive(ctx context.Context, queueURL string) {
+	for {
+		res, err := n.config.SQS.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: aws.Int64(sqsMaxMessages),
+			WaitTimeSeconds:     aws.Int64(sqsWaitTimeSeconds),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			n.config.Logger.Error("sqs notifier receive failed", "queue", queueURL, "error", err)
+
+			// end the subscriptions, so the watches subscribe again.
+			n.mu.Lock()
+			if ctx.Err() == nil {
+				n.stop()
+			}
+			n.mu.Unlock()
+
+			return
+		}
+
+		if len(res.Messages) == 0 {
+			continue
+		}
+
+		n.dispatch(ctx, res.Messages)
+
+		entries := make([]*sqs.DeleteMessageBatchRequestEntry, 0, len(res.Messages))
+		for i, message := range res.Messages {
+			entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+		}
+
+		_, err = n.config.SQS.DeleteMessageBatchWithContext(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil && ctx.Err() == nil {
+			// the messages are received again after their visibility timeout.
+			n.config.Logger.Info("sqs notifier delete failed", "queue", queueURL, "error", err)
+		}
+	}
+}
+
+// dispatch queues the events of the messages to the matching subscribers.
+func (n *snsNotifier) dispatch(ctx context.Context, messages []*sqs.Message) {
+	events := make([]*Event, 0, len(messages))
+
+	for _, message := range messages {
+		event, err := decodeSNSMessage(aws.StringValue(message.Body))
+		if err != nil {
+			n.config.Logger.Info("sqs notifier message ignored", "message", aws.StringValue(message.MessageId), "error", err)
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	n.mu.Lock()
+	// the receiver was stopped while receiving the messages.
+	if ctx.Err() != nil {
+		n.mu.Unlock()
+		return
+	}
+	subscribers := subscriberList(n.subscribers)
+	n.mu.Unlock()
+
+	pushEvents(subscribers, events)
+}
+
+// decodeSNSMessage returns the event of a message, delivered raw or in the SNS envelope.
+func decodeSNSMessage(body string) (*Event, error) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal([]byte(body), &envelope); err == nil && envelope.Message != "" {
+		body = envelope.Message
+	}
+
+	var message snsMessage
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		return nil, err
+	}
+
+	return &Event{Key: message.Key, Type: message.Type}, nil
+}
+
+// stop stops the receiver and closes all the subscriptions.
+// n.mu must be held.
+func (n *snsNotifier) stop() {
+	if n.cancel != nil {
+		n.cancel()
+		n.cancel = nil
+	}
+
+	for sub := range n.subscribers {
+		delete(n.subscribers, sub)
+		sub.close()
+	}
+}
+
+func (n *snsNotifier) unsubscribe(sub *streamSubscriber) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(n.subscribers, sub)
+	sub.close()
+}
@@ -0,0 +1,127 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSNSNotifier(t *testing.T) {
+	_, err := NewSNSNotifier(SNSNotifierConfig{})
+	assert.ErrorIs(t, err, ErrSNSNotifierConfig)
+
+	queue := &mockedQueue{messages: make(chan *sqs.Message, 10)}
+	topic := &mockedTopic{queue: queue}
+
+	notifier, err := NewSNSNotifier(SNSNotifierConfig{SNS: topic, SQS: queue, TopicArn: "arn:topic"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	events, err := notifier.Subscribe(ctx, "a/")
+	require.NoError(t, err)
+
+	// the queue of the process is created, and subscribed to the topic.
+	assert.Equal(t, "arn:queue", topic.endpoint)
+	assert.Contains(t, queue.policy, "arn:topic")
+
+	require.NoError(t, notifier.Publish(ctx, &Event{Key: "a/1", Type: EventCreate}))
+	require.NoError(t, notifier.Publish(ctx, &Event{Key: "b/1", Type: EventCreate}))
+	require.NoError(t, notifier.Publish(ctx, &Event{Key: "a/2", Type: EventDelete}))
+
+	for _, expected := range []*Event{{Key: "a/1", Type: EventCreate}, {Key: "a/2", Type: EventDelete}} {
+		select {
+		case event := <-events:
+			assert.Equal(t, expected, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timeout reached")
+		}
+	}
+
+	// the messages of the SNS envelope are decoded.
+	event, err := decodeSNSMessage(`{"Type":"Notification","Message":"{\"key\":\"a/3\",\"type\":\"UPDATE\"}"}`)
+	require.NoError(t, err)
+	assert.Equal(t, &Event{Key: "a/3", Type: EventUpdate}, event)
+
+	require.NoError(t, notifier.Close())
+
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.True(t, topic.unsubscribed)
+	assert.True(t, queue.deleted)
+}
+
+// mockedTopic delivers the published messages to queue.
+type mockedTopic struct {
+	snsiface.SNSAPI
+
+	queue        *mockedQueue
+	endpoint     string
+	unsubscribed bool
+}
+
+func (m *mockedTopic) PublishWithContext(_ aws.Context, input *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	m.queue.messages <- &sqs.Message{Body: input.Message, ReceiptHandle: aws.String("handle")}
+	return &sns.PublishOutput{}, nil
+}
+
+func (m *mockedTopic) SubscribeWithContext(_ aws.Context, input *sns.SubscribeInput, _ ...request.Option) (*sns.SubscribeOutput, error) {
+	m.endpoint = aws.StringValue(input.Endpoint)
+	return &sns.SubscribeOutput{SubscriptionArn: aws.String("arn:subscription")}, nil
+}
+
+func (m *mockedTopic) UnsubscribeWithContext(_ aws.Context, _ *sns.UnsubscribeInput, _ ...request.Option) (*sns.UnsubscribeOutput, error) {
+	m.unsubscribed = true
+	return &sns.UnsubscribeOutput{}, nil
+}
+
+// mockedQueue is an in-memory SQS queue.
+type mockedQueue struct {
+	sqsiface.SQSAPI
+
+	messages chan *sqs.Message
+	policy   string
+	deleted  bool
+}
+
+func (m *mockedQueue) CreateQueueWithContext(_ aws.Context, _ *sqs.CreateQueueInput, _ ...request.Option) (*sqs.CreateQueueOutput, error) {
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String("https://queue")}, nil
+}
+
+func (m *mockedQueue) GetQueueAttributesWithContext(_ aws.Context, _ *sqs.GetQueueAttributesInput, _ ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	return &sqs.GetQueueAttributesOutput{Attributes: map[string]*string{sqs.QueueAttributeNameQueueArn: aws.String("arn:queue")}}, nil
+}
+
+func (m *mockedQueue) SetQueueAttributesWithContext(_ aws.Context, input *sqs.SetQueueAttributesInput, _ ...request.Option) (*sqs.SetQueueAttributesOutput, error) {
+	m.policy = aws.StringValue(input.Attributes[sqs.QueueAttributeNamePolicy])
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (m *mockedQueue) ReceiveMessageWithContext(ctx aws.Context, _ *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	select {
+	case message := <-m.messages:
+		return &sqs.ReceiveMessageOutput{Messages: []*sqs.Message{message}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *mockedQueue) DeleteMessageBatchWithContext(_ aws.Context, _ *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (m *mockedQueue) DeleteQueueWithContext(_ aws.Context, _ *sqs.DeleteQueueInput, _ ...request.Option) (*sqs.DeleteQueueOutput, error) {
+	m.deleted = true
+
+	return &sqs.DeleteQueueOutput{}, nil
+}
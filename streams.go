@@ -0,0 +1,615 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+const streamPollInterval = time.Second
+
+// ErrStreamNotEnabled is returned when watching a table without a DynamoDB stream.
+var ErrStreamNotEnabled = errors.New("dynamodb stream is not enabled on the table")
+
+// streamNotifier is a Notifier reading the DynamoDB stream of the table.
+// A single stream consumer is shared by all the subscribers,
+// it runs while there is at least one subscriber.
+// Changes are published by DynamoDB itself, so Publish is a no-op.
+type streamNotifier struct {
+	ddb *Store
+
+	// checkpointTable is the table used to store the shard positions, if any.
+	checkpointTable string
+	// consumerID identifies the consumer in checkpointTable.
+	consumerID string
+
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	// cancel stops the running consumer, nil if the consumer is not running.
+	cancel context.CancelFunc
+}
+
+// streamSubscriber receives the events on keys starting with prefix.
+// The events are queued, and sent to events by the goroutine of the subscriber,
+// so a slow subscriber doesn't hold up the others until its queue is full.
+type streamSubscriber struct {
+	ctx    context.Context
+	prefix string
+	events chan *Event
+	limit  watchQueue
+
+	mu    sync.Mutex
+	queue []*Event
+	// wakeCh signals the events queued.
+	wakeCh chan struct{}
+	// roomCh signals the events taken from the queue.
+	roomCh chan struct{}
+	// doneCh is closed when the subscription ends.
+	doneCh chan struct{}
+}
+
+func newStreamSubscriber(ctx context.Context, prefix string, limit watchQueue) *streamSubscriber {
+	sub := &streamSubscriber{
+		ctx:    ctx,
+		prefix: prefix,
+		events: make(chan *Event),
+		limit:  limit,
+		wakeCh: make(chan struct{}, 1),
+		roomCh: make(chan struct{}, 1),
+		doneCh: make(chan struct{}),
+	}
+
+	go sub.forward()
+
+	return sub
+}
+
+// push queues event, without waiting for the subscriber to receive it.
+// When the queue is full, it drops the oldest event with WatchOverflowDropOldest,
+// and waits for room otherwise.
+func (s *streamSubscriber) push(event *Event) {
+	s.mu.Lock()
+	for len(s.queue) >= s.limit.size {
+		if s.limit.overflow == WatchOverflowDropOldest {
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+			continue
+		}
+
+		s.mu.Unlock()
+		select {
+		case <-s.roomCh:
+		case <-s.doneCh:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+		s.mu.Lock()
+	}
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+
+	signal(s.wakeCh)
+}
+
+// signal wakes the goroutine waiting on ch, if any.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// close ends the subscription, the events still queued being dropped.
+// It must be called once.
+func (s *streamSubscriber) close() {
+	close(s.doneCh)
+}
+
+// forward sends the queued events to events, which is closed when the subscription ends.
+func (s *streamSubscriber) forward() {
+	defer close(s.events)
+
+	for {
+		select {
+		case <-s.wakeCh:
+		case <-s.doneCh:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+
+		for {
+			event, ok := s.pop()
+			if !ok {
+				break
+			}
+
+			select {
+			case s.events <- event:
+			case <-s.doneCh:
+				return
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// pop takes the oldest event of the queue, and reports whether there was one.
+func (s *streamSubscriber) pop() (*Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+
+	event := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+
+	signal(s.roomCh)
+
+	return event, true
+}
+
+func newStreamNotifier(ddb *Store, checkpointTable, consumerID string) *streamNotifier {
+	return &streamNotifier{
+		ddb:             ddb,
+		checkpointTable: checkpointTable,
+		consumerID:      consumerID,
+		subscribers:     make(map[*streamSubscriber]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events on keys starting with prefix.
+func (n *streamNotifier) Subscribe(ctx context.Context, prefix string) (<-chan *Event, error) {
+	return n.subscribeQueued(ctx, prefix, defaultWatchQueue)
+}
+
+func (n *streamNotifier) subscribeQueued(ctx context.Context, prefix string, queue watchQueue) (<-chan *Event, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cancel == nil {
+		if err := n.start(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := newStreamSubscriber(ctx, prefix, queue)
+	n.subscribers[sub] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		n.unsubscribe(sub)
+	}()
+
+	return sub.events, nil
+}
+
+// Publish does nothing: DynamoDB writes the stream records.
+func (n *streamNotifier) Publish(_ context.Context, _ *Event) error {
+	return nil
+}
+
+// Close stops the stream consumer and closes all the subscriptions.
+func (n *streamNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.stop()
+
+	return nil
+}
+
+// start positions a new consumer at the tip of the stream and runs it.
+// n.mu must be held.
+func (n *streamNotifier) start(ctx context.Context) error {
+	streamArn, err := n.ddb.latestStreamArn(ctx)
+	if err != nil {
+		return err
+	}
+
+	var cp checkpointer = newMemoryCheckpointer()
+	if n.checkpointTable != "" {
+		cp = &tableCheckpointer{svc: n.ddb.dynamoSvc, tableName: n.checkpointTable, consumerID: n.consumerID}
+	}
+
+	reader, err := newStreamReader(ctx, n.ddb.streamsSvc, streamArn, cp)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+
+	go func() {
+		err := reader.run(runCtx, func(records []*dynamodbstreams.Record) {
+			n.dispatch(runCtx, records)
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			n.ddb.log().Error("stream consumer failed", "table", n.ddb.tableName, "error", err)
+
+			// the consumer failed: end the subscriptions, so watchers don't wait forever.
+			n.mu.Lock()
+			if runCtx.Err() == nil {
+				n.stop()
+			}
+			n.mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// stop stops the consumer and closes all the subscriptions.
+// n.mu must be held.
+func (n *streamNotifier) stop() {
+	if n.cancel != nil {
+		n.cancel()
+		n.cancel = nil
+	}
+
+	for sub := range n.subscribers {
+		delete(n.subscribers, sub)
+		sub.close()
+	}
+}
+
+func (n *streamNotifier) unsubscribe(sub *streamSubscriber) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(n.subscribers, sub)
+	sub.close()
+
+	if len(n.subscribers) == 0 && n.cancel != nil {
+		n.cancel()
+		n.cancel = nil
+	}
+}
+
+// dispatch queues the events of the records to the matching subscribers.
+func (n *streamNotifier) dispatch(runCtx context.Context, records []*dynamodbstreams.Record) {
+	events := make([]*Event, 0, len(records))
+
+	for _, record := range records {
+		event := n.ddb.recordEvent(record)
+		if event == nil || isChunkKey(event.Key) {
+			continue
+		}
+
+		if event.Type == EventUpdate {
+			n.ddb.detectConflict(runCtx, record)
+		}
+
+		events = append(events, event)
+	}
+
+	n.mu.Lock()
+	// the consumer was stopped while handling the records.
+	if runCtx.Err() != nil {
+		n.mu.Unlock()
+		return
+	}
+	subscribers := subscriberList(n.subscribers)
+	n.mu.Unlock()
+
+	pushEvents(subscribers, events)
+}
+
+// subscriberList returns the subscribers, so the events are pushed without holding the lock of the notifier:
+// a full subscriber may wait for room, and the subscriptions end meanwhile.
+func subscriberList(subscribers map[*streamSubscriber]struct{}) []*streamSubscriber {
+	list := make([]*streamSubscriber, 0, len(subscribers))
+	for sub := range subscribers {
+		list = append(list, sub)
+	}
+	return list
+}
+
+// pushEvents queues the events to the subscribers whose prefix they match.
+func pushEvents(subscribers []*streamSubscriber, events []*Event) {
+	for _, event := range events {
+		for _, sub := range subscribers {
+			if strings.HasPrefix(event.Key, sub.prefix) {
+				sub.push(event)
+			}
+		}
+	}
+}
+
+// latestStreamArn returns the ARN of the table stream.
+func (ddb *Store) latestStreamArn(ctx context.Context) (string, error) {
+	res, err := ddb.dynamoSvc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	spec := res.Table.StreamSpecification
+	if spec == nil || !aws.BoolValue(spec.StreamEnabled) || res.Table.LatestStreamArn == nil {
+		return "", ErrStreamNotEnabled
+	}
+
+	return aws.StringValue(res.Table.LatestStreamArn), nil
+}
+
+// shardEnd is the checkpoint of a shard read until its end.
+const shardEnd = "SHARD_END"
+
+// streamReader follows all the shards of a DynamoDB stream.
+type streamReader struct {
+	svc        dynamodbstreamsiface.DynamoDBStreamsAPI
+	streamArn  string
+	checkpoint checkpointer
+
+	// iterators of the shards being read, by shard ID.
+	iterators map[string]*string
+	// shards already known, by shard ID.
+	known map[string]bool
+}
+
+// newStreamReader positions a reader on every shard of the stream:
+// after the checkpoint of the shard if any, at the tip of the open shards otherwise.
+func newStreamReader(ctx context.Context, svc dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn string, cp checkpointer) (*streamReader, error) {
+	r := &streamReader{
+		svc:        svc,
+		streamArn:  streamArn,
+		checkpoint: cp,
+		iterators:  make(map[string]*string),
+		known:      make(map[string]bool),
+	}
+
+	shards, err := r.describeShards(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shard := range shards {
+		shardID := aws.StringValue(shard.ShardId)
+		r.known[shardID] = true
+
+		// closed shards only contain records older than the watch.
+		closed := shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil
+
+		if err := r.startShard(ctx, shardID, closed, dynamodbstreams.ShardIteratorTypeLatest); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// startShard gets an iterator for the shard, resuming after its checkpoint if any.
+// Without checkpoint, closed shards are skipped and open shards are read from iteratorType.
+func (r *streamReader) startShard(ctx context.Context, shardID string, closed bool, iteratorType string) error {
+	sequenceNumber, err := r.checkpoint.get(ctx, r.shardKey(shardID))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case sequenceNumber == shardEnd:
+		return nil
+	case sequenceNumber != "":
+		r.iterators[shardID], err = r.shardIteratorAfter(ctx, shardID, sequenceNumber)
+	case closed:
+		return nil
+	default:
+		r.iterators[shardID], err = r.shardIterator(ctx, shardID, iteratorType)
+	}
+
+	return err
+}
+
+// run polls the shards until the context is done, calling handle with each batch of records.
+// The position in a shard is recorded once handle returns:
+// the records are delivered at most once if handle doesn't wait for their processing.
+func (r *streamReader) run(ctx context.Context, handle func(records []*dynamodbstreams.Record)) error {
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.poll(ctx, handle); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *streamReader) poll(ctx context.Context, handle func(records []*dynamodbstreams.Record)) error {
+	var shardClosed bool
+
+	for shardID, iterator := range r.iterators {
+		res, err := r.svc.GetRecordsWithContext(ctx, &dynamodbstreams.GetRecordsInput{
+			ShardIterator: iterator,
+		})
+		if err != nil {
+			if !isIteratorInvalid(err) {
+				return err
+			}
+
+			// the iterator expired (or the data was trimmed): resume from the last checkpoint.
+			delete(r.iterators, shardID)
+			if err = r.startShard(ctx, shardID, false, dynamodbstreams.ShardIteratorTypeLatest); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(res.Records) > 0 {
+			handle(res.Records)
+
+			last := res.Records[len(res.Records)-1].Dynamodb
+			if last != nil && last.SequenceNumber != nil {
+				if err = r.checkpoint.set(ctx, r.shardKey(shardID), aws.StringValue(last.SequenceNumber)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if res.NextShardIterator == nil {
+			delete(r.iterators, shardID)
+			shardClosed = true
+
+			if err = r.checkpoint.set(ctx, r.shardKey(shardID), shardEnd); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r.iterators[shardID] = res.NextShardIterator
+	}
+
+	if !shardClosed {
+		return nil
+	}
+
+	return r.refreshShards(ctx)
+}
+
+// refreshShards starts reading the shards created since the last refresh.
+func (r *streamReader) refreshShards(ctx context.Context) error {
+	shards, err := r.describeShards(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		shardID := aws.StringValue(shard.ShardId)
+		if r.known[shardID] {
+			continue
+		}
+
+		r.known[shardID] = true
+
+		// new shards are children of closed ones: read them from the beginning.
+		if err := r.startShard(ctx, shardID, false, dynamodbstreams.ShardIteratorTypeTrimHorizon); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *streamReader) shardKey(shardID string) string {
+	return r.streamArn + "/" + shardID
+}
+
+func (r *streamReader) describeShards(ctx context.Context) ([]*dynamodbstreams.Shard, error) {
+	var shards []*dynamodbstreams.Shard
+	var lastShardID *string
+
+	for {
+		res, err := r.svc.DescribeStreamWithContext(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             aws.String(r.streamArn),
+			ExclusiveStartShardId: lastShardID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		shards = append(shards, res.StreamDescription.Shards...)
+
+		lastShardID = res.StreamDescription.LastEvaluatedShardId
+		if lastShardID == nil {
+			return shards, nil
+		}
+	}
+}
+
+func (r *streamReader) shardIterator(ctx context.Context, shardID, iteratorType string) (*string, error) {
+	res, err := r.svc.GetShardIteratorWithContext(ctx, &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(r.streamArn),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: aws.String(iteratorType),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.ShardIterator, nil
+}
+
+func (r *streamReader) shardIteratorAfter(ctx context.Context, shardID, sequenceNumber string) (*string, error) {
+	res, err := r.svc.GetShardIteratorWithContext(ctx, &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(r.streamArn),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: aws.String(dynamodbstreams.ShardIteratorTypeAfterSequenceNumber),
+		SequenceNumber:    aws.String(sequenceNumber),
+	})
+	if err != nil {
+		if isIteratorInvalid(err) {
+			// the checkpoint is older than the stream retention.
+			return r.shardIterator(ctx, shardID, dynamodbstreams.ShardIteratorTypeTrimHorizon)
+		}
+		return nil, err
+	}
+
+	return res.ShardIterator, nil
+}
+
+func isIteratorInvalid(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case dynamodbstreams.ErrCodeExpiredIteratorException, dynamodbstreams.ErrCodeTrimmedDataAccessException:
+			return true
+		}
+	}
+	return false
+}
+
+// recordEvent converts a stream record to an Event, or returns nil if the item doesn't belong to the store.
+func (ddb *Store) recordEvent(record *dynamodbstreams.Record) *Event {
+	event := &Event{}
+
+	if record.Dynamodb != nil {
+		if !ddb.ownsItem(record.Dynamodb.Keys) {
+			return nil
+		}
+		event.Key = ddb.itemKey(record.Dynamodb.Keys)
+		event.oldImage = record.Dynamodb.OldImage
+		event.newImage = record.Dynamodb.NewImage
+	}
+
+	switch aws.StringValue(record.EventName) {
+	case dynamodbstreams.OperationTypeInsert:
+		event.Type = EventCreate
+	case dynamodbstreams.OperationTypeModify:
+		event.Type = EventUpdate
+	case dynamodbstreams.OperationTypeRemove:
+		event.Type = EventDelete
+		if isExpiration(record.UserIdentity) {
+			event.Type = EventExpire
+		}
+	}
+
+	return event
+}
+
+// isExpiration reports whether the identity of a removal is the Time To Live of DynamoDB.
+func isExpiration(identity *dynamodbstreams.Identity) bool {
+	return identity != nil &&
+		aws.StringValue(identity.Type) == "Service" &&
+		aws.StringValue(identity.PrincipalId) == "dynamodb.amazonaws.com"
+}
@@ -0,0 +1,315 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamNotifier(t *testing.T) {
+	streams := &mockedStreams{
+		records: []*dynamodbstreams.Record{
+			newStreamRecord(dynamodbstreams.OperationTypeInsert, "a/1", "1"),
+			newStreamRecord(dynamodbstreams.OperationTypeModify, "b/1", "2"),
+			newStreamRecord(dynamodbstreams.OperationTypeRemove, "a/2", "3"),
+		},
+	}
+
+	kv := &Store{
+		dynamoSvc:  &mockedDescribeTable{},
+		streamsSvc: streams,
+		tableName:  TestTableName,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	events, err := kv.getNotifier().Subscribe(ctx, "a/")
+	require.NoError(t, err)
+
+	for _, expected := range []*Event{{Key: "a/1", Type: EventCreate}, {Key: "a/2", Type: EventDelete}} {
+		select {
+		case event := <-events:
+			assert.Equal(t, expected, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timeout reached")
+		}
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("events channel not closed")
+	}
+}
+
+func TestStreamNotifierSlowSubscriber(t *testing.T) {
+	n := newStreamNotifier(&Store{tableName: TestTableName}, "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// the slow subscriber never receives its events.
+	slow := newStreamSubscriber(ctx, "a/", defaultWatchQueue)
+	fast := newStreamSubscriber(ctx, "a/", defaultWatchQueue)
+	n.subscribers[slow] = struct{}{}
+	n.subscribers[fast] = struct{}{}
+
+	dispatched := make(chan struct{})
+	go func() {
+		n.dispatch(ctx, []*dynamodbstreams.Record{
+			newStreamRecord(dynamodbstreams.OperationTypeInsert, "a/1", "1"),
+			newStreamRecord(dynamodbstreams.OperationTypeRemove, "a/2", "2"),
+		})
+		close(dispatched)
+	}()
+
+	select {
+	case <-dispatched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatch blocked by the slow subscriber")
+	}
+
+	for _, expected := range []*Event{{Key: "a/1", Type: EventCreate}, {Key: "a/2", Type: EventDelete}} {
+		select {
+		case event := <-fast.events:
+			assert.Equal(t, expected, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timeout reached")
+		}
+	}
+
+	n.unsubscribe(slow)
+
+	// the events still queued are dropped.
+	timeout := time.After(time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-slow.events:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("events channel not closed")
+		}
+	}
+}
+
+func TestStreamSubscriberOverflow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// the oldest events are dropped, the subscriber never receiving them.
+	dropping := newStreamSubscriber(ctx, "a/", watchQueue{size: 2, overflow: WatchOverflowDropOldest})
+	defer dropping.close()
+
+	dropping.push(&Event{Key: "a/1"})
+
+	// the forwarding goroutine holds the first event until it's received.
+	require.Eventually(t, func() bool { return len(dropping.queued()) == 0 }, time.Second, 10*time.Millisecond)
+
+	for _, key := range []string{"a/2", "a/3", "a/4"} {
+		dropping.push(&Event{Key: key})
+	}
+
+	assert.Equal(t, []string{"a/3", "a/4"}, dropping.queued())
+
+	// the push waits for room in the queue.
+	blocking := newStreamSubscriber(ctx, "a/", watchQueue{size: 1})
+	defer blocking.close()
+
+	blocking.push(&Event{Key: "a/1"})
+	require.Eventually(t, func() bool { return len(blocking.queued()) == 0 }, time.Second, 10*time.Millisecond)
+	blocking.push(&Event{Key: "a/2"})
+
+	pushed := make(chan struct{})
+	go func() {
+		blocking.push(&Event{Key: "a/3"})
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push didn't wait for room")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for _, key := range []string{"a/1", "a/2", "a/3"} {
+		select {
+		case event := <-blocking.events:
+			assert.Equal(t, key, event.Key)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timeout reached")
+		}
+	}
+
+	<-pushed
+}
+
+func (s *streamSubscriber) queued() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.queue))
+	for _, event := range s.queue {
+		keys = append(keys, event.Key)
+	}
+	return keys
+}
+
+func TestStreamReaderCheckpoint(t *testing.T) {
+	streams := &mockedStreams{
+		records: []*dynamodbstreams.Record{
+			newStreamRecord(dynamodbstreams.OperationTypeInsert, "a/1", "1"),
+			newStreamRecord(dynamodbstreams.OperationTypeModify, "a/1", "2"),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	cp := newMemoryCheckpointer()
+
+	reader, err := newStreamReader(ctx, streams, "arn:stream", cp)
+	require.NoError(t, err)
+	assert.Empty(t, streams.lastIteratorSequenceNumber())
+
+	var handled int
+	err = reader.poll(ctx, func(records []*dynamodbstreams.Record) { handled += len(records) })
+	require.NoError(t, err)
+	assert.Equal(t, 2, handled)
+
+	position, err := cp.get(ctx, "arn:stream/shard-1")
+	require.NoError(t, err)
+	assert.Equal(t, "2", position)
+
+	// a new reader resumes after the checkpoint.
+	_, err = newStreamReader(ctx, streams, "arn:stream", cp)
+	require.NoError(t, err)
+	assert.Equal(t, "2", streams.lastIteratorSequenceNumber())
+}
+
+func TestTableCheckpointerConsumers(t *testing.T) {
+	ctx := context.Background()
+
+	svc := &mockedCheckpointTable{items: make(map[string]string)}
+
+	first := &tableCheckpointer{svc: svc, tableName: "checkpoints", consumerID: "first"}
+	second := &tableCheckpointer{svc: svc, tableName: "checkpoints", consumerID: "second"}
+
+	require.NoError(t, first.set(ctx, "arn:stream/shard-1", "1"))
+	require.NoError(t, second.set(ctx, "arn:stream/shard-1", "2"))
+
+	position, err := first.get(ctx, "arn:stream/shard-1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", position)
+
+	position, err = second.get(ctx, "arn:stream/shard-1")
+	require.NoError(t, err)
+	assert.Equal(t, "2", position)
+}
+
+func newStreamRecord(operation, key, sequenceNumber string) *dynamodbstreams.Record {
+	return &dynamodbstreams.Record{
+		EventName: aws.String(operation),
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			Keys:           map[string]*dynamodb.AttributeValue{partitionKey: {S: aws.String(key)}},
+			SequenceNumber: aws.String(sequenceNumber),
+		},
+	}
+}
+
+type mockedDescribeTable struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockedDescribeTable) DescribeTableWithContext(_ aws.Context, _ *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		LatestStreamArn:     aws.String("arn:stream"),
+		StreamSpecification: &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(true)},
+	}}, nil
+}
+
+// mockedStreams serves a single open shard, returning all the records on the first read.
+type mockedStreams struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	mu       sync.Mutex
+	records  []*dynamodbstreams.Record
+	afterSeq string
+	read     bool
+}
+
+func (m *mockedStreams) lastIteratorSequenceNumber() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.afterSeq
+}
+
+func (m *mockedStreams) DescribeStreamWithContext(_ aws.Context, _ *dynamodbstreams.DescribeStreamInput, _ ...request.Option) (*dynamodbstreams.DescribeStreamOutput, error) {
+	return &dynamodbstreams.DescribeStreamOutput{StreamDescription: &dynamodbstreams.StreamDescription{
+		Shards: []*dynamodbstreams.Shard{{
+			ShardId:             aws.String("shard-1"),
+			SequenceNumberRange: &dynamodbstreams.SequenceNumberRange{StartingSequenceNumber: aws.String("1")},
+		}},
+	}}, nil
+}
+
+func (m *mockedStreams) GetShardIteratorWithContext(_ aws.Context, input *dynamodbstreams.GetShardIteratorInput, _ ...request.Option) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.afterSeq = aws.StringValue(input.SequenceNumber)
+
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String("iterator")}, nil
+}
+
+func (m *mockedStreams) GetRecordsWithContext(_ aws.Context, _ *dynamodbstreams.GetRecordsInput, _ ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.read {
+		return &dynamodbstreams.GetRecordsOutput{NextShardIterator: aws.String("iterator")}, nil
+	}
+	m.read = true
+
+	return &dynamodbstreams.GetRecordsOutput{
+		Records:           m.records,
+		NextShardIterator: aws.String("iterator"),
+	}, nil
+}
+
+type mockedCheckpointTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	items map[string]string
+}
+
+func (m *mockedCheckpointTable) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	position, ok := m.items[aws.StringValue(input.Key[partitionKey].S)]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		sequenceNumberAttribute: {S: aws.String(position)},
+	}}, nil
+}
+
+func (m *mockedCheckpointTable) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.items[aws.StringValue(input.Item[partitionKey].S)] = aws.StringValue(input.Item[sequenceNumberAttribute].S)
+
+	return &dynamodb.PutItemOutput{}, nil
+}
@@ -0,0 +1,111 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// resubscribeBaseDelay and resubscribeMaxDelay bound the delay between the attempts to subscribe again.
+	resubscribeBaseDelay = time.Second
+	resubscribeMaxDelay  = 30 * time.Second
+)
+
+// subscription is a subscription of a watch to the events of the notifier,
+// subscribed again when the notifier ends it before the watch, such as when the stream consumer fails.
+type subscription struct {
+	ddb    *Store
+	prefix string
+	queue  watchQueue
+	events <-chan *Event
+}
+
+// subscribe subscribes to the events on the keys starting with prefix,
+// the events queued by the notifier being bounded by the buffering options of the watch.
+func (ddb *Store) subscribe(ctx context.Context, prefix string, opts *WatchOptions) (*subscription, error) {
+	sub := &subscription{ddb: ddb, prefix: prefix, queue: opts.queue()}
+
+	events, err := sub.notify(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.events = events
+
+	return sub, nil
+}
+
+// notify subscribes to the notifier.
+func (s *subscription) notify(ctx context.Context) (<-chan *Event, error) {
+	notifier := s.ddb.getNotifier()
+
+	if queued, ok := notifier.(queuedNotifier); ok {
+		return queued.subscribeQueued(ctx, s.prefix, s.queue)
+	}
+
+	return notifier.Subscribe(ctx, s.prefix)
+}
+
+// next returns the next event, or nil after subscribing again, the events sent meanwhile being missed,
+// so the watch reads the current values to fill the gap.
+// It returns an error only when ctx is done.
+func (s *subscription) next(ctx context.Context) (*Event, error) {
+	select {
+	case event, ok := <-s.events:
+		if ok {
+			return event, nil
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if err := s.resubscribe(ctx); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// resubscribe subscribes again, with an exponential backoff between the attempts, until ctx is done.
+func (s *subscription) resubscribe(ctx context.Context) error {
+	delay := resubscribeBaseDelay
+
+	for {
+		s.ddb.log().Info("watch subscription ended, subscribing again", "prefix", s.prefix, "delay", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		events, err := s.notify(ctx)
+		if err == nil {
+			s.events = events
+			return nil
+		}
+
+		s.ddb.log().Error("watch subscription failed", "prefix", s.prefix, "error", err)
+
+		if delay *= 2; delay > resubscribeMaxDelay {
+			delay = resubscribeMaxDelay
+		}
+	}
+}
+
+// drain discards the pending events, coalescing them into the change being handled.
+// An ended subscription is left to next.
+func (s *subscription) drain() {
+	for {
+		select {
+		case _, ok := <-s.events:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchResubscribe(t *testing.T) {
+	mock := &mockedGetItem{items: map[string]string{"testWatch": "dmFsdWUx"}}
+	notifier := &endingNotifier{}
+
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		notifier:  notifier,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	events, err := kv.Watch(ctx, "testWatch", nil)
+	require.NoError(t, err)
+
+	pair := <-events
+	assert.Equal(t, []byte("value1"), pair.Value)
+
+	// the change made while the subscription was ended is read after subscribing again.
+	mock.set("testWatch", "dmFsdWUy")
+	notifier.end()
+
+	pair = <-events
+	assert.Equal(t, []byte("value2"), pair.Value)
+	assert.Equal(t, 2, notifier.count())
+
+	// the unchanged value isn't sent again.
+	notifier.end()
+	assert.Eventually(t, func() bool { return notifier.count() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	mock.set("testWatch", "dmFsdWUz")
+	notifier.publish(&Event{Key: "testWatch"})
+
+	pair = <-events
+	assert.Equal(t, []byte("value3"), pair.Value)
+}
+
+// endingNotifier is an in-memory Notifier with a single subscription, which can be ended.
+type endingNotifier struct {
+	mu            sync.Mutex
+	events        chan *Event
+	subscriptions int
+}
+
+func (n *endingNotifier) Subscribe(_ context.Context, _ string) (<-chan *Event, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.events = make(chan *Event, 10)
+	n.subscriptions++
+
+	return n.events, nil
+}
+
+func (n *endingNotifier) Publish(_ context.Context, event *Event) error {
+	n.publish(event)
+	return nil
+}
+
+func (n *endingNotifier) publish(event *Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.events <- event
+}
+
+func (n *endingNotifier) end() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.events)
+}
+
+func (n *endingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.subscriptions
+}
+
+func (n *endingNotifier) Close() error { return nil }
@@ -0,0 +1,365 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// ErrTableNotActive is returned by Ping when the table can't serve the operations of the store.
+	ErrTableNotActive = errors.New("table is not active")
+	// ErrTableMismatch is returned by EnsureTable when the existing table doesn't match the store or the specification.
+	ErrTableMismatch = errors.New("table doesn't match its specification")
+)
+
+// TableSpec is the specification of the table created by EnsureTable.
+// The key schema and the prefix index of the table follow the configuration of the store.
+type TableSpec struct {
+	// BillingMode is dynamodb.BillingModeProvisioned (default) or dynamodb.BillingModePayPerRequest.
+	BillingMode string
+
+	// ReadCapacityUnits and WriteCapacityUnits are the provisioned throughput of the table and its index,
+	// default to DefaultReadCapacityUnits and DefaultWriteCapacityUnits.
+	ReadCapacityUnits  int64
+	WriteCapacityUnits int64
+
+	// KMSKeyARN is the customer managed KMS key encrypting the table.
+	// The table is encrypted with an AWS owned key if empty.
+	KMSKeyARN string
+
+	// StreamViewType enables the stream of the table, read by Watch and WatchTree,
+	// with the item images it holds, such as dynamodb.StreamViewTypeNewAndOldImages.
+	// The stream is disabled if empty.
+	StreamViewType string
+
+	// Tags are the resource tags of the table, such as for the cost allocation.
+	Tags map[string]string
+
+	// TableClass is dynamodb.TableClassStandard (default) or dynamodb.TableClassStandardInfrequentAccess.
+	TableClass string
+
+	// AutoScaling registers the auto scaling of the capacity of the table, with the provisioned billing mode.
+	// The store must have an Application Auto Scaling client, created with Config.AutoScaling.
+	AutoScaling *AutoScalingConfig
+}
+
+// tableSpec returns the specification of the table created by AutoCreateTable.
+func (c *Config) tableSpec() *TableSpec {
+	return &TableSpec{
+		BillingMode:        c.BillingMode,
+		ReadCapacityUnits:  c.ReadCapacityUnits,
+		WriteCapacityUnits: c.WriteCapacityUnits,
+		KMSKeyARN:          c.KMSKeyARN,
+		StreamViewType:     c.StreamViewType,
+		Tags:               c.TableTags,
+		TableClass:         c.TableClass,
+		AutoScaling:        c.AutoScaling,
+	}
+}
+
+// EnsureTable creates the table with spec if it doesn't exist, and waits for it to be active.
+// The TTL attribute is enabled on the created table, and its auto scaling registered if any.
+// An existing table is left unchanged, but it must have the key schema of the store,
+// and the stream of spec, if any: ErrTableMismatch is returned otherwise.
+func (ddb *Store) EnsureTable(ctx context.Context, spec *TableSpec) error {
+	if spec == nil {
+		spec = &TableSpec{}
+	}
+
+	autoScaling := spec.AutoScaling != nil && spec.provisionedThroughput() != nil
+	if autoScaling && ddb.scalingSvc == nil {
+		return ErrAutoScalingNotConfigured
+	}
+
+	attributes, keySchema := ddb.keySchema()
+	indexAttributes, indexes := ddb.indexSchema(spec.provisionedThroughput())
+
+	input := &dynamodb.CreateTableInput{
+		AttributeDefinitions:   append(attributes, indexAttributes...),
+		KeySchema:              keySchema,
+		GlobalSecondaryIndexes: indexes,
+		// enable encryption of data by default.
+		SSESpecification:      spec.sseSpecification(),
+		BillingMode:           spec.billingMode(),
+		ProvisionedThroughput: spec.provisionedThroughput(),
+		StreamSpecification:   spec.streamSpecification(),
+		Tags:                  spec.tags(),
+		TableName:             aws.String(ddb.tableName),
+	}
+	if spec.TableClass != "" {
+		input.TableClass = aws.String(spec.TableClass)
+	}
+
+	_, err := ddb.dynamoSvc.CreateTableWithContext(ctx, input)
+
+	created := true
+	if err != nil {
+		awsErr, ok := err.(awserr.Error)
+		if !ok || awsErr.Code() != dynamodb.ErrCodeResourceInUseException {
+			return err
+		}
+
+		// the table exists, or is being created.
+		created = false
+	}
+
+	err = ddb.dynamoSvc.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		return ddb.checkTable(ctx, keySchema, spec)
+	}
+
+	if autoScaling {
+		if err = ddb.registerAutoScaling(ctx, spec.AutoScaling); err != nil {
+			return err
+		}
+	}
+
+	return ddb.EnsureTTL(ctx)
+}
+
+// checkTable checks the existing table has keySchema, and the stream of spec if any.
+func (ddb *Store) checkTable(ctx context.Context, keySchema []*dynamodb.KeySchemaElement, spec *TableSpec) error {
+	res, err := ddb.dynamoSvc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	if !sameKeySchema(res.Table.KeySchema, keySchema) {
+		return fmt.Errorf("%w: key schema", ErrTableMismatch)
+	}
+
+	if spec.StreamViewType == "" {
+		return nil
+	}
+
+	stream := res.Table.StreamSpecification
+	if stream == nil || !aws.BoolValue(stream.StreamEnabled) || aws.StringValue(stream.StreamViewType) != spec.StreamViewType {
+		return fmt.Errorf("%w: stream", ErrTableMismatch)
+	}
+
+	return nil
+}
+
+// sameKeySchema reports whether the key schemas have the same attributes, of the same key types.
+func sameKeySchema(a, b []*dynamodb.KeySchemaElement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	types := make(map[string]string, len(a))
+	for _, e := range a {
+		types[aws.StringValue(e.AttributeName)] = aws.StringValue(e.KeyType)
+	}
+
+	for _, e := range b {
+		if keyType, ok := types[aws.StringValue(e.AttributeName)]; !ok || keyType != aws.StringValue(e.KeyType) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// billingMode returns the billing mode of the table.
+func (s *TableSpec) billingMode() *string {
+	if s.BillingMode == "" {
+		return aws.String(dynamodb.BillingModeProvisioned)
+	}
+
+	return aws.String(s.BillingMode)
+}
+
+// provisionedThroughput returns the provisioned throughput of the table and its index,
+// nil for the on-demand billing mode.
+func (s *TableSpec) provisionedThroughput() *dynamodb.ProvisionedThroughput {
+	if s.BillingMode == dynamodb.BillingModePayPerRequest {
+		return nil
+	}
+
+	read, write := s.ReadCapacityUnits, s.WriteCapacityUnits
+	if read <= 0 {
+		read = DefaultReadCapacityUnits
+	}
+	if write <= 0 {
+		write = DefaultWriteCapacityUnits
+	}
+
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(read),
+		WriteCapacityUnits: aws.Int64(write),
+	}
+}
+
+// streamSpecification returns the stream settings of the table, nil if the stream is disabled.
+func (s *TableSpec) streamSpecification() *dynamodb.StreamSpecification {
+	if s.StreamViewType == "" {
+		return nil
+	}
+
+	return &dynamodb.StreamSpecification{
+		StreamEnabled:  aws.Bool(true),
+		StreamViewType: aws.String(s.StreamViewType),
+	}
+}
+
+// tags returns the tags of the table, sorted by key.
+func (s *TableSpec) tags() []*dynamodb.Tag {
+	if len(s.Tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(s.Tags))
+	for key := range s.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := make([]*dynamodb.Tag, len(keys))
+	for i, key := range keys {
+		tags[i] = &dynamodb.Tag{Key: aws.String(key), Value: aws.String(s.Tags[key])}
+	}
+
+	return tags
+}
+
+// TableInfo describes the table of the store.
+type TableInfo struct {
+	Name string
+	// Status is the status of the table, such as dynamodb.TableStatusActive.
+	Status string
+
+	// ItemCount and SizeBytes are the number of items and the size of the table,
+	// updated by DynamoDB about every six hours.
+	ItemCount int64
+	SizeBytes int64
+
+	// BillingMode is dynamodb.BillingModeProvisioned or dynamodb.BillingModePayPerRequest.
+	BillingMode string
+
+	// StreamEnabled reports whether the stream read by Watch is enabled, with the StreamViewType.
+	StreamEnabled  bool
+	StreamViewType string
+
+	// TTLStatus is the status of the TTL of the table, such as dynamodb.TimeToLiveStatusEnabled,
+	// enabled on the TTLAttribute.
+	TTLStatus    string
+	TTLAttribute string
+}
+
+// sseSpecification returns the encryption settings of the table.
+func (s *TableSpec) sseSpecification() *dynamodb.SSESpecification {
+	if s.KMSKeyARN == "" {
+		return &dynamodb.SSESpecification{
+			Enabled: aws.Bool(true),
+			SSEType: aws.String(dynamodb.SSETypeAes256),
+		}
+	}
+
+	return &dynamodb.SSESpecification{
+		Enabled:        aws.Bool(true),
+		SSEType:        aws.String(dynamodb.SSETypeKms),
+		KMSMasterKeyId: aws.String(s.KMSKeyARN),
+	}
+}
+
+// UpdateEncryption switches the server-side encryption of the table:
+// to the KMS key kmsKeyARN, or to an AWS owned key if kmsKeyARN is empty.
+// The table remains available while its encryption is updated.
+func (ddb *Store) UpdateEncryption(ctx context.Context, kmsKeyARN string) error {
+	sse := &dynamodb.SSESpecification{Enabled: aws.Bool(false)}
+	if kmsKeyARN != "" {
+		sse = &dynamodb.SSESpecification{
+			Enabled:        aws.Bool(true),
+			SSEType:        aws.String(dynamodb.SSETypeKms),
+			KMSMasterKeyId: aws.String(kmsKeyARN),
+		}
+	}
+
+	_, err := ddb.dynamoSvc.UpdateTableWithContext(ctx, &dynamodb.UpdateTableInput{
+		TableName:        aws.String(ddb.tableName),
+		SSESpecification: sse,
+	})
+
+	return err
+}
+
+// Ping checks the table is reachable and active, such as for a readiness probe before serving traffic.
+// The tables being updated are active.
+func (ddb *Store) Ping(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	res, err := ddb.dynamoSvc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	switch status := aws.StringValue(res.Table.TableStatus); status {
+	case dynamodb.TableStatusActive, dynamodb.TableStatusUpdating:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrTableNotActive, status)
+	}
+}
+
+// TableInfo returns the description of the table, with its stream and TTL settings.
+func (ddb *Store) TableInfo(ctx context.Context) (*TableInfo, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	res, err := ddb.dynamoSvc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	table := res.Table
+	info := &TableInfo{
+		Name:      aws.StringValue(table.TableName),
+		Status:    aws.StringValue(table.TableStatus),
+		ItemCount: aws.Int64Value(table.ItemCount),
+		SizeBytes: aws.Int64Value(table.TableSizeBytes),
+		// the tables created before the on-demand billing mode have no billing mode summary.
+		BillingMode: dynamodb.BillingModeProvisioned,
+	}
+
+	if table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode != nil {
+		info.BillingMode = aws.StringValue(table.BillingModeSummary.BillingMode)
+	}
+
+	if spec := table.StreamSpecification; spec != nil && aws.BoolValue(spec.StreamEnabled) {
+		info.StreamEnabled = true
+		info.StreamViewType = aws.StringValue(spec.StreamViewType)
+	}
+
+	ttl, err := ddb.dynamoSvc.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if desc := ttl.TimeToLiveDescription; desc != nil {
+		info.TTLStatus = aws.StringValue(desc.TimeToLiveStatus)
+		info.TTLAttribute = aws.StringValue(desc.AttributeName)
+	}
+
+	return info, nil
+}
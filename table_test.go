@@ -0,0 +1,161 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSESpecification(t *testing.T) {
+	spec := &TableSpec{}
+	assert.Equal(t, dynamodb.SSETypeAes256, aws.StringValue(spec.sseSpecification().SSEType))
+
+	spec.KMSKeyARN = "arn:aws:kms:us-east-1:123456789012:key/test"
+	sse := spec.sseSpecification()
+	assert.Equal(t, dynamodb.SSETypeKms, aws.StringValue(sse.SSEType))
+	assert.Equal(t, spec.KMSKeyARN, aws.StringValue(sse.KMSMasterKeyId))
+}
+
+func TestUpdateEncryption(t *testing.T) {
+	mock := &mockedUpdateTable{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	err := kv.UpdateEncryption(context.Background(), "arn:aws:kms:us-east-1:123456789012:key/test")
+	require.NoError(t, err)
+	assert.Equal(t, dynamodb.SSETypeKms, aws.StringValue(mock.input.SSESpecification.SSEType))
+
+	err = kv.UpdateEncryption(context.Background(), "")
+	require.NoError(t, err)
+	assert.False(t, aws.BoolValue(mock.input.SSESpecification.Enabled))
+}
+
+func TestEnsureTable(t *testing.T) {
+	mock := &mockedCreateTable{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	spec := &TableSpec{
+		BillingMode:    dynamodb.BillingModePayPerRequest,
+		StreamViewType: dynamodb.StreamViewTypeNewAndOldImages,
+		Tags:           map[string]string{"team": "platform", "env": "prod"},
+		TableClass:     dynamodb.TableClassStandardInfrequentAccess,
+	}
+
+	ctx := context.Background()
+
+	err := kv.EnsureTable(ctx, spec)
+	require.NoError(t, err)
+	assert.Equal(t, dynamodb.StreamViewTypeNewAndOldImages, aws.StringValue(mock.input.StreamSpecification.StreamViewType))
+	assert.Equal(t, dynamodb.TableClassStandardInfrequentAccess, aws.StringValue(mock.input.TableClass))
+	assert.Equal(t, []*dynamodb.Tag{
+		{Key: aws.String("env"), Value: aws.String("prod")},
+		{Key: aws.String("team"), Value: aws.String("platform")},
+	}, mock.input.Tags)
+
+	// the existing table matches, unless the stream or the key schema differ.
+	mock.exists = true
+
+	err = kv.EnsureTable(ctx, spec)
+	require.NoError(t, err)
+
+	mock = &mockedCreateTable{}
+	kv.dynamoSvc = mock
+
+	require.NoError(t, kv.EnsureTable(ctx, nil))
+	mock.exists = true
+
+	err = kv.EnsureTable(ctx, spec)
+	assert.ErrorIs(t, err, ErrTableMismatch)
+
+	kv.partitionKeyName = "directory"
+
+	err = kv.EnsureTable(ctx, nil)
+	assert.ErrorIs(t, err, ErrTableMismatch)
+}
+
+func TestConfigTableSpec(t *testing.T) {
+	config := &Config{
+		BillingMode:    dynamodb.BillingModePayPerRequest,
+		StreamViewType: dynamodb.StreamViewTypeNewAndOldImages,
+		TableTags:      map[string]string{"team": "platform"},
+		TableClass:     dynamodb.TableClassStandardInfrequentAccess,
+	}
+
+	spec := config.tableSpec()
+	assert.Equal(t, dynamodb.BillingModePayPerRequest, spec.BillingMode)
+	assert.Equal(t, dynamodb.TableClassStandardInfrequentAccess, spec.TableClass)
+	assert.Equal(t, []*dynamodb.Tag{{Key: aws.String("team"), Value: aws.String("platform")}}, spec.tags())
+	assert.Equal(t, &dynamodb.StreamSpecification{
+		StreamEnabled:  aws.Bool(true),
+		StreamViewType: aws.String(dynamodb.StreamViewTypeNewAndOldImages),
+	}, spec.streamSpecification())
+}
+
+func TestTableInfo(t *testing.T) {
+	mock := &mockedTableInfo{status: dynamodb.TableStatusActive}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+	require.NoError(t, kv.Ping(ctx))
+
+	info, err := kv.TableInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &TableInfo{
+		Name:           TestTableName,
+		Status:         dynamodb.TableStatusActive,
+		ItemCount:      42,
+		SizeBytes:      4096,
+		BillingMode:    dynamodb.BillingModePayPerRequest,
+		StreamEnabled:  true,
+		StreamViewType: dynamodb.StreamViewTypeNewAndOldImages,
+		TTLStatus:      dynamodb.TimeToLiveStatusEnabled,
+		TTLAttribute:   ttlAttribute,
+	}, info)
+
+	mock.status = dynamodb.TableStatusCreating
+	assert.ErrorIs(t, kv.Ping(ctx), ErrTableNotActive)
+}
+
+// mockedTableInfo describes an on-demand table with a stream and the TTL enabled, of status.
+type mockedTableInfo struct {
+	dynamodbiface.DynamoDBAPI
+
+	status string
+}
+
+func (m *mockedTableInfo) DescribeTableWithContext(_ aws.Context, input *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableName:          input.TableName,
+		TableStatus:        aws.String(m.status),
+		ItemCount:          aws.Int64(42),
+		TableSizeBytes:     aws.Int64(4096),
+		BillingModeSummary: &dynamodb.BillingModeSummary{BillingMode: aws.String(dynamodb.BillingModePayPerRequest)},
+		StreamSpecification: &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(dynamodb.StreamViewTypeNewAndOldImages),
+		},
+	}}, nil
+}
+
+func (m *mockedTableInfo) DescribeTimeToLiveWithContext(_ aws.Context, _ *dynamodb.DescribeTimeToLiveInput, _ ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String(ttlAttribute),
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+	}}, nil
+}
+
+type mockedUpdateTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	input *dynamodb.UpdateTableInput
+}
+
+func (m *mockedUpdateTable) UpdateTableWithContext(_ aws.Context, input *dynamodb.UpdateTableInput, _ ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	m.input = input
+	return &dynamodb.UpdateTableOutput{}, nil
+}
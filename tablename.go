@@ -0,0 +1,59 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrTableNameVariable is returned when a variable of a table name template has no value.
+var ErrTableNameVariable = errors.New("undefined table name variable")
+
+// expandTableName resolves the variables of a table name template, such as "myapp-{env}-kv".
+// The value of a variable is read from vars, then from the environment variable of the same name,
+// or of its upper case name ("ENV" for "{env}").
+func expandTableName(template string, vars map[string]string) (string, error) {
+	var name strings.Builder
+
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		variable := template[start+1 : end]
+
+		value, ok := lookupTableNameVariable(variable, vars)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrTableNameVariable, variable)
+		}
+
+		name.WriteString(template[:start])
+		name.WriteString(value)
+		template = template[end+1:]
+	}
+
+	name.WriteString(template)
+
+	return name.String(), nil
+}
+
+// lookupTableNameVariable returns the value of a table name variable.
+func lookupTableNameVariable(variable string, vars map[string]string) (string, bool) {
+	if value, ok := vars[variable]; ok {
+		return value, true
+	}
+
+	if value, ok := os.LookupEnv(variable); ok {
+		return value, true
+	}
+
+	return os.LookupEnv(strings.ToUpper(variable))
+}
@@ -0,0 +1,28 @@
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTableName(t *testing.T) {
+	t.Setenv("ENV", "prod")
+	t.Setenv("region", "eu")
+
+	name, err := expandTableName("myapp-{env}-{region}-kv", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "myapp-prod-eu-kv", name)
+
+	name, err = expandTableName("myapp-{env}-kv", map[string]string{"env": "dev"})
+	require.NoError(t, err)
+	assert.Equal(t, "myapp-dev-kv", name)
+
+	name, err = expandTableName("kv", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "kv", name)
+
+	_, err = expandTableName("myapp-{stage}-kv", nil)
+	assert.ErrorIs(t, err, ErrTableNameVariable)
+}
@@ -0,0 +1,70 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrTenantConflict is returned when the tenant mode is combined with another partitioning of the keys.
+var ErrTenantConflict = errors.New("tenant mode conflicts with the partitioning of the keys")
+
+// partitionKeyValue returns the hash key value of all the keys, the tenant ID in the tenant mode.
+func (c *Config) partitionKeyValue() (string, error) {
+	if c.TenantID == "" {
+		return c.PartitionKeyValue, nil
+	}
+
+	if c.PartitionKeyName == "" {
+		return "", ErrPartitionKeyNameMissing
+	}
+
+	if c.PartitionKeyValue != "" || c.PartitionKeyFunc != nil || c.DirectoryLayout {
+		return "", fmt.Errorf("%w: tenant %s", ErrTenantConflict, c.TenantID)
+	}
+
+	return c.TenantID, nil
+}
+
+// tenantActions the DynamoDB actions of the store allowed on the items of a tenant.
+// The scans, reading all the partitions, and the streams are not allowed.
+func tenantActions() []string {
+	return []string{
+		"dynamodb:GetItem",
+		"dynamodb:BatchGetItem",
+		"dynamodb:Query",
+		"dynamodb:PutItem",
+		"dynamodb:UpdateItem",
+		"dynamodb:DeleteItem",
+		"dynamodb:BatchWriteItem",
+		"dynamodb:ConditionCheckItem",
+	}
+}
+
+// TenantPolicy returns the IAM policy document allowing the store operations on the items of a tenant only,
+// with a dynamodb:LeadingKeys condition on its partition.
+// The credentials of the stores of the tenant (see Config.Credentials) must be restricted by this policy,
+// such as the session policy of the assumed role, to enforce the isolation of the tenants.
+// The watches of the tenant must use polling (see Config.WatchPollInterval), the stream reads not being allowed.
+func TenantPolicy(tableARN, tenantID string) (string, error) {
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":   "Allow",
+			"Action":   tenantActions(),
+			"Resource": tableARN,
+			"Condition": map[string]interface{}{
+				"ForAllValues:StringEquals": map[string]interface{}{
+					"dynamodb:LeadingKeys": []string{tenantID},
+				},
+			},
+		}},
+	}
+
+	document, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+
+	return string(document), nil
+}
@@ -0,0 +1,60 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenant(t *testing.T) {
+	value, err := (&Config{PartitionKeyName: "tenant", TenantID: "acme"}).partitionKeyValue()
+	require.NoError(t, err)
+	assert.Equal(t, "acme", value)
+
+	_, err = (&Config{TenantID: "acme"}).partitionKeyValue()
+	assert.ErrorIs(t, err, ErrPartitionKeyNameMissing)
+
+	_, err = (&Config{PartitionKeyName: "tenant", TenantID: "acme", DirectoryLayout: true}).partitionKeyValue()
+	assert.ErrorIs(t, err, ErrTenantConflict)
+
+	kv := &Store{partitionKeyName: "tenant", partitionKeyValue: "acme"}
+
+	attrs := kv.keyAttributes("a/b")
+	assert.Equal(t, "acme", aws.StringValue(attrs["tenant"].S))
+	assert.True(t, kv.ownsItem(attrs))
+
+	// the items of the other tenants are ignored.
+	assert.False(t, kv.ownsItem(map[string]*dynamodb.AttributeValue{
+		"tenant":     {S: aws.String("other")},
+		partitionKey: {S: aws.String("a/b")},
+	}))
+
+	// the prefix reads query the partition of the tenant.
+	qi := kv.prefixQuery("a/", true)
+	require.NotNil(t, qi)
+	assert.Equal(t, "acme", aws.StringValue(qi.ExpressionAttributeValues[":hashValue"].S))
+}
+
+func TestTenantPolicy(t *testing.T) {
+	document, err := TenantPolicy("arn:aws:dynamodb:us-east-1:123456789012:table/kv", "acme")
+	require.NoError(t, err)
+
+	var policy struct {
+		Statement []struct {
+			Action    []string
+			Resource  string
+			Condition map[string]map[string][]string
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(document), &policy))
+	require.Len(t, policy.Statement, 1)
+
+	statement := policy.Statement[0]
+	assert.Equal(t, "arn:aws:dynamodb:us-east-1:123456789012:table/kv", statement.Resource)
+	assert.Equal(t, []string{"acme"}, statement.Condition["ForAllValues:StringEquals"]["dynamodb:LeadingKeys"])
+	assert.NotContains(t, statement.Action, "dynamodb:Scan")
+}
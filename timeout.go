@@ -0,0 +1,33 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts bounds the duration of the store operations, including their retries,
+// on top of the deadline of their context.
+// The zero durations leave the operations bounded by their context only.
+type Timeouts struct {
+	// Read bounds Get, Exists, GetMany, and BatchGet.
+	Read time.Duration
+
+	// Write bounds Put, AtomicPut, Delete, AtomicDelete, PutMany, DeleteMany, and the commit of the transactions.
+	Write time.Duration
+
+	// List bounds List and ListPage.
+	// ListStream, reading the pages as they are consumed, is bounded by its context only.
+	List time.Duration
+
+	// DeleteTree bounds DeleteTree, listing the keys and writing all the delete batches.
+	// CopyTree, writing the keys page by page, is bounded by its context only.
+	DeleteTree time.Duration
+}
+
+// withTimeout returns a copy of ctx canceled after timeout, if positive.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
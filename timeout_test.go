@@ -0,0 +1,51 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeouts(t *testing.T) {
+	kv := &Store{
+		dynamoSvc: &mockedBlockingCalls{},
+		tableName: TestTableName,
+		timeouts:  Timeouts{Read: 10 * time.Millisecond, Write: 10 * time.Millisecond},
+	}
+
+	_, err := kv.Get(context.Background(), "key", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = kv.Put(context.Background(), "key", []byte("value"), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// without timeout, the operations are bounded by their context only.
+	kv.timeouts = Timeouts{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = kv.Get(ctx, "key", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// mockedBlockingCalls blocks the calls until their context is done.
+type mockedBlockingCalls struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockedBlockingCalls) GetItemWithContext(ctx aws.Context, _ *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *mockedBlockingCalls) UpdateItemWithContext(ctx aws.Context, _ *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
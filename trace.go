@@ -0,0 +1,113 @@
+package dynamodb
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// The attributes of the spans, following the OpenTelemetry semantic conventions.
+const (
+	TraceAttributeDBSystem         = "db.system"
+	TraceAttributeOperation        = "db.operation"
+	TraceAttributeTableNames       = "aws.dynamodb.table_names"
+	TraceAttributeConsumedCapacity = "aws.dynamodb.consumed_capacity"
+	TraceAttributeKey              = "db.kv.key"
+)
+
+// Tracer starts the spans of the DynamoDB calls of the store.
+// It's implemented by an adapter of the tracing library, such as OpenTelemetry:
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...dynamodb.TraceAttribute) (context.Context, dynamodb.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(otelAttributes(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// Start starts a span, child of the span of ctx if any, and returns the context holding it.
+	Start(ctx context.Context, name string, attrs ...TraceAttribute) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs ...TraceAttribute)
+	// End ends the span, with an error status if err is not nil.
+	End(err error)
+}
+
+// TraceAttribute is an attribute of a span.
+// Value is a string, a []string, or a float64.
+type TraceAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// traceInterceptor returns the interceptor wrapping the calls in spans of tracer.
+func traceInterceptor(tracer Tracer) callInterceptor {
+	return func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error) {
+		attrs := []TraceAttribute{
+			{Key: TraceAttributeDBSystem, Value: "dynamodb"},
+			{Key: TraceAttributeOperation, Value: call.operation},
+		}
+		if call.table != "" {
+			attrs = append(attrs, TraceAttribute{Key: TraceAttributeTableNames, Value: []string{call.table}})
+		}
+		if call.key != "" {
+			attrs = append(attrs, TraceAttribute{Key: TraceAttributeKey, Value: call.key})
+		}
+
+		ctx, span := tracer.Start(ctx, "DynamoDB."+call.operation, attrs...)
+
+		out, err := next(ctx)
+
+		if units := consumedCapacity(out); units > 0 {
+			span.SetAttributes(TraceAttribute{Key: TraceAttributeConsumedCapacity, Value: units})
+		}
+		span.End(err)
+
+		return out, err
+	}
+}
+
+// consumedCapacity returns the capacity units consumed by a call, reported in its output.
+func consumedCapacity(output interface{}) float64 {
+	if v := reflect.ValueOf(output); !v.IsValid() || v.IsNil() {
+		return 0
+	}
+
+	var capacities []*dynamodb.ConsumedCapacity
+
+	switch out := output.(type) {
+	case *dynamodb.GetItemOutput:
+		capacities = append(capacities, out.ConsumedCapacity)
+	case *dynamodb.PutItemOutput:
+		capacities = append(capacities, out.ConsumedCapacity)
+	case *dynamodb.UpdateItemOutput:
+		capacities = append(capacities, out.ConsumedCapacity)
+	case *dynamodb.DeleteItemOutput:
+		capacities = append(capacities, out.ConsumedCapacity)
+	case *dynamodb.QueryOutput:
+		capacities = append(capacities, out.ConsumedCapacity)
+	case *dynamodb.ScanOutput:
+		capacities = append(capacities, out.ConsumedCapacity)
+	case *dynamodb.BatchGetItemOutput:
+		capacities = out.ConsumedCapacity
+	case *dynamodb.BatchWriteItemOutput:
+		capacities = out.ConsumedCapacity
+	case *dynamodb.TransactGetItemsOutput:
+		capacities = out.ConsumedCapacity
+	case *dynamodb.TransactWriteItemsOutput:
+		capacities = out.ConsumedCapacity
+	}
+
+	var units float64
+	for _, c := range capacities {
+		if c != nil {
+			units += aws.Float64Value(c.CapacityUnits)
+		}
+	}
+
+	return units
+}
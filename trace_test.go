@@ -0,0 +1,79 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+
+	kv := &Store{tableName: TestTableName}
+	require.NoError(t, kv.initClients(nil, &Config{
+		DynamoDBClient: &mockedGetItem{items: map[string]string{"key": "dmFsdWU="}},
+		StreamsClient:  &mockedStreams{},
+		Tracer:         tracer,
+	}))
+
+	_, err := kv.Get(context.Background(), "key", nil)
+	require.NoError(t, err)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "DynamoDB.GetItem", span.name)
+	assert.Equal(t, "key", span.attributes[TraceAttributeKey])
+	assert.Equal(t, []string{TestTableName}, span.attributes[TraceAttributeTableNames])
+	assert.True(t, span.ended)
+	assert.NoError(t, span.err)
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	assert.Zero(t, consumedCapacity((*dynamodb.GetItemOutput)(nil)))
+	assert.Zero(t, consumedCapacity(&dynamodb.GetItemOutput{}))
+	assert.Equal(t, 1.5, consumedCapacity(&dynamodb.UpdateItemOutput{
+		ConsumedCapacity: &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(1.5)},
+	}))
+	assert.Equal(t, 3.0, consumedCapacity(&dynamodb.TransactWriteItemsOutput{
+		ConsumedCapacity: []*dynamodb.ConsumedCapacity{{CapacityUnits: aws.Float64(1)}, {CapacityUnits: aws.Float64(2)}},
+	}))
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, attrs ...TraceAttribute) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	span := &recordedSpan{name: name, attributes: make(map[string]interface{})}
+	span.SetAttributes(attrs...)
+	r.spans = append(r.spans, span)
+
+	return ctx, span
+}
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+	err        error
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...TraceAttribute) {
+	for _, attr := range attrs {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
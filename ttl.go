@@ -0,0 +1,45 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrTTLAttributeConflict is returned when the TTL of the table is enabled on another attribute.
+var ErrTTLAttributeConflict = errors.New("table TTL enabled on another attribute")
+
+// EnsureTTL enables the TTL of the table on the expiration time attribute,
+// so DynamoDB deletes the expired items instead of only filtering them out on reads.
+// It does nothing if the TTL is already enabled, or being enabled.
+func (ddb *Store) EnsureTTL(ctx context.Context) error {
+	res, err := ddb.dynamoSvc.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(ddb.tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	if desc := res.TimeToLiveDescription; desc != nil {
+		switch aws.StringValue(desc.TimeToLiveStatus) {
+		case dynamodb.TimeToLiveStatusEnabled, dynamodb.TimeToLiveStatusEnabling:
+			if name := aws.StringValue(desc.AttributeName); name != ddb.ttlName() {
+				return fmt.Errorf("%w: %s", ErrTTLAttributeConflict, name)
+			}
+			return nil
+		}
+	}
+
+	_, err = ddb.dynamoSvc.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(ddb.tableName),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(ddb.ttlName()),
+			Enabled:       aws.Bool(true),
+		},
+	})
+
+	return err
+}
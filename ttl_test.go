@@ -0,0 +1,53 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStoreEnsureTTL(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// the TTL is enabled on table creation, ensure this is idempotent.
+	err := ddbStore.EnsureTTL(ctx)
+	require.NoError(t, err)
+
+	res, err := ddbStore.dynamoSvc.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(ddbStore.tableName),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ttlAttribute, aws.StringValue(res.TimeToLiveDescription.AttributeName))
+}
+
+func TestEnsureTTL(t *testing.T) {
+	mock := &mockedCreateTable{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	err := kv.EnsureTTL(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, mock.ttl)
+	assert.True(t, aws.BoolValue(mock.ttl.TimeToLiveSpecification.Enabled))
+
+	mock = &mockedCreateTable{ttlDescription: &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String(ttlAttribute),
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+	}}
+	kv.dynamoSvc = mock
+
+	err = kv.EnsureTTL(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, mock.ttl)
+
+	mock.ttlDescription.AttributeName = aws.String("ttl")
+
+	err = kv.EnsureTTL(context.Background())
+	assert.ErrorIs(t, err, ErrTTLAttributeConflict)
+}
@@ -0,0 +1,521 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+var (
+	// ErrTxnDuplicateKey is returned when a transaction writes a key twice.
+	ErrTxnDuplicateKey = errors.New("key written twice in the transaction")
+	// ErrTxnTooLarge is returned when a transaction has more operations than DynamoDB supports.
+	ErrTxnTooLarge = errors.New("too many operations in the transaction")
+)
+
+type txnOpKind int
+
+const (
+	txnCheck txnOpKind = iota
+	txnPut
+	txnDelete
+)
+
+// txnOp an operation on a key of a transaction, with its condition if any.
+type txnOp struct {
+	key  string
+	kind txnOpKind
+
+	// put.
+	attrs map[string]*dynamodb.AttributeValue
+	opts  *store.WriteOptions
+
+	// condition: the key must not exist if previous is nil.
+	conditional bool
+	previous    *store.KVPair
+
+	// version the value attributes recorded in the history, if enabled.
+	version map[string]*dynamodb.AttributeValue
+}
+
+// Txn is a set of writes and checks on multiple keys, committed atomically in a DynamoDB transaction.
+// A check and a write on the same key are merged in a conditional write.
+// With the history, the keys are read first, and the revisions written are recorded in the same transaction.
+//
+// The values stored in chunks or in S3 can't be written in a transaction,
+// and the chunks or S3 objects of the values replaced or deleted by a transaction are not removed.
+type Txn struct {
+	ddb *Store
+	ctx context.Context
+
+	ops   []*txnOp
+	index map[string]*txnOp
+	err   error
+}
+
+// Transact starts a transaction, run by Commit.
+func (ddb *Store) Transact(ctx context.Context) *Txn {
+	return &Txn{
+		ddb:   ddb,
+		ctx:   ctx,
+		index: make(map[string]*txnOp),
+	}
+}
+
+// Put writes a value at key.
+func (t *Txn) Put(key string, value []byte, opts *store.WriteOptions) *Txn {
+	key = t.ddb.normalizeKey(key)
+
+	if err := t.ddb.checkKey(key); err != nil {
+		t.err = err
+		return t
+	}
+
+	data, enc, err := t.ddb.encodeValue(t.ctx, key, value)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	if t.ddb.isChunkSize(data) || (t.ddb.s3Overflow != nil && len(data) > t.ddb.s3Overflow.Threshold) {
+		t.err = fmt.Errorf("%w in a transaction: %s", ErrValueTooLarge, key)
+		return t
+	}
+
+	if err = t.ddb.checkValueSize(key, data, enc); err != nil {
+		t.err = err
+		return t
+	}
+
+	op := &txnOp{key: key, kind: txnPut, attrs: t.ddb.valueAttributes(data, enc), opts: opts}
+	if t.ddb.history != nil {
+		op.version = t.ddb.historyValue(key, data, enc)
+	}
+
+	return t.write(op)
+}
+
+// Delete deletes key.
+func (t *Txn) Delete(key string) *Txn {
+	return t.write(&txnOp{key: t.ddb.normalizeKey(key), kind: txnDelete})
+}
+
+// CheckRevision requires key to exist at revision, and not to be expired.
+func (t *Txn) CheckRevision(key string, revision uint64) *Txn {
+	return t.check(key, &store.KVPair{Key: key, LastIndex: revision})
+}
+
+// CheckNotExists requires key not to exist, or to be expired.
+func (t *Txn) CheckNotExists(key string) *Txn {
+	return t.check(key, nil)
+}
+
+func (t *Txn) check(key string, previous *store.KVPair) *Txn {
+	key = t.ddb.normalizeKey(key)
+
+	if err := t.ddb.checkKey(key); err != nil {
+		t.err = err
+		return t
+	}
+
+	if op, ok := t.index[key]; ok {
+		op.conditional = true
+		op.previous = previous
+		return t
+	}
+
+	return t.add(&txnOp{key: key, kind: txnCheck, conditional: true, previous: previous})
+}
+
+func (t *Txn) write(op *txnOp) *Txn {
+	if err := t.ddb.checkKey(op.key); err != nil {
+		t.err = err
+		return t
+	}
+
+	existing, ok := t.index[op.key]
+	if !ok {
+		return t.add(op)
+	}
+
+	if existing.kind != txnCheck {
+		t.err = fmt.Errorf("%w: %s", ErrTxnDuplicateKey, op.key)
+		return t
+	}
+
+	// the write takes the condition of the check.
+	existing.kind, existing.attrs, existing.opts, existing.version = op.kind, op.attrs, op.opts, op.version
+
+	return t
+}
+
+func (t *Txn) add(op *txnOp) *Txn {
+	t.ops = append(t.ops, op)
+	t.index[op.key] = op
+
+	return t
+}
+
+// Commit runs the transaction, through the middlewares as an OperationCommit with the Keys written or deleted.
+// If a condition fails, nothing is written and store.ErrKeyModified is returned,
+// or store.ErrKeyExists for a CheckNotExists condition.
+func (t *Txn) Commit() error {
+	if t.err != nil {
+		return t.err
+	}
+
+	if len(t.ops) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(t.ops))
+	for _, op := range t.ops {
+		if op.kind != txnCheck {
+			keys = append(keys, op.key)
+		}
+	}
+
+	return t.ddb.runOperation(t.ctx, &Operation{Name: OperationCommit, Keys: keys}, func(ctx context.Context, _ *Operation) error {
+		return t.commit(ctx)
+	})
+}
+
+// commit runs the transaction with ctx, without the middlewares.
+func (t *Txn) commit(ctx context.Context) error {
+	if t.err != nil {
+		return t.err
+	}
+
+	if len(t.ops) == 0 {
+		return nil
+	}
+
+	if len(t.ops) > maxTransactionItems {
+		return fmt.Errorf("%w: %d", ErrTxnTooLarge, len(t.ops))
+	}
+
+	if t.ddb.history != nil {
+		return t.commitRecorded(ctx)
+	}
+
+	items := make([]*dynamodb.TransactWriteItem, len(t.ops))
+	for i, op := range t.ops {
+		items[i] = t.ddb.txnItem(op)
+	}
+
+	ctx, cancel := withTimeout(ctx, t.ddb.timeouts.Write)
+	defer cancel()
+
+	_, err := t.ddb.dynamoSvc.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		return t.commitError(err)
+	}
+
+	return nil
+}
+
+// commitRecorded runs the transaction, recording the revisions written in the history.
+// The current items of the keys are read first, and the conditions of the transaction are checked on them.
+// The transaction is conditioned on the revisions read, and retried if a key is modified concurrently.
+func (t *Txn) commitRecorded(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, t.ddb.timeouts.Write)
+	defer cancel()
+
+	keys := make([]string, len(t.ops))
+	for i, op := range t.ops {
+		keys[i] = op.key
+	}
+
+	for i := 0; i < maxHistoryWriteAttempts; i++ {
+		current, err := t.ddb.transactGetItems(ctx, keys)
+		if err != nil {
+			return err
+		}
+
+		items, err := t.recordedItems(ctx, current)
+		if err != nil {
+			return err
+		}
+
+		_, err = t.ddb.dynamoSvc.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		if isTransactionConditionFailed(err) {
+			continue
+		}
+
+		return err
+	}
+
+	return store.ErrKeyModified
+}
+
+// recordedItems returns the transaction items of the operations on the current items of the keys,
+// conditioned on their revisions, followed by the items of their history.
+func (t *Txn) recordedItems(ctx context.Context, current map[string]map[string]*dynamodb.AttributeValue) ([]*dynamodb.TransactWriteItem, error) {
+	items := make([]*dynamodb.TransactWriteItem, 0, len(t.ops))
+	var versions []*dynamodb.TransactWriteItem
+
+	for _, op := range t.ops {
+		item := current[op.key]
+
+		if op.conditional {
+			if err := t.ddb.atomicPutCheck(op.previous)(item); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, op.key)
+			}
+		}
+
+		exAttr := make(map[string]*dynamodb.AttributeValue)
+		exNames := make(map[string]*string)
+
+		switch op.kind {
+		case txnPut:
+			revision, seed, err := t.ddb.nextRevision(ctx, op.key, item)
+			if err != nil {
+				return nil, err
+			}
+
+			var updateExp string
+			updateExp, exAttr, exNames = t.ddb.seededWriteUpdate(t.ddb.indexAttributes(op.key, op.attrs), op.opts, seed)
+			condExp := t.ddb.revisionCondition(item, exAttr, exNames)
+
+			items = append(items, &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+				TableName:                 aws.String(t.ddb.tableName),
+				Key:                       t.ddb.keyAttributes(op.key),
+				UpdateExpression:          aws.String(updateExp),
+				ConditionExpression:       aws.String(condExp),
+				ExpressionAttributeNames:  exNames,
+				ExpressionAttributeValues: exAttr,
+			}})
+			versions = append(versions, t.ddb.versionItems(op.key, revision, op.version)...)
+
+		case txnDelete:
+			condExp := t.ddb.revisionCondition(item, exAttr, exNames)
+
+			items = append(items, &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+				TableName:                 aws.String(t.ddb.tableName),
+				Key:                       t.ddb.keyAttributes(op.key),
+				ConditionExpression:       aws.String(condExp),
+				ExpressionAttributeNames:  exNames,
+				ExpressionAttributeValues: exAttr,
+			}})
+			// the keys which don't exist, or are expired, are deleted without tombstone.
+			if item != nil && !t.ddb.isItemExpired(item) {
+				revision, _, err := t.ddb.nextRevision(ctx, op.key, item)
+				if err != nil {
+					return nil, err
+				}
+				versions = append(versions, t.ddb.versionItems(op.key, revision, nil)...)
+			}
+
+		default:
+			condExp := t.ddb.revisionCondition(item, exAttr, exNames)
+
+			items = append(items, &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+				TableName:                 aws.String(t.ddb.tableName),
+				Key:                       t.ddb.keyAttributes(op.key),
+				ConditionExpression:       aws.String(condExp),
+				ExpressionAttributeNames:  exNames,
+				ExpressionAttributeValues: exAttr,
+			}})
+		}
+	}
+
+	if len(items)+len(versions) > maxTransactionItems {
+		return nil, fmt.Errorf("%w: %d with the history", ErrTxnTooLarge, len(items)+len(versions))
+	}
+
+	return append(items, versions...), nil
+}
+
+// commitError returns the error of the first failed condition, if any.
+func (t *Txn) commitError(err error) error {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return err
+	}
+
+	for i, reason := range canceled.CancellationReasons {
+		if aws.StringValue(reason.Code) != "ConditionalCheckFailed" || i >= len(t.ops) {
+			continue
+		}
+
+		if t.ops[i].previous == nil {
+			return fmt.Errorf("%w: %s", store.ErrKeyExists, t.ops[i].key)
+		}
+		return fmt.Errorf("%w: %s", store.ErrKeyModified, t.ops[i].key)
+	}
+
+	return err
+}
+
+// txnItem returns the transaction item of an operation.
+func (ddb *Store) txnItem(op *txnOp) *dynamodb.TransactWriteItem {
+	exAttr := make(map[string]*dynamodb.AttributeValue)
+	exNames := make(map[string]*string)
+
+	var condExp *string
+	if op.conditional {
+		condExp = aws.String(ddb.atomicPutCondition(op.previous, exAttr, exNames))
+	}
+
+	switch op.kind {
+	case txnPut:
+		updateExp, updateAttr, updateNames := ddb.writeUpdate(ddb.indexAttributes(op.key, op.attrs), op.opts)
+		for name, v := range updateAttr {
+			exAttr[name] = v
+		}
+		for placeholder, name := range updateNames {
+			exNames[placeholder] = name
+		}
+
+		return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:                 aws.String(ddb.tableName),
+			Key:                       ddb.keyAttributes(op.key),
+			UpdateExpression:          aws.String(updateExp),
+			ConditionExpression:       condExp,
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		}}
+
+	case txnDelete:
+		if !op.conditional {
+			exAttr, exNames = nil, nil
+		}
+
+		return &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+			TableName:                 aws.String(ddb.tableName),
+			Key:                       ddb.keyAttributes(op.key),
+			ConditionExpression:       condExp,
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		}}
+
+	default:
+		return &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+			TableName:                 aws.String(ddb.tableName),
+			Key:                       ddb.keyAttributes(op.key),
+			ConditionExpression:       condExp,
+			ExpressionAttributeNames:  exNames,
+			ExpressionAttributeValues: exAttr,
+		}}
+	}
+}
+
+// GetMany returns the values of keys, in the order of keys, skipping the keys not found.
+// With consistent reads (the default), the keys are read in a single transaction,
+// so the values are a consistent snapshot, and at most 100 keys can be read.
+// Otherwise, the keys are read in batches.
+// The values stored in chunks or in S3 are read after the transaction.
+func (ddb *Store) GetMany(ctx context.Context, keys []string, opts *store.ReadOptions) ([]*store.KVPair, error) {
+	ctx, cancel := withTimeout(ctx, ddb.timeouts.Read)
+	defer cancel()
+
+	if opts == nil {
+		opts = &store.ReadOptions{
+			Consistent: !ddb.eventuallyConsistent, // default to enabling read consistency.
+		}
+	}
+
+	keys = uniqueKeys(ddb.normalizeKeys(keys))
+
+	if err := ddb.checkKeys(keys); err != nil {
+		return nil, err
+	}
+
+	var items map[string]map[string]*dynamodb.AttributeValue
+	var err error
+
+	if opts.Consistent {
+		items, err = ddb.transactGetItems(ctx, keys)
+	} else {
+		items, err = ddb.batchGetItems(ctx, keys, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.decodeKeys(ctx, keys, items, opts.Consistent)
+}
+
+// transactGetItems reads items by normalized key in a single transaction.
+func (ddb *Store) transactGetItems(ctx context.Context, keys []string) (map[string]map[string]*dynamodb.AttributeValue, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	if len(keys) > maxTransactionItems {
+		return nil, fmt.Errorf("%w: %d keys", ErrTxnTooLarge, len(keys))
+	}
+
+	gets := make([]*dynamodb.TransactGetItem, len(keys))
+	for i, key := range keys {
+		gets[i] = &dynamodb.TransactGetItem{Get: &dynamodb.Get{
+			TableName: aws.String(ddb.tableName),
+			Key:       ddb.keyAttributes(key),
+		}}
+	}
+
+	res, err := ddb.readSvc().TransactGetItemsWithContext(ctx, &dynamodb.TransactGetItemsInput{TransactItems: gets})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]map[string]*dynamodb.AttributeValue, len(keys))
+	for _, response := range res.Responses {
+		if response.Item != nil {
+			items[ddb.itemKey(response.Item)] = response.Item
+		}
+	}
+
+	return items, nil
+}
+
+// decodeKeys returns the pairs of the items read for keys, in the order of keys,
+// skipping the keys not found or expired.
+func (ddb *Store) decodeKeys(ctx context.Context, keys []string, items map[string]map[string]*dynamodb.AttributeValue, consistent bool) ([]*store.KVPair, error) {
+	pairs := make([]*store.KVPair, 0, len(items))
+
+	for _, key := range keys {
+		item, ok := items[key]
+		if !ok || ddb.isItemExpired(item) {
+			continue
+		}
+
+		item, err := ddb.loadExternal(ctx, item, consistent, items)
+		if err != nil {
+			return nil, err
+		}
+
+		pair, err := ddb.decodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// uniqueKeys returns keys without the duplicates, in order.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	unique := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, key)
+	}
+
+	return unique
+}
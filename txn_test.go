@@ -0,0 +1,217 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBStoreTxn(t *testing.T) {
+	ddbStore := newDynamoDBStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	err := ddbStore.Put(ctx, "testTxn/a", []byte("a"), nil)
+	require.NoError(t, err)
+	err = ddbStore.Put(ctx, "testTxn/c", []byte("c"), nil)
+	require.NoError(t, err)
+
+	a, err := ddbStore.Get(ctx, "testTxn/a", nil)
+	require.NoError(t, err)
+
+	err = ddbStore.Transact(ctx).
+		CheckRevision("testTxn/a", a.LastIndex).
+		Put("testTxn/b", []byte("b"), nil).
+		Delete("testTxn/c").
+		Commit()
+	require.NoError(t, err)
+
+	b, err := ddbStore.Get(ctx, "testTxn/b", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), b.Value)
+
+	_, err = ddbStore.Get(ctx, "testTxn/c", nil)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	// the transaction fails on the stale revision, and nothing is written.
+	err = ddbStore.Transact(ctx).
+		Put("testTxn/a", []byte("a2"), nil).
+		CheckRevision("testTxn/b", b.LastIndex+1).
+		Commit()
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	a2, err := ddbStore.Get(ctx, "testTxn/a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, a, a2)
+}
+
+func TestTxn(t *testing.T) {
+	mock := &mockedTransactWrite{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	err := kv.Transact(ctx).
+		CheckRevision("a", 3).
+		Put("a", []byte("value"), nil).
+		CheckNotExists("b").
+		Delete("c").
+		Commit()
+	require.NoError(t, err)
+
+	items := mock.input.TransactItems
+	require.Len(t, items, 3)
+
+	// the check is merged in the put.
+	require.NotNil(t, items[0].Update)
+	assert.Contains(t, aws.StringValue(items[0].Update.ConditionExpression), ":lastRevision")
+	assert.Equal(t, "3", aws.StringValue(items[0].Update.ExpressionAttributeValues[":lastRevision"].N))
+
+	require.NotNil(t, items[1].ConditionCheck)
+	assert.Contains(t, aws.StringValue(items[1].ConditionCheck.ConditionExpression), "attribute_not_exists")
+
+	require.NotNil(t, items[2].Delete)
+	assert.Nil(t, items[2].Delete.ConditionExpression)
+
+	err = kv.Transact(ctx).Put("a", []byte("1"), nil).Delete("a").Commit()
+	assert.ErrorIs(t, err, ErrTxnDuplicateKey)
+
+	mock.failedIndex = aws.Int(1)
+
+	err = kv.Transact(ctx).Delete("a").CheckNotExists("b").Commit()
+	assert.ErrorIs(t, err, store.ErrKeyExists)
+
+	err = kv.Transact(ctx).Delete("a").CheckRevision("b", 1).Commit()
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+}
+
+func TestTxnNormalizedKeys(t *testing.T) {
+	mock := &mockedTransactWrite{}
+	kv := &Store{
+		dynamoSvc:        mock,
+		tableName:        TestTableName,
+		keyNormalization: &KeyNormalization{TrimLeadingSlash: true},
+	}
+
+	err := kv.Transact(context.Background()).
+		CheckRevision("/a", 3).
+		Put("a", []byte("value"), nil).
+		Commit()
+	require.NoError(t, err)
+
+	// the check of "/a" is merged in the put of "a".
+	items := mock.input.TransactItems
+	require.Len(t, items, 1)
+	require.NotNil(t, items[0].Update)
+	assert.Equal(t, "3", aws.StringValue(items[0].Update.ExpressionAttributeValues[":lastRevision"].N))
+
+	err = kv.Transact(context.Background()).Put("a", []byte("1"), nil).Delete("/a").Commit()
+	assert.ErrorIs(t, err, ErrTxnDuplicateKey)
+}
+
+// mockedTransactWrite fails the condition of the item at failedIndex if set.
+type mockedTransactWrite struct {
+	dynamodbiface.DynamoDBAPI
+
+	failedIndex *int
+	input       *dynamodb.TransactWriteItemsInput
+}
+
+func (m *mockedTransactWrite) TransactWriteItemsWithContext(_ aws.Context, input *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.input = input
+
+	if m.failedIndex == nil {
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+
+	reasons := make([]*dynamodb.CancellationReason, len(input.TransactItems))
+	for i := range reasons {
+		reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+	}
+	reasons[*m.failedIndex].Code = aws.String("ConditionalCheckFailed")
+
+	return nil, &dynamodb.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func TestGetMany(t *testing.T) {
+	mock := &mockedMultiGet{items: map[string]map[string]*dynamodb.AttributeValue{
+		"a": newTestItem("a", "dmFsdWUx"),
+		"c": newTestItem("c", "dmFsdWUz"),
+	}}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	pairs, err := kv.GetMany(ctx, []string{"c", "b", "a", "c"}, nil)
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "c", pairs[0].Key)
+	assert.Equal(t, []byte("value1"), pairs[1].Value)
+	assert.Equal(t, 1, mock.transactions)
+
+	pairs, err = kv.GetMany(ctx, []string{"a", "b"}, &store.ReadOptions{})
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, 1, mock.batches)
+}
+
+func TestGetManyNormalizedKeys(t *testing.T) {
+	mock := &mockedMultiGet{items: map[string]map[string]*dynamodb.AttributeValue{
+		"a/b": newTestItem("a/b", "dmFsdWU="),
+	}}
+	kv := &Store{
+		dynamoSvc:        mock,
+		tableName:        TestTableName,
+		keyNormalization: &KeyNormalization{TrimLeadingSlash: true, CollapseSlashes: true},
+	}
+
+	for _, opts := range []*store.ReadOptions{nil, {}} {
+		pairs, err := kv.GetMany(context.Background(), []string{"/a//b", "a/b"}, opts)
+		require.NoError(t, err)
+		require.Len(t, pairs, 1)
+		assert.Equal(t, "a/b", pairs[0].Key)
+	}
+}
+
+// mockedMultiGet serves items by key.
+type mockedMultiGet struct {
+	dynamodbiface.DynamoDBAPI
+
+	items        map[string]map[string]*dynamodb.AttributeValue
+	transactions int
+	batches      int
+}
+
+func (m *mockedMultiGet) TransactGetItemsWithContext(_ aws.Context, input *dynamodb.TransactGetItemsInput, _ ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	m.transactions++
+
+	out := &dynamodb.TransactGetItemsOutput{}
+	for _, get := range input.TransactItems {
+		out.Responses = append(out.Responses, &dynamodb.ItemResponse{Item: m.items[itemKey(get.Get.Key)]})
+	}
+
+	return out, nil
+}
+
+func (m *mockedMultiGet) BatchGetItemWithContext(_ aws.Context, input *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	m.batches++
+
+	out := &dynamodb.BatchGetItemOutput{Responses: make(map[string][]map[string]*dynamodb.AttributeValue)}
+	for table, request := range input.RequestItems {
+		for _, key := range request.Keys {
+			if item, ok := m.items[itemKey(key)]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+
+	return out, nil
+}
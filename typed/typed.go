@@ -0,0 +1,136 @@
+// Package typed provides a Store of typed values over a DynamoDB store,
+// the values being marshaled to and from the bytes stored.
+package typed
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kvtools/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// Marshaler converts the values of type T to and from the bytes stored.
+type Marshaler[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSON is the Marshaler of the values as JSON documents.
+type JSON[T any] struct{}
+
+// Marshal returns the JSON document of v.
+func (JSON[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal returns the value of the JSON document data.
+func (JSON[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+
+	return v, err
+}
+
+// Entry is a typed value, with its key and its revision.
+type Entry[T any] struct {
+	Key      string
+	Value    T
+	Revision uint64
+}
+
+// Store stores values of type T in a DynamoDB store.
+type Store[T any] struct {
+	kv        *dynamodb.Store
+	marshaler Marshaler[T]
+}
+
+// New returns the Store of the values of type T in kv, marshaled by marshaler, as JSON documents if nil.
+func New[T any](kv *dynamodb.Store, marshaler Marshaler[T]) *Store[T] {
+	if marshaler == nil {
+		marshaler = JSON[T]{}
+	}
+
+	return &Store[T]{kv: kv, marshaler: marshaler}
+}
+
+// Get returns the value of key, and its revision.
+// It returns store.ErrKeyNotFound if the key doesn't exist.
+func (s *Store[T]) Get(ctx context.Context, key string) (T, uint64, error) {
+	var zero T
+
+	pair, err := s.kv.Get(ctx, key, nil)
+	if err != nil {
+		return zero, 0, err
+	}
+
+	v, err := s.marshaler.Unmarshal(pair.Value)
+	if err != nil {
+		return zero, 0, err
+	}
+
+	return v, pair.LastIndex, nil
+}
+
+// Put writes the value of key, and returns its new revision.
+func (s *Store[T]) Put(ctx context.Context, key string, v T, opts *store.WriteOptions) (uint64, error) {
+	data, err := s.marshaler.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+
+	pair, err := s.kv.PutWithResult(ctx, key, data, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return pair.LastIndex, nil
+}
+
+// CASPut writes the value of key only if it's at revision, or if it doesn't exist when revision is 0,
+// and returns its new revision.
+// It returns store.ErrKeyModified if the key is at another revision, store.ErrKeyExists if it exists when revision is 0.
+func (s *Store[T]) CASPut(ctx context.Context, key string, v T, revision uint64, opts *store.WriteOptions) (uint64, error) {
+	data, err := s.marshaler.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+
+	var previous *store.KVPair
+	if revision > 0 {
+		previous = &store.KVPair{Key: key, LastIndex: revision}
+	}
+
+	_, pair, err := s.kv.AtomicPut(ctx, key, data, previous, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return pair.LastIndex, nil
+}
+
+// Delete removes key.
+func (s *Store[T]) Delete(ctx context.Context, key string) error {
+	return s.kv.Delete(ctx, key)
+}
+
+// List returns the values of the keys starting with prefix.
+// It returns store.ErrKeyNotFound if there are none.
+func (s *Store[T]) List(ctx context.Context, prefix string) ([]Entry[T], error) {
+	pairs, err := s.kv.List(ctx, prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry[T], 0, len(pairs))
+	for _, pair := range pairs {
+		v, err := s.marshaler.Unmarshal(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry[T]{Key: pair.Key, Value: v, Revision: pair.LastIndex})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,119 @@
+package typed
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type config struct {
+	Name     string `json:"name"`
+	Replicas int    `json:"replicas"`
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	kv, err := dynamodb.NewFromClient(ctx, &mockedTable{items: make(map[string]map[string]*awsdynamodb.AttributeValue)}, "table")
+	require.NoError(t, err)
+
+	configs := New[config](kv, nil)
+
+	_, _, err = configs.Get(ctx, "app")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	revision, err := configs.CASPut(ctx, "app", config{Name: "app", Replicas: 1}, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), revision)
+
+	_, err = configs.CASPut(ctx, "app", config{Name: "app", Replicas: 2}, 0, nil)
+	assert.ErrorIs(t, err, store.ErrKeyExists)
+
+	revision, err = configs.CASPut(ctx, "app", config{Name: "app", Replicas: 2}, revision, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), revision)
+
+	_, err = configs.CASPut(ctx, "app", config{Name: "app", Replicas: 3}, 1, nil)
+	assert.ErrorIs(t, err, store.ErrKeyModified)
+
+	v, revision, err := configs.Get(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, config{Name: "app", Replicas: 2}, v)
+	assert.Equal(t, uint64(2), revision)
+
+	names := New[string](kv, upperMarshaler{})
+
+	revision, err = names.Put(ctx, "name", "valkeyrie", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), revision)
+
+	name, _, err := names.Get(ctx, "name")
+	require.NoError(t, err)
+	assert.Equal(t, "valkeyrie", name)
+}
+
+// upperMarshaler stores the strings in upper case, and reads them in lower case.
+type upperMarshaler struct{}
+
+func (upperMarshaler) Marshal(v string) ([]byte, error) {
+	return []byte(strings.ToUpper(v)), nil
+}
+
+func (upperMarshaler) Unmarshal(data []byte) (string, error) {
+	return strings.ToLower(string(data)), nil
+}
+
+// mockedTable stores the values and the revisions of the keys, and checks the revisions of the AtomicPut updates.
+type mockedTable struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string]map[string]*awsdynamodb.AttributeValue
+}
+
+func (m *mockedTable) GetItemWithContext(_ aws.Context, input *awsdynamodb.GetItemInput, _ ...request.Option) (*awsdynamodb.GetItemOutput, error) {
+	return &awsdynamodb.GetItemOutput{Item: m.items[aws.StringValue(input.Key["id"].S)]}, nil
+}
+
+func (m *mockedTable) UpdateItemWithContext(_ aws.Context, input *awsdynamodb.UpdateItemInput, _ ...request.Option) (*awsdynamodb.UpdateItemOutput, error) {
+	key := aws.StringValue(input.Key["id"].S)
+	old, exists := m.items[key]
+
+	var revision uint64
+	if exists {
+		revision, _ = strconv.ParseUint(aws.StringValue(old["version"].N), 10, 64)
+	}
+
+	if input.ConditionExpression != nil {
+		lastRevision, ok := input.ExpressionAttributeValues[":lastRevision"]
+		if (!ok && exists) || (ok && aws.StringValue(lastRevision.N) != strconv.FormatUint(revision, 10)) {
+			return nil, awserr.New(awsdynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+		}
+	}
+
+	item := map[string]*awsdynamodb.AttributeValue{
+		"id":      {S: aws.String(key)},
+		"version": {N: aws.String(strconv.FormatUint(revision+1, 10))},
+	}
+	for placeholder, name := range input.ExpressionAttributeNames {
+		if aws.StringValue(name) == "encoded_value" {
+			item["encoded_value"] = input.ExpressionAttributeValues[":val"+strings.TrimPrefix(placeholder, "#attr")]
+		}
+	}
+	m.items[key] = item
+
+	if aws.StringValue(input.ReturnValues) == awsdynamodb.ReturnValueAllNew {
+		return &awsdynamodb.UpdateItemOutput{Attributes: item}, nil
+	}
+
+	return &awsdynamodb.UpdateItemOutput{Attributes: old}, nil
+}
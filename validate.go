@@ -0,0 +1,121 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const (
+	// maxItemSize the maximum size of a DynamoDB item.
+	maxItemSize = 400 * 1024
+	// maxPartitionKeySize and maxSortKeySize the maximum sizes of the key attributes.
+	maxPartitionKeySize = 2048
+	maxSortKeySize      = 1024
+	// itemAttributesSize the room left in the items for their attributes other than the key and the value.
+	itemAttributesSize = 512
+)
+
+var (
+	// ErrEmptyKey is returned by the operations on an empty key.
+	ErrEmptyKey = errors.New("key is empty")
+	// ErrKeyTooLong is returned by the operations on a key above the size limit of the DynamoDB key attributes.
+	ErrKeyTooLong = errors.New("key too long")
+)
+
+// checkKey returns a descriptive error if key can't be stored in the key attribute of the table.
+func (ddb *Store) checkKey(key string) error {
+	if ddb.normalizeKey(key) == "" {
+		return ErrEmptyKey
+	}
+
+	// the key is the sort key of the composite primary keys.
+	limit := maxPartitionKeySize
+	if ddb.hashKeyName() != "" {
+		limit = maxSortKeySize
+	}
+
+	if size := len(ddb.storedKey(key)); size > limit {
+		return fmt.Errorf("%w: %d bytes, above the %d bytes of the DynamoDB key attribute", ErrKeyTooLong, size, limit)
+	}
+
+	return nil
+}
+
+// checkKeys returns the error of the first key which can't be stored, if any.
+func (ddb *Store) checkKeys(keys []string) error {
+	for _, key := range keys {
+		if err := ddb.checkKey(key); err != nil {
+			return fmt.Errorf("%w: %q", err, key)
+		}
+	}
+
+	return nil
+}
+
+// checkValueSize returns a descriptive ErrValueTooLarge if the value data of key can't be stored in its item.
+// The values encoded by a Codec are left to DynamoDB.
+func (ddb *Store) checkValueSize(key string, data []byte, enc valueEncoding) error {
+	if enc.attrs != nil {
+		return nil
+	}
+
+	size := ddb.storedDataSize(data)
+
+	if size+len(ddb.storedKey(key))+itemAttributesSize > maxItemSize {
+		return fmt.Errorf("%w: %d bytes encoded, above the 400KB DynamoDB item size, unless stored in chunks or in S3",
+			ErrValueTooLarge, size)
+	}
+
+	return nil
+}
+
+// storedDataSize returns the size of the value data in its attribute, base64 encoded unless BinaryValues is set.
+func (ddb *Store) storedDataSize(data []byte) int {
+	if ddb.binaryValues {
+		return len(data)
+	}
+
+	return base64.StdEncoding.EncodedLen(len(data))
+}
+
+// checkStoredValue returns the error of encoding value, or a descriptive ErrValueTooLarge
+// if Put can't store it at key, in its item, in chunks, or in S3.
+func (ddb *Store) checkStoredValue(ctx context.Context, key string, value []byte) error {
+	if len(value) == 0 {
+		return nil
+	}
+
+	data, enc, err := ddb.encodeValue(ctx, key, value)
+	if err != nil {
+		return err
+	}
+
+	if ddb.isChunkSize(data) || (ddb.s3Overflow != nil && len(data) > ddb.s3Overflow.Threshold) {
+		return nil
+	}
+
+	return ddb.checkValueSize(key, data, enc)
+}
+
+// validateOperation returns the OperationFunc checking the key of the single key operations,
+// or the keys of the batches, before running run.
+func (ddb *Store) validateOperation(run OperationFunc) OperationFunc {
+	return func(ctx context.Context, op *Operation) error {
+		switch {
+		case op.Name == OperationList, op.Name == OperationDeleteTree, op.Name == OperationCommit,
+			op.Name == OperationTruncate, op.Name == OperationDeleteTable:
+		case isBatch(op):
+			if err := ddb.checkKeys(op.Keys); err != nil {
+				return err
+			}
+		default:
+			if err := ddb.checkKey(op.Key); err != nil {
+				return err
+			}
+		}
+
+		return run(ctx, op)
+	}
+}
@@ -0,0 +1,49 @@
+package dynamodb
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidation(t *testing.T) {
+	mock := &mockedRegion{}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	err := kv.Put(ctx, "", []byte("value"), nil)
+	assert.ErrorIs(t, err, ErrEmptyKey)
+
+	_, err = kv.Get(ctx, strings.Repeat("k", 2049), nil)
+	assert.ErrorIs(t, err, ErrKeyTooLong)
+
+	// the keys are the sort keys of the composite primary keys.
+	kv.partitionKeyName, kv.partitionKeyValue = "pk", "all"
+	_, err = kv.Exists(ctx, strings.Repeat("k", 1025), nil)
+	assert.ErrorIs(t, err, ErrKeyTooLong)
+	kv.partitionKeyName, kv.partitionKeyValue = "", ""
+
+	_, err = kv.BatchGet(ctx, []string{"a", ""})
+	assert.ErrorIs(t, err, ErrEmptyKey)
+
+	err = kv.Transact(ctx).Put("", []byte("value"), nil).Commit()
+	assert.ErrorIs(t, err, ErrEmptyKey)
+
+	err = kv.Transact(ctx).Delete(strings.Repeat("k", 2049)).Commit()
+	assert.ErrorIs(t, err, ErrKeyTooLong)
+
+	err = kv.Transact(ctx).CheckNotExists("").Commit()
+	assert.ErrorIs(t, err, ErrEmptyKey)
+
+	// the encoded value must fit in the item.
+	err = kv.Put(ctx, "key", make([]byte, 300*1024), nil)
+	assert.ErrorIs(t, err, ErrValueTooLarge)
+	assert.Equal(t, 0, mock.puts)
+
+	kv.binaryValues = true
+	require.NoError(t, kv.Put(ctx, "key", make([]byte, 300*1024), nil))
+}
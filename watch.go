@@ -0,0 +1,258 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+)
+
+// EventType is the kind of change notified by an Event.
+type EventType string
+
+// Event types.
+const (
+	EventCreate EventType = "CREATE"
+	EventUpdate EventType = "UPDATE"
+	EventDelete EventType = "DELETE"
+	// EventExpire is the deletion of an expired item by the Time To Live of DynamoDB.
+	EventExpire EventType = "EXPIRE"
+)
+
+// Event is a change notification on a key.
+type Event struct {
+	Key string
+	// Type of the change, may be empty if the notifier doesn't know it.
+	Type EventType
+	// Old and New are the values before and after the change, nil if the notifier doesn't know them.
+	// WatchEvents fills them from the images of the stream records.
+	Old *store.KVPair
+	New *store.KVPair
+
+	// oldImage and newImage are the items before and after the change held by the stream record, if any.
+	oldImage map[string]*dynamodb.AttributeValue
+	newImage map[string]*dynamodb.AttributeValue
+}
+
+// Notifier delivers the change notifications used by Watch and WatchTree.
+type Notifier interface {
+	// Subscribe returns a channel receiving the events on keys starting with prefix.
+	// The channel is closed when ctx is done.
+	Subscribe(ctx context.Context, prefix string) (<-chan *Event, error)
+
+	// Publish notifies the subscribers of a change.
+	Publish(ctx context.Context, event *Event) error
+
+	// Close releases the resources held by the notifier.
+	Close() error
+}
+
+// WatchOptions are the options of WatchWithOptions, WatchTreeWithOptions and WatchEventsWithOptions.
+type WatchOptions struct {
+	// ReadOptions are the options of the reads of the watched values, nil defaulting to the consistent reads.
+	ReadOptions *store.ReadOptions
+	// SkipInitial doesn't send the current value, or the current content of the directory, only the changes.
+	// The watched key doesn't need to exist.
+	SkipInitial bool
+	// AfterRevision resumes the watch of a key from a revision already received:
+	// the values with a revision up to AfterRevision are skipped, until a newer value or a deletion is sent.
+	// It's ignored by WatchTreeWithOptions.
+	AfterRevision uint64
+
+	// BufferSize is the number of values buffered for a slow consumer, 0 for an unbuffered channel.
+	// It also bounds the events queued for the watch by the stream and the SNS notifiers.
+	BufferSize int
+	// Overflow is the policy when the buffer is full, defaults to WatchOverflowBlock.
+	// WatchOverflowDropOldest requires a buffer.
+	Overflow WatchOverflow
+	// OnLag is called with the delivery lag of the watch each time a value is queued, such as to monitor a slow consumer.
+	OnLag func(lag *WatchLag)
+}
+
+// Watch for changes on a key.
+// The current value is sent first, then the new value is sent each time the key is changed.
+// An empty pair (only the key) is sent when the key is deleted.
+// If the notifier ends the subscription, such as when the stream consumer fails,
+// the watch subscribes again with backoff, and sends the current value if it changed meanwhile.
+func (ddb *Store) Watch(ctx context.Context, key string, opts *store.ReadOptions) (<-chan *store.KVPair, error) {
+	return ddb.WatchWithOptions(ctx, key, &WatchOptions{ReadOptions: opts})
+}
+
+// WatchWithOptions watches for changes on a key like Watch,
+// with the read options of the values, and without sending the current value or the values already received.
+func (ddb *Store) WatchWithOptions(ctx context.Context, key string, opts *WatchOptions) (<-chan *store.KVPair, error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+
+	ctx, done, err := ddb.tasks.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key = ddb.normalizeKey(key)
+
+	// subscribe before reading the current value, so no change is missed.
+	sub, err := ddb.subscribe(ctx, key, opts)
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	var pair *store.KVPair
+	if !opts.SkipInitial {
+		pair, err = ddb.Get(ctx, key, opts.ReadOptions)
+		if err != nil {
+			done()
+			return nil, err
+		}
+	}
+
+	out := newWatchOutput[*store.KVPair](key, opts)
+
+	go func() {
+		defer done()
+		defer close(out.ch)
+
+		after := opts.AfterRevision
+
+		for {
+			// the deletions are always sent, the revisions starting over when the key is created again.
+			if pair != nil && (pair.LastIndex == 0 || pair.LastIndex > after) {
+				if !out.send(ctx, pair) {
+					return
+				}
+
+				after = 0
+			}
+
+			if pair, err = ddb.nextWatchedPair(ctx, key, sub, pair, opts.ReadOptions); err != nil {
+				if ctx.Err() == nil {
+					ddb.log().Error("watch stopped", "key", key, "error", err)
+				}
+				return
+			}
+		}
+	}()
+
+	return out.ch, nil
+}
+
+// nextWatchedPair waits for the next event on key and returns the new value.
+// When the subscription was renewed, the changes made meanwhile being missed,
+// the current value is returned if it differs from last, the value read before.
+func (ddb *Store) nextWatchedPair(ctx context.Context, key string, sub *subscription, last *store.KVPair, opts *store.ReadOptions) (*store.KVPair, error) {
+	for {
+		event, err := sub.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if event != nil && event.Key != key {
+			continue
+		}
+
+		pair, err := ddb.Get(ctx, key, opts)
+		if errors.Is(err, store.ErrKeyNotFound) {
+			pair, err = &store.KVPair{Key: key}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if event == nil && samePair(pair, last) {
+			continue
+		}
+
+		return pair, nil
+	}
+}
+
+// samePair reports whether pair has the revision and the value of last.
+func samePair(pair, last *store.KVPair) bool {
+	return last != nil && pair.LastIndex == last.LastIndex && bytes.Equal(pair.Value, last.Value)
+}
+
+// WatchTree watches for changes on child nodes under a given directory.
+// The current content of the directory is sent first,
+// then a new snapshot is sent each time a child node is changed,
+// and after subscribing again if the notifier ends the subscription.
+func (ddb *Store) WatchTree(ctx context.Context, directory string, opts *store.ReadOptions) (<-chan []*store.KVPair, error) {
+	return ddb.WatchTreeWithOptions(ctx, directory, &WatchOptions{ReadOptions: opts})
+}
+
+// WatchTreeWithOptions watches for changes on child nodes under a given directory like WatchTree,
+// with the read options of the snapshots, and without sending the current content of the directory.
+func (ddb *Store) WatchTreeWithOptions(ctx context.Context, directory string, opts *WatchOptions) (<-chan []*store.KVPair, error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+
+	ctx, done, err := ddb.tasks.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// subscribe before taking the initial snapshot, so no change is missed.
+	sub, err := ddb.subscribe(ctx, ddb.normalizePrefix(directory), opts)
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	out := newWatchOutput[[]*store.KVPair](directory, opts)
+
+	go func() {
+		defer done()
+		defer close(out.ch)
+
+		if opts.SkipInitial && !nextWatchedChange(ctx, sub) {
+			return
+		}
+
+		for {
+			pairs, err := ddb.List(ctx, directory, opts.ReadOptions)
+			if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+				if ctx.Err() == nil {
+					ddb.log().Error("watch stopped", "directory", directory, "error", err)
+				}
+				return
+			}
+
+			if !out.send(ctx, pairs) {
+				return
+			}
+
+			if !nextWatchedChange(ctx, sub) {
+				return
+			}
+		}
+	}()
+
+	return out.ch, nil
+}
+
+// nextWatchedChange waits for the next event, or the renewal of the subscription,
+// and discards the events already pending, coalescing them into a single snapshot.
+// It reports whether the watch goes on, false when ctx is done.
+func nextWatchedChange(ctx context.Context, sub *subscription) bool {
+	if _, err := sub.next(ctx); err != nil {
+		return false
+	}
+
+	sub.drain()
+
+	return true
+}
+
+func (ddb *Store) getNotifier() Notifier {
+	ddb.notifierOnce.Do(func() {
+		if ddb.notifier == nil {
+			ddb.notifier = newStreamNotifier(ddb, "", "")
+		}
+	})
+
+	return ddb.notifier
+}
@@ -0,0 +1,199 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchNotifier(t *testing.T) {
+	mock := &mockedGetItem{items: map[string]string{"testWatch": "d29ybGQ="}}
+	notifier := &chanNotifier{}
+
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		notifier:  notifier,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	events, err := kv.Watch(ctx, "testWatch", nil)
+	require.NoError(t, err)
+
+	pair := <-events
+	assert.Equal(t, []byte("world"), pair.Value)
+
+	// events on other keys are ignored.
+	notifier.publish(&Event{Key: "testWatchOther"})
+
+	mock.set("testWatch", "d29ybGQh")
+	notifier.publish(&Event{Key: "testWatch"})
+
+	pair = <-events
+	assert.Equal(t, []byte("world!"), pair.Value)
+
+	mock.set("testWatch", "")
+	notifier.publish(&Event{Key: "testWatch"})
+
+	pair = <-events
+	assert.Equal(t, &store.KVPair{Key: "testWatch"}, pair)
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("watch channel not closed")
+	}
+}
+
+func TestWatchOptions(t *testing.T) {
+	mock := &mockedGetItem{items: map[string]string{}}
+	notifier := &chanNotifier{}
+
+	kv := &Store{
+		dynamoSvc: mock,
+		tableName: TestTableName,
+		notifier:  notifier,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// the key doesn't need to exist without the initial value.
+	watchCtx, stop := context.WithCancel(ctx)
+	events, err := kv.WatchWithOptions(watchCtx, "testWatch", &WatchOptions{
+		ReadOptions: &store.ReadOptions{Consistent: false},
+		SkipInitial: true,
+	})
+	require.NoError(t, err)
+
+	mock.set("testWatch", "d29ybGQ=")
+	notifier.publish(&Event{Key: "testWatch"})
+
+	pair := <-events
+	assert.Equal(t, []byte("world"), pair.Value)
+	assert.False(t, mock.readConsistent())
+
+	stop()
+	for range events {
+	}
+
+	// the revisions already received are skipped, until the deletion.
+	resumed, err := kv.WatchWithOptions(ctx, "testWatch", &WatchOptions{AfterRevision: 1})
+	require.NoError(t, err)
+
+	notifier.publish(&Event{Key: "testWatch"})
+	mock.set("testWatch", "")
+	notifier.publish(&Event{Key: "testWatch"})
+
+	pair = <-resumed
+	assert.Equal(t, &store.KVPair{Key: "testWatch"}, pair)
+	assert.True(t, mock.readConsistent())
+}
+
+// chanNotifier is an in-memory Notifier.
+type chanNotifier struct {
+	mu          sync.Mutex
+	subscribers []chan *Event
+}
+
+func (n *chanNotifier) Subscribe(ctx context.Context, _ string) (<-chan *Event, error) {
+	events := make(chan *Event, 10)
+
+	n.mu.Lock()
+	n.subscribers = append(n.subscribers, events)
+	n.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		for i, sub := range n.subscribers {
+			if sub == events {
+				n.subscribers = append(n.subscribers[:i], n.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(events)
+	}()
+
+	return events, nil
+}
+
+func (n *chanNotifier) Publish(_ context.Context, event *Event) error {
+	n.publish(event)
+	return nil
+}
+
+func (n *chanNotifier) publish(event *Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, sub := range n.subscribers {
+		sub <- event
+	}
+}
+
+func (n *chanNotifier) Close() error { return nil }
+
+// mockedGetItem serves GetItem from in-memory base64 encoded values.
+type mockedGetItem struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]string
+	// consistent records the consistency of the last read.
+	consistent bool
+}
+
+func (m *mockedGetItem) set(key, encodedValue string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if encodedValue == "" {
+		delete(m.items, key)
+		return
+	}
+	m.items[key] = encodedValue
+}
+
+func (m *mockedGetItem) readConsistent() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.consistent
+}
+
+func (m *mockedGetItem) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := aws.StringValue(input.Key[partitionKey].S)
+	m.consistent = aws.BoolValue(input.ConsistentRead)
+
+	value, ok := m.items[key]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		partitionKey:          {S: aws.String(key)},
+		revisionAttribute:     {N: aws.String("1")},
+		encodedValueAttribute: {S: aws.String(value)},
+	}}, nil
+}
@@ -0,0 +1,120 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+)
+
+// defaultWatchQueueSize is the number of events queued by the notifier for a watch without BufferSize.
+const defaultWatchQueueSize = 1024
+
+// WatchOverflow is the policy of a watch whose buffer is full, the consumer being slower than the changes.
+// With the stream and the SNS notifiers, it also applies to the events queued by the notifier for the watch,
+// up to BufferSize events (1024 without BufferSize).
+type WatchOverflow int
+
+const (
+	// WatchOverflowBlock waits for the consumer.
+	// Once the events queued by the stream or the SNS notifier for the watch are full too,
+	// it holds back the notifier, and so all the watches of the store, until the consumer catches up.
+	WatchOverflowBlock WatchOverflow = iota
+	// WatchOverflowDropOldest drops the oldest value of the buffer, so the consumer receives the latest values,
+	// the intermediate ones being coalesced.
+	// The notifier drops the oldest events queued for the watch the same way.
+	WatchOverflowDropOldest
+)
+
+// watchQueue bounds the events queued by a notifier for a watch.
+type watchQueue struct {
+	size     int
+	overflow WatchOverflow
+}
+
+// defaultWatchQueue is the queue of the subscriptions without watch options, such as with Notifier.Subscribe.
+var defaultWatchQueue = watchQueue{size: defaultWatchQueueSize}
+
+func (o *WatchOptions) queue() watchQueue {
+	size := o.BufferSize
+	if size <= 0 {
+		size = defaultWatchQueueSize
+	}
+
+	return watchQueue{size: size, overflow: o.Overflow}
+}
+
+// queuedNotifier is a Notifier queuing the events of each subscriber, within the bounds of the watch.
+type queuedNotifier interface {
+	subscribeQueued(ctx context.Context, prefix string, queue watchQueue) (<-chan *Event, error)
+}
+
+// WatchLag is the delivery lag of a watch, reported to WatchOptions.OnLag each time a value is queued.
+type WatchLag struct {
+	// Key is the watched key, or directory.
+	Key string
+	// Wait is the time the value waited for room in the buffer.
+	Wait time.Duration
+	// Pending is the number of values in the buffer, not yet received by the consumer.
+	Pending int
+	// Dropped is the number of values dropped by WatchOverflowDropOldest since the start of the watch.
+	Dropped uint64
+}
+
+// watchOutput queues the values of a watch in its channel, applying the buffering options of the watch.
+type watchOutput[T any] struct {
+	ch      chan T
+	key     string
+	opts    *WatchOptions
+	dropped uint64
+}
+
+func newWatchOutput[T any](key string, opts *WatchOptions) *watchOutput[T] {
+	size := opts.BufferSize
+	if size < 0 {
+		size = 0
+	}
+
+	return &watchOutput[T]{
+		ch:   make(chan T, size),
+		key:  key,
+		opts: opts,
+	}
+}
+
+// send queues v, and reports whether it was queued, false when ctx is done.
+func (o *watchOutput[T]) send(ctx context.Context, v T) bool {
+	start := time.Now()
+
+	if o.opts.Overflow == WatchOverflowDropOldest && cap(o.ch) > 0 {
+		o.sendDropOldest(v)
+	} else {
+		select {
+		case o.ch <- v:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if o.opts.OnLag != nil {
+		o.opts.OnLag(&WatchLag{Key: o.key, Wait: time.Since(start), Pending: len(o.ch), Dropped: o.dropped})
+	}
+
+	return true
+}
+
+// sendDropOldest queues v, dropping the oldest values to make room for it.
+// The watch being the only sender, the loop ends once the consumer or the drop freed a slot.
+func (o *watchOutput[T]) sendDropOldest(v T) {
+	for {
+		select {
+		case o.ch <- v:
+			return
+		default:
+		}
+
+		select {
+		case <-o.ch:
+			o.dropped++
+		default:
+		}
+	}
+}
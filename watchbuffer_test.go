@@ -0,0 +1,43 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchOutput(t *testing.T) {
+	var lags []*WatchLag
+
+	out := newWatchOutput[int]("key", &WatchOptions{
+		BufferSize: 2,
+		Overflow:   WatchOverflowDropOldest,
+		OnLag:      func(lag *WatchLag) { lags = append(lags, lag) },
+	})
+
+	ctx := context.Background()
+
+	// the oldest values are dropped for the latest ones.
+	for i := 1; i <= 4; i++ {
+		assert.True(t, out.send(ctx, i))
+	}
+
+	assert.Equal(t, 3, <-out.ch)
+	assert.Equal(t, 4, <-out.ch)
+
+	assert.Len(t, lags, 4)
+	assert.Equal(t, "key", lags[3].Key)
+	assert.Equal(t, 2, lags[3].Pending)
+	assert.Equal(t, uint64(2), lags[3].Dropped)
+
+	// the full buffer blocks the watch until the context is done.
+	out = newWatchOutput[int]("key", &WatchOptions{BufferSize: 1})
+	assert.True(t, out.send(ctx, 1))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	assert.False(t, out.send(canceled, 2))
+	assert.Equal(t, 1, <-out.ch)
+}
@@ -0,0 +1,199 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// apiCall describes a DynamoDB call to the interceptors.
+type apiCall struct {
+	operation string
+	table     string
+	// key the key of the single item operations.
+	key string
+	// input the input of the call, such as *dynamodb.GetItemInput.
+	input interface{}
+}
+
+// callFunc runs a DynamoDB call, returning its output.
+type callFunc func(ctx aws.Context) (interface{}, error)
+
+// callInterceptor runs a DynamoDB call with next, adding a behavior around it.
+type callInterceptor func(ctx aws.Context, call *apiCall, next callFunc) (interface{}, error)
+
+// wrappedDynamoDB runs the calls of a DynamoDB client through interceptors.
+// The calls not used by the store are not intercepted.
+type wrappedDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	ddb *Store
+	// interceptors the interceptors, from the outermost.
+	interceptors []callInterceptor
+}
+
+// wrapCall runs invoke through the interceptors.
+func wrapCall[O any](w *wrappedDynamoDB, ctx aws.Context, call *apiCall, invoke func(aws.Context) (O, error)) (O, error) {
+	next := func(ctx aws.Context) (interface{}, error) {
+		return invoke(ctx)
+	}
+
+	for i := len(w.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := w.interceptors[i], next
+		next = func(ctx aws.Context) (interface{}, error) {
+			return interceptor(ctx, call, inner)
+		}
+	}
+
+	out, err := next(ctx)
+	output, _ := out.(O)
+
+	return output, err
+}
+
+func (w *wrappedDynamoDB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "GetItem", table: aws.StringValue(input.TableName), key: w.ddb.itemKey(input.Key), input: input}, func(ctx aws.Context) (*dynamodb.GetItemOutput, error) {
+		return w.DynamoDBAPI.GetItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "PutItem", table: aws.StringValue(input.TableName), key: w.ddb.itemKey(input.Item), input: input}, func(ctx aws.Context) (*dynamodb.PutItemOutput, error) {
+		return w.DynamoDBAPI.PutItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "UpdateItem", table: aws.StringValue(input.TableName), key: w.ddb.itemKey(input.Key), input: input}, func(ctx aws.Context) (*dynamodb.UpdateItemOutput, error) {
+		return w.DynamoDBAPI.UpdateItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "DeleteItem", table: aws.StringValue(input.TableName), key: w.ddb.itemKey(input.Key), input: input}, func(ctx aws.Context) (*dynamodb.DeleteItemOutput, error) {
+		return w.DynamoDBAPI.DeleteItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "Query", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.QueryOutput, error) {
+		return w.DynamoDBAPI.QueryWithContext(ctx, input, opts...)
+	})
+}
+
+// QueryPagesWithContext resumes the query after the last page handled, when the call is run again.
+// The interceptors see an output holding the total capacity consumed by the pages.
+func (w *wrappedDynamoDB) QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	resumed := input
+
+	_, err := wrapCall(w, ctx, &apiCall{operation: "Query", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.QueryOutput, error) {
+		// the output reports the capacity consumed by the pages read.
+		out := &dynamodb.QueryOutput{}
+
+		err := w.DynamoDBAPI.QueryPagesWithContext(ctx, resumed, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			out.ConsumedCapacity = addConsumedCapacity(out.ConsumedCapacity, page.ConsumedCapacity)
+			if page.LastEvaluatedKey != nil {
+				next := *input
+				next.ExclusiveStartKey = page.LastEvaluatedKey
+				resumed = &next
+			}
+			return fn(page, lastPage)
+		}, opts...)
+
+		return out, err
+	})
+
+	return err
+}
+
+func (w *wrappedDynamoDB) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "Scan", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.ScanOutput, error) {
+		return w.DynamoDBAPI.ScanWithContext(ctx, input, opts...)
+	})
+}
+
+// ScanPagesWithContext resumes the scan after the last page handled, when the call is run again.
+// The interceptors see an output holding the total capacity consumed by the pages.
+func (w *wrappedDynamoDB) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	resumed := input
+
+	_, err := wrapCall(w, ctx, &apiCall{operation: "Scan", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.ScanOutput, error) {
+		// the output reports the capacity consumed by the pages read.
+		out := &dynamodb.ScanOutput{}
+
+		err := w.DynamoDBAPI.ScanPagesWithContext(ctx, resumed, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			out.ConsumedCapacity = addConsumedCapacity(out.ConsumedCapacity, page.ConsumedCapacity)
+			if page.LastEvaluatedKey != nil {
+				next := *input
+				next.ExclusiveStartKey = page.LastEvaluatedKey
+				resumed = &next
+			}
+			return fn(page, lastPage)
+		}, opts...)
+
+		return out, err
+	})
+
+	return err
+}
+
+func (w *wrappedDynamoDB) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "BatchGetItem", table: w.ddb.tableName, input: input}, func(ctx aws.Context) (*dynamodb.BatchGetItemOutput, error) {
+		return w.DynamoDBAPI.BatchGetItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "BatchWriteItem", table: w.ddb.tableName, input: input}, func(ctx aws.Context) (*dynamodb.BatchWriteItemOutput, error) {
+		return w.DynamoDBAPI.BatchWriteItemWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) TransactGetItemsWithContext(ctx aws.Context, input *dynamodb.TransactGetItemsInput, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "TransactGetItems", table: w.ddb.tableName, input: input}, func(ctx aws.Context) (*dynamodb.TransactGetItemsOutput, error) {
+		return w.DynamoDBAPI.TransactGetItemsWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) TransactWriteItemsWithContext(ctx aws.Context, input *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "TransactWriteItems", table: w.ddb.tableName, input: input}, func(ctx aws.Context) (*dynamodb.TransactWriteItemsOutput, error) {
+		return w.DynamoDBAPI.TransactWriteItemsWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "CreateTable", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.CreateTableOutput, error) {
+		return w.DynamoDBAPI.CreateTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "DescribeTable", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.DescribeTableOutput, error) {
+		return w.DynamoDBAPI.DescribeTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) UpdateTableWithContext(ctx aws.Context, input *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "UpdateTable", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.UpdateTableOutput, error) {
+		return w.DynamoDBAPI.UpdateTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) DeleteTableWithContext(ctx aws.Context, input *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "DeleteTable", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.DeleteTableOutput, error) {
+		return w.DynamoDBAPI.DeleteTableWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) DescribeTimeToLiveWithContext(ctx aws.Context, input *dynamodb.DescribeTimeToLiveInput, opts ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "DescribeTimeToLive", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return w.DynamoDBAPI.DescribeTimeToLiveWithContext(ctx, input, opts...)
+	})
+}
+
+func (w *wrappedDynamoDB) UpdateTimeToLiveWithContext(ctx aws.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return wrapCall(w, ctx, &apiCall{operation: "UpdateTimeToLive", table: aws.StringValue(input.TableName), input: input}, func(ctx aws.Context) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		return w.DynamoDBAPI.UpdateTimeToLiveWithContext(ctx, input, opts...)
+	})
+}
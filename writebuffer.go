@@ -0,0 +1,311 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kvtools/valkeyrie/store"
+)
+
+const (
+	defaultWriteBufferFlushInterval = time.Second
+	defaultWriteBufferMaxPending    = maxBatchWriteItems
+
+	// writeBufferCloseTimeout bounds the retries of the pending writes of the open buffers by Store.Close.
+	writeBufferCloseTimeout = 30 * time.Second
+)
+
+// ErrWriteBufferClosed is returned when writing to a closed WriteBuffer.
+var ErrWriteBufferClosed = errors.New("write buffer closed")
+
+// WriteBufferConfig configures a WriteBuffer.
+type WriteBufferConfig struct {
+	// FlushInterval is the time between two flushes of the pending writes, defaults to 1 second.
+	FlushInterval time.Duration
+
+	// MaxPending is the number of pending keys flushed without waiting for the interval,
+	// defaults to the size of a write batch (25).
+	MaxPending int
+}
+
+// bufferedPut a pending write.
+type bufferedPut struct {
+	pair *store.KVPair
+	opts *store.WriteOptions
+}
+
+// WriteBuffer buffers the writes of a store, and flushes them like PutMany,
+// the successive writes of a key being coalesced in the last one.
+// Each pending key is written by an update conditioned on the revision read by the flush,
+// running through the middlewares of the store as an OperationPutMany.
+//
+// It trades the durability and the visibility of the writes for fewer write requests:
+// a buffered write is not visible to the reads until it's flushed, and it's lost if the process stops before.
+// The open buffers are closed by Store.Close.
+// The writes failed by a flush with a retryable error, or conflicting with a write of the key since it was read,
+// are retried by the next one, unless the key was written again to the buffer,
+// the writes failed with a permanent error, such as an invalid value, are dropped.
+type WriteBuffer struct {
+	ddb        *Store
+	maxPending int
+
+	mu      sync.Mutex
+	pending map[string]*bufferedPut
+	closed  bool
+
+	// flushMu serializes the flushes, so the writes of a key are applied in order.
+	flushMu sync.Mutex
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// NewWriteBuffer returns a WriteBuffer flushing its writes in the background, until closed, or the store is.
+// The buffers of a closed store are closed.
+func (ddb *Store) NewWriteBuffer(config *WriteBufferConfig) *WriteBuffer {
+	if config == nil {
+		config = &WriteBufferConfig{}
+	}
+
+	interval := config.FlushInterval
+	if interval <= 0 {
+		interval = defaultWriteBufferFlushInterval
+	}
+
+	b := &WriteBuffer{
+		ddb:        ddb,
+		maxPending: config.MaxPending,
+		pending:    make(map[string]*bufferedPut),
+		flushCh:    make(chan struct{}, 1),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+
+	if b.maxPending <= 0 {
+		b.maxPending = defaultWriteBufferMaxPending
+	}
+
+	if !ddb.writeBuffers.add(b) {
+		b.closed = true
+		close(b.doneCh)
+		return b
+	}
+
+	go b.run(interval)
+
+	return b
+}
+
+// run flushes the pending writes at each interval, or when there are too many, until the buffer is closed.
+func (b *WriteBuffer) run(interval time.Duration) {
+	defer close(b.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.flushCh:
+		case <-b.stopCh:
+			return
+		}
+
+		if err := b.Flush(context.Background()); err != nil {
+			b.ddb.log().Info("write buffer flush failed", "error", err)
+		}
+	}
+}
+
+// Put buffers the write of a value at key, replacing the pending write of the key, if any.
+// The key and the value are checked before being buffered, the value being encoded once to check its size.
+func (b *WriteBuffer) Put(key string, value []byte, opts *store.WriteOptions) error {
+	key = b.ddb.normalizeKey(key)
+
+	if err := b.ddb.checkKey(key); err != nil {
+		return err
+	}
+
+	if err := b.ddb.checkStoredValue(context.Background(), key, value); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrWriteBufferClosed
+	}
+
+	// the caller may reuse value.
+	value = append([]byte(nil), value...)
+	b.pending[key] = &bufferedPut{pair: &store.KVPair{Key: key, Value: value}, opts: opts}
+
+	if len(b.pending) >= b.maxPending {
+		select {
+		case b.flushCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Pending returns the number of keys with a pending write.
+func (b *WriteBuffer) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.pending)
+}
+
+// Flush writes the pending writes like PutMany.
+// If some keys are not written, a *BatchWriteError is returned,
+// and their writes stay pending if they failed with a retryable error, or conflicted with another write of the key.
+func (b *WriteBuffer) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	writes := b.pending
+	b.pending = make(map[string]*bufferedPut)
+	b.mu.Unlock()
+
+	if len(writes) == 0 {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(ctx, b.ddb.timeouts.Write)
+	defer cancel()
+
+	pairs := make([]*store.KVPair, 0, len(writes))
+	for _, write := range writes {
+		pairs = append(pairs, write.pair)
+	}
+
+	failed := make(map[string]error)
+
+	b.ddb.putPairs(ctx, OperationPutMany, pairs, func(pair *store.KVPair) *store.WriteOptions {
+		return writes[pair.Key].opts
+	}, failed)
+
+	b.requeue(writes, failed)
+
+	return batchError(failed)
+}
+
+// requeue makes the writes failed with a retryable error pending again, unless their key was written since.
+func (b *WriteBuffer) requeue(writes map[string]*bufferedPut, failed map[string]error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, write := range writes {
+		if err, ok := failed[key]; !ok || !isRetryableWrite(err) {
+			continue
+		}
+
+		if _, ok := b.pending[key]; !ok {
+			b.pending[key] = write
+		}
+	}
+}
+
+// Close stops the background flushes, and flushes the pending writes,
+// retrying the writes which stay pending until they are written, or ctx is done.
+// The writes still pending then are dropped, and the *BatchWriteError of the last flush names their keys.
+func (b *WriteBuffer) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stopCh)
+	<-b.doneCh
+
+	defer b.ddb.writeBuffers.remove(b)
+
+	err := b.Flush(ctx)
+	for attempt := 1; err != nil && b.Pending() > 0; attempt++ {
+		if sleepBackoff(ctx, attempt) != nil {
+			break
+		}
+
+		err = b.Flush(ctx)
+	}
+
+	// the buffer being closed, the writes still pending would never be flushed.
+	b.flushMu.Lock()
+	b.mu.Lock()
+	b.pending = make(map[string]*bufferedPut)
+	b.mu.Unlock()
+	b.flushMu.Unlock()
+
+	return err
+}
+
+// isRetryableWrite reports whether a write failed with err may succeed at the next flush:
+// a retryable error, a conflict with another write of the key, read again by the next flush,
+// or a flush interrupted by its context.
+func isRetryableWrite(err error) bool {
+	return IsRetryable(err) || errors.Is(err, store.ErrKeyModified) || errors.Is(err, store.ErrKeyExists) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// writeBuffers tracks the open write buffers of a store, flushed by Close.
+type writeBuffers struct {
+	mu      sync.Mutex
+	buffers map[*WriteBuffer]struct{}
+	closed  bool
+}
+
+// add registers b, and reports whether the store is still open.
+func (w *writeBuffers) add(b *WriteBuffer) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return false
+	}
+
+	if w.buffers == nil {
+		w.buffers = make(map[*WriteBuffer]struct{})
+	}
+	w.buffers[b] = struct{}{}
+
+	return true
+}
+
+// remove unregisters b, once closed.
+func (w *writeBuffers) remove(b *WriteBuffer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.buffers, b)
+}
+
+// close closes the open buffers, flushing their pending writes, and returns the first flush error.
+// The buffers created after are closed.
+func (w *writeBuffers) close(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	buffers := make([]*WriteBuffer, 0, len(w.buffers))
+	for b := range w.buffers {
+		buffers = append(buffers, b)
+	}
+	w.mu.Unlock()
+
+	var firstErr error
+	for _, b := range buffers {
+		if err := b.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,203 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kvtools/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	mock := &mockedBatchStore{items: map[string]map[string]*dynamodb.AttributeValue{}, failUpdates: 2, failBatch: -1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+
+	require.NoError(t, buffer.Put("a", []byte("1"), nil))
+	require.NoError(t, buffer.Put("a", []byte("2"), nil))
+	require.NoError(t, buffer.Put("b", []byte("1"), nil))
+	assert.Equal(t, 2, buffer.Pending())
+
+	// the failed writes stay pending.
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, buffer.Flush(ctx), &batchErr)
+	assert.Len(t, batchErr.Failed, 2)
+	assert.Equal(t, 2, buffer.Pending())
+
+	// the successive writes of a key are coalesced.
+	require.NoError(t, buffer.Flush(ctx))
+	assert.Zero(t, buffer.Pending())
+	assert.Len(t, mock.written, 2)
+	assert.Equal(t, "Mg==", aws.StringValue(mock.written["a"][encodedValueAttribute].S))
+
+	require.NoError(t, buffer.Close(ctx))
+	assert.ErrorIs(t, buffer.Put("a", []byte("3"), nil), ErrWriteBufferClosed)
+}
+
+func TestWriteBufferMaxPending(t *testing.T) {
+	mock := &mockedBatchStore{items: map[string]map[string]*dynamodb.AttributeValue{}, failBatch: -1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour, MaxPending: 3})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, buffer.Put(fmt.Sprintf("key%d", i), []byte("value"), nil))
+	}
+
+	// flushed without waiting for the interval.
+	assert.Eventually(t, func() bool { return buffer.Pending() == 0 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, buffer.Put("key3", []byte("value"), nil))
+	require.NoError(t, buffer.Close(context.Background()))
+	assert.Equal(t, 4, mock.updates)
+}
+
+func TestWriteBufferDistinctKeys(t *testing.T) {
+	mock := &mockedBatchStore{items: map[string]map[string]*dynamodb.AttributeValue{}, failBatch: -1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour, MaxPending: 100})
+	defer func() { require.NoError(t, buffer.Close(ctx)) }()
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, buffer.Put(fmt.Sprintf("key%02d", i), []byte("value"), nil))
+	}
+
+	// the revisions of the distinct keys are read in a batch, and each key updated.
+	require.NoError(t, buffer.Flush(ctx))
+	assert.Equal(t, 1, mock.reads)
+	assert.Equal(t, 30, mock.updates)
+	assert.Len(t, mock.written, 30)
+}
+
+func TestWriteBufferConflict(t *testing.T) {
+	mock := &mockedBatchStore{items: map[string]map[string]*dynamodb.AttributeValue{}, failBatch: -1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+
+	require.NoError(t, buffer.Put("a", []byte("1"), nil))
+
+	// "a" is written by another client between the read of its revision and its write.
+	mock.afterRead = func() {
+		assert.NoError(t, kv.Put(ctx, "a", []byte("concurrent"), nil))
+	}
+
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, buffer.Flush(ctx), &batchErr)
+	assert.ErrorIs(t, batchErr.Failed["a"], store.ErrKeyExists)
+	assert.Equal(t, 1, buffer.Pending())
+
+	// the write stays pending, and is written at the next revision by the next flush.
+	require.NoError(t, buffer.Flush(ctx))
+	assert.Equal(t, "MQ==", aws.StringValue(mock.written["a"][encodedValueAttribute].S))
+	assert.Equal(t, "2", aws.StringValue(mock.written["a"][revisionAttribute].N))
+	require.NoError(t, buffer.Close(ctx))
+}
+
+func TestWriteBufferCloseRetries(t *testing.T) {
+	mock := &mockedBatchStore{items: map[string]map[string]*dynamodb.AttributeValue{}, failUpdates: 2, failBatch: -1}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+	require.NoError(t, buffer.Put("a", []byte("1"), nil))
+	require.NoError(t, buffer.Put("b", []byte("1"), nil))
+
+	// the writes failed by the flush of Close are retried.
+	require.NoError(t, buffer.Close(context.Background()))
+	assert.Len(t, mock.written, 2)
+	assert.Equal(t, 4, mock.updates)
+
+	mock.failUpdates = 1000
+
+	buffer = kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+	require.NoError(t, buffer.Put("c", []byte("1"), nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// until ctx is done, the writes still pending being dropped.
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, buffer.Close(ctx), &batchErr)
+	assert.Contains(t, batchErr.Failed, "c")
+	assert.Greater(t, mock.updates, 5)
+	assert.Zero(t, buffer.Pending())
+}
+
+func TestWriteBufferInvalidPut(t *testing.T) {
+	kv := &Store{dynamoSvc: &mockedBatchStore{failBatch: -1}, tableName: TestTableName}
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+	defer func() { require.NoError(t, buffer.Close(context.Background())) }()
+
+	// the invalid writes are rejected instead of failing the next flush.
+	assert.ErrorIs(t, buffer.Put("", []byte("value"), nil), ErrEmptyKey)
+	assert.ErrorIs(t, buffer.Put("key", make([]byte, 300*1024), nil), ErrValueTooLarge)
+	assert.Zero(t, buffer.Pending())
+}
+
+func TestWriteBufferPermanentFailure(t *testing.T) {
+	mock := &mockedBatchStore{
+		items:       map[string]map[string]*dynamodb.AttributeValue{},
+		failUpdates: 1,
+		failBatch:   -1,
+		failErr:     awserr.New("ValidationException", "invalid item", nil),
+	}
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName}
+
+	ctx := context.Background()
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+
+	require.NoError(t, buffer.Put("a", []byte("1"), nil))
+
+	// the write failed with a permanent error is reported, and dropped.
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, buffer.Flush(ctx), &batchErr)
+	assert.Contains(t, batchErr.Failed, "a")
+	assert.Zero(t, buffer.Pending())
+
+	// it's not retried by the next flush.
+	require.NoError(t, buffer.Flush(ctx))
+	assert.Equal(t, 1, mock.updates)
+	require.NoError(t, buffer.Close(ctx))
+}
+
+func TestWriteBufferStoreClose(t *testing.T) {
+	mock := &mockedBatchStore{items: map[string]map[string]*dynamodb.AttributeValue{}, failBatch: -1}
+
+	var operations []string
+	record := func(next OperationFunc) OperationFunc {
+		return func(ctx context.Context, op *Operation) error {
+			operations = append(operations, op.Name)
+			return next(ctx, op)
+		}
+	}
+
+	kv := &Store{dynamoSvc: mock, tableName: TestTableName, notifier: &chanNotifier{}, middlewares: []Middleware{record}}
+
+	buffer := kv.NewWriteBuffer(&WriteBufferConfig{FlushInterval: time.Hour})
+	require.NoError(t, buffer.Put("a", []byte("1"), nil))
+
+	// the pending writes are flushed like PutMany by closing the store.
+	require.NoError(t, kv.Close())
+	assert.Contains(t, mock.written, "a")
+	assert.Equal(t, []string{OperationPutMany}, operations)
+	assert.ErrorIs(t, buffer.Put("a", []byte("2"), nil), ErrWriteBufferClosed)
+
+	// the buffers of a closed store are closed.
+	assert.ErrorIs(t, kv.NewWriteBuffer(nil).Put("a", []byte("2"), nil), ErrWriteBufferClosed)
+}